@@ -18,6 +18,7 @@ import (
 	"golinks/internal/jobs"
 	"golinks/internal/oidchealth"
 	"golinks/internal/server"
+	"golinks/internal/tracing"
 )
 
 func main() {
@@ -30,18 +31,26 @@ func main() {
 	// Validate configuration
 	cfg.Validate()
 
+	// Initialize tracing — a no-op unless an OTLP endpoint is configured
+	shutdownTracing, err := tracing.Init(ctx, cfg.TracingEnabled, cfg.TracingServiceName)
+	if err != nil {
+		slog.Error("failed to initialize tracing", "error", err)
+		os.Exit(1)
+	}
+
 	// Log startup configuration (omit secrets)
 	slog.Info("configuration loaded",
 		"env", cfg.Env,
 		"addr", cfg.ServerAddr,
 		"base_url", cfg.BaseURL,
 		"tls_enabled", cfg.TLSEnabled,
-		"oidc_issuer", cfg.OIDCIssuer,
+		"oidc_providers", len(cfg.OIDCProviders),
 		"personal_links", cfg.EnablePersonalLinks,
 		"org_links", cfg.EnableOrgLinks,
 		"simple_mode", cfg.IsSimpleMode(),
 		"smtp_enabled", cfg.IsEmailEnabled(),
 		"log_level", cfg.LogLevel,
+		"tracing_enabled", cfg.TracingEnabled,
 	)
 
 	// Wait for the database to become available before proceeding.
@@ -61,6 +70,11 @@ func main() {
 	}
 	slog.Info("migrations completed successfully")
 
+	if err := database.SetOrgResolutionOrder(cfg.OrgResolutionOrder); err != nil {
+		slog.Error("invalid ORG_RESOLUTION_ORDER", "error", err)
+		os.Exit(1)
+	}
+
 	// Sync fallback redirect options from config
 	if len(cfg.RedirectFallbacks) > 0 {
 		if err := database.SyncFallbackRedirects(ctx, cfg.RedirectFallbacks); err != nil {
@@ -83,6 +97,7 @@ func main() {
 	if cfg.RedisURL != "" {
 		if opt, err := redis.ParseURL(cfg.EffectiveRedisURL()); err == nil {
 			rdb := redis.NewClient(opt)
+			rdb.AddHook(tracing.RedisHook())
 			defer rdb.Close()
 			database.AttachRedis(rdb)
 			slog.Info("click deduplication: redis", "url", cfg.RedisURL)
@@ -98,10 +113,17 @@ func main() {
 	// Create server
 	srv := server.New(cfg)
 
-	// Background probe of the OIDC issuer so the redirect path can fall back
-	// to global-only resolution when the auth server is unreachable.
-	oidcProbe := oidchealth.New(cfg.OIDCIssuer)
-	go oidcProbe.Start(ctx)
+	// Background probe of the primary OIDC issuer so the redirect path can fall
+	// back to global-only resolution when the auth server is unreachable. With
+	// proxy authentication only there is no issuer and the probe stays healthy.
+	var oidcIssuer string
+	if p := cfg.PrimaryOIDCProvider(); p != nil {
+		oidcIssuer = p.Issuer
+	}
+	oidcProbe := oidchealth.New(oidcIssuer)
+	if oidcIssuer != "" {
+		go oidcProbe.Start(ctx)
+	}
 
 	// Register routes
 	if err := srv.RegisterRoutes(ctx, database, oidcProbe); err != nil {
@@ -116,6 +138,30 @@ func main() {
 	healthChecker := jobs.NewHealthChecker(database, 1*time.Hour, 24*time.Hour)
 	go healthChecker.Start(ctx)
 
+	// Start background retention job — rolls up click history and prunes stale rows
+	retentionJob := jobs.NewRetentionJob(database, time.Duration(cfg.RetentionIntervalHours)*time.Hour, jobs.RetentionPolicy{
+		ClickHistoryAge:     days(cfg.ClickHistoryRetentionDays),
+		KeywordLookupAge:    days(cfg.KeywordLookupRetentionDays),
+		KeywordLookupMax:    cfg.KeywordLookupMaxNotFound,
+		ReadNotificationAge: days(cfg.ReadNotificationRetentionDays),
+		RejectedLinkAge:     days(cfg.RejectedLinkRetentionDays),
+		EditRequestAge:      days(cfg.EditRequestRetentionDays),
+		ExpiredSessionAge:   24 * time.Hour,
+	})
+	go retentionJob.Start(ctx)
+
+	// Start background inactive user job — deactivates accounts unused for too long
+	if cfg.InactiveUserDeactivationDays > 0 {
+		inactiveUserJob := jobs.NewInactiveUserJob(database, time.Duration(cfg.RetentionIntervalHours)*time.Hour, days(cfg.InactiveUserDeactivationDays))
+		go inactiveUserJob.Start(ctx)
+	}
+
+	// Start background keyword metrics refresh — exports only the top-N keywords
+	if cfg.MetricsTopKeywords > 0 {
+		keywordMetricsJob := jobs.NewKeywordMetricsJob(database, time.Duration(cfg.MetricsTopKeywordsIntervalSecs)*time.Second, cfg.MetricsTopKeywords)
+		go keywordMetricsJob.Start(ctx)
+	}
+
 	// Start server
 	go func() {
 		if err := srv.Start(); err != nil {
@@ -142,6 +188,9 @@ func main() {
 	}
 	slog.Info("server exited")
 	database.FlushWriteBuffer(shutdownCtx)
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Warn("failed to flush traces", "error", err)
+	}
 }
 
 // waitForDB retries connecting to the database until it succeeds or the timeout
@@ -172,6 +221,15 @@ func waitForDB(ctx context.Context, connString string, timeout time.Duration, ma
 	}
 }
 
+// days converts a day count from config into a duration. Non-positive values
+// map to zero, which disables the corresponding retention task.
+func days(n int) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	return time.Duration(n) * 24 * time.Hour
+}
+
 // initLogger configures the default slog logger with the given level.
 func initLogger(level string) {
 	var logLevel slog.Level
@@ -189,5 +247,6 @@ func initLogger(level string) {
 	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
 		Level: logLevel,
 	})
-	slog.SetDefault(slog.New(handler))
+	// Records logged with a request or job context carry its trace ID.
+	slog.SetDefault(slog.New(tracing.NewLogHandler(handler)))
 }
@@ -0,0 +1,255 @@
+// Package analytics builds click statistics for links from the hourly and
+// rolled-up daily click history tables.
+package analytics
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"golinks/internal/db"
+	"golinks/internal/models"
+)
+
+// Supported ranges and granularities.
+const (
+	Range7d  = "7d"
+	Range30d = "30d"
+	Range90d = "90d"
+	Range1y  = "1y"
+
+	GranularityDaily  = "daily"
+	GranularityWeekly = "weekly"
+)
+
+// rangeDays maps each supported range to its length in days.
+var rangeDays = map[string]int{
+	Range7d:  7,
+	Range30d: 30,
+	Range90d: 90,
+	Range1y:  365,
+}
+
+// Ranges lists the supported ranges in display order.
+var Ranges = []string{Range7d, Range30d, Range90d, Range1y}
+
+var (
+	ErrInvalidRange       = errors.New("range must be one of 7d, 30d, 90d, 1y")
+	ErrInvalidGranularity = errors.New("granularity must be daily or weekly")
+	ErrInvalidScope       = errors.New("scope must be one of global, org, all")
+	ErrNoOrganization     = errors.New("no organization selected")
+	ErrForbiddenOrg       = errors.New("not allowed to view this organization")
+)
+
+// Window is a half-open [From, To) range of whole UTC days together with the
+// equally long period immediately before it, used for trend comparison.
+type Window struct {
+	Range       string
+	Granularity string
+	PrevFrom    time.Time
+	From        time.Time
+	To          time.Time
+}
+
+// NewWindow validates the range and granularity and returns the window ending
+// with (and including) the current UTC day. Empty values default to 30d and
+// daily; the 1y range defaults to weekly buckets.
+func NewWindow(rangeStr, granularity string, now time.Time) (Window, error) {
+	if rangeStr == "" {
+		rangeStr = Range30d
+	}
+	days, ok := rangeDays[rangeStr]
+	if !ok {
+		return Window{}, ErrInvalidRange
+	}
+	if granularity == "" {
+		granularity = GranularityDaily
+		if rangeStr == Range1y {
+			granularity = GranularityWeekly
+		}
+	}
+	if granularity != GranularityDaily && granularity != GranularityWeekly {
+		return Window{}, ErrInvalidGranularity
+	}
+
+	to := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -days)
+	return Window{
+		Range:       rangeStr,
+		Granularity: granularity,
+		PrevFrom:    from.AddDate(0, 0, -days),
+		From:        from,
+		To:          to,
+	}, nil
+}
+
+// BuildSeries buckets daily totals falling inside [w.From, w.To) into a
+// zero-filled series. Weekly buckets are consecutive 7-day spans starting at
+// w.From, so the last bucket may be shorter.
+func BuildSeries(daily []models.DailyClicks, w Window) []models.ClickPoint {
+	step := 1
+	if w.Granularity == GranularityWeekly {
+		step = 7
+	}
+	days := int(w.To.Sub(w.From).Hours() / 24)
+	series := make([]models.ClickPoint, 0, (days+step-1)/step)
+	for i := 0; i < days; i += step {
+		series = append(series, models.ClickPoint{Start: w.From.AddDate(0, 0, i)})
+	}
+	for _, dc := range daily {
+		day := dc.Day.UTC()
+		if day.Before(w.From) || !day.Before(w.To) {
+			continue
+		}
+		idx := int(day.Sub(w.From).Hours()/24) / step
+		series[idx].Clicks += dc.Clicks
+	}
+	return series
+}
+
+// Trend returns the percentage change from previous to current, or nil when
+// there is nothing to compare against.
+func Trend(current, previous int64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	pct := float64(current-previous) / float64(previous) * 100
+	return &pct
+}
+
+// ForLink loads click analytics for a single link over the given window.
+func ForLink(ctx context.Context, database *db.DB, link *models.Link, w Window) (*models.LinkStats, error) {
+	daily, err := database.GetLinkDailyClicks(ctx, link.ID, w.PrevFrom, w.To)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.LinkStats{
+		LinkID:      link.ID,
+		Keyword:     link.Keyword,
+		Range:       w.Range,
+		Granularity: w.Granularity,
+		From:        w.From,
+		To:          w.To,
+		Series:      BuildSeries(daily, w),
+	}
+	for _, dc := range daily {
+		if dc.Day.Before(w.From) {
+			stats.PreviousTotal += dc.Clicks
+		} else {
+			stats.Total += dc.Clicks
+		}
+	}
+	stats.TrendPercent = Trend(stats.Total, stats.PreviousTotal)
+	return stats, nil
+}
+
+// TopLinks loads the top-N leaderboard for the given scope over the window,
+// leaving out links hidden from viewerID. See db.GetTopLinksByClicks for the
+// scope values.
+func TopLinks(ctx context.Context, database *db.DB, viewerID, orgID *uuid.UUID, scope string, w Window, limit int) ([]models.TopLinkStat, error) {
+	top, err := database.GetTopLinksByClicks(ctx, viewerID, orgID, scope, w.PrevFrom, w.From, w.To, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range top {
+		top[i].TrendPercent = Trend(top[i].Clicks, top[i].PreviousClicks)
+	}
+	return top, nil
+}
+
+// OrgForScope resolves which organization a top-N view should cover.
+// requested is an optional organization ID; admins and global moderators may
+// pick any organization, everyone else is limited to their own. The "global"
+// scope needs no organization and returns nil.
+func OrgForScope(user *models.User, scope, requested string) (*uuid.UUID, error) {
+	switch scope {
+	case models.ScopeGlobal:
+		return nil, nil
+	case "", "all", models.ScopeOrg:
+	default:
+		return nil, ErrInvalidScope
+	}
+
+	if requested != "" {
+		id, err := uuid.Parse(requested)
+		if err != nil {
+			return nil, ErrNoOrganization
+		}
+		if user.OrganizationID != nil && *user.OrganizationID == id {
+			return &id, nil
+		}
+		if !user.IsGlobalMod() {
+			return nil, ErrForbiddenOrg
+		}
+		return &id, nil
+	}
+	if user.OrganizationID == nil && scope == models.ScopeOrg {
+		return nil, ErrNoOrganization
+	}
+	return user.OrganizationID, nil
+}
+
+// CanView reports whether a user may see analytics for a link. Approved
+// global links are visible to everyone; approved org links to members and
+// moderators of that org; anything else only to moderators and the author.
+func CanView(user *models.User, link *models.Link) bool {
+	if user == nil {
+		return false
+	}
+	if user.IsGlobalMod() {
+		return true
+	}
+	if link.IsApproved() {
+		if link.Scope == models.ScopeGlobal {
+			return true
+		}
+		if link.OrganizationID != nil {
+			if user.OrganizationID != nil && *user.OrganizationID == *link.OrganizationID {
+				return true
+			}
+			if user.CanModerateOrg(*link.OrganizationID) {
+				return true
+			}
+		}
+	}
+	return link.CreatedBy != nil && *link.CreatedBy == user.ID
+}
+
+// WriteTopLinksCSV writes a leaderboard as CSV. orgNames maps organization IDs
+// to display names for the organization column.
+func WriteTopLinksCSV(w io.Writer, top []models.TopLinkStat, orgNames map[string]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"rank", "keyword", "url", "scope", "organization", "clicks", "previous_clicks", "trend_percent"}); err != nil {
+		return err
+	}
+	for i, s := range top {
+		org := ""
+		if s.OrganizationID != nil {
+			org = orgNames[s.OrganizationID.String()]
+		}
+		trend := ""
+		if s.TrendPercent != nil {
+			trend = strconv.FormatFloat(*s.TrendPercent, 'f', 1, 64)
+		}
+		if err := cw.Write([]string{
+			strconv.Itoa(i + 1),
+			s.Keyword,
+			s.URL,
+			s.Scope,
+			org,
+			strconv.FormatInt(s.Clicks, 10),
+			strconv.FormatInt(s.PreviousClicks, 10),
+			trend,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
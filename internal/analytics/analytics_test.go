@@ -0,0 +1,226 @@
+package analytics
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"golinks/internal/models"
+)
+
+var testNow = time.Date(2026, 3, 10, 15, 30, 0, 0, time.UTC)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestNewWindow(t *testing.T) {
+	tests := []struct {
+		name            string
+		rangeStr        string
+		granularity     string
+		wantRange       string
+		wantGranularity string
+		wantFrom        time.Time
+		wantErr         error
+	}{
+		{"defaults", "", "", Range30d, GranularityDaily, day(2026, 2, 9), nil},
+		{"7d", Range7d, "", Range7d, GranularityDaily, day(2026, 3, 4), nil},
+		{"1y defaults to weekly", Range1y, "", Range1y, GranularityWeekly, day(2025, 3, 11), nil},
+		{"1y daily", Range1y, GranularityDaily, Range1y, GranularityDaily, day(2025, 3, 11), nil},
+		{"invalid range", "2w", "", "", "", time.Time{}, ErrInvalidRange},
+		{"invalid granularity", Range7d, "hourly", "", "", time.Time{}, ErrInvalidGranularity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := NewWindow(tt.rangeStr, tt.granularity, testNow)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewWindow() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if w.Range != tt.wantRange || w.Granularity != tt.wantGranularity {
+				t.Errorf("NewWindow() = %s/%s, want %s/%s", w.Range, w.Granularity, tt.wantRange, tt.wantGranularity)
+			}
+			if !w.To.Equal(day(2026, 3, 11)) {
+				t.Errorf("To = %v, want end of current day", w.To)
+			}
+			if !w.From.Equal(tt.wantFrom) {
+				t.Errorf("From = %v, want %v", w.From, tt.wantFrom)
+			}
+			if !w.PrevFrom.Equal(w.From.Add(-w.To.Sub(w.From))) {
+				t.Errorf("PrevFrom = %v, want a period as long as the current one", w.PrevFrom)
+			}
+		})
+	}
+}
+
+func TestBuildSeriesDaily(t *testing.T) {
+	w, _ := NewWindow(Range7d, GranularityDaily, testNow)
+	series := BuildSeries([]models.DailyClicks{
+		{Day: day(2026, 3, 1), Clicks: 100}, // previous period, ignored
+		{Day: day(2026, 3, 4), Clicks: 3},
+		{Day: day(2026, 3, 10), Clicks: 5},
+	}, w)
+
+	if len(series) != 7 {
+		t.Fatalf("len(series) = %d, want 7", len(series))
+	}
+	want := []int64{3, 0, 0, 0, 0, 0, 5}
+	for i, p := range series {
+		if p.Clicks != want[i] {
+			t.Errorf("series[%d].Clicks = %d, want %d", i, p.Clicks, want[i])
+		}
+		if !p.Start.Equal(w.From.AddDate(0, 0, i)) {
+			t.Errorf("series[%d].Start = %v, want %v", i, p.Start, w.From.AddDate(0, 0, i))
+		}
+	}
+}
+
+func TestBuildSeriesWeekly(t *testing.T) {
+	w, _ := NewWindow(Range30d, GranularityWeekly, testNow)
+	series := BuildSeries([]models.DailyClicks{
+		{Day: w.From, Clicks: 1},
+		{Day: w.From.AddDate(0, 0, 6), Clicks: 2},
+		{Day: w.From.AddDate(0, 0, 7), Clicks: 4},
+		{Day: w.To.AddDate(0, 0, -1), Clicks: 8},
+	}, w)
+
+	// 30 days = four full weeks plus a 2-day tail.
+	if len(series) != 5 {
+		t.Fatalf("len(series) = %d, want 5", len(series))
+	}
+	want := []int64{3, 4, 0, 0, 8}
+	for i, p := range series {
+		if p.Clicks != want[i] {
+			t.Errorf("series[%d].Clicks = %d, want %d", i, p.Clicks, want[i])
+		}
+	}
+}
+
+func TestTrend(t *testing.T) {
+	if got := Trend(10, 0); got != nil {
+		t.Errorf("Trend(10, 0) = %v, want nil", *got)
+	}
+	if got := Trend(15, 10); got == nil || *got != 50 {
+		t.Errorf("Trend(15, 10) = %v, want 50", got)
+	}
+	if got := Trend(5, 10); got == nil || *got != -50 {
+		t.Errorf("Trend(5, 10) = %v, want -50", got)
+	}
+}
+
+func TestCanView(t *testing.T) {
+	orgA := uuid.New()
+	orgB := uuid.New()
+	author := uuid.New()
+
+	member := &models.User{ID: uuid.New(), Role: models.RoleUser, OrganizationID: &orgA}
+	outsider := &models.User{ID: uuid.New(), Role: models.RoleUser, OrganizationID: &orgB}
+	orgMod := &models.User{ID: uuid.New(), Role: models.RoleOrgMod, OrganizationID: &orgA}
+	globalMod := &models.User{ID: uuid.New(), Role: models.RoleGlobalMod}
+	authorUser := &models.User{ID: author, Role: models.RoleUser}
+
+	globalLink := &models.Link{Scope: models.ScopeGlobal, Status: models.StatusApproved}
+	orgLink := &models.Link{Scope: models.ScopeOrg, OrganizationID: &orgA, Status: models.StatusApproved}
+	pending := &models.Link{Scope: models.ScopeGlobal, Status: models.StatusPending, CreatedBy: &author}
+
+	tests := []struct {
+		name string
+		user *models.User
+		link *models.Link
+		want bool
+	}{
+		{"nil user", nil, globalLink, false},
+		{"anyone sees approved global", outsider, globalLink, true},
+		{"member sees org link", member, orgLink, true},
+		{"org mod sees org link", orgMod, orgLink, true},
+		{"outsider cannot see org link", outsider, orgLink, false},
+		{"global mod sees org link", globalMod, orgLink, true},
+		{"author sees pending link", authorUser, pending, true},
+		{"others cannot see pending link", outsider, pending, false},
+		{"global mod sees pending link", globalMod, pending, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanView(tt.user, tt.link); got != tt.want {
+				t.Errorf("CanView() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrgForScope(t *testing.T) {
+	orgA := uuid.New()
+	orgB := uuid.New()
+	member := &models.User{ID: uuid.New(), Role: models.RoleUser, OrganizationID: &orgA}
+	noOrg := &models.User{ID: uuid.New(), Role: models.RoleUser}
+	admin := &models.User{ID: uuid.New(), Role: models.RoleAdmin}
+
+	tests := []struct {
+		name      string
+		user      *models.User
+		scope     string
+		requested string
+		want      *uuid.UUID
+		wantErr   error
+	}{
+		{"global needs no org", member, "global", "", nil, nil},
+		{"org defaults to own org", member, "org", "", &orgA, nil},
+		{"all includes own org", member, "all", "", &orgA, nil},
+		{"all without org", noOrg, "all", "", nil, nil},
+		{"org without org", noOrg, "org", "", nil, ErrNoOrganization},
+		{"own org explicitly", member, "org", orgA.String(), &orgA, nil},
+		{"other org forbidden", member, "org", orgB.String(), nil, ErrForbiddenOrg},
+		{"admin picks any org", admin, "org", orgB.String(), &orgB, nil},
+		{"malformed org", admin, "org", "nope", nil, ErrNoOrganization},
+		{"invalid scope", member, "personal", "", nil, ErrInvalidScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := OrgForScope(tt.user, tt.scope, tt.requested)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("OrgForScope() error = %v, want %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("OrgForScope() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteTopLinksCSV(t *testing.T) {
+	org := uuid.New()
+	trend := 25.0
+	top := []models.TopLinkStat{
+		{Keyword: "docs", URL: "https://docs.example.com", Scope: models.ScopeGlobal, Clicks: 50, PreviousClicks: 40, TrendPercent: &trend},
+		{Keyword: "wiki", URL: "https://wiki.example.com/a,b", Scope: models.ScopeOrg, OrganizationID: &org, Clicks: 10},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTopLinksCSV(&buf, top, map[string]string{org.String(): "Engineering"}); err != nil {
+		t.Fatalf("WriteTopLinksCSV() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+	if lines[0] != "rank,keyword,url,scope,organization,clicks,previous_clicks,trend_percent" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if lines[1] != "1,docs,https://docs.example.com,global,,50,40,25.0" {
+		t.Errorf("row 1 = %q", lines[1])
+	}
+	if lines[2] != `2,wiki,"https://wiki.example.com/a,b",org,Engineering,10,0,` {
+		t.Errorf("row 2 = %q", lines[2])
+	}
+}
@@ -2,11 +2,13 @@ package config
 
 import (
 	"log/slog"
+	"net/netip"
 	"net/url"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
+
+	"golinks/internal/validation"
 )
 
 // Config holds all application configuration loaded from environment variables.
@@ -30,7 +32,22 @@ type Config struct {
 	// Client cert via header (for ingress-terminated TLS)
 	ClientCertHeader string // Header name containing client cert CN, e.g. "X-Client-CN"
 
-	// OIDC
+	// Trusted reverse-proxy authentication; see ProxyAuthEnabled
+	ProxyAuthUserHeader   string         // env: PROXY_AUTH_USER_HEADER, e.g. "X-Forwarded-User"; empty disables proxy auth
+	ProxyAuthEmailHeader  string         // env: PROXY_AUTH_EMAIL_HEADER, default "X-Forwarded-Email"
+	ProxyAuthGroupsHeader string         // env: PROXY_AUTH_GROUPS_HEADER, default "X-Forwarded-Groups" (comma-separated)
+	ProxyAuthOrgHeader    string         // env: PROXY_AUTH_ORG_HEADER, optional organization slug header
+	ProxyAuthTrustedCIDRs []netip.Prefix // env: PROXY_AUTH_TRUSTED_CIDRS, proxy addresses allowed to set the headers
+	ProxyAuthIssuer       string         // env: PROXY_AUTH_ISSUER, default "proxy"; issuer recorded for proxy-authenticated users
+
+	// SCIM 2.0 provisioning at /scim/v2; see SCIMEnabled
+	SCIMBearerToken    string // env: SCIM_BEARER_TOKEN; empty disables SCIM
+	SCIMIssuer         string // env: SCIM_ISSUER, issuer provisioned users sign in with; default the primary OIDC issuer
+	SCIMOrgGroupPrefix string // env: SCIM_ORG_GROUP_PREFIX, default "org:"; groups "<prefix><slug>" map to organizations
+
+	// OIDC. The single-provider settings below describe the "default" provider
+	// unless OIDC_PROVIDERS lists providers; OIDCProviders holds the result.
+	OIDCProviders    []OIDCProvider // env: OIDC_PROVIDERS, comma-separated provider names configured via OIDC_<NAME>_* vars
 	OIDCIssuer       string
 	OIDCClientID     string
 	OIDCClientSecret string
@@ -39,6 +56,9 @@ type Config struct {
 	OIDCGroupsClaim     string   // OIDC claim name for group memberships (default: "groups")
 	OIDCAdminGroups     []string // OIDC groups that grant the admin role
 	OIDCModeratorGroups []string // OIDC groups that grant the moderator role (org_mod when user has an org, global_mod otherwise)
+	OIDCOfflineAccess   bool     // env: OIDC_OFFLINE_ACCESS, request the offline_access scope so the provider issues refresh tokens
+	OIDCRefreshMins     int      // env: OIDC_REFRESH_INTERVAL_MINUTES, default 15; how often a session's refresh token re-validates the user (0 = sessions end after 24h)
+	OIDCRPLogout        bool     // env: OIDC_RP_LOGOUT, default true; send /auth/logout on to the provider's end_session_endpoint
 
 	// Database pool
 	DBPoolMaxConns int32 // env: DB_POOL_MAX_CONNS, default 10
@@ -47,6 +67,7 @@ type Config struct {
 	// Session
 	SessionSecret  string // Used for signing cookies (min 32 chars)
 	SessionStore   string // "memory" (default) or "redis"
+	SessionMaxLifetimeHours int // env: SESSION_MAX_LIFETIME_HOURS, default 168; hard cap for sessions extended by refresh tokens
 	RedisURL       string // Redis/Dragonfly connection URL, e.g. "redis://localhost:6379"
 	RedisUsername  string // Redis ACL username (optional)
 	RedisPassword  string // Redis password (optional; kept separate so it can come from a Secret)
@@ -58,6 +79,7 @@ type Config struct {
 	EnableRandomKeywords bool // Enable random keywords section and "I'm Feeling Lucky" feature
 	EnablePersonalLinks  bool // Enable personal link scopes (requires auth)
 	EnableOrgLinks       bool // Enable organization link scopes (requires auth)
+	OrgResolutionOrder   string // env: ORG_RESOLUTION_ORDER, default "active" — which of a user's organizations wins a keyword: "active", "joined" or "name"
 
 	// Fallback Redirects
 	RedirectFallbacks map[string]string // Map of org slug to fallback redirect URL, e.g. {"org1": "https://other.com/go/"}
@@ -74,9 +96,47 @@ type Config struct {
 	BannerTextColor string // env: BANNER_TEXT_COLOR, default: "#ffffff"
 	BannerBGColor   string // env: BANNER_BG_COLOR, default: "#0891b2" (brand-600)
 
+	// Content-Security-Policy (extra sources are appended to the built-in policy)
+	CSPReportOnly     bool     // env: CSP_REPORT_ONLY, default false — send Content-Security-Policy-Report-Only instead of enforcing
+	CSPImgSources     []string // env: CSP_IMG_SOURCES, comma-separated, e.g. "https://cdn.example.com" for a logo CDN
+	CSPScriptSources  []string // env: CSP_SCRIPT_SOURCES, comma-separated
+	CSPStyleSources   []string // env: CSP_STYLE_SOURCES, comma-separated
+	CSPFontSources    []string // env: CSP_FONT_SOURCES, comma-separated
+	CSPConnectSources []string // env: CSP_CONNECT_SOURCES, comma-separated
+
 	// Logging
 	LogLevel string // "debug", "info", "warn", "error" (default: "info")
 
+	// Data Retention (0 disables the corresponding task)
+	RetentionIntervalHours        int // env: RETENTION_INTERVAL_HOURS, default 24
+	ClickHistoryRetentionDays     int // env: CLICK_HISTORY_RETENTION_DAYS, default 30 — hourly buckets older than this roll up into daily totals
+	KeywordLookupRetentionDays    int // env: KEYWORD_LOOKUP_RETENTION_DAYS, default 90 — not_found lookups unseen for this long are deleted
+	KeywordLookupMaxNotFound      int // env: KEYWORD_LOOKUP_MAX_NOT_FOUND, default 10000 — cap on retained not_found keywords
+	ReadNotificationRetentionDays int // env: READ_NOTIFICATION_RETENTION_DAYS, default 30
+	RejectedLinkRetentionDays     int // env: REJECTED_LINK_RETENTION_DAYS, default 90
+	EditRequestRetentionDays      int // env: EDIT_REQUEST_RETENTION_DAYS, default 90 — reviewed edit requests only
+
+	// Inactive users
+	InactiveUserDeactivationDays int // env: INACTIVE_USER_DEACTIVATION_DAYS, default 0 (disabled) — non-admins inactive this long are deactivated
+
+	// Tracing (OTLP exporter settings are read from the standard OTEL_* variables)
+	TracingEnabled     bool   // true when OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set and OTEL_SDK_DISABLED is not "true"
+	TracingServiceName string // env: OTEL_SERVICE_NAME, default "golinks"
+
+	// Metrics
+	MetricsTopKeywords             int // env: METRICS_TOP_KEYWORDS, default 20 — keywords per outcome exported as golinks_top_keyword_lookups (0 disables)
+	MetricsTopKeywordsIntervalSecs int // env: METRICS_TOP_KEYWORDS_INTERVAL_SECONDS, default 60
+
+	// Rate Limiting (requests per window per client; shared across replicas when SESSION_STORE=redis; 0 disables a policy)
+	RateLimitWindowSecs     int                       // env: RATE_LIMIT_WINDOW_SECONDS, default 60
+	RateLimitRedirect       int                       // env: RATE_LIMIT_REDIRECT, default 300 — /go/:keyword and /random
+	RateLimitSearch         int                       // env: RATE_LIMIT_SEARCH, default 120 — search, suggestions and keyword checks
+	RateLimitWrite          int                       // env: RATE_LIMIT_WRITE, default 60 — UI POST/PUT/PATCH/DELETE
+	RateLimitAPI            int                       // env: RATE_LIMIT_API, default 300 — /api/*
+	RateLimitDefault        int                       // env: RATE_LIMIT_DEFAULT, default 100 — all other pages
+	RateLimitRoleOverrides  map[string]map[string]int // env: RATE_LIMIT_ROLE_OVERRIDES, default "org_mod:write=300,global_mod:write=300,admin:write=300"
+	RateLimitTokenOverrides map[string]map[string]int // env: RATE_LIMIT_TOKEN_OVERRIDES, keyed by bearer token SHA-256 (hex), e.g. "<sha256>:api=5000"
+
 	// SMTP Email Configuration
 	SMTPEnabled  bool   // Enable email notifications
 	SMTPHost     string // SMTP server hostname
@@ -97,7 +157,7 @@ type Config struct {
 
 // Load reads configuration from environment variables with sensible defaults.
 func Load() *Config {
-	return &Config{
+	cfg := &Config{
 		Env:              getEnv("ENV", "production"),
 		ServerAddr:       getEnv("SERVER_ADDR", ":3000"),
 		BaseURL:          getEnv("BASE_URL", "http://localhost:3000"),
@@ -107,6 +167,15 @@ func Load() *Config {
 		TLSKeyFile:       getEnv("TLS_KEY_FILE", ""),
 		TLSCAFile:        getEnv("TLS_CA_FILE", ""),
 		ClientCertHeader: getEnv("CLIENT_CERT_HEADER", ""),
+		ProxyAuthUserHeader:   getEnv("PROXY_AUTH_USER_HEADER", ""),
+		ProxyAuthEmailHeader:  getEnv("PROXY_AUTH_EMAIL_HEADER", "X-Forwarded-Email"),
+		ProxyAuthGroupsHeader: getEnv("PROXY_AUTH_GROUPS_HEADER", "X-Forwarded-Groups"),
+		ProxyAuthOrgHeader:    getEnv("PROXY_AUTH_ORG_HEADER", ""),
+		ProxyAuthTrustedCIDRs: parseCIDRs("PROXY_AUTH_TRUSTED_CIDRS", getEnv("PROXY_AUTH_TRUSTED_CIDRS", "")),
+		ProxyAuthIssuer:       getEnv("PROXY_AUTH_ISSUER", "proxy"),
+		SCIMBearerToken:       getEnv("SCIM_BEARER_TOKEN", ""),
+		SCIMIssuer:            getEnv("SCIM_ISSUER", ""),
+		SCIMOrgGroupPrefix:    getEnv("SCIM_ORG_GROUP_PREFIX", "org:"),
 		OIDCIssuer:       getEnv("OIDC_ISSUER", ""),
 		OIDCClientID:     getEnv("OIDC_CLIENT_ID", ""),
 		OIDCClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
@@ -115,10 +184,14 @@ func Load() *Config {
 		OIDCGroupsClaim:     getEnv("OIDC_GROUPS_CLAIM", "groups"),
 		OIDCAdminGroups:     parseStringList(getEnv("OIDC_ADMIN_GROUPS", "")),
 		OIDCModeratorGroups: parseStringList(getEnv("OIDC_MODERATOR_GROUPS", "")),
+		OIDCOfflineAccess:   getEnv("OIDC_OFFLINE_ACCESS", "") == "true",
+		OIDCRefreshMins:     getEnvInt("OIDC_REFRESH_INTERVAL_MINUTES", 15),
+		OIDCRPLogout:        getEnv("OIDC_RP_LOGOUT", "true") != "false",
 		DBPoolMaxConns:   int32(getEnvInt("DB_POOL_MAX_CONNS", 10)),
 		DBPoolMinConns:   int32(getEnvInt("DB_POOL_MIN_CONNS", 2)),
 		SessionSecret:    getEnv("SESSION_SECRET", "change-me-in-production-min-32-chars"),
 		SessionStore:     strings.ToLower(getEnv("SESSION_STORE", "memory")),
+		SessionMaxLifetimeHours: getEnvInt("SESSION_MAX_LIFETIME_HOURS", 168),
 		RedisURL:         getEnv("REDIS_URL", "redis://localhost:6379"),
 		RedisUsername:    getEnv("REDIS_USERNAME", ""),
 		RedisPassword:    getEnv("REDIS_PASSWORD", ""),
@@ -126,6 +199,7 @@ func Load() *Config {
 		EnableRandomKeywords: getEnv("ENABLE_RANDOM_KEYWORDS", "false") == "true",
 		EnablePersonalLinks:  getEnv("ENABLE_PERSONAL_LINKS", "true") != "false",
 		EnableOrgLinks:       getEnv("ENABLE_ORG_LINKS", "true") != "false",
+		OrgResolutionOrder:   strings.ToLower(getEnv("ORG_RESOLUTION_ORDER", "active")),
 		RedirectFallbacks:    parseRedirectFallbacks(getEnv("REDIRECT_FALLBACKS", "")),
 
 		SiteTitle:   getEnv("SITE_TITLE", "GoLinks"),
@@ -135,12 +209,51 @@ func Load() *Config {
 		EnableAnimatedBackground: getEnv("ENABLE_ANIMATED_BACKGROUND", "") != "",
 
 		BannerText:      getEnv("BANNER_TEXT", ""),
-		BannerTextColor: sanitizeCSSColor(getEnv("BANNER_TEXT_COLOR", "#ffffff"), "#ffffff"),
-		BannerBGColor:   sanitizeCSSColor(getEnv("BANNER_BG_COLOR", "#0891b2"), "#0891b2"),
+		BannerTextColor: validation.SanitizeCSSColor(getEnv("BANNER_TEXT_COLOR", "#ffffff"), "#ffffff"),
+		BannerBGColor:   validation.SanitizeCSSColor(getEnv("BANNER_BG_COLOR", "#0891b2"), "#0891b2"),
+
+		// Content-Security-Policy
+		CSPReportOnly:     getEnv("CSP_REPORT_ONLY", "") == "true",
+		CSPImgSources:     parseStringList(getEnv("CSP_IMG_SOURCES", "")),
+		CSPScriptSources:  parseStringList(getEnv("CSP_SCRIPT_SOURCES", "")),
+		CSPStyleSources:   parseStringList(getEnv("CSP_STYLE_SOURCES", "")),
+		CSPFontSources:    parseStringList(getEnv("CSP_FONT_SOURCES", "")),
+		CSPConnectSources: parseStringList(getEnv("CSP_CONNECT_SOURCES", "")),
 
 		// Logging
 		LogLevel: strings.ToLower(getEnv("LOG_LEVEL", "info")),
 
+		// Data Retention
+		RetentionIntervalHours:        getEnvInt("RETENTION_INTERVAL_HOURS", 24),
+		ClickHistoryRetentionDays:     getEnvInt("CLICK_HISTORY_RETENTION_DAYS", 30),
+		KeywordLookupRetentionDays:    getEnvInt("KEYWORD_LOOKUP_RETENTION_DAYS", 90),
+		KeywordLookupMaxNotFound:      getEnvInt("KEYWORD_LOOKUP_MAX_NOT_FOUND", 10000),
+		ReadNotificationRetentionDays: getEnvInt("READ_NOTIFICATION_RETENTION_DAYS", 30),
+		RejectedLinkRetentionDays:     getEnvInt("REJECTED_LINK_RETENTION_DAYS", 90),
+		EditRequestRetentionDays:      getEnvInt("EDIT_REQUEST_RETENTION_DAYS", 90),
+
+		// Inactive users
+		InactiveUserDeactivationDays: getEnvInt("INACTIVE_USER_DEACTIVATION_DAYS", 0),
+
+		// Tracing
+		TracingEnabled: (getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "") != "" || getEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "") != "") &&
+			strings.ToLower(getEnv("OTEL_SDK_DISABLED", "")) != "true",
+		TracingServiceName: getEnv("OTEL_SERVICE_NAME", "golinks"),
+
+		// Metrics
+		MetricsTopKeywords:             getEnvInt("METRICS_TOP_KEYWORDS", 20),
+		MetricsTopKeywordsIntervalSecs: getEnvInt("METRICS_TOP_KEYWORDS_INTERVAL_SECONDS", 60),
+
+		// Rate Limiting
+		RateLimitWindowSecs:     getEnvInt("RATE_LIMIT_WINDOW_SECONDS", 60),
+		RateLimitRedirect:       getEnvInt("RATE_LIMIT_REDIRECT", 300),
+		RateLimitSearch:         getEnvInt("RATE_LIMIT_SEARCH", 120),
+		RateLimitWrite:          getEnvInt("RATE_LIMIT_WRITE", 60),
+		RateLimitAPI:            getEnvInt("RATE_LIMIT_API", 300),
+		RateLimitDefault:        getEnvInt("RATE_LIMIT_DEFAULT", 100),
+		RateLimitRoleOverrides:  parseRateLimitOverrides("RATE_LIMIT_ROLE_OVERRIDES", getEnv("RATE_LIMIT_ROLE_OVERRIDES", "org_mod:write=300,global_mod:write=300,admin:write=300")),
+		RateLimitTokenOverrides: parseRateLimitOverrides("RATE_LIMIT_TOKEN_OVERRIDES", strings.ToLower(getEnv("RATE_LIMIT_TOKEN_OVERRIDES", ""))),
+
 		// SMTP Configuration
 		SMTPEnabled:  getEnv("SMTP_ENABLED", "") != "",
 		SMTPHost:     getEnv("SMTP_HOST", ""),
@@ -158,6 +271,8 @@ func Load() *Config {
 		EmailNotifyUserOnDeletion:      getEnv("EMAIL_NOTIFY_USER_ON_DELETION", "true") != "false",
 		EmailNotifyModsOnHealthFailure: getEnv("EMAIL_NOTIFY_MODS_ON_HEALTH_FAILURE", "true") != "false",
 	}
+	cfg.OIDCProviders = loadOIDCProviders(cfg)
+	return cfg
 }
 
 func getEnv(key, fallback string) string {
@@ -210,10 +325,24 @@ func (c *Config) IsEmailEnabled() bool {
 	return c.SMTPEnabled && c.SMTPHost != "" && c.SMTPFrom != ""
 }
 
-// HasGroupRoleMapping returns true if at least one OIDC group is mapped to a role.
-// When false the group-to-role logic is entirely skipped and roles remain as manually set.
-func (c *Config) HasGroupRoleMapping() bool {
-	return len(c.OIDCAdminGroups) > 0 || len(c.OIDCModeratorGroups) > 0
+// PrimaryOIDCProvider returns the first configured identity provider, or nil
+// when OIDC is not configured. Users and sessions from before multiple
+// providers were supported belong to it.
+func (c *Config) PrimaryOIDCProvider() *OIDCProvider {
+	if len(c.OIDCProviders) == 0 {
+		return nil
+	}
+	return &c.OIDCProviders[0]
+}
+
+// OIDCProviderByName returns the provider with the given name, or nil.
+func (c *Config) OIDCProviderByName(name string) *OIDCProvider {
+	for i := range c.OIDCProviders {
+		if c.OIDCProviders[i].Name == name {
+			return &c.OIDCProviders[i]
+		}
+	}
+	return nil
 }
 
 // parseStringList splits a comma-separated string into trimmed, non-empty tokens.
@@ -232,51 +361,110 @@ func parseStringList(val string) []string {
 	return result
 }
 
-// cssColorRe matches hex colors (#rgb, #rrggbb, #rrggbbaa).
-var cssColorRe = regexp.MustCompile(`^#[0-9a-fA-F]{3,8}$`)
-
-// cssNamedColors is the set of valid CSS named colours. Restricting to known
-// names prevents arbitrary alphabetic strings from being injected into HTML.
-var cssNamedColors = map[string]bool{
-	"black": true, "white": true, "red": true, "green": true, "blue": true,
-	"yellow": true, "orange": true, "purple": true, "pink": true, "brown": true,
-	"gray": true, "grey": true, "cyan": true, "magenta": true, "lime": true,
-	"maroon": true, "navy": true, "olive": true, "teal": true, "aqua": true,
-	"coral": true, "salmon": true, "gold": true, "indigo": true, "violet": true,
-	"transparent": true,
-}
-
-// sanitizeCSSColor validates a color value, returning the fallback if invalid.
-// Allows hex colors (#rgb / #rrggbb / #rrggbbaa) and a whitelist of CSS named colors.
-func sanitizeCSSColor(val, fallback string) string {
-	val = strings.TrimSpace(val)
-	if cssColorRe.MatchString(val) {
-		return val
-	}
-	if cssNamedColors[strings.ToLower(val)] {
-		return val
-	}
-	return fallback
-}
-
 // Validate checks configuration for common issues and logs warnings.
 func (c *Config) Validate() {
 	if c.SessionSecret == "change-me-in-production-min-32-chars" && !c.IsDev() {
 		slog.Warn("SESSION_SECRET is using the default value — set a strong random secret in production")
 	}
-	if c.OIDCIssuer != "" {
-		if c.OIDCClientID == "" {
-			slog.Warn("OIDC_CLIENT_ID is not set — OIDC authentication will fail")
+	for _, p := range c.OIDCProviders {
+		if p.ClientID == "" {
+			slog.Warn("OIDC client ID is not set — OIDC authentication will fail", "provider", p.Name)
 		}
-		if c.OIDCClientSecret == "" {
-			slog.Warn("OIDC_CLIENT_SECRET is not set — OIDC authentication will fail")
+		if p.ClientSecret == "" {
+			slog.Warn("OIDC client secret is not set — OIDC authentication will fail", "provider", p.Name)
 		}
 	}
+	if c.ProxyAuthUserHeader != "" && len(c.ProxyAuthTrustedCIDRs) == 0 {
+		slog.Warn("PROXY_AUTH_USER_HEADER is set but PROXY_AUTH_TRUSTED_CIDRS is empty — proxy authentication is disabled")
+	}
+	if c.SCIMEnabled() && len(c.SCIMBearerToken) < 32 {
+		slog.Warn("SCIM_BEARER_TOKEN is shorter than 32 characters — use a long random secret")
+	}
+	if c.SCIMEnabled() && c.SCIMProvider().Issuer == "" {
+		slog.Warn("SCIM is enabled without an issuer — set SCIM_ISSUER so provisioned users match signed-in users")
+	}
+	if c.OIDCRefreshMins < 0 {
+		slog.Warn("OIDC_REFRESH_INTERVAL_MINUTES must not be negative — disabling session refresh")
+		c.OIDCRefreshMins = 0
+	}
+	if c.OIDCRefreshMins >= 24*60 {
+		slog.Warn("OIDC_REFRESH_INTERVAL_MINUTES must be shorter than the 24-hour session lifetime — defaulting to 15")
+		c.OIDCRefreshMins = 15
+	}
+	if c.SessionMaxLifetimeHours < 24 {
+		slog.Warn("SESSION_MAX_LIFETIME_HOURS must be at least 24 — defaulting to 24")
+		c.SessionMaxLifetimeHours = 24
+	}
+	if c.RetentionIntervalHours <= 0 {
+		slog.Warn("RETENTION_INTERVAL_HOURS must be positive — defaulting to 24")
+		c.RetentionIntervalHours = 24
+	}
+	if c.MetricsTopKeywordsIntervalSecs <= 0 {
+		slog.Warn("METRICS_TOP_KEYWORDS_INTERVAL_SECONDS must be positive — defaulting to 60")
+		c.MetricsTopKeywordsIntervalSecs = 60
+	}
+	if c.RateLimitWindowSecs <= 0 {
+		slog.Warn("RATE_LIMIT_WINDOW_SECONDS must be positive — defaulting to 60")
+		c.RateLimitWindowSecs = 60
+	}
+	for env, limit := range map[string]*int{
+		"RATE_LIMIT_REDIRECT": &c.RateLimitRedirect,
+		"RATE_LIMIT_SEARCH":   &c.RateLimitSearch,
+		"RATE_LIMIT_WRITE":    &c.RateLimitWrite,
+		"RATE_LIMIT_API":      &c.RateLimitAPI,
+		"RATE_LIMIT_DEFAULT":  &c.RateLimitDefault,
+	} {
+		if *limit < 0 {
+			slog.Warn(env + " must not be negative — disabling the limit")
+			*limit = 0
+		}
+	}
+	c.CSPImgSources = filterCSPSources("CSP_IMG_SOURCES", c.CSPImgSources)
+	c.CSPScriptSources = filterCSPSources("CSP_SCRIPT_SOURCES", c.CSPScriptSources)
+	c.CSPStyleSources = filterCSPSources("CSP_STYLE_SOURCES", c.CSPStyleSources)
+	c.CSPFontSources = filterCSPSources("CSP_FONT_SOURCES", c.CSPFontSources)
+	c.CSPConnectSources = filterCSPSources("CSP_CONNECT_SOURCES", c.CSPConnectSources)
 	if c.SMTPEnabled && c.SMTPHost == "" {
 		slog.Warn("SMTP_ENABLED is set but SMTP_HOST is not configured — email notifications will be disabled")
 	}
 }
 
+// filterCSPSources drops CSP source expressions that could break out of their
+// directive (a ";" starts a new directive) and warns about each one dropped.
+func filterCSPSources(env string, sources []string) []string {
+	valid := sources[:0]
+	for _, src := range sources {
+		if strings.ContainsAny(src, "; \t\r\n") {
+			slog.Warn("ignoring invalid CSP source", "env", env, "source", src)
+			continue
+		}
+		valid = append(valid, src)
+	}
+	return valid
+}
+
+// parseRateLimitOverrides parses "subject:policy=limit" entries, e.g.
+// "global_mod:write=600,admin:*=0", into subject -> policy -> limit. The
+// policy "*" applies to every policy; a limit of 0 means unlimited.
+func parseRateLimitOverrides(env, val string) map[string]map[string]int {
+	result := make(map[string]map[string]int)
+	for _, entry := range parseStringList(val) {
+		subject, rest, ok1 := strings.Cut(entry, ":")
+		policy, limitStr, ok2 := strings.Cut(rest, "=")
+		limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+		subject, policy = strings.TrimSpace(subject), strings.TrimSpace(policy)
+		if !ok1 || !ok2 || err != nil || limit < 0 || subject == "" || policy == "" {
+			slog.Warn("ignoring invalid rate limit override", "env", env, "entry", entry)
+			continue
+		}
+		if result[subject] == nil {
+			result[subject] = make(map[string]int)
+		}
+		result[subject][policy] = limit
+	}
+	return result
+}
+
 // parseRedirectFallbacks parses REDIRECT_FALLBACKS env var format: "org1=https://url1/go/,org2=https://url2/"
 func parseRedirectFallbacks(val string) map[string]string {
 	result := make(map[string]string)
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// OIDCProvider is one identity provider users can sign in with. Each has its
+// own client and claim mapping; users are keyed by (Issuer, sub).
+type OIDCProvider struct {
+	Name            string   // identifier used in /auth/login?provider=<name>
+	DisplayName     string   // button label on the /login chooser
+	Issuer          string   // env: OIDC_<NAME>_ISSUER
+	ClientID        string   // env: OIDC_<NAME>_CLIENT_ID
+	ClientSecret    string   // env: OIDC_<NAME>_CLIENT_SECRET
+	OrgClaim        string   // env: OIDC_<NAME>_ORG_CLAIM, defaults to OIDC_ORG_CLAIM
+	GroupsClaim     string   // env: OIDC_<NAME>_GROUPS_CLAIM, defaults to OIDC_GROUPS_CLAIM
+	AdminGroups     []string // env: OIDC_<NAME>_ADMIN_GROUPS
+	ModeratorGroups []string // env: OIDC_<NAME>_MODERATOR_GROUPS
+}
+
+// HasGroupRoleMapping returns true if at least one of the provider's groups is
+// mapped to a role. When false the group-to-role logic is entirely skipped and
+// roles remain as manually set.
+func (p *OIDCProvider) HasGroupRoleMapping() bool {
+	return len(p.AdminGroups) > 0 || len(p.ModeratorGroups) > 0
+}
+
+// defaultOIDCProviderName names the provider built from the single-provider
+// OIDC_* settings.
+const defaultOIDCProviderName = "default"
+
+// oidcProviderNameRe limits provider names to what fits in an env var name.
+var oidcProviderNameRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// loadOIDCProviders reads the providers named in OIDC_PROVIDERS, e.g.
+// "corp,acme" configured by OIDC_CORP_ISSUER, OIDC_ACME_CLIENT_ID and so on.
+// Without OIDC_PROVIDERS the single-provider settings become the one provider.
+// Invalid, duplicate and issuer-less entries are skipped with a warning.
+func loadOIDCProviders(cfg *Config) []OIDCProvider {
+	names := parseStringList(strings.ToLower(getEnv("OIDC_PROVIDERS", "")))
+	if len(names) == 0 {
+		if cfg.OIDCIssuer == "" {
+			return nil
+		}
+		return []OIDCProvider{{
+			Name:            defaultOIDCProviderName,
+			DisplayName:     "SSO",
+			Issuer:          cfg.OIDCIssuer,
+			ClientID:        cfg.OIDCClientID,
+			ClientSecret:    cfg.OIDCClientSecret,
+			OrgClaim:        cfg.OIDCOrgClaim,
+			GroupsClaim:     cfg.OIDCGroupsClaim,
+			AdminGroups:     cfg.OIDCAdminGroups,
+			ModeratorGroups: cfg.OIDCModeratorGroups,
+		}}
+	}
+
+	var providers []OIDCProvider
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !oidcProviderNameRe.MatchString(name) || seen[name] {
+			slog.Warn("ignoring invalid or duplicate OIDC provider name", "env", "OIDC_PROVIDERS", "name", name)
+			continue
+		}
+		seen[name] = true
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		p := OIDCProvider{
+			Name:            name,
+			DisplayName:     getEnv(prefix+"DISPLAY_NAME", name),
+			Issuer:          getEnv(prefix+"ISSUER", ""),
+			ClientID:        getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret:    getEnv(prefix+"CLIENT_SECRET", ""),
+			OrgClaim:        getEnv(prefix+"ORG_CLAIM", cfg.OIDCOrgClaim),
+			GroupsClaim:     getEnv(prefix+"GROUPS_CLAIM", cfg.OIDCGroupsClaim),
+			AdminGroups:     parseStringList(getEnv(prefix+"ADMIN_GROUPS", "")),
+			ModeratorGroups: parseStringList(getEnv(prefix+"MODERATOR_GROUPS", "")),
+		}
+		if p.Issuer == "" {
+			slog.Warn("ignoring OIDC provider without an issuer", "env", prefix+"ISSUER", "name", name)
+			continue
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
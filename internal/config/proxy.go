@@ -0,0 +1,57 @@
+package config
+
+import (
+	"log/slog"
+	"net/netip"
+	"strings"
+)
+
+// ProxyAuthEnabled reports whether users may be authenticated by a trusted
+// reverse proxy (oauth2-proxy, Envoy ext_authz, ...) through request headers.
+func (c *Config) ProxyAuthEnabled() bool {
+	return c.ProxyAuthUserHeader != "" && len(c.ProxyAuthTrustedCIDRs) > 0
+}
+
+// ProxyAuthProvider describes the authenticating proxy as an identity
+// provider so its users are synced like OIDC users: keyed by
+// (PROXY_AUTH_ISSUER, user header) and mapped to roles by the OIDC admin and
+// moderator groups.
+func (c *Config) ProxyAuthProvider() *OIDCProvider {
+	p := &OIDCProvider{
+		Name:            "proxy",
+		DisplayName:     "Proxy",
+		Issuer:          c.ProxyAuthIssuer,
+		GroupsClaim:     "groups",
+		AdminGroups:     c.OIDCAdminGroups,
+		ModeratorGroups: c.OIDCModeratorGroups,
+	}
+	if c.ProxyAuthOrgHeader != "" {
+		p.OrgClaim = "organization"
+	}
+	return p
+}
+
+// parseCIDRs parses a comma-separated list of CIDR prefixes. Bare addresses
+// are accepted as single-host prefixes; invalid entries are skipped with a
+// warning.
+func parseCIDRs(env, val string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range parseStringList(val) {
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				slog.Warn("ignoring invalid CIDR", "env", env, "entry", entry)
+				continue
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			slog.Warn("ignoring invalid CIDR", "env", env, "entry", entry)
+			continue
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
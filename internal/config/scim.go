@@ -0,0 +1,36 @@
+package config
+
+// SCIMEnabled reports whether the SCIM 2.0 provisioning endpoint is served.
+func (c *Config) SCIMEnabled() bool {
+	return c.SCIMBearerToken != ""
+}
+
+// SCIMProvider returns the identity provider SCIM-provisioned users sign in
+// with, so they are keyed by its issuer and mapped to roles by its admin and
+// moderator groups. SCIM_ISSUER picks an OIDC provider or the proxy issuer;
+// without it the primary OIDC provider, or else the proxy, is used.
+func (c *Config) SCIMProvider() *OIDCProvider {
+	issuer := c.SCIMIssuer
+	if issuer == "" {
+		if p := c.PrimaryOIDCProvider(); p != nil {
+			return p
+		}
+		if c.ProxyAuthEnabled() {
+			return c.ProxyAuthProvider()
+		}
+	}
+	for i := range c.OIDCProviders {
+		if c.OIDCProviders[i].Issuer == issuer {
+			return &c.OIDCProviders[i]
+		}
+	}
+	if c.ProxyAuthEnabled() && issuer == c.ProxyAuthIssuer {
+		return c.ProxyAuthProvider()
+	}
+	return &OIDCProvider{
+		Name:            "scim",
+		Issuer:          issuer,
+		AdminGroups:     c.OIDCAdminGroups,
+		ModeratorGroups: c.OIDCModeratorGroups,
+	}
+}
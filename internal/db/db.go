@@ -11,6 +11,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 
+	"golinks/internal/tracing"
 	"golinks/migrations"
 )
 
@@ -19,6 +20,8 @@ type DB struct {
 	Pool  *pgxpool.Pool
 	buf   *writeBuffer
 	redis *redis.Client // optional; nil when Redis is not configured
+
+	orgOrder string // ORG_RESOLUTION_ORDER; see SetOrgResolutionOrder
 }
 
 // AttachRedis wires a Redis client into the DB for click deduplication.
@@ -38,6 +41,7 @@ func New(ctx context.Context, connString string, maxConns, minConns int32) (*DB,
 	cfg.MaxConnLifetime   = 30 * time.Minute
 	cfg.MaxConnIdleTime   = 5 * time.Minute
 	cfg.HealthCheckPeriod = 1 * time.Minute
+	cfg.ConnConfig.Tracer = tracing.PgxTracer{}
 
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
@@ -9,10 +9,20 @@ var (
 	ErrDuplicateKeyword = errors.New("keyword already exists")
 
 	// User errors
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrUserAuthorsLinks = errors.New("user still authors links; offboard them to transfer the links first")
+
+	// User session errors
+	ErrUserSessionNotFound = errors.New("session not found")
 
 	// Organisation errors
-	ErrOrgNotFound = errors.New("organization not found")
+	ErrOrgNotFound      = errors.New("organization not found")
+	ErrNotOrgMember     = errors.New("user is not a member of this organization")
+	ErrOrgCycle         = errors.New("an organization cannot be nested under itself or one of its descendants")
+	ErrDuplicateOrgSlug = errors.New("an organization with this slug already exists")
+	ErrOrgNotEmpty      = errors.New("organization still has members or links")
+	ErrOrgMergeTarget   = errors.New("an organization cannot be merged into itself or one of its descendants")
+	ErrOrgMergeConflict = errors.New("both organizations have links with the same keyword")
 
 	// User link errors
 	ErrUserLinkNotFound = errors.New("user link not found")
@@ -28,6 +38,13 @@ var (
 	ErrPendingRequestLimit  = errors.New("you have reached the maximum number of pending requests (5)")
 	ErrDuplicateEditRequest = errors.New("you already have a pending edit request for this link")
 
+	// Link owner errors
+	ErrOwnerNotFound = errors.New("no user or organization matches this owner")
+
+	// SCIM group errors
+	ErrSCIMGroupNotFound  = errors.New("group not found")
+	ErrDuplicateSCIMGroup = errors.New("a group with this name already exists")
+
 	// Fallback redirect errors
 	ErrFallbackRedirectNotFound = errors.New("fallback redirect not found")
 )
@@ -13,9 +13,19 @@ func (d *DB) IncrementKeywordLookup(_ context.Context, keyword, outcome string)
 	return nil
 }
 
-// GetAllKeywordLookups returns all keyword lookup rows for metrics export.
-func (d *DB) GetAllKeywordLookups(ctx context.Context) ([]models.KeywordLookup, error) {
-	rows, err := d.Pool.Query(ctx, `SELECT keyword, outcome, count, last_seen_at FROM keyword_lookups`)
+// GetTopKeywordLookups returns the limit most requested keywords for each
+// outcome, used to export a bounded set of per-keyword metrics.
+func (d *DB) GetTopKeywordLookups(ctx context.Context, limit int) ([]models.KeywordLookup, error) {
+	rows, err := d.Pool.Query(ctx, `
+		SELECT keyword, outcome, count, last_seen_at
+		FROM (
+			SELECT keyword, outcome, count, last_seen_at,
+				ROW_NUMBER() OVER (PARTITION BY outcome ORDER BY count DESC, keyword ASC) AS rank
+			FROM keyword_lookups
+		) ranked
+		WHERE rank <= $1
+		ORDER BY outcome, rank
+	`, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"golinks/internal/models"
+)
+
+func TestGetTopKeywordLookups(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	db.Pool.Exec(ctx, "DELETE FROM keyword_lookups")
+	defer db.Pool.Exec(ctx, "DELETE FROM keyword_lookups")
+
+	for _, kw := range []struct {
+		keyword, outcome string
+		count            int
+	}{
+		{"docs", models.OutcomeResolved, 50},
+		{"wiki", models.OutcomeResolved, 40},
+		{"jira", models.OutcomeResolved, 10},
+		{"typo", models.OutcomeNotFound, 5},
+	} {
+		if _, err := db.Pool.Exec(ctx,
+			`INSERT INTO keyword_lookups (keyword, outcome, count) VALUES ($1, $2, $3)`,
+			kw.keyword, kw.outcome, kw.count,
+		); err != nil {
+			t.Fatalf("failed to seed keyword_lookups: %v", err)
+		}
+	}
+
+	lookups, err := db.GetTopKeywordLookups(ctx, 2)
+	if err != nil {
+		t.Fatalf("GetTopKeywordLookups() error = %v", err)
+	}
+
+	got := make(map[string]int64)
+	for _, l := range lookups {
+		got[l.Keyword+"/"+l.Outcome] = l.Count
+	}
+	want := map[string]int64{
+		"docs/" + models.OutcomeResolved: 50,
+		"wiki/" + models.OutcomeResolved: 40,
+		"typo/" + models.OutcomeNotFound: 5,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("GetTopKeywordLookups() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("GetTopKeywordLookups()[%s] = %d, want %d", k, got[k], v)
+		}
+	}
+}
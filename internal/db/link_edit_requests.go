@@ -73,7 +73,8 @@ func (d *DB) GetEditRequestByID(ctx context.Context, id uuid.UUID) (*models.Link
 	return &req, nil
 }
 
-// GetPendingEditRequests returns pending edit requests scoped by user role.
+// GetPendingEditRequests returns pending edit requests scoped by user role,
+// plus requests for links the user co-owns.
 func (d *DB) GetPendingEditRequests(ctx context.Context, user *models.User) ([]models.LinkEditRequest, error) {
 	var sql string
 	var args []any
@@ -98,12 +99,24 @@ func (d *DB) GetPendingEditRequests(ctx context.Context, user *models.User) ([]m
 			FROM link_edit_requests r
 			JOIN links l ON l.id = r.link_id
 			JOIN users u ON u.id = r.user_id
-			WHERE r.status = $1 AND l.scope = $2 AND l.organization_id = $3
+			WHERE r.status = $1
+				AND ((l.scope = $2 AND l.organization_id IN ` + orgSubtree("$3") + `) OR ` + linkOwnedBy("l", "$4") + `)
 			ORDER BY r.created_at ASC
 		`
-		args = []any{models.StatusPending, models.ScopeOrg, *user.OrganizationID}
+		args = []any{models.StatusPending, models.ScopeOrg, *user.OrganizationID, user.ID}
 	} else {
-		return []models.LinkEditRequest{}, nil
+		// Link co-owners review suggestions for their links.
+		sql = `
+			SELECT r.id, r.link_id, r.user_id, r.url, r.description, r.reason, r.status,
+				r.reviewed_by, r.reviewed_at, r.created_at,
+				l.keyword, COALESCE(u.name, ''), COALESCE(u.email, '')
+			FROM link_edit_requests r
+			JOIN links l ON l.id = r.link_id
+			JOIN users u ON u.id = r.user_id
+			WHERE r.status = $1 AND ` + linkOwnedBy("l", "$2") + `
+			ORDER BY r.created_at ASC
+		`
+		args = []any{models.StatusPending, user.ID}
 	}
 
 	rows, err := d.Pool.Query(ctx, sql, args...)
@@ -0,0 +1,306 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"golinks/internal/models"
+)
+
+// linkOwnedBy returns an SQL condition that holds when the user whose ID is
+// the SQL expression viewer co-owns the link in table or alias link: as a
+// listed user, through a group from their last sign-in or SCIM, or as a
+// direct member of an owning organization.
+func linkOwnedBy(link, viewer string) string {
+	return `EXISTS (
+		SELECT 1 FROM link_owners lo
+		JOIN users ov ON ov.id = ` + viewer + `::uuid
+		WHERE lo.link_id = ` + link + `.id AND (
+			lo.user_id = ov.id
+			OR lo.group_name = ANY(ov.groups)
+			OR EXISTS (
+				SELECT 1 FROM scim_group_members gm
+				JOIN scim_groups g ON g.id = gm.group_id
+				WHERE gm.user_id = ov.id AND g.display_name = lo.group_name
+			)
+			OR EXISTS (
+				SELECT 1 FROM user_organizations m
+				WHERE m.user_id = ov.id AND m.organization_id = lo.organization_id
+			)
+		)
+	)`
+}
+
+const linkOwnersQuery = `
+	SELECT lo.id, lo.link_id, lo.user_id, COALESCE(lo.group_name, ''), lo.organization_id,
+		lo.created_by, lo.created_at, COALESCE(u.email, lo.group_name, o.slug, '')
+	FROM link_owners lo
+	LEFT JOIN users u ON u.id = lo.user_id
+	LEFT JOIN organizations o ON o.id = lo.organization_id
+	WHERE lo.link_id = $1
+	ORDER BY lo.created_at, lo.id
+`
+
+func scanLinkOwners(rows pgx.Rows) ([]models.LinkOwner, error) {
+	defer rows.Close()
+	owners := []models.LinkOwner{}
+	for rows.Next() {
+		var o models.LinkOwner
+		if err := rows.Scan(&o.ID, &o.LinkID, &o.UserID, &o.Group, &o.OrganizationID,
+			&o.CreatedBy, &o.CreatedAt, &o.Name); err != nil {
+			return nil, err
+		}
+		switch {
+		case o.UserID != nil:
+			o.Kind = models.OwnerKindUser
+		case o.OrganizationID != nil:
+			o.Kind = models.OwnerKindOrg
+		default:
+			o.Kind = models.OwnerKindGroup
+		}
+		owners = append(owners, o)
+	}
+	return owners, rows.Err()
+}
+
+// GetLinkOwners returns the co-owners of a link in the order they were added.
+func (d *DB) GetLinkOwners(ctx context.Context, linkID uuid.UUID) ([]models.LinkOwner, error) {
+	rows, err := d.Pool.Query(ctx, linkOwnersQuery, linkID)
+	if err != nil {
+		return nil, err
+	}
+	return scanLinkOwners(rows)
+}
+
+// IsLinkOwner reports whether userID co-owns a link.
+func (d *DB) IsLinkOwner(ctx context.Context, linkID, userID uuid.UUID) (bool, error) {
+	var owned bool
+	err := d.Pool.QueryRow(ctx, `SELECT `+linkOwnedBy("l", "$2")+` FROM links l WHERE l.id = $1`, linkID, userID).Scan(&owned)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrLinkNotFound
+	}
+	return owned, err
+}
+
+// GetOwnedLinkIDs returns the subset of linkIDs that userID co-owns, keyed by
+// link ID string.
+func (d *DB) GetOwnedLinkIDs(ctx context.Context, userID uuid.UUID, linkIDs []uuid.UUID) (map[string]bool, error) {
+	result := make(map[string]bool)
+	if len(linkIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := d.Pool.Query(ctx, `
+		SELECT l.id FROM links l
+		WHERE l.id = ANY($2) AND `+linkOwnedBy("l", "$1"), userID, linkIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		result[id.String()] = true
+	}
+	return result, rows.Err()
+}
+
+// GetLinkOwnerUserIDs returns the active users who co-own a link, directly or
+// through a group or organization.
+func (d *DB) GetLinkOwnerUserIDs(ctx context.Context, linkID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := d.Pool.Query(ctx, `
+		SELECT u.id FROM users u, links l
+		WHERE l.id = $1 AND u.deactivated_at IS NULL AND `+linkOwnedBy("l", "u.id"), linkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ResolveLinkOwners turns user emails, group names and organization slugs into
+// link owners for SetLinkOwners, dropping blanks and duplicates. It returns an
+// error wrapping ErrOwnerNotFound for an email or slug that matches no active
+// user or organization.
+func (d *DB) ResolveLinkOwners(ctx context.Context, emails, groups, orgSlugs []string) ([]models.LinkOwner, error) {
+	var owners []models.LinkOwner
+	seen := make(map[string]bool)
+	add := func(o models.LinkOwner) {
+		if !seen[o.Key()] {
+			seen[o.Key()] = true
+			owners = append(owners, o)
+		}
+	}
+
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+		var id uuid.UUID
+		var name string
+		err := d.Pool.QueryRow(ctx, `
+			SELECT id, email FROM users
+			WHERE LOWER(email) = LOWER($1) AND deactivated_at IS NULL
+			ORDER BY last_login_at DESC NULLS LAST
+			LIMIT 1
+		`, email).Scan(&id, &name)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrOwnerNotFound, email)
+		}
+		if err != nil {
+			return nil, err
+		}
+		add(models.LinkOwner{Kind: models.OwnerKindUser, UserID: &id, Name: name})
+	}
+
+	for _, group := range groups {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			continue
+		}
+		add(models.LinkOwner{Kind: models.OwnerKindGroup, Group: group, Name: group})
+	}
+
+	for _, slug := range orgSlugs {
+		slug = strings.TrimSpace(slug)
+		if slug == "" {
+			continue
+		}
+		org, err := d.GetOrganizationBySlug(ctx, slug)
+		if errors.Is(err, ErrOrgNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrOwnerNotFound, slug)
+		}
+		if err != nil {
+			return nil, err
+		}
+		add(models.LinkOwner{Kind: models.OwnerKindOrg, OrganizationID: &org.ID, Name: org.Slug})
+	}
+
+	return owners, nil
+}
+
+// SetLinkOwners replaces a link's co-owners with owners, so it adds, removes
+// and transfers ownership in one step. Each owner added or removed is
+// recorded as a link owner event by actorID; the events are returned.
+func (d *DB) SetLinkOwners(ctx context.Context, linkID, actorID uuid.UUID, owners []models.LinkOwner) ([]models.LinkOwnerEvent, error) {
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the link so concurrent changes don't interleave.
+	var locked uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT id FROM links WHERE id = $1 FOR UPDATE`, linkID).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrLinkNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, linkOwnersQuery, linkID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := scanLinkOwners(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(owners))
+	for _, o := range owners {
+		wanted[o.Key()] = true
+	}
+	existing := make(map[string]bool, len(current))
+	var events []models.LinkOwnerEvent
+
+	for _, o := range current {
+		existing[o.Key()] = true
+		if wanted[o.Key()] {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM link_owners WHERE id = $1`, o.ID); err != nil {
+			return nil, err
+		}
+		events = append(events, models.LinkOwnerEvent{Action: models.OwnerRemoved, OwnerKind: o.Kind, OwnerName: o.Name})
+	}
+
+	for _, o := range owners {
+		if existing[o.Key()] {
+			continue
+		}
+		var group *string
+		if o.Kind == models.OwnerKindGroup {
+			group = &o.Group
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO link_owners (link_id, user_id, group_name, organization_id, created_by)
+			VALUES ($1, $2, $3, $4, $5)
+		`, linkID, o.UserID, group, o.OrganizationID, actorID); err != nil {
+			return nil, err
+		}
+		existing[o.Key()] = true
+		events = append(events, models.LinkOwnerEvent{Action: models.OwnerAdded, OwnerKind: o.Kind, OwnerName: o.Name})
+	}
+
+	for i := range events {
+		events[i].LinkID = linkID
+		events[i].ActorID = &actorID
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO link_owner_events (link_id, actor_id, action, owner_kind, owner_name)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at
+		`, linkID, actorID, events[i].Action, events[i].OwnerKind, events[i].OwnerName).Scan(&events[i].ID, &events[i].CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	return events, tx.Commit(ctx)
+}
+
+// GetLinkOwnerEvents returns a link's most recent owner changes, newest first.
+func (d *DB) GetLinkOwnerEvents(ctx context.Context, linkID uuid.UUID, limit int) ([]models.LinkOwnerEvent, error) {
+	rows, err := d.Pool.Query(ctx, `
+		SELECT e.id, e.link_id, e.actor_id, e.action, e.owner_kind, e.owner_name, e.created_at,
+			COALESCE(NULLIF(u.name, ''), u.email, '')
+		FROM link_owner_events e
+		LEFT JOIN users u ON u.id = e.actor_id
+		WHERE e.link_id = $1
+		ORDER BY e.created_at DESC, e.id
+		LIMIT $2
+	`, linkID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.LinkOwnerEvent{}
+	for rows.Next() {
+		var e models.LinkOwnerEvent
+		if err := rows.Scan(&e.ID, &e.LinkID, &e.ActorID, &e.Action, &e.OwnerKind, &e.OwnerName,
+			&e.CreatedAt, &e.ActorName); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golinks/internal/models"
+)
+
+func TestLinkOwners(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	org := createTestOrgs(t, db, "owners-platform")[0]
+
+	moderator := &models.User{Sub: "owners-mod", Email: "mod@example.com", Name: "Mod", Role: models.RoleGlobalMod}
+	alice := &models.User{Sub: "owners-alice", Email: "alice@example.com", Name: "Alice"}
+	grouped := &models.User{Sub: "owners-grouped", Email: "grouped@example.com", Name: "Grouped"}
+	member := &models.User{Sub: "owners-member", Email: "member@example.com", Name: "Member"}
+	other := &models.User{Sub: "owners-other", Email: "other@example.com", Name: "Other"}
+	for _, u := range []*models.User{moderator, alice, grouped, member, other} {
+		if err := db.UpsertUser(ctx, u); err != nil {
+			t.Fatalf("UpsertUser() error = %v", err)
+		}
+	}
+	if err := db.SetUserGroups(ctx, grouped.ID, []string{"docs-team"}); err != nil {
+		t.Fatalf("SetUserGroups() error = %v", err)
+	}
+	if err := db.AddUserOrganization(ctx, member.ID, org.ID, models.OrgRoleMember); err != nil {
+		t.Fatalf("AddUserOrganization() error = %v", err)
+	}
+
+	link := &models.Link{Keyword: "handbook", URL: "https://handbook.example.com", Scope: models.ScopeGlobal}
+	if err := db.CreateLink(ctx, link); err != nil {
+		t.Fatalf("CreateLink() error = %v", err)
+	}
+
+	if _, err := db.ResolveLinkOwners(ctx, []string{"nobody@example.com"}, nil, nil); !errors.Is(err, ErrOwnerNotFound) {
+		t.Fatalf("ResolveLinkOwners(unknown email) error = %v, want ErrOwnerNotFound", err)
+	}
+
+	owners, err := db.ResolveLinkOwners(ctx, []string{"Alice@Example.com", "alice@example.com"}, []string{"docs-team", ""}, []string{org.Slug})
+	if err != nil {
+		t.Fatalf("ResolveLinkOwners() error = %v", err)
+	}
+	if len(owners) != 3 {
+		t.Fatalf("ResolveLinkOwners() returned %d owners, want 3", len(owners))
+	}
+
+	events, err := db.SetLinkOwners(ctx, link.ID, moderator.ID, owners)
+	if err != nil {
+		t.Fatalf("SetLinkOwners() error = %v", err)
+	}
+	if len(events) != 3 {
+		t.Errorf("SetLinkOwners() recorded %d events, want 3", len(events))
+	}
+
+	tests := []struct {
+		name string
+		user *models.User
+		want bool
+	}{
+		{"listed user", alice, true},
+		{"group member", grouped, true},
+		{"org member", member, true},
+		{"other user", other, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := db.IsLinkOwner(ctx, link.ID, tt.user.ID)
+			if err != nil {
+				t.Fatalf("IsLinkOwner() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsLinkOwner() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	ownerIDs, err := db.GetLinkOwnerUserIDs(ctx, link.ID)
+	if err != nil {
+		t.Fatalf("GetLinkOwnerUserIDs() error = %v", err)
+	}
+	if len(ownerIDs) != 3 {
+		t.Errorf("GetLinkOwnerUserIDs() returned %d users, want 3", len(ownerIDs))
+	}
+
+	// Owners see suggestions for their link and the link on /manage.
+	req := &models.LinkEditRequest{LinkID: link.ID, UserID: other.ID, URL: "https://new.example.com", Reason: "moved"}
+	if err := db.CreateEditRequest(ctx, req); err != nil {
+		t.Fatalf("CreateEditRequest() error = %v", err)
+	}
+	pending, err := db.GetPendingEditRequests(ctx, alice)
+	if err != nil {
+		t.Fatalf("GetPendingEditRequests() error = %v", err)
+	}
+	if len(pending) != 1 {
+		t.Errorf("GetPendingEditRequests(owner) returned %d requests, want 1", len(pending))
+	}
+	if pending, _ := db.GetPendingEditRequests(ctx, other); len(pending) != 0 {
+		t.Errorf("GetPendingEditRequests(other) returned %d requests, want 0", len(pending))
+	}
+	managed, err := db.GetLinksForManagement(ctx, member, "all", "all", "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetLinksForManagement() error = %v", err)
+	}
+	if len(managed) != 1 {
+		t.Errorf("GetLinksForManagement(owner) returned %d links, want 1", len(managed))
+	}
+
+	// Transferring ownership to another user removes the previous owners.
+	transfer, err := db.ResolveLinkOwners(ctx, []string{other.Email}, nil, nil)
+	if err != nil {
+		t.Fatalf("ResolveLinkOwners() error = %v", err)
+	}
+	events, err = db.SetLinkOwners(ctx, link.ID, alice.ID, transfer)
+	if err != nil {
+		t.Fatalf("SetLinkOwners() error = %v", err)
+	}
+	if len(events) != 4 {
+		t.Errorf("SetLinkOwners() recorded %d events, want 4", len(events))
+	}
+	if owned, _ := db.IsLinkOwner(ctx, link.ID, alice.ID); owned {
+		t.Error("IsLinkOwner(alice) = true after transfer, want false")
+	}
+	if owned, _ := db.IsLinkOwner(ctx, link.ID, other.ID); !owned {
+		t.Error("IsLinkOwner(other) = false after transfer, want true")
+	}
+
+	history, err := db.GetLinkOwnerEvents(ctx, link.ID, 50)
+	if err != nil {
+		t.Fatalf("GetLinkOwnerEvents() error = %v", err)
+	}
+	if len(history) != 7 {
+		t.Errorf("GetLinkOwnerEvents() returned %d events, want 7", len(history))
+	}
+}
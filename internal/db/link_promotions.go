@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"golinks/internal/models"
+)
+
+const linkPromotionColumns = `link_id, user_link_id, user_id, click_count, recent_clicks, last_used_at,
+	personal_created_at, keep_personal, applied_at, created_at`
+
+func scanLinkPromotion(row pgx.Row) (*models.LinkPromotion, error) {
+	var p models.LinkPromotion
+	if err := row.Scan(&p.LinkID, &p.UserLinkID, &p.UserID, &p.ClickCount, &p.RecentClicks, &p.LastUsedAt,
+		&p.PersonalCreatedAt, &p.KeepPersonal, &p.AppliedAt, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// CreateLinkPromotion records that the pending link linkID was proposed from
+// the personal link userLinkID, snapshotting the personal link's usage as
+// evidence for moderators. keepPersonal keeps the personal link once the
+// proposal is approved instead of deleting it.
+func (d *DB) CreateLinkPromotion(ctx context.Context, linkID, userLinkID uuid.UUID, keepPersonal bool) (*models.LinkPromotion, error) {
+	p, err := scanLinkPromotion(d.Pool.QueryRow(ctx, `
+		INSERT INTO link_promotions (link_id, user_link_id, user_id, click_count, recent_clicks, last_used_at,
+		                             personal_created_at, keep_personal)
+		SELECT $1, ul.id, ul.user_id, ul.click_count,
+			COALESCE((
+				SELECT SUM(h.click_count) FROM user_link_click_history h
+				WHERE h.user_link_id = ul.id AND h.hour_bucket >= NOW() - make_interval(days => $3)
+			), 0),
+			(SELECT MAX(h.hour_bucket) FROM user_link_click_history h WHERE h.user_link_id = ul.id),
+			ul.created_at, $4
+		FROM user_links ul
+		WHERE ul.id = $2
+		RETURNING `+linkPromotionColumns,
+		linkID, userLinkID, models.PromotionRecentDays, keepPersonal))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserLinkNotFound
+	}
+	return p, err
+}
+
+// GetLinkPromotions returns the promotions behind any of linkIDs, keyed by
+// link ID string. Links that weren't proposed from a personal link are absent.
+func (d *DB) GetLinkPromotions(ctx context.Context, linkIDs []uuid.UUID) (map[string]*models.LinkPromotion, error) {
+	result := make(map[string]*models.LinkPromotion)
+	if len(linkIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := d.Pool.Query(ctx, `SELECT `+linkPromotionColumns+` FROM link_promotions WHERE link_id = ANY($1)`, linkIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := scanLinkPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		result[p.LinkID.String()] = p
+	}
+	return result, rows.Err()
+}
+
+// ApplyLinkPromotion completes the promotion behind a freshly approved link:
+// the personal link's click count and hourly click history move to the link,
+// and the personal link is deleted unless the submitter asked to keep it. It
+// returns nil when the link wasn't proposed from a personal link, or the
+// promotion was already applied.
+func (d *DB) ApplyLinkPromotion(ctx context.Context, linkID uuid.UUID) (*models.LinkPromotion, error) {
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	p, err := scanLinkPromotion(tx.QueryRow(ctx, `
+		SELECT `+linkPromotionColumns+` FROM link_promotions
+		WHERE link_id = $1 AND applied_at IS NULL
+		FOR UPDATE
+	`, linkID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The personal link may have been removed while the proposal was pending.
+	if p.UserLinkID != nil {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO click_history (link_id, hour_bucket, click_count)
+			SELECT $1, hour_bucket, click_count FROM user_link_click_history WHERE user_link_id = $2
+			ON CONFLICT (link_id, hour_bucket)
+			DO UPDATE SET click_count = click_history.click_count + EXCLUDED.click_count
+		`, linkID, *p.UserLinkID); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE links SET click_count = click_count + COALESCE((SELECT click_count FROM user_links WHERE id = $2), 0)
+			WHERE id = $1
+		`, linkID, *p.UserLinkID); err != nil {
+			return nil, err
+		}
+		if !p.KeepPersonal {
+			if _, err := tx.Exec(ctx, `DELETE FROM user_links WHERE id = $1`, *p.UserLinkID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.QueryRow(ctx, `
+		UPDATE link_promotions SET applied_at = NOW() WHERE link_id = $1 RETURNING applied_at
+	`, linkID).Scan(&p.AppliedAt); err != nil {
+		return nil, err
+	}
+	return p, tx.Commit(ctx)
+}
+
+// GetLinkInterestedUserEmails returns the emails of the active users with a
+// stake in a link — its author, its submitter and its co-owners — as the
+// default recipients when a moderator retires it into personal links.
+func (d *DB) GetLinkInterestedUserEmails(ctx context.Context, linkID uuid.UUID) ([]string, error) {
+	rows, err := d.Pool.Query(ctx, `
+		SELECT u.email FROM users u, links l
+		WHERE l.id = $1 AND u.deactivated_at IS NULL
+			AND (u.id = l.created_by OR u.id = l.submitted_by OR `+linkOwnedBy("l", "u.id")+`)
+		ORDER BY u.email
+	`, linkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := []string{}
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
+
+// RetireLink demotes an approved org or global link into personal links: each
+// of userIDs gets a personal link with the same keyword, URL and description,
+// then the link is deleted. Users who already have a personal link for the
+// keyword keep theirs. It returns the users who received a new personal link.
+func (d *DB) RetireLink(ctx context.Context, linkID uuid.UUID, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	err = tx.QueryRow(ctx, `SELECT status FROM links WHERE id = $1 FOR UPDATE`, linkID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && status != models.StatusApproved) {
+		return nil, ErrLinkNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		INSERT INTO user_links (user_id, keyword, url, description)
+		SELECT u.id, l.keyword, l.url, l.description
+		FROM links l, users u
+		WHERE l.id = $1 AND u.id = ANY($2) AND u.deactivated_at IS NULL
+		ON CONFLICT (user_id, keyword) DO NOTHING
+		RETURNING user_id
+	`, linkID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	created := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		created = append(created, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM links WHERE id = $1`, linkID); err != nil {
+		return nil, err
+	}
+	return created, tx.Commit(ctx)
+}
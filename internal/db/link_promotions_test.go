@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"golinks/internal/models"
+)
+
+func TestLinkPromotion(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	user := &models.User{Sub: "promote-user", Email: "promote@example.com", Name: "Promoter"}
+	mod := &models.User{Sub: "promote-mod", Email: "promote-mod@example.com", Name: "Mod", Role: models.RoleGlobalMod}
+	for _, u := range []*models.User{user, mod} {
+		if err := db.UpsertUser(ctx, u); err != nil {
+			t.Fatalf("UpsertUser() error = %v", err)
+		}
+	}
+
+	personal := &models.UserLink{UserID: user.ID, Keyword: "runbook", URL: "https://runbook.example.com", Description: "On-call runbook"}
+	if err := db.CreateUserLink(ctx, personal); err != nil {
+		t.Fatalf("CreateUserLink() error = %v", err)
+	}
+	for _, hour := range []time.Time{
+		time.Now().Add(-40 * 24 * time.Hour).Truncate(time.Hour),
+		time.Now().Truncate(time.Hour),
+	} {
+		if _, err := db.Pool.Exec(ctx,
+			`INSERT INTO user_link_click_history (user_link_id, hour_bucket, click_count) VALUES ($1, $2, 1)`,
+			personal.ID, hour,
+		); err != nil {
+			t.Fatalf("failed to seed user_link_click_history: %v", err)
+		}
+	}
+	if _, err := db.Pool.Exec(ctx, `UPDATE user_links SET click_count = 2 WHERE id = $1`, personal.ID); err != nil {
+		t.Fatalf("failed to seed click count: %v", err)
+	}
+
+	link := &models.Link{
+		Keyword:     personal.Keyword,
+		URL:         personal.URL,
+		Description: personal.Description,
+		Scope:       models.ScopeGlobal,
+		Reason:      "the whole team uses it",
+		SubmittedBy: &user.ID,
+	}
+	if err := db.SubmitLinkForApproval(ctx, link); err != nil {
+		t.Fatalf("SubmitLinkForApproval() error = %v", err)
+	}
+
+	p, err := db.CreateLinkPromotion(ctx, link.ID, personal.ID, false)
+	if err != nil {
+		t.Fatalf("CreateLinkPromotion() error = %v", err)
+	}
+	if p.ClickCount != 2 || p.RecentClicks != 1 || p.LastUsedAt == nil {
+		t.Errorf("CreateLinkPromotion() evidence = %d clicks, %d recent, last used %v; want 2, 1, set",
+			p.ClickCount, p.RecentClicks, p.LastUsedAt)
+	}
+
+	promotions, err := db.GetLinkPromotions(ctx, []uuid.UUID{link.ID})
+	if err != nil {
+		t.Fatalf("GetLinkPromotions() error = %v", err)
+	}
+	if promotions[link.ID.String()] == nil {
+		t.Error("GetLinkPromotions() is missing the promoted link")
+	}
+
+	if err := db.ApproveLink(ctx, link.ID, mod.ID); err != nil {
+		t.Fatalf("ApproveLink() error = %v", err)
+	}
+	applied, err := db.ApplyLinkPromotion(ctx, link.ID)
+	if err != nil {
+		t.Fatalf("ApplyLinkPromotion() error = %v", err)
+	}
+	if applied == nil || applied.AppliedAt == nil {
+		t.Fatal("ApplyLinkPromotion() = nil, want the applied promotion")
+	}
+
+	approved, err := db.GetLinkByID(ctx, link.ID)
+	if err != nil {
+		t.Fatalf("GetLinkByID() error = %v", err)
+	}
+	if approved.ClickCount != 2 {
+		t.Errorf("promoted link ClickCount = %d, want 2", approved.ClickCount)
+	}
+	var buckets int
+	if err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM click_history WHERE link_id = $1`, link.ID).Scan(&buckets); err != nil {
+		t.Fatalf("failed to count click history: %v", err)
+	}
+	if buckets != 2 {
+		t.Errorf("promoted link has %d click history buckets, want 2", buckets)
+	}
+	if _, err := db.GetUserLinkByID(ctx, personal.ID, user.ID); !errors.Is(err, ErrUserLinkNotFound) {
+		t.Errorf("GetUserLinkByID() after promotion error = %v, want ErrUserLinkNotFound", err)
+	}
+
+	if again, err := db.ApplyLinkPromotion(ctx, link.ID); err != nil || again != nil {
+		t.Errorf("ApplyLinkPromotion() twice = %v, %v; want nil, nil", again, err)
+	}
+}
+
+func TestRetireLink(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	author := &models.User{Sub: "retire-author", Email: "author@example.com", Name: "Author"}
+	keeper := &models.User{Sub: "retire-keeper", Email: "keeper@example.com", Name: "Keeper"}
+	for _, u := range []*models.User{author, keeper} {
+		if err := db.UpsertUser(ctx, u); err != nil {
+			t.Fatalf("UpsertUser() error = %v", err)
+		}
+	}
+
+	link := &models.Link{Keyword: "oldwiki", URL: "https://wiki.example.com", Scope: models.ScopeGlobal, CreatedBy: &author.ID}
+	if err := db.CreateLink(ctx, link); err != nil {
+		t.Fatalf("CreateLink() error = %v", err)
+	}
+
+	emails, err := db.GetLinkInterestedUserEmails(ctx, link.ID)
+	if err != nil {
+		t.Fatalf("GetLinkInterestedUserEmails() error = %v", err)
+	}
+	if len(emails) != 1 || emails[0] != author.Email {
+		t.Errorf("GetLinkInterestedUserEmails() = %v, want [%s]", emails, author.Email)
+	}
+
+	// The keeper's own personal link for the keyword survives the retirement.
+	own := &models.UserLink{UserID: keeper.ID, Keyword: "oldwiki", URL: "https://mine.example.com"}
+	if err := db.CreateUserLink(ctx, own); err != nil {
+		t.Fatalf("CreateUserLink() error = %v", err)
+	}
+
+	ids, err := db.ResolveUserEmails(ctx, []string{"AUTHOR@example.com", keeper.Email, ""})
+	if err != nil {
+		t.Fatalf("ResolveUserEmails() error = %v", err)
+	}
+	if _, err := db.ResolveUserEmails(ctx, []string{"nobody@example.com"}); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("ResolveUserEmails(unknown) error = %v, want ErrUserNotFound", err)
+	}
+
+	created, err := db.RetireLink(ctx, link.ID, ids)
+	if err != nil {
+		t.Fatalf("RetireLink() error = %v", err)
+	}
+	if len(created) != 1 || created[0] != author.ID {
+		t.Errorf("RetireLink() created personal links for %v, want [%s]", created, author.ID)
+	}
+
+	if _, err := db.GetLinkByID(ctx, link.ID); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("GetLinkByID() after retirement error = %v, want ErrLinkNotFound", err)
+	}
+	copied, err := db.GetUserLinkByKeyword(ctx, author.ID, "oldwiki")
+	if err != nil {
+		t.Fatalf("GetUserLinkByKeyword() error = %v", err)
+	}
+	if copied.URL != link.URL {
+		t.Errorf("retired personal link URL = %q, want %q", copied.URL, link.URL)
+	}
+	kept, err := db.GetUserLinkByKeyword(ctx, keeper.ID, "oldwiki")
+	if err != nil {
+		t.Fatalf("GetUserLinkByKeyword() error = %v", err)
+	}
+	if kept.URL != own.URL {
+		t.Errorf("keeper's personal link URL = %q, want %q", kept.URL, own.URL)
+	}
+
+	if _, err := db.RetireLink(ctx, link.ID, ids); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("RetireLink() twice error = %v, want ErrLinkNotFound", err)
+	}
+}
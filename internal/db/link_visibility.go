@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"golinks/internal/models"
+)
+
+// linkVisibleTo returns an SQL condition that holds when the link in table or
+// alias link is visible to the user whose ID is the SQL expression viewer
+// (NULL for signed-out users): public links to everyone, authenticated links
+// to any user, org links to direct members of the link's organization, and
+// restricted links to the listed users and members of the listed OIDC or
+// SCIM groups. Authors and admins see all of their links.
+func linkVisibleTo(link, viewer string) string {
+	return `(` + link + `.visibility = 'public' OR EXISTS (
+		SELECT 1 FROM users viewer
+		WHERE viewer.id = ` + viewer + `::uuid AND (
+			` + link + `.visibility = 'authenticated'
+			OR viewer.role = 'admin'
+			OR viewer.id = ` + link + `.created_by OR viewer.id = ` + link + `.submitted_by
+			OR (` + link + `.visibility = 'org' AND EXISTS (
+				SELECT 1 FROM user_organizations m
+				WHERE m.user_id = viewer.id AND m.organization_id = ` + link + `.organization_id
+			))
+			OR (` + link + `.visibility = 'restricted' AND (
+				LOWER(viewer.email) = ANY(` + link + `.allowed_users)
+				OR viewer.groups && ` + link + `.allowed_groups
+				OR EXISTS (
+					SELECT 1 FROM scim_group_members gm
+					JOIN scim_groups g ON g.id = gm.group_id
+					WHERE gm.user_id = viewer.id AND g.display_name = ANY(` + link + `.allowed_groups)
+				)
+			))
+		)
+	))`
+}
+
+// LinkVisibleTo reports whether a link is visible to viewerID (nil for
+// signed-out users) under the link's visibility setting. It returns
+// ErrLinkNotFound for unknown links.
+func (d *DB) LinkVisibleTo(ctx context.Context, linkID uuid.UUID, viewerID *uuid.UUID) (bool, error) {
+	var visible bool
+	err := d.Pool.QueryRow(ctx, `SELECT `+linkVisibleTo("l", "$2")+` FROM links l WHERE l.id = $1`, linkID, viewerID).Scan(&visible)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrLinkNotFound
+	}
+	return visible, err
+}
+
+// UpdateLinkVisibility saves who can see a link. The link's visibility is
+// expected to have been checked with NormalizeVisibility.
+func (d *DB) UpdateLinkVisibility(ctx context.Context, link *models.Link) error {
+	query := `
+		UPDATE links
+		SET visibility = $1, allowed_groups = $2, allowed_users = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING updated_at
+	`
+	err := d.Pool.QueryRow(ctx, query, link.Visibility, link.AllowedGroups, link.AllowedUsers, link.ID).Scan(&link.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrLinkNotFound
+	}
+	return err
+}
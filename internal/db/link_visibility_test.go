@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"golinks/internal/models"
+)
+
+func TestLinkVisibility(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	org := createTestOrgs(t, db, "vis-platform")[0]
+
+	author := &models.User{Sub: "vis-author", Email: "author@example.com", Name: "Author"}
+	allowed := &models.User{Sub: "vis-allowed", Email: "Allowed@Example.com", Name: "Allowed"}
+	grouped := &models.User{Sub: "vis-grouped", Email: "grouped@example.com", Name: "Grouped"}
+	member := &models.User{Sub: "vis-member", Email: "member@example.com", Name: "Member"}
+	other := &models.User{Sub: "vis-other", Email: "other@example.com", Name: "Other"}
+	for _, u := range []*models.User{author, allowed, grouped, member, other} {
+		if err := db.UpsertUser(ctx, u); err != nil {
+			t.Fatalf("UpsertUser() error = %v", err)
+		}
+	}
+	if err := db.SetUserGroups(ctx, grouped.ID, []string{"finance"}); err != nil {
+		t.Fatalf("SetUserGroups() error = %v", err)
+	}
+	if err := db.AddUserOrganization(ctx, member.ID, org.ID, models.OrgRoleMember); err != nil {
+		t.Fatalf("AddUserOrganization() error = %v", err)
+	}
+
+	secret := &models.Link{
+		Keyword:       "payroll",
+		URL:           "https://payroll.example.com",
+		Scope:         models.ScopeGlobal,
+		CreatedBy:     &author.ID,
+		Visibility:    models.VisibilityRestricted,
+		AllowedGroups: []string{"finance"},
+		AllowedUsers:  []string{"allowed@example.com"},
+	}
+	internal := &models.Link{
+		Keyword:        "roadmap",
+		URL:            "https://roadmap.example.com",
+		Scope:          models.ScopeOrg,
+		OrganizationID: &org.ID,
+		Visibility:     models.VisibilityOrg,
+	}
+	signedIn := &models.Link{
+		Keyword:    "wiki",
+		URL:        "https://wiki.example.com",
+		Scope:      models.ScopeGlobal,
+		Visibility: models.VisibilityAuthenticated,
+	}
+	for _, l := range []*models.Link{secret, internal, signedIn} {
+		if err := db.CreateLink(ctx, l); err != nil {
+			t.Fatalf("CreateLink(%s) error = %v", l.Keyword, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		link   *models.Link
+		viewer *uuid.UUID
+		want   bool
+	}{
+		{"restricted, signed out", secret, nil, false},
+		{"restricted, author", secret, &author.ID, true},
+		{"restricted, listed user", secret, &allowed.ID, true},
+		{"restricted, listed group", secret, &grouped.ID, true},
+		{"restricted, other user", secret, &other.ID, false},
+		{"org, member", internal, &member.ID, true},
+		{"org, non-member", internal, &other.ID, false},
+		{"authenticated, signed out", signedIn, nil, false},
+		{"authenticated, any user", signedIn, &other.ID, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := db.LinkVisibleTo(ctx, tt.link.ID, tt.viewer)
+			if err != nil {
+				t.Fatalf("LinkVisibleTo() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("LinkVisibleTo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := db.LinkVisibleTo(ctx, uuid.New(), nil); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("LinkVisibleTo(unknown) error = %v, want ErrLinkNotFound", err)
+	}
+
+	// Hidden links are left out of search and don't resolve.
+	results, err := db.SearchApprovedLinks(ctx, "payroll", &other.ID, nil, "all", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchApprovedLinks() error = %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("SearchApprovedLinks() for other user returned %d results, want 0", len(results))
+	}
+	results, err = db.SearchApprovedLinks(ctx, "payroll", &grouped.ID, nil, "all", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchApprovedLinks() error = %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("SearchApprovedLinks() for group member returned %d results, want 1", len(results))
+	}
+
+	if _, err := db.ResolveKeywordForUser(ctx, &other.ID, nil, "payroll"); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("ResolveKeywordForUser(other) error = %v, want ErrLinkNotFound", err)
+	}
+	if _, err := db.ResolveKeywordForUser(ctx, nil, nil, "wiki"); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("ResolveKeywordForUser(signed out) error = %v, want ErrLinkNotFound", err)
+	}
+	resolved, err := db.ResolveKeywordForUser(ctx, &allowed.ID, nil, "payroll")
+	if err != nil {
+		t.Fatalf("ResolveKeywordForUser(allowed) error = %v", err)
+	}
+	if resolved.URL != secret.URL {
+		t.Errorf("ResolveKeywordForUser(allowed) URL = %q, want %q", resolved.URL, secret.URL)
+	}
+
+	// Opening the link to everyone makes it resolve for anyone.
+	secret.Visibility = models.VisibilityPublic
+	if err := secret.NormalizeVisibility(); err != nil {
+		t.Fatalf("NormalizeVisibility() error = %v", err)
+	}
+	if err := db.UpdateLinkVisibility(ctx, secret); err != nil {
+		t.Fatalf("UpdateLinkVisibility() error = %v", err)
+	}
+	if _, err := db.ResolveKeywordForUser(ctx, nil, nil, "payroll"); err != nil {
+		t.Errorf("ResolveKeywordForUser(public) error = %v", err)
+	}
+}
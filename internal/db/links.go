@@ -18,7 +18,7 @@ import (
 // linkColumns is the standard column list for link queries.
 const linkColumns = `id, keyword, url, description, scope, organization_id, status,
 	created_by, submitted_by, reviewed_by, reviewed_at, reason, click_count, created_at, updated_at,
-	health_status, health_checked_at, health_error`
+	health_status, health_checked_at, health_error, visibility, allowed_groups, allowed_users`
 
 // scanLink scans a row into a Link struct.
 func scanLink(row pgx.Row) (*models.Link, error) {
@@ -42,6 +42,9 @@ func scanLink(row pgx.Row) (*models.Link, error) {
 		&link.HealthStatus,
 		&link.HealthCheckedAt,
 		&link.HealthError,
+		&link.Visibility,
+		&link.AllowedGroups,
+		&link.AllowedUsers,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, ErrLinkNotFound
@@ -78,6 +81,9 @@ func scanLinks(rows pgx.Rows) ([]models.Link, error) {
 			&link.HealthStatus,
 			&link.HealthCheckedAt,
 			&link.HealthError,
+			&link.Visibility,
+			&link.AllowedGroups,
+			&link.AllowedUsers,
 		); err != nil {
 			return nil, err
 		}
@@ -90,8 +96,9 @@ func scanLinks(rows pgx.Rows) ([]models.Link, error) {
 // CreateLink creates a new link (for moderators creating approved links directly).
 func (d *DB) CreateLink(ctx context.Context, link *models.Link) error {
 	query := `
-		INSERT INTO links (keyword, url, description, scope, organization_id, status, created_by, reason)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO links (keyword, url, description, scope, organization_id, status, created_by, reason,
+		                   visibility, allowed_groups, allowed_users)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE(NULLIF($9, ''), 'public'), COALESCE($10, '{}'), COALESCE($11, '{}'))
 		RETURNING id, click_count, created_at, updated_at
 	`
 
@@ -110,6 +117,9 @@ func (d *DB) CreateLink(ctx context.Context, link *models.Link) error {
 		status,
 		link.CreatedBy,
 		link.Reason,
+		link.Visibility,
+		link.AllowedGroups,
+		link.AllowedUsers,
 	).Scan(&link.ID, &link.ClickCount, &link.CreatedAt, &link.UpdatedAt)
 
 	if err != nil {
@@ -127,8 +137,9 @@ func (d *DB) CreateLink(ctx context.Context, link *models.Link) error {
 // SubmitLinkForApproval creates a new link with pending status for moderator review.
 func (d *DB) SubmitLinkForApproval(ctx context.Context, link *models.Link) error {
 	query := `
-		INSERT INTO links (keyword, url, description, scope, organization_id, status, submitted_by, reason)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO links (keyword, url, description, scope, organization_id, status, submitted_by, reason,
+		                   visibility, allowed_groups, allowed_users)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE(NULLIF($9, ''), 'public'), COALESCE($10, '{}'), COALESCE($11, '{}'))
 		RETURNING id, click_count, created_at, updated_at
 	`
 
@@ -141,6 +152,9 @@ func (d *DB) SubmitLinkForApproval(ctx context.Context, link *models.Link) error
 		models.StatusPending,
 		link.SubmittedBy,
 		link.Reason,
+		link.Visibility,
+		link.AllowedGroups,
+		link.AllowedUsers,
 	).Scan(&link.ID, &link.ClickCount, &link.CreatedAt, &link.UpdatedAt)
 
 	if err != nil {
@@ -334,6 +348,7 @@ func (d *DB) GetPendingGlobalLinks(ctx context.Context) ([]models.Link, error) {
 		SELECT l.id, l.keyword, l.url, l.description, l.scope, l.organization_id, l.status,
 			l.created_by, l.submitted_by, l.reviewed_by, l.reviewed_at, l.reason, l.click_count,
 			l.created_at, l.updated_at, l.health_status, l.health_checked_at, l.health_error,
+			l.visibility, l.allowed_groups, l.allowed_users,
 			COALESCE(u.name, ''), COALESCE(u.email, '')
 		FROM links l
 		LEFT JOIN users u ON u.id = l.submitted_by
@@ -347,16 +362,18 @@ func (d *DB) GetPendingGlobalLinks(ctx context.Context) ([]models.Link, error) {
 	return scanLinksWithAuthor(rows)
 }
 
-// GetPendingOrgLinks retrieves all pending org links for a specific organization, including submitter info.
+// GetPendingOrgLinks retrieves all pending org links for an organization and
+// the organizations nested under it, including submitter info.
 func (d *DB) GetPendingOrgLinks(ctx context.Context, orgID uuid.UUID) ([]models.Link, error) {
 	sql := `
 		SELECT l.id, l.keyword, l.url, l.description, l.scope, l.organization_id, l.status,
 			l.created_by, l.submitted_by, l.reviewed_by, l.reviewed_at, l.reason, l.click_count,
 			l.created_at, l.updated_at, l.health_status, l.health_checked_at, l.health_error,
+			l.visibility, l.allowed_groups, l.allowed_users,
 			COALESCE(u.name, ''), COALESCE(u.email, '')
 		FROM links l
 		LEFT JOIN users u ON u.id = l.submitted_by
-		WHERE l.scope = $1 AND l.organization_id = $2 AND l.status = $3
+		WHERE l.scope = $1 AND l.organization_id IN ` + orgSubtree("$2") + ` AND l.status = $3
 		ORDER BY l.created_at ASC
 	`
 	rows, err := d.Pool.Query(ctx, sql, models.ScopeOrg, orgID, models.StatusPending)
@@ -372,6 +389,7 @@ func (d *DB) GetAllPendingOrgLinks(ctx context.Context) ([]models.Link, error) {
 		SELECT l.id, l.keyword, l.url, l.description, l.scope, l.organization_id, l.status,
 			l.created_by, l.submitted_by, l.reviewed_by, l.reviewed_at, l.reason, l.click_count,
 			l.created_at, l.updated_at, l.health_status, l.health_checked_at, l.health_error,
+			l.visibility, l.allowed_groups, l.allowed_users,
 			COALESCE(u.name, ''), COALESCE(u.email, '')
 		FROM links l
 		LEFT JOIN users u ON u.id = l.submitted_by
@@ -417,8 +435,10 @@ func (d *DB) GetApprovedOrgLinks(ctx context.Context, orgID uuid.UUID) ([]models
 
 // SearchApprovedLinks searches for approved links by keyword, URL, or description.
 // scope: "all" = global + org, "global" = global only, "org" = org only (requires orgID).
+// Org links include those inherited from the organizations orgID is nested in.
+// Only links visible to viewerID (nil for signed-out users) are returned.
 // offset enables pagination.
-func (d *DB) SearchApprovedLinks(ctx context.Context, queryStr string, orgID *uuid.UUID, scope string, limit int, offset int) ([]models.Link, error) {
+func (d *DB) SearchApprovedLinks(ctx context.Context, queryStr string, viewerID, orgID *uuid.UUID, scope string, limit int, offset int) ([]models.Link, error) {
 	if scope == "" {
 		scope = "all"
 	}
@@ -428,13 +448,14 @@ func (d *DB) SearchApprovedLinks(ctx context.Context, queryStr string, orgID *uu
 		WHERE status = $1
 			AND (
 				(scope = 'global' AND ($2 = 'all' OR $2 = 'global'))
-				OR (scope = 'org' AND $3::uuid IS NOT NULL AND organization_id = $3 AND ($2 = 'all' OR $2 = 'org'))
+				OR (scope = 'org' AND $3::uuid IS NOT NULL AND organization_id IN (SELECT id FROM ` + orgLineage("$3") + ` lineage) AND ($2 = 'all' OR $2 = 'org'))
 			)
 			AND ($4 = '' OR keyword ILIKE '%' || $4 || '%' OR url ILIKE '%' || $4 || '%' OR description ILIKE '%' || $4 || '%')
+			AND ` + linkVisibleTo("links", "$7") + `
 		ORDER BY click_count DESC, keyword ASC
 		LIMIT $5 OFFSET $6
 	`
-	rows, err := d.Pool.Query(ctx, sql, models.StatusApproved, scope, orgID, strings.TrimSpace(queryStr), limit, offset)
+	rows, err := d.Pool.Query(ctx, sql, models.StatusApproved, scope, orgID, strings.TrimSpace(queryStr), limit, offset, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -442,7 +463,7 @@ func (d *DB) SearchApprovedLinks(ctx context.Context, queryStr string, orgID *uu
 }
 
 // CountApprovedLinks returns the total number of approved links matching the given filters.
-func (d *DB) CountApprovedLinks(ctx context.Context, queryStr string, orgID *uuid.UUID, scope string) (int, error) {
+func (d *DB) CountApprovedLinks(ctx context.Context, queryStr string, viewerID, orgID *uuid.UUID, scope string) (int, error) {
 	if scope == "" {
 		scope = "all"
 	}
@@ -452,22 +473,24 @@ func (d *DB) CountApprovedLinks(ctx context.Context, queryStr string, orgID *uui
 		WHERE status = $1
 			AND (
 				(scope = 'global' AND ($2 = 'all' OR $2 = 'global'))
-				OR (scope = 'org' AND $3::uuid IS NOT NULL AND organization_id = $3 AND ($2 = 'all' OR $2 = 'org'))
+				OR (scope = 'org' AND $3::uuid IS NOT NULL AND organization_id IN (SELECT id FROM ` + orgLineage("$3") + ` lineage) AND ($2 = 'all' OR $2 = 'org'))
 			)
 			AND ($4 = '' OR keyword ILIKE '%' || $4 || '%' OR url ILIKE '%' || $4 || '%' OR description ILIKE '%' || $4 || '%')
+			AND ` + linkVisibleTo("links", "$5") + `
 	`
 	var count int
-	err := d.Pool.QueryRow(ctx, sql, models.StatusApproved, scope, orgID, strings.TrimSpace(queryStr)).Scan(&count)
+	err := d.Pool.QueryRow(ctx, sql, models.StatusApproved, scope, orgID, strings.TrimSpace(queryStr), viewerID).Scan(&count)
 	return count, err
 }
 
-// SearchLinks is kept for backwards compatibility - searches approved global links.
+// SearchLinks is kept for backwards compatibility - searches approved public global links.
 func (d *DB) SearchLinks(ctx context.Context, query string, limit int) ([]models.Link, error) {
-	return d.SearchApprovedLinks(ctx, query, nil, "all", limit, 0)
+	return d.SearchApprovedLinks(ctx, query, nil, nil, "all", limit, 0)
 }
 
 // SearchLinksForUser searches approved links plus the user's personal links.
-// Personal links are included at the top of results; org and global links follow.
+// Personal links are included at the top of results; org (including inherited)
+// and global links the user can see follow.
 func (d *DB) SearchLinksForUser(ctx context.Context, queryStr string, userID uuid.UUID, orgID *uuid.UUID, limit int) ([]models.Link, error) {
 	sql := `
 		WITH combined AS (
@@ -475,18 +498,20 @@ func (d *DB) SearchLinksForUser(ctx context.Context, queryStr string, userID uui
 				'approved' AS status, NULL::uuid AS created_by, NULL::uuid AS submitted_by,
 				NULL::uuid AS reviewed_by, NULL::timestamp AS reviewed_at,
 				'' AS reason, click_count, created_at, updated_at,
-				health_status, health_checked_at, health_error
+				health_status, health_checked_at, health_error,
+				'public' AS visibility, '{}'::text[] AS allowed_groups, '{}'::text[] AS allowed_users
 			FROM user_links
 			WHERE user_id = $2
 				AND ($3 = '' OR keyword ILIKE '%' || $3 || '%' OR url ILIKE '%' || $3 || '%' OR description ILIKE '%' || $3 || '%')
 			UNION ALL
 			SELECT id, keyword, url, description, scope, organization_id, status,
 				created_by, submitted_by, reviewed_by, reviewed_at, reason, click_count, created_at, updated_at,
-				health_status, health_checked_at, health_error
+				health_status, health_checked_at, health_error, visibility, allowed_groups, allowed_users
 			FROM links
 			WHERE status = $1
-				AND (scope = 'global' OR ($4::uuid IS NOT NULL AND scope = 'org' AND organization_id = $4))
+				AND (scope = 'global' OR ($4::uuid IS NOT NULL AND scope = 'org' AND organization_id IN (SELECT id FROM ` + orgLineage("$4") + ` lineage)))
 				AND ($3 = '' OR keyword ILIKE '%' || $3 || '%' OR url ILIKE '%' || $3 || '%' OR description ILIKE '%' || $3 || '%')
+				AND ` + linkVisibleTo("links", "$2") + `
 		)
 		SELECT * FROM combined ORDER BY click_count DESC, keyword ASC LIMIT $5
 	`
@@ -504,7 +529,8 @@ func (d *DB) SearchPersonalLinks(ctx context.Context, userID uuid.UUID, queryStr
 			'approved' AS status, NULL::uuid AS created_by, NULL::uuid AS submitted_by,
 			NULL::uuid AS reviewed_by, NULL::timestamp AS reviewed_at,
 			'' AS reason, click_count, created_at, updated_at,
-			health_status, health_checked_at, health_error
+			health_status, health_checked_at, health_error,
+			'public' AS visibility, '{}'::text[] AS allowed_groups, '{}'::text[] AS allowed_users
 		FROM user_links
 		WHERE user_id = $1
 			AND ($2 = '' OR keyword ILIKE '%' || $2 || '%' OR url ILIKE '%' || $2 || '%' OR description ILIKE '%' || $2 || '%')
@@ -657,6 +683,7 @@ func (d *DB) GetLinksForManagement(ctx context.Context, user *models.User, healt
 			SELECT l.id, l.keyword, l.url, l.description, l.scope, l.organization_id, l.status,
 				l.created_by, l.submitted_by, l.reviewed_by, l.reviewed_at, l.reason, l.click_count,
 				l.created_at, l.updated_at, l.health_status, l.health_checked_at, l.health_error,
+				l.visibility, l.allowed_groups, l.allowed_users,
 				COALESCE(u.name, ''), COALESCE(u.email, '')
 			FROM links l
 			LEFT JOIN users u ON u.id = COALESCE(l.created_by, l.submitted_by)
@@ -668,12 +695,14 @@ func (d *DB) GetLinksForManagement(ctx context.Context, user *models.User, healt
 			SELECT l.id, l.keyword, l.url, l.description, l.scope, l.organization_id, l.status,
 				l.created_by, l.submitted_by, l.reviewed_by, l.reviewed_at, l.reason, l.click_count,
 				l.created_at, l.updated_at, l.health_status, l.health_checked_at, l.health_error,
+				l.visibility, l.allowed_groups, l.allowed_users,
 				COALESCE(u.name, ''), COALESCE(u.email, '')
 			FROM links l
 			LEFT JOIN users u ON u.id = COALESCE(l.created_by, l.submitted_by)
-			WHERE l.status IN ($1, $2) AND l.scope = $3 AND l.organization_id = $4
+			WHERE l.status IN ($1, $2)
+				AND ((l.scope = $3 AND l.organization_id IN ` + orgSubtree("$4") + `) OR ` + linkOwnedBy("l", "$5") + `)
 		`
-		args = []any{models.StatusApproved, models.StatusDeletionRequested, models.ScopeOrg, *user.OrganizationID}
+		args = []any{models.StatusApproved, models.StatusDeletionRequested, models.ScopeOrg, *user.OrganizationID, user.ID}
 	} else {
 		return d.GetAuthoredLinksForUser(ctx, user.ID, healthFilter, scope, search, limit, offset)
 	}
@@ -717,8 +746,9 @@ func (d *DB) CountLinksForManagement(ctx context.Context, user *models.User, hea
 		sql = `SELECT COUNT(*) FROM links l WHERE l.status IN ($1, $2)`
 		args = []any{models.StatusApproved, models.StatusDeletionRequested}
 	} else if user.IsOrgMod() && user.OrganizationID != nil {
-		sql = `SELECT COUNT(*) FROM links l WHERE l.status IN ($1, $2) AND l.scope = $3 AND l.organization_id = $4`
-		args = []any{models.StatusApproved, models.StatusDeletionRequested, models.ScopeOrg, *user.OrganizationID}
+		sql = `SELECT COUNT(*) FROM links l WHERE l.status IN ($1, $2)
+			AND ((l.scope = $3 AND l.organization_id IN ` + orgSubtree("$4") + `) OR ` + linkOwnedBy("l", "$5") + `)`
+		args = []any{models.StatusApproved, models.StatusDeletionRequested, models.ScopeOrg, *user.OrganizationID, user.ID}
 	} else {
 		return d.countAuthoredLinksForUser(ctx, user.ID, healthFilter, scope, search)
 	}
@@ -768,6 +798,9 @@ func scanLinksWithAuthor(rows pgx.Rows) ([]models.Link, error) {
 			&link.HealthStatus,
 			&link.HealthCheckedAt,
 			&link.HealthError,
+			&link.Visibility,
+			&link.AllowedGroups,
+			&link.AllowedUsers,
 			&link.AuthorName,
 			&link.AuthorEmail,
 		); err != nil {
@@ -779,7 +812,8 @@ func scanLinksWithAuthor(rows pgx.Rows) ([]models.Link, error) {
 	return links, rows.Err()
 }
 
-// GetAuthoredLinksForUser returns links where the user is the author.
+// GetAuthoredLinksForUser returns links where the user is the author, plus
+// approved links they co-own.
 func (d *DB) GetAuthoredLinksForUser(ctx context.Context, userID uuid.UUID, healthFilter string, scope string, search string, limit int, offset int) ([]models.Link, error) {
 	if scope == "" {
 		scope = "all"
@@ -787,9 +821,10 @@ func (d *DB) GetAuthoredLinksForUser(ctx context.Context, userID uuid.UUID, heal
 	sql := `
 		SELECT ` + linkColumns + `
 		FROM links
-		WHERE (created_by = $1 OR (submitted_by = $1 AND status IN ($2, $3)))
+		WHERE (created_by = $1 OR (submitted_by = $1 AND status IN ($2, $3))
+			OR (status IN ($4, $3) AND ` + linkOwnedBy("links", "$1") + `))
 	`
-	args := []any{userID, models.StatusPending, models.StatusDeletionRequested}
+	args := []any{userID, models.StatusPending, models.StatusDeletionRequested, models.StatusApproved}
 
 	if scope != "all" {
 		sql += ` AND scope = $` + strconv.Itoa(len(args)+1)
@@ -818,9 +853,10 @@ func (d *DB) GetAuthoredLinksForUser(ctx context.Context, userID uuid.UUID, heal
 func (d *DB) countAuthoredLinksForUser(ctx context.Context, userID uuid.UUID, healthFilter string, scope string, search string) (int, error) {
 	sql := `
 		SELECT COUNT(*) FROM links
-		WHERE (created_by = $1 OR (submitted_by = $1 AND status IN ($2, $3)))
+		WHERE (created_by = $1 OR (submitted_by = $1 AND status IN ($2, $3))
+			OR (status IN ($4, $3) AND ` + linkOwnedBy("links", "$1") + `))
 	`
-	args := []any{userID, models.StatusPending, models.StatusDeletionRequested}
+	args := []any{userID, models.StatusPending, models.StatusDeletionRequested, models.StatusApproved}
 
 	if scope != "all" {
 		sql += ` AND scope = $` + strconv.Itoa(len(args)+1)
@@ -921,6 +957,7 @@ func (d *DB) GetPendingDeletionRequests(ctx context.Context, user *models.User)
 			SELECT l.id, l.keyword, l.url, l.description, l.scope, l.organization_id, l.status,
 				l.created_by, l.submitted_by, l.reviewed_by, l.reviewed_at, l.reason, l.click_count,
 				l.created_at, l.updated_at, l.health_status, l.health_checked_at, l.health_error,
+				l.visibility, l.allowed_groups, l.allowed_users,
 				COALESCE(u.name, ''), COALESCE(u.email, '')
 			FROM links l
 			LEFT JOIN users u ON u.id = COALESCE(l.created_by, l.submitted_by)
@@ -933,10 +970,11 @@ func (d *DB) GetPendingDeletionRequests(ctx context.Context, user *models.User)
 			SELECT l.id, l.keyword, l.url, l.description, l.scope, l.organization_id, l.status,
 				l.created_by, l.submitted_by, l.reviewed_by, l.reviewed_at, l.reason, l.click_count,
 				l.created_at, l.updated_at, l.health_status, l.health_checked_at, l.health_error,
+				l.visibility, l.allowed_groups, l.allowed_users,
 				COALESCE(u.name, ''), COALESCE(u.email, '')
 			FROM links l
 			LEFT JOIN users u ON u.id = COALESCE(l.created_by, l.submitted_by)
-			WHERE l.status = $1 AND l.scope = $2 AND l.organization_id = $3
+			WHERE l.status = $1 AND l.scope = $2 AND l.organization_id IN ` + orgSubtree("$3") + `
 			ORDER BY l.updated_at ASC
 		`
 		args = []any{models.StatusDeletionRequested, models.ScopeOrg, *user.OrganizationID}
@@ -951,7 +989,8 @@ func (d *DB) GetPendingDeletionRequests(ctx context.Context, user *models.User)
 	return scanLinksWithAuthor(rows)
 }
 
-// GetTopUsedLinksForUser retrieves the most used keywords for a user (personal + org + global).
+// GetTopUsedLinksForUser retrieves the most used keywords for a user (personal + org + global),
+// leaving out links hidden from the user.
 func (d *DB) GetTopUsedLinksForUser(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, limit int) ([]models.Link, error) {
 	rows, err := d.Pool.Query(ctx, `
 		WITH combined AS (
@@ -959,13 +998,15 @@ func (d *DB) GetTopUsedLinksForUser(ctx context.Context, userID uuid.UUID, orgID
 				'approved' as status, NULL::uuid as created_by, NULL::uuid as submitted_by,
 				NULL::uuid as reviewed_by, NULL::timestamp as reviewed_at,
 				'' as reason, click_count, created_at, updated_at,
-				health_status, health_checked_at, health_error
+				health_status, health_checked_at, health_error,
+				'public' as visibility, '{}'::text[] as allowed_groups, '{}'::text[] as allowed_users
 			FROM user_links WHERE user_id = $1
 			UNION ALL
 			SELECT `+linkColumns+`
 			FROM links
 			WHERE status = $2
 				AND (scope = 'global' OR ($3::uuid IS NOT NULL AND scope = 'org' AND organization_id = $3))
+				AND `+linkVisibleTo("links", "$1")+`
 		)
 		SELECT * FROM combined ORDER BY click_count DESC LIMIT $4
 	`, userID, models.StatusApproved, orgID, limit)
@@ -979,8 +1020,9 @@ func (d *DB) GetTopUsedLinksForUser(ctx context.Context, userID uuid.UUID, orgID
 // time-decay score computed from click_history. Each hourly bucket is weighted
 // by EXP(-age_hours/24) so clicks older than ~24 hours contribute less than
 // recent ones, making it hard to hold the top spot via a short spam burst.
-// Links with no recent history fall back to a score of 0.
-func (d *DB) GetTopApprovedLinks(ctx context.Context, orgID *uuid.UUID, limit int) ([]models.Link, error) {
+// Links with no recent history fall back to a score of 0. Only links visible
+// to viewerID (nil for signed-out users) are returned.
+func (d *DB) GetTopApprovedLinks(ctx context.Context, viewerID, orgID *uuid.UUID, limit int) ([]models.Link, error) {
 	rows, err := d.Pool.Query(ctx, `
 		WITH recent_activity AS (
 			SELECT
@@ -997,50 +1039,55 @@ func (d *DB) GetTopApprovedLinks(ctx context.Context, orgID *uuid.UUID, limit in
 		LEFT JOIN recent_activity ON recent_activity.link_id = links.id
 		WHERE links.status = $1
 			AND (links.scope = 'global' OR ($2::uuid IS NOT NULL AND links.scope = 'org' AND links.organization_id = $2))
+			AND `+linkVisibleTo("links", "$4")+`
 		ORDER BY COALESCE(recent_activity.decay_score, 0) DESC, links.keyword ASC
 		LIMIT $3
-	`, models.StatusApproved, orgID, limit)
+	`, models.StatusApproved, orgID, limit, viewerID)
 	if err != nil {
 		return nil, err
 	}
 	return scanLinks(rows)
 }
 
-// GetNewestApprovedLinks retrieves the newest approved links (global + org if provided).
-func (d *DB) GetNewestApprovedLinks(ctx context.Context, orgID *uuid.UUID, limit int) ([]models.Link, error) {
+// GetNewestApprovedLinks retrieves the newest approved links (global + org if
+// provided) visible to viewerID.
+func (d *DB) GetNewestApprovedLinks(ctx context.Context, viewerID, orgID *uuid.UUID, limit int) ([]models.Link, error) {
 	rows, err := d.Pool.Query(ctx, `
 		SELECT `+linkColumns+`
 		FROM links
 		WHERE status = $1
 			AND (scope = 'global' OR ($2::uuid IS NOT NULL AND scope = 'org' AND organization_id = $2))
+			AND `+linkVisibleTo("links", "$4")+`
 		ORDER BY created_at DESC
 		LIMIT $3
-	`, models.StatusApproved, orgID, limit)
+	`, models.StatusApproved, orgID, limit, viewerID)
 	if err != nil {
 		return nil, err
 	}
 	return scanLinks(rows)
 }
 
-// GetRandomApprovedLinks retrieves random approved links (global + org if provided).
-func (d *DB) GetRandomApprovedLinks(ctx context.Context, orgID *uuid.UUID, limit int) ([]models.Link, error) {
+// GetRandomApprovedLinks retrieves random approved links (global + org if
+// provided) visible to viewerID.
+func (d *DB) GetRandomApprovedLinks(ctx context.Context, viewerID, orgID *uuid.UUID, limit int) ([]models.Link, error) {
 	rows, err := d.Pool.Query(ctx, `
 		SELECT `+linkColumns+`
 		FROM links
 		WHERE status = $1
 			AND (scope = 'global' OR ($2::uuid IS NOT NULL AND scope = 'org' AND organization_id = $2))
+			AND `+linkVisibleTo("links", "$4")+`
 		ORDER BY RANDOM()
 		LIMIT $3
-	`, models.StatusApproved, orgID, limit)
+	`, models.StatusApproved, orgID, limit, viewerID)
 	if err != nil {
 		return nil, err
 	}
 	return scanLinks(rows)
 }
 
-// GetRandomApprovedLink retrieves a single random approved link.
-func (d *DB) GetRandomApprovedLink(ctx context.Context, orgID *uuid.UUID) (*models.Link, error) {
-	links, err := d.GetRandomApprovedLinks(ctx, orgID, 1)
+// GetRandomApprovedLink retrieves a single random approved link visible to viewerID.
+func (d *DB) GetRandomApprovedLink(ctx context.Context, viewerID, orgID *uuid.UUID) (*models.Link, error) {
+	links, err := d.GetRandomApprovedLinks(ctx, viewerID, orgID, 1)
 	if err != nil {
 		return nil, err
 	}
@@ -1052,16 +1099,18 @@ func (d *DB) GetRandomApprovedLink(ctx context.Context, orgID *uuid.UUID) (*mode
 
 // GetSimilarKeywords returns approved links with keywords similar to the input,
 // ranked by trigram similarity. Uses the pg_trgm extension (idx_links_keyword_trgm index).
-func (d *DB) GetSimilarKeywords(ctx context.Context, keyword string, orgID *uuid.UUID, limit int) ([]models.Link, error) {
+// Only links visible to viewerID (nil for signed-out users) are suggested.
+func (d *DB) GetSimilarKeywords(ctx context.Context, keyword string, viewerID, orgID *uuid.UUID, limit int) ([]models.Link, error) {
 	rows, err := d.Pool.Query(ctx, `
 		SELECT `+linkColumns+`
 		FROM links
 		WHERE status = $1
 			AND (scope = 'global' OR ($2::uuid IS NOT NULL AND scope = 'org' AND organization_id = $2))
 			AND similarity(keyword, $3) > 0.15
+			AND `+linkVisibleTo("links", "$5")+`
 		ORDER BY similarity(keyword, $3) DESC
 		LIMIT $4
-	`, models.StatusApproved, orgID, keyword, limit)
+	`, models.StatusApproved, orgID, keyword, limit, viewerID)
 	if err != nil {
 		return nil, err
 	}
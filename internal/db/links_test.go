@@ -41,6 +41,7 @@ func setupTestDB(t *testing.T) (*DB, func()) {
 		// Clean up in order
 		database.Pool.Exec(ctx, "DELETE FROM user_links")
 		database.Pool.Exec(ctx, "DELETE FROM links")
+		database.Pool.Exec(ctx, "DELETE FROM scim_groups")
 		database.Pool.Exec(ctx, "DELETE FROM users")
 		database.Pool.Exec(ctx, "DELETE FROM organizations")
 		database.Close()
@@ -49,6 +50,7 @@ func setupTestDB(t *testing.T) (*DB, func()) {
 	// Clean before test
 	database.Pool.Exec(ctx, "DELETE FROM user_links")
 	database.Pool.Exec(ctx, "DELETE FROM links")
+	database.Pool.Exec(ctx, "DELETE FROM scim_groups")
 	database.Pool.Exec(ctx, "DELETE FROM users")
 	database.Pool.Exec(ctx, "DELETE FROM organizations")
 
@@ -347,7 +349,7 @@ func TestSearchApprovedLinks(t *testing.T) {
 	}
 
 	// Search for "go"
-	results, err := db.SearchApprovedLinks(ctx, "go", nil, "all", 10, 0)
+	results, err := db.SearchApprovedLinks(ctx, "go", nil, nil, "all", 10, 0)
 	if err != nil {
 		t.Fatalf("SearchApprovedLinks() error = %v", err)
 	}
@@ -356,7 +358,7 @@ func TestSearchApprovedLinks(t *testing.T) {
 	}
 
 	// Search with empty query returns all
-	all, err := db.SearchApprovedLinks(ctx, "", nil, "all", 10, 0)
+	all, err := db.SearchApprovedLinks(ctx, "", nil, nil, "all", 10, 0)
 	if err != nil {
 		t.Fatalf("SearchApprovedLinks('') error = %v", err)
 	}
@@ -556,7 +558,7 @@ func TestGetNewestApprovedLinks(t *testing.T) {
 	}
 
 	// Get newest 5 links
-	newestLinks, err := db.GetNewestApprovedLinks(ctx, nil, 5)
+	newestLinks, err := db.GetNewestApprovedLinks(ctx, nil, nil, 5)
 	if err != nil {
 		t.Fatalf("GetNewestApprovedLinks() error = %v", err)
 	}
@@ -605,7 +607,7 @@ func TestGetNewestApprovedLinks_WithOrg(t *testing.T) {
 	}
 
 	// Get newest links with org ID - should include both
-	newestLinks, err := db.GetNewestApprovedLinks(ctx, &org.ID, 5)
+	newestLinks, err := db.GetNewestApprovedLinks(ctx, nil, &org.ID, 5)
 	if err != nil {
 		t.Fatalf("GetNewestApprovedLinks() error = %v", err)
 	}
@@ -639,7 +641,7 @@ func TestGetRandomApprovedLinks(t *testing.T) {
 	}
 
 	// Get 5 random links
-	randomLinks, err := db.GetRandomApprovedLinks(ctx, nil, 5)
+	randomLinks, err := db.GetRandomApprovedLinks(ctx, nil, nil, 5)
 	if err != nil {
 		t.Fatalf("GetRandomApprovedLinks() error = %v", err)
 	}
@@ -694,7 +696,7 @@ func TestGetRandomApprovedLinks_ExcludesPending(t *testing.T) {
 	}
 
 	// Get random links - should only return approved
-	randomLinks, err := db.GetRandomApprovedLinks(ctx, nil, 10)
+	randomLinks, err := db.GetRandomApprovedLinks(ctx, nil, nil, 10)
 	if err != nil {
 		t.Fatalf("GetRandomApprovedLinks() error = %v", err)
 	}
@@ -727,7 +729,7 @@ func TestGetRandomApprovedLink(t *testing.T) {
 	}
 
 	// Get a single random link
-	randomLink, err := db.GetRandomApprovedLink(ctx, nil)
+	randomLink, err := db.GetRandomApprovedLink(ctx, nil, nil)
 	if err != nil {
 		t.Fatalf("GetRandomApprovedLink() error = %v", err)
 	}
@@ -748,7 +750,7 @@ func TestGetRandomApprovedLink_NoLinks(t *testing.T) {
 	ctx := context.Background()
 
 	// Try to get random link when none exist
-	_, err := db.GetRandomApprovedLink(ctx, nil)
+	_, err := db.GetRandomApprovedLink(ctx, nil, nil)
 	if err != ErrLinkNotFound {
 		t.Errorf("GetRandomApprovedLink() error = %v, want ErrLinkNotFound", err)
 	}
@@ -0,0 +1,234 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"golinks/internal/models"
+)
+
+// OffboardOptions controls what OffboardUser does with a departing user's
+// links and requests.
+type OffboardOptions struct {
+	// TransferTo becomes the author of the user's links and pending
+	// submissions and takes over their pending edit requests. Nil hands the
+	// links to the organization: they keep no individual author and are
+	// managed by moderators, and pending edit requests stay with the
+	// departing user for moderators to review.
+	TransferTo *uuid.UUID
+
+	// SubmitUserLinks lists personal links to submit for approval as links
+	// in the user's organization (global links for users without one).
+	SubmitUserLinks []uuid.UUID
+
+	// Reason is recorded on the submissions.
+	Reason string
+}
+
+// OffboardResult summarizes what OffboardUser changed.
+type OffboardResult struct {
+	LinksTransferred        int64    `json:"links_transferred"`
+	LinksSubmitted          []string `json:"links_submitted"`  // keywords of submitted personal links
+	KeywordsSkipped         []string `json:"keywords_skipped"` // personal links not submitted because the keyword is taken
+	EditRequestsTransferred int64    `json:"edit_requests_transferred"`
+	EditRequestsWithdrawn   int64    `json:"edit_requests_withdrawn"` // the new owner already had one pending for the link
+	SharesDiscarded         int64    `json:"shares_discarded"`
+}
+
+// OffboardUser deactivates a user and hands over what they leave behind, in
+// one transaction: their authored links and pending submissions move to
+// opts.TransferTo (or the organization), the selected personal links are
+// submitted for approval, pending edit requests follow the links, and links
+// shared with them that they never accepted are discarded. Links they shared
+// with others stay on offer. Personal links are kept with the deactivated
+// account.
+func (d *DB) OffboardUser(ctx context.Context, userID uuid.UUID, opts OffboardOptions) (*OffboardResult, error) {
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var orgID *uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT organization_id FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&orgID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OffboardResult{LinksSubmitted: []string{}, KeywordsSkipped: []string{}}
+
+	tag, err := tx.Exec(ctx, `UPDATE links SET created_by = $2, updated_at = NOW() WHERE created_by = $1`, userID, opts.TransferTo)
+	if err != nil {
+		return nil, err
+	}
+	result.LinksTransferred = tag.RowsAffected()
+
+	tag, err = tx.Exec(ctx, `
+		UPDATE links SET submitted_by = $2, updated_at = NOW()
+		WHERE submitted_by = $1 AND status = $3
+	`, userID, opts.TransferTo, models.StatusPending)
+	if err != nil {
+		return nil, err
+	}
+	result.LinksTransferred += tag.RowsAffected()
+
+	if err := submitUserLinks(ctx, tx, userID, orgID, opts, result); err != nil {
+		return nil, err
+	}
+
+	if opts.TransferTo != nil {
+		// The new owner may already have a pending request for the same
+		// link; only one is allowed, so theirs wins.
+		tag, err = tx.Exec(ctx, `
+			DELETE FROM link_edit_requests r
+			WHERE r.user_id = $1 AND r.status = $3
+			  AND EXISTS (
+				SELECT 1 FROM link_edit_requests o
+				WHERE o.link_id = r.link_id AND o.user_id = $2 AND o.status = $3
+			  )
+		`, userID, *opts.TransferTo, models.StatusPending)
+		if err != nil {
+			return nil, err
+		}
+		result.EditRequestsWithdrawn = tag.RowsAffected()
+
+		tag, err = tx.Exec(ctx, `
+			UPDATE link_edit_requests SET user_id = $2
+			WHERE user_id = $1 AND status = $3
+		`, userID, *opts.TransferTo, models.StatusPending)
+		if err != nil {
+			return nil, err
+		}
+		result.EditRequestsTransferred = tag.RowsAffected()
+	}
+
+	tag, err = tx.Exec(ctx, `DELETE FROM shared_links WHERE recipient_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	result.SharesDiscarded = tag.RowsAffected()
+
+	_, err = tx.Exec(ctx, `UPDATE users SET deactivated_at = COALESCE(deactivated_at, NOW()), updated_at = NOW() WHERE id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// submitUserLinks submits the selected personal links for approval in the
+// user's organization, skipping keywords that are already taken there.
+func submitUserLinks(ctx context.Context, tx pgx.Tx, userID uuid.UUID, orgID *uuid.UUID, opts OffboardOptions, result *OffboardResult) error {
+	if len(opts.SubmitUserLinks) == 0 {
+		return nil
+	}
+	scope := models.ScopeGlobal
+	if orgID != nil {
+		scope = models.ScopeOrg
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT keyword, url, description FROM user_links
+		WHERE user_id = $1 AND id = ANY($2)
+		ORDER BY keyword ASC
+	`, userID, opts.SubmitUserLinks)
+	if err != nil {
+		return err
+	}
+	var links []models.UserLink
+	for rows.Next() {
+		var l models.UserLink
+		if err := rows.Scan(&l.Keyword, &l.URL, &l.Description); err != nil {
+			rows.Close()
+			return err
+		}
+		links = append(links, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, l := range links {
+		var id uuid.UUID
+		err := tx.QueryRow(ctx, `
+			INSERT INTO links (keyword, url, description, scope, organization_id, status, submitted_by, reason)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT DO NOTHING
+			RETURNING id
+		`, l.Keyword, l.URL, l.Description, scope, orgID, models.StatusPending, opts.TransferTo, opts.Reason).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			result.KeywordsSkipped = append(result.KeywordsSkipped, l.Keyword)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		result.LinksSubmitted = append(result.LinksSubmitted, l.Keyword)
+	}
+	return nil
+}
+
+// CountAuthoredLinks counts the links a user authors: those they created and
+// their pending submissions.
+func (d *DB) CountAuthoredLinks(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := d.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM links
+		WHERE created_by = $1 OR (submitted_by = $1 AND status = $2)
+	`, userID, models.StatusPending).Scan(&count)
+	return count, err
+}
+
+// DeactivateInactiveUsers deactivates users whose last activity is older than
+// before. Activity is the later of their last sign-in and their last tracked
+// session request; users who never signed in count from when they were
+// created. Admins are never deactivated automatically. It returns the IDs of
+// the users deactivated.
+func (d *DB) DeactivateInactiveUsers(ctx context.Context, before time.Time) ([]uuid.UUID, error) {
+	rows, err := d.Pool.Query(ctx, `
+		UPDATE users u
+		SET deactivated_at = NOW(), updated_at = NOW()
+		WHERE u.deactivated_at IS NULL
+		  AND u.role <> $2
+		  AND GREATEST(
+			u.created_at,
+			u.last_login_at,
+			(SELECT MAX(s.last_seen_at) FROM user_sessions s WHERE s.user_id = u.id)
+		  ) < $1
+		RETURNING u.id
+	`, before, models.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// CountPendingEditRequestsByUser counts a user's pending edit requests.
+func (d *DB) CountPendingEditRequestsByUser(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := d.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM link_edit_requests WHERE user_id = $1 AND status = $2
+	`, userID, models.StatusPending).Scan(&count)
+	return count, err
+}
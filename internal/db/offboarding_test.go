@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"golinks/internal/models"
+)
+
+func TestOffboardUser(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	leaver := &models.User{Sub: "offboard-leaver", Email: "leaver@example.com", Name: "Leaver"}
+	heir := &models.User{Sub: "offboard-heir", Email: "heir@example.com", Name: "Heir"}
+	for _, u := range []*models.User{leaver, heir} {
+		if err := db.UpsertUser(ctx, u); err != nil {
+			t.Fatalf("UpsertUser() error = %v", err)
+		}
+	}
+
+	authored := &models.Link{Keyword: "offboard-authored", URL: "https://example.com/a", Scope: models.ScopeGlobal, CreatedBy: &leaver.ID}
+	if err := db.CreateLink(ctx, authored); err != nil {
+		t.Fatalf("CreateLink() error = %v", err)
+	}
+	taken := &models.Link{Keyword: "offboard-taken", URL: "https://example.com/t", Scope: models.ScopeGlobal}
+	if err := db.CreateLink(ctx, taken); err != nil {
+		t.Fatalf("CreateLink() error = %v", err)
+	}
+
+	var personal []uuid.UUID
+	for _, kw := range []string{"offboard-personal", "offboard-taken"} {
+		ul := &models.UserLink{UserID: leaver.ID, Keyword: kw, URL: "https://example.com/p"}
+		if err := db.CreateUserLink(ctx, ul); err != nil {
+			t.Fatalf("CreateUserLink() error = %v", err)
+		}
+		personal = append(personal, ul.ID)
+	}
+
+	req := &models.LinkEditRequest{LinkID: taken.ID, UserID: leaver.ID, URL: "https://example.com/new", Reason: "moved"}
+	if err := db.CreateEditRequest(ctx, req); err != nil {
+		t.Fatalf("CreateEditRequest() error = %v", err)
+	}
+	share := &models.SharedLink{SenderID: heir.ID, RecipientID: leaver.ID, Keyword: "offboard-share", URL: "https://example.com/s"}
+	if err := db.CreateSharedLink(ctx, share); err != nil {
+		t.Fatalf("CreateSharedLink() error = %v", err)
+	}
+
+	result, err := db.OffboardUser(ctx, leaver.ID, OffboardOptions{TransferTo: &heir.ID, SubmitUserLinks: personal, Reason: "offboarded"})
+	if err != nil {
+		t.Fatalf("OffboardUser() error = %v", err)
+	}
+
+	if result.LinksTransferred != 1 {
+		t.Errorf("LinksTransferred = %d, want 1", result.LinksTransferred)
+	}
+	if len(result.LinksSubmitted) != 1 || result.LinksSubmitted[0] != "offboard-personal" {
+		t.Errorf("LinksSubmitted = %v, want [offboard-personal]", result.LinksSubmitted)
+	}
+	if len(result.KeywordsSkipped) != 1 || result.KeywordsSkipped[0] != "offboard-taken" {
+		t.Errorf("KeywordsSkipped = %v, want [offboard-taken]", result.KeywordsSkipped)
+	}
+	if result.EditRequestsTransferred != 1 || result.SharesDiscarded != 1 {
+		t.Errorf("EditRequestsTransferred = %d, SharesDiscarded = %d, want 1 and 1", result.EditRequestsTransferred, result.SharesDiscarded)
+	}
+
+	link, err := db.GetLinkByID(ctx, authored.ID)
+	if err != nil {
+		t.Fatalf("GetLinkByID() error = %v", err)
+	}
+	if link.CreatedBy == nil || *link.CreatedBy != heir.ID {
+		t.Errorf("CreatedBy = %v, want %v", link.CreatedBy, heir.ID)
+	}
+
+	submitted, err := db.GetPendingLinksByUser(ctx, heir.ID)
+	if err != nil {
+		t.Fatalf("GetPendingLinksByUser() error = %v", err)
+	}
+	if len(submitted) != 1 || submitted[0].Keyword != "offboard-personal" || submitted[0].Reason != "offboarded" {
+		t.Errorf("pending submissions = %+v, want offboard-personal", submitted)
+	}
+
+	got, err := db.GetEditRequestByID(ctx, req.ID)
+	if err != nil {
+		t.Fatalf("GetEditRequestByID() error = %v", err)
+	}
+	if got.UserID != heir.ID {
+		t.Errorf("edit request UserID = %v, want %v", got.UserID, heir.ID)
+	}
+
+	user, err := db.GetUserByID(ctx, leaver.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	if !user.IsDeactivated() {
+		t.Error("offboarded user is not deactivated")
+	}
+}
+
+func TestDeleteUser_AuthorsLinks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	user := &models.User{Sub: "delete-author", Email: "author@example.com", Name: "Author"}
+	if err := db.UpsertUser(ctx, user); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+	link := &models.Link{Keyword: "delete-author-link", URL: "https://example.com", Scope: models.ScopeGlobal, CreatedBy: &user.ID}
+	if err := db.CreateLink(ctx, link); err != nil {
+		t.Fatalf("CreateLink() error = %v", err)
+	}
+
+	if err := db.DeleteUser(ctx, user.ID); !errors.Is(err, ErrUserAuthorsLinks) {
+		t.Fatalf("DeleteUser() error = %v, want ErrUserAuthorsLinks", err)
+	}
+
+	if _, err := db.OffboardUser(ctx, user.ID, OffboardOptions{}); err != nil {
+		t.Fatalf("OffboardUser() error = %v", err)
+	}
+	if err := db.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteUser() after offboarding error = %v", err)
+	}
+
+	got, err := db.GetLinkByID(ctx, link.ID)
+	if err != nil {
+		t.Fatalf("GetLinkByID() error = %v", err)
+	}
+	if got.CreatedBy != nil {
+		t.Errorf("CreatedBy = %v, want nil (organization-owned)", got.CreatedBy)
+	}
+}
+
+func TestDeactivateInactiveUsers(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	stale := &models.User{Sub: "inactive-stale", Email: "stale@example.com", Name: "Stale"}
+	admin := &models.User{Sub: "inactive-admin", Email: "admin@example.com", Name: "Admin", Role: models.RoleAdmin}
+	active := &models.User{Sub: "inactive-active", Email: "active@example.com", Name: "Active"}
+	for _, u := range []*models.User{stale, admin, active} {
+		if err := db.UpsertUser(ctx, u); err != nil {
+			t.Fatalf("UpsertUser() error = %v", err)
+		}
+	}
+	old := time.Now().Add(-200 * 24 * time.Hour)
+	if _, err := db.Pool.Exec(ctx, `UPDATE users SET created_at = $1, last_login_at = $1 WHERE id = ANY($2)`, old, []uuid.UUID{stale.ID, admin.ID}); err != nil {
+		t.Fatalf("backdating users: %v", err)
+	}
+
+	ids, err := db.DeactivateInactiveUsers(ctx, time.Now().Add(-90*24*time.Hour))
+	if err != nil {
+		t.Fatalf("DeactivateInactiveUsers() error = %v", err)
+	}
+	if len(ids) != 1 || ids[0] != stale.ID {
+		t.Errorf("DeactivateInactiveUsers() = %v, want [%v]", ids, stale.ID)
+	}
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"golinks/internal/models"
+)
+
+// GetOrgBranding returns the branding an organization has set itself, empty
+// where it has none. It returns ErrOrgNotFound for unknown organizations.
+func (d *DB) GetOrgBranding(ctx context.Context, orgID uuid.UUID) (*models.OrgBranding, error) {
+	query := `
+		SELECT COALESCE(b.site_title, ''), COALESCE(b.logo_url, ''), COALESCE(b.accent_color, ''),
+		       COALESCE(b.banner_text, ''), COALESCE(b.banner_text_color, ''), COALESCE(b.banner_bg_color, ''),
+		       b.updated_by, b.updated_at
+		FROM organizations o
+		LEFT JOIN org_branding b ON b.organization_id = o.id
+		WHERE o.id = $1
+	`
+
+	b := models.OrgBranding{OrganizationID: orgID}
+	var updatedAt *time.Time
+	err := d.Pool.QueryRow(ctx, query, orgID).Scan(
+		&b.SiteTitle, &b.LogoURL, &b.AccentColor,
+		&b.BannerText, &b.BannerTextColor, &b.BannerBGColor,
+		&b.UpdatedBy, &updatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOrgNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if updatedAt != nil {
+		b.UpdatedAt = *updatedAt
+	}
+	return &b, nil
+}
+
+// GetEffectiveOrgBranding returns the branding members of an organization
+// see: each field from the organization itself, or else from the nearest
+// enclosing organization that sets it. Fields nobody sets are empty, leaving
+// the site-wide value.
+func (d *DB) GetEffectiveOrgBranding(ctx context.Context, orgID uuid.UUID) (*models.OrgBranding, error) {
+	query := `
+		SELECT COALESCE((ARRAY_AGG(b.site_title ORDER BY l.depth) FILTER (WHERE b.site_title <> ''))[1], ''),
+		       COALESCE((ARRAY_AGG(b.logo_url ORDER BY l.depth) FILTER (WHERE b.logo_url <> ''))[1], ''),
+		       COALESCE((ARRAY_AGG(b.accent_color ORDER BY l.depth) FILTER (WHERE b.accent_color <> ''))[1], ''),
+		       COALESCE((ARRAY_AGG(b.banner_text ORDER BY l.depth) FILTER (WHERE b.banner_text <> ''))[1], ''),
+		       COALESCE((ARRAY_AGG(b.banner_text_color ORDER BY l.depth) FILTER (WHERE b.banner_text_color <> ''))[1], ''),
+		       COALESCE((ARRAY_AGG(b.banner_bg_color ORDER BY l.depth) FILTER (WHERE b.banner_bg_color <> ''))[1], '')
+		FROM ` + orgLineage("$1") + ` l
+		JOIN org_branding b ON b.organization_id = l.id
+	`
+
+	b := models.OrgBranding{OrganizationID: orgID}
+	err := d.Pool.QueryRow(ctx, query, orgID).Scan(
+		&b.SiteTitle, &b.LogoURL, &b.AccentColor,
+		&b.BannerText, &b.BannerTextColor, &b.BannerBGColor,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// SaveOrgBranding stores an organization's branding, recording who changed
+// it. It returns ErrOrgNotFound for unknown organizations.
+func (d *DB) SaveOrgBranding(ctx context.Context, b *models.OrgBranding, updatedBy uuid.UUID) error {
+	query := `
+		INSERT INTO org_branding (organization_id, site_title, logo_url, accent_color,
+		                          banner_text, banner_text_color, banner_bg_color, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (organization_id) DO UPDATE SET
+			site_title = EXCLUDED.site_title,
+			logo_url = EXCLUDED.logo_url,
+			accent_color = EXCLUDED.accent_color,
+			banner_text = EXCLUDED.banner_text,
+			banner_text_color = EXCLUDED.banner_text_color,
+			banner_bg_color = EXCLUDED.banner_bg_color,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING updated_by, updated_at
+	`
+
+	err := d.Pool.QueryRow(ctx, query,
+		b.OrganizationID, b.SiteTitle, b.LogoURL, b.AccentColor,
+		b.BannerText, b.BannerTextColor, b.BannerBGColor, updatedBy,
+	).Scan(&b.UpdatedBy, &b.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrOrgNotFound
+		}
+		return err
+	}
+	return nil
+}
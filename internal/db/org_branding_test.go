@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"golinks/internal/models"
+)
+
+func TestOrgBranding(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orgs := createTestOrgs(t, db, "brand-parent", "brand-child")
+	parent, child := orgs[0], orgs[1]
+	if err := db.SetOrganizationParent(ctx, child.ID, &parent.ID); err != nil {
+		t.Fatalf("SetOrganizationParent() error = %v", err)
+	}
+
+	user := &models.User{Sub: "brand-admin", Email: "brand@example.com", Name: "Brand"}
+	if err := db.UpsertUser(ctx, user); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+
+	got, err := db.GetEffectiveOrgBranding(ctx, child.ID)
+	if err != nil {
+		t.Fatalf("GetEffectiveOrgBranding() error = %v", err)
+	}
+	if *got != (models.OrgBranding{OrganizationID: child.ID}) {
+		t.Errorf("GetEffectiveOrgBranding(unset) = %+v, want empty", got)
+	}
+
+	if err := db.SaveOrgBranding(ctx, &models.OrgBranding{OrganizationID: parent.ID, SiteTitle: "Acme", AccentColor: "#ff6600"}, user.ID); err != nil {
+		t.Fatalf("SaveOrgBranding() error = %v", err)
+	}
+	if err := db.SaveOrgBranding(ctx, &models.OrgBranding{OrganizationID: child.ID, BannerText: "Welcome"}, user.ID); err != nil {
+		t.Fatalf("SaveOrgBranding() error = %v", err)
+	}
+
+	// The child's own fields win; the rest come from its parent.
+	got, err = db.GetEffectiveOrgBranding(ctx, child.ID)
+	if err != nil {
+		t.Fatalf("GetEffectiveOrgBranding() error = %v", err)
+	}
+	if got.SiteTitle != "Acme" || got.AccentColor != "#ff6600" || got.BannerText != "Welcome" {
+		t.Errorf("GetEffectiveOrgBranding() = %+v, want the parent's title and accent with the child's banner", got)
+	}
+
+	own, err := db.GetOrgBranding(ctx, child.ID)
+	if err != nil {
+		t.Fatalf("GetOrgBranding() error = %v", err)
+	}
+	if own.SiteTitle != "" || own.BannerText != "Welcome" || own.UpdatedBy == nil || *own.UpdatedBy != user.ID {
+		t.Errorf("GetOrgBranding() = %+v, want only the child's own banner", own)
+	}
+
+	missing := uuid.New()
+	if _, err := db.GetOrgBranding(ctx, missing); !errors.Is(err, ErrOrgNotFound) {
+		t.Errorf("GetOrgBranding(unknown) error = %v, want ErrOrgNotFound", err)
+	}
+	if err := db.SaveOrgBranding(ctx, &models.OrgBranding{OrganizationID: missing}, user.ID); !errors.Is(err, ErrOrgNotFound) {
+		t.Errorf("SaveOrgBranding(unknown) error = %v, want ErrOrgNotFound", err)
+	}
+}
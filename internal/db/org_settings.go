@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"golinks/internal/models"
+)
+
+// GetOrgSettings returns an organization's settings, or the defaults when it
+// has never changed them. It returns ErrOrgNotFound for unknown organizations.
+func (d *DB) GetOrgSettings(ctx context.Context, orgID uuid.UUID) (*models.OrgSettings, error) {
+	// The defaults mirror the column defaults of org_settings.
+	query := `
+		SELECT COALESCE(s.require_moderation, TRUE), COALESCE(s.allowed_domains, '{}'),
+		       COALESCE(s.keyword_prefix, ''), COALESCE(s.keyword_pattern, ''),
+		       COALESCE(s.notify_moderators, TRUE), COALESCE(s.notify_emails, '{}'),
+		       s.updated_by, s.updated_at
+		FROM organizations o
+		LEFT JOIN org_settings s ON s.organization_id = o.id
+		WHERE o.id = $1
+	`
+
+	s := models.OrgSettings{OrganizationID: orgID}
+	var updatedAt *time.Time
+	err := d.Pool.QueryRow(ctx, query, orgID).Scan(
+		&s.RequireModeration, &s.AllowedDomains, &s.KeywordPrefix, &s.KeywordPattern,
+		&s.NotifyModerators, &s.NotifyEmails, &s.UpdatedBy, &updatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOrgNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if updatedAt != nil {
+		s.UpdatedAt = *updatedAt
+	}
+	return &s, nil
+}
+
+// SaveOrgSettings stores an organization's settings, recording who changed
+// them. It returns ErrOrgNotFound for unknown organizations.
+func (d *DB) SaveOrgSettings(ctx context.Context, s *models.OrgSettings, updatedBy uuid.UUID) error {
+	if s.AllowedDomains == nil {
+		s.AllowedDomains = []string{}
+	}
+	if s.NotifyEmails == nil {
+		s.NotifyEmails = []string{}
+	}
+
+	query := `
+		INSERT INTO org_settings (organization_id, require_moderation, allowed_domains, keyword_prefix,
+		                          keyword_pattern, notify_moderators, notify_emails, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (organization_id) DO UPDATE SET
+			require_moderation = EXCLUDED.require_moderation,
+			allowed_domains = EXCLUDED.allowed_domains,
+			keyword_prefix = EXCLUDED.keyword_prefix,
+			keyword_pattern = EXCLUDED.keyword_pattern,
+			notify_moderators = EXCLUDED.notify_moderators,
+			notify_emails = EXCLUDED.notify_emails,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING updated_by, updated_at
+	`
+
+	err := d.Pool.QueryRow(ctx, query,
+		s.OrganizationID, s.RequireModeration, s.AllowedDomains, s.KeywordPrefix,
+		s.KeywordPattern, s.NotifyModerators, s.NotifyEmails, updatedBy,
+	).Scan(&s.UpdatedBy, &s.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrOrgNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"golinks/internal/models"
+)
+
+func TestOrgSettings(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	org := createTestOrgs(t, db, "settings-org")[0]
+
+	user := &models.User{Sub: "settings-mod", Email: "mod@example.com", Name: "Mod"}
+	if err := db.UpsertUser(ctx, user); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+
+	got, err := db.GetOrgSettings(ctx, org.ID)
+	if err != nil {
+		t.Fatalf("GetOrgSettings() error = %v", err)
+	}
+	if !got.RequireModeration || !got.NotifyModerators || len(got.AllowedDomains) != 0 || got.UpdatedBy != nil {
+		t.Errorf("GetOrgSettings(unsaved) = %+v, want the defaults", got)
+	}
+
+	settings := &models.OrgSettings{
+		OrganizationID: org.ID,
+		AllowedDomains: []string{"example.com"},
+		KeywordPrefix:  "eng-",
+		NotifyEmails:   []string{"team@example.com"},
+	}
+	if err := db.SaveOrgSettings(ctx, settings, user.ID); err != nil {
+		t.Fatalf("SaveOrgSettings() error = %v", err)
+	}
+	got, err = db.GetOrgSettings(ctx, org.ID)
+	if err != nil {
+		t.Fatalf("GetOrgSettings() error = %v", err)
+	}
+	if got.RequireModeration || got.NotifyModerators || got.KeywordPrefix != "eng-" ||
+		!slices.Equal(got.AllowedDomains, []string{"example.com"}) || !slices.Equal(got.NotifyEmails, []string{"team@example.com"}) {
+		t.Errorf("GetOrgSettings() = %+v, want the saved settings", got)
+	}
+	if got.UpdatedBy == nil || *got.UpdatedBy != user.ID {
+		t.Errorf("UpdatedBy = %v, want %v", got.UpdatedBy, user.ID)
+	}
+
+	missing := uuid.New()
+	if _, err := db.GetOrgSettings(ctx, missing); !errors.Is(err, ErrOrgNotFound) {
+		t.Errorf("GetOrgSettings(unknown) error = %v, want ErrOrgNotFound", err)
+	}
+	if err := db.SaveOrgSettings(ctx, models.DefaultOrgSettings(missing), user.ID); !errors.Is(err, ErrOrgNotFound) {
+		t.Errorf("SaveOrgSettings(unknown) error = %v, want ErrOrgNotFound", err)
+	}
+}
@@ -3,9 +3,11 @@ package db
 import (
 	"context"
 	"errors"
+	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 
 	"golinks/internal/models"
 )
@@ -14,11 +16,11 @@ import (
 // CreateOrganization creates a new organization.
 func (d *DB) CreateOrganization(ctx context.Context, org *models.Organization) error {
 	query := `
-		INSERT INTO organizations (name, slug)
-		VALUES ($1, $2)
+		INSERT INTO organizations (name, slug, parent_id)
+		VALUES ($1, $2, $3)
 		RETURNING id, created_at, updated_at
 	`
-	return d.Pool.QueryRow(ctx, query, org.Name, org.Slug).Scan(
+	return d.Pool.QueryRow(ctx, query, org.Name, org.Slug, org.ParentID).Scan(
 		&org.ID, &org.CreatedAt, &org.UpdatedAt,
 	)
 }
@@ -26,13 +28,13 @@ func (d *DB) CreateOrganization(ctx context.Context, org *models.Organization) e
 // GetOrganizationByID retrieves an organization by ID.
 func (d *DB) GetOrganizationByID(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
 	query := `
-		SELECT id, name, slug, created_at, updated_at
+		SELECT id, name, slug, parent_id, created_at, updated_at
 		FROM organizations WHERE id = $1
 	`
 
 	var org models.Organization
 	err := d.Pool.QueryRow(ctx, query, id).Scan(
-		&org.ID, &org.Name, &org.Slug, &org.CreatedAt, &org.UpdatedAt,
+		&org.ID, &org.Name, &org.Slug, &org.ParentID, &org.CreatedAt, &org.UpdatedAt,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -48,13 +50,13 @@ func (d *DB) GetOrganizationByID(ctx context.Context, id uuid.UUID) (*models.Org
 // GetOrganizationBySlug retrieves an organization by its slug.
 func (d *DB) GetOrganizationBySlug(ctx context.Context, slug string) (*models.Organization, error) {
 	query := `
-		SELECT id, name, slug, created_at, updated_at
+		SELECT id, name, slug, parent_id, created_at, updated_at
 		FROM organizations WHERE slug = $1
 	`
 
 	var org models.Organization
 	err := d.Pool.QueryRow(ctx, query, slug).Scan(
-		&org.ID, &org.Name, &org.Slug, &org.CreatedAt, &org.UpdatedAt,
+		&org.ID, &org.Name, &org.Slug, &org.ParentID, &org.CreatedAt, &org.UpdatedAt,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -70,7 +72,7 @@ func (d *DB) GetOrganizationBySlug(ctx context.Context, slug string) (*models.Or
 // GetAllOrganizations retrieves all organizations.
 func (d *DB) GetAllOrganizations(ctx context.Context) ([]models.Organization, error) {
 	query := `
-		SELECT id, name, slug, created_at, updated_at
+		SELECT id, name, slug, parent_id, created_at, updated_at
 		FROM organizations ORDER BY name ASC
 	`
 
@@ -83,7 +85,7 @@ func (d *DB) GetAllOrganizations(ctx context.Context) ([]models.Organization, er
 	var orgs []models.Organization
 	for rows.Next() {
 		var org models.Organization
-		if err := rows.Scan(&org.ID, &org.Name, &org.Slug, &org.CreatedAt, &org.UpdatedAt); err != nil {
+		if err := rows.Scan(&org.ID, &org.Name, &org.Slug, &org.ParentID, &org.CreatedAt, &org.UpdatedAt); err != nil {
 			return nil, err
 		}
 		orgs = append(orgs, org)
@@ -118,3 +120,423 @@ func (d *DB) GetOrCreateOrganization(ctx context.Context, slug string) (*models.
 	}
 	return org, true, nil
 }
+
+// maxOrgDepth bounds the walks up and down the organization tree, so they end
+// even on a cycle written around SetOrganizationParent.
+const maxOrgDepth = 32
+
+// orgLineage returns a subquery selecting the organization whose ID is the SQL
+// expression org, then its parent, grandparent and so on, with each one's
+// distance from org as depth.
+func orgLineage(org string) string {
+	return `(WITH RECURSIVE lineage AS (
+		SELECT id, parent_id, 0 AS depth FROM organizations WHERE id = ` + org + `
+		UNION ALL
+		SELECT p.id, p.parent_id, c.depth + 1
+		FROM organizations p JOIN lineage c ON p.id = c.parent_id
+		WHERE c.depth < ` + strconv.Itoa(maxOrgDepth) + `
+	) SELECT id, depth FROM lineage)`
+}
+
+// orgSubtree returns a subquery selecting the organization whose ID is the SQL
+// expression org and all of its descendants.
+func orgSubtree(org string) string {
+	return `(WITH RECURSIVE subtree AS (
+		SELECT id, 0 AS depth FROM organizations WHERE id = ` + org + `
+		UNION ALL
+		SELECT o.id, s.depth + 1
+		FROM organizations o JOIN subtree s ON o.parent_id = s.id
+		WHERE s.depth < ` + strconv.Itoa(maxOrgDepth) + `
+	) SELECT id FROM subtree)`
+}
+
+// SetOrganizationParent nests an organization under parentID, or makes it top
+// level when parentID is nil. It returns ErrOrgCycle if parentID is the
+// organization itself or one of its descendants.
+func (d *DB) SetOrganizationParent(ctx context.Context, orgID uuid.UUID, parentID *uuid.UUID) error {
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Two concurrent moves could each pass the cycle check and together
+	// form a cycle, so they are serialized.
+	if _, err := tx.Exec(ctx, `LOCK TABLE organizations IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return err
+	}
+
+	if parentID != nil {
+		var cycle bool
+		err := tx.QueryRow(ctx, `SELECT $1 IN (SELECT id FROM `+orgLineage("$2")+` lineage)`, orgID, *parentID).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrOrgCycle
+		}
+	}
+
+	tag, err := tx.Exec(ctx, `UPDATE organizations SET parent_id = $2, updated_at = NOW() WHERE id = $1`, orgID, parentID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrOrgNotFound // the parent does not exist
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOrgNotFound
+	}
+	return tx.Commit(ctx)
+}
+
+// GetOrganizationAncestors returns the organizations an organization is nested
+// in, nearest first.
+func (d *DB) GetOrganizationAncestors(ctx context.Context, orgID uuid.UUID) ([]models.Organization, error) {
+	query := `
+		SELECT o.id, o.name, o.slug, o.parent_id, o.created_at, o.updated_at
+		FROM ` + orgLineage("$1") + ` lineage
+		JOIN organizations o ON o.id = lineage.id
+		WHERE lineage.depth > 0
+		ORDER BY lineage.depth ASC
+	`
+
+	rows, err := d.Pool.Query(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []models.Organization
+	for rows.Next() {
+		var org models.Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.Slug, &org.ParentID, &org.CreatedAt, &org.UpdatedAt); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+
+	return orgs, rows.Err()
+}
+
+// OrgWithCounts is an organization with how many members and links it has.
+type OrgWithCounts struct {
+	models.Organization
+	ParentName  string `json:"parent_name,omitempty"`
+	MemberCount int    `json:"member_count"`
+	LinkCount   int    `json:"link_count"` // links in any status, excluding inherited ones
+}
+
+// orgWithCountsQuery selects organizations with their counts; callers append
+// WHERE and ORDER BY clauses.
+const orgWithCountsQuery = `
+	SELECT o.id, o.name, o.slug, o.parent_id, o.created_at, o.updated_at,
+		COALESCE(p.name, ''),
+		(SELECT COUNT(*) FROM user_organizations m WHERE m.organization_id = o.id),
+		(SELECT COUNT(*) FROM links l WHERE l.organization_id = o.id)
+	FROM organizations o
+	LEFT JOIN organizations p ON p.id = o.parent_id
+`
+
+func scanOrgWithCounts(row pgx.Row) (OrgWithCounts, error) {
+	var o OrgWithCounts
+	err := row.Scan(&o.ID, &o.Name, &o.Slug, &o.ParentID, &o.CreatedAt, &o.UpdatedAt,
+		&o.ParentName, &o.MemberCount, &o.LinkCount)
+	return o, err
+}
+
+// ListOrganizationsWithCounts retrieves all organizations with their member
+// and link counts, ordered by name.
+func (d *DB) ListOrganizationsWithCounts(ctx context.Context) ([]OrgWithCounts, error) {
+	rows, err := d.Pool.Query(ctx, orgWithCountsQuery+` ORDER BY o.name ASC, o.slug ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []OrgWithCounts
+	for rows.Next() {
+		org, err := scanOrgWithCounts(rows)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+
+	return orgs, rows.Err()
+}
+
+// GetOrganizationWithCounts retrieves an organization with its member and
+// link counts.
+func (d *DB) GetOrganizationWithCounts(ctx context.Context, id uuid.UUID) (*OrgWithCounts, error) {
+	org, err := scanOrgWithCounts(d.Pool.QueryRow(ctx, orgWithCountsQuery+` WHERE o.id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOrgNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// UpdateOrganization renames an organization and changes its slug. Identity
+// providers refer to organizations by slug, so a new slug must be matched by
+// the org claim or SCIM group, or the old slug is created again on sign-in.
+func (d *DB) UpdateOrganization(ctx context.Context, id uuid.UUID, name, slug string) error {
+	tag, err := d.Pool.Exec(ctx, `
+		UPDATE organizations SET name = $2, slug = $3, updated_at = NOW() WHERE id = $1
+	`, id, name, slug)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrDuplicateOrgSlug
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOrgNotFound
+	}
+	return nil
+}
+
+// DeleteOrganization deletes an organization without members or links. Its
+// fallback redirects go with it and the organizations nested in it move to
+// the top level. It returns ErrOrgNotEmpty otherwise.
+func (d *DB) DeleteOrganization(ctx context.Context, id uuid.UUID) error {
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var empty bool
+	err = tx.QueryRow(ctx, `
+		SELECT NOT EXISTS (SELECT 1 FROM user_organizations WHERE organization_id = o.id)
+		   AND NOT EXISTS (SELECT 1 FROM links WHERE organization_id = o.id)
+		   AND NOT EXISTS (SELECT 1 FROM users WHERE organization_id = o.id)
+		FROM organizations o WHERE o.id = $1
+		FOR UPDATE
+	`, id).Scan(&empty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrOrgNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return ErrOrgNotEmpty
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM organizations WHERE id = $1`, id); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrOrgNotEmpty // a member or link was added concurrently
+		}
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// OrgMergeResult summarizes what MergeOrganizations moved.
+type OrgMergeResult struct {
+	LinksMoved          int64    `json:"links_moved"`
+	MembersMoved        int64    `json:"members_moved"`
+	FallbacksMoved      int64    `json:"fallbacks_moved"`
+	ConflictingKeywords []string `json:"conflicting_keywords,omitempty"` // set with ErrOrgMergeConflict
+}
+
+// MergeOrganizations folds source into target, for two slugs that mean the
+// same team, and deletes source. In one transaction it moves source's links,
+// members (keeping the higher role of the two organizations), fallback
+// redirects (a same-named redirect in target replaces source's), link
+// co-ownerships, missing keyword demand and nested organizations to target.
+//
+// Nothing changes if both organizations have an org link with the same
+// keyword: the error is ErrOrgMergeConflict and the result lists the
+// keywords. Merging into source itself or one of its descendants returns
+// ErrOrgMergeTarget.
+func (d *DB) MergeOrganizations(ctx context.Context, sourceID, targetID uuid.UUID) (*OrgMergeResult, error) {
+	if sourceID == targetID {
+		return nil, ErrOrgMergeTarget
+	}
+
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Keeps SetOrganizationParent from nesting target under source meanwhile.
+	if _, err := tx.Exec(ctx, `LOCK TABLE organizations IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return nil, err
+	}
+
+	var found int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM organizations WHERE id IN ($1, $2)`, sourceID, targetID).Scan(&found); err != nil {
+		return nil, err
+	}
+	if found != 2 {
+		return nil, ErrOrgNotFound
+	}
+
+	var nested bool
+	if err := tx.QueryRow(ctx, `SELECT $2 IN `+orgSubtree("$1"), sourceID, targetID).Scan(&nested); err != nil {
+		return nil, err
+	}
+	if nested {
+		return nil, ErrOrgMergeTarget
+	}
+
+	result := &OrgMergeResult{}
+
+	rows, err := tx.Query(ctx, `
+		SELECT s.keyword
+		FROM links s
+		JOIN links t ON t.keyword = s.keyword AND t.scope = 'org' AND t.organization_id = $2
+		WHERE s.scope = 'org' AND s.organization_id = $1
+		ORDER BY s.keyword
+	`, sourceID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var keyword string
+		if err := rows.Scan(&keyword); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		result.ConflictingKeywords = append(result.ConflictingKeywords, keyword)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(result.ConflictingKeywords) > 0 {
+		return result, ErrOrgMergeConflict
+	}
+
+	tag, err := tx.Exec(ctx, `UPDATE links SET organization_id = $2 WHERE organization_id = $1`, sourceID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	result.LinksMoved = tag.RowsAffected()
+
+	// Members of both keep the earlier join date and the higher role.
+	tag, err = tx.Exec(ctx, `
+		INSERT INTO user_organizations (user_id, organization_id, role, created_at)
+		SELECT user_id, $2, role, created_at FROM user_organizations WHERE organization_id = $1
+		ON CONFLICT (user_id, organization_id) DO UPDATE SET
+			role = CASE WHEN EXCLUDED.role = $3 THEN EXCLUDED.role ELSE user_organizations.role END,
+			created_at = LEAST(user_organizations.created_at, EXCLUDED.created_at)
+	`, sourceID, targetID, models.OrgRoleMod)
+	if err != nil {
+		return nil, err
+	}
+	result.MembersMoved = tag.RowsAffected()
+
+	// Users who had source active now have target active, with its role.
+	_, err = tx.Exec(ctx, `
+		UPDATE users u
+		SET organization_id = $2,
+		    role = CASE
+		        WHEN u.role NOT IN ($3, $4) THEN u.role
+		        WHEN m.role = $5 THEN $4
+		        ELSE $3
+		    END,
+		    updated_at = NOW()
+		FROM user_organizations m
+		WHERE u.organization_id = $1 AND m.user_id = u.id AND m.organization_id = $2
+	`, sourceID, targetID, models.RoleUser, models.RoleOrgMod, models.OrgRoleMod)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_organizations WHERE organization_id = $1`, sourceID); err != nil {
+		return nil, err
+	}
+
+	// A fallback redirect named like one of target's is replaced by it.
+	_, err = tx.Exec(ctx, `
+		UPDATE users u SET fallback_redirect_id = t.id
+		FROM fallback_redirects s
+		JOIN fallback_redirects t ON t.organization_id = $2 AND t.name = s.name
+		WHERE s.organization_id = $1 AND u.fallback_redirect_id = s.id
+	`, sourceID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		DELETE FROM fallback_redirects s
+		USING fallback_redirects t
+		WHERE s.organization_id = $1 AND t.organization_id = $2 AND t.name = s.name
+	`, sourceID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	tag, err = tx.Exec(ctx, `UPDATE fallback_redirects SET organization_id = $2, updated_at = NOW() WHERE organization_id = $1`, sourceID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	result.FallbacksMoved = tag.RowsAffected()
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO missing_keyword_lookups (keyword, organization_id, count, last_seen_at)
+		SELECT keyword, $2, count, last_seen_at FROM missing_keyword_lookups WHERE organization_id = $1
+		ON CONFLICT (keyword, COALESCE(organization_id, '00000000-0000-0000-0000-000000000000'::uuid)) DO UPDATE SET
+			count = missing_keyword_lookups.count + EXCLUDED.count,
+			last_seen_at = GREATEST(missing_keyword_lookups.last_seen_at, EXCLUDED.last_seen_at)
+	`, sourceID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO dismissed_wanted_keywords (keyword, organization_id, dismissed_by, dismissed_at)
+		SELECT keyword, $2, dismissed_by, dismissed_at FROM dismissed_wanted_keywords WHERE organization_id = $1
+		ON CONFLICT (keyword, COALESCE(organization_id, '00000000-0000-0000-0000-000000000000'::uuid)) DO NOTHING
+	`, sourceID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Links co-owned by source become co-owned by target, unless target
+	// already co-owns them; the owner history records the swap.
+	_, err = tx.Exec(ctx, `
+		INSERT INTO link_owner_events (link_id, action, owner_kind, owner_name)
+		SELECT s.link_id, e.action, $5::text, e.slug
+		FROM link_owners s
+		CROSS JOIN LATERAL (VALUES
+			($3::text, (SELECT slug FROM organizations WHERE id = $1)),
+			($4, (SELECT slug FROM organizations WHERE id = $2))
+		) AS e (action, slug)
+		WHERE s.organization_id = $1 AND (e.action = $3 OR NOT EXISTS (
+			SELECT 1 FROM link_owners t WHERE t.link_id = s.link_id AND t.organization_id = $2
+		))
+	`, sourceID, targetID, models.OwnerRemoved, models.OwnerAdded, models.OwnerKindOrg)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE link_owners s SET organization_id = $2
+		WHERE s.organization_id = $1 AND NOT EXISTS (
+			SELECT 1 FROM link_owners t WHERE t.link_id = s.link_id AND t.organization_id = $2
+		)
+	`, sourceID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE organizations SET parent_id = $2, updated_at = NOW() WHERE parent_id = $1`, sourceID, targetID); err != nil {
+		return nil, err
+	}
+
+	// Source's missing keyword demand and dismissals, copied above, and the
+	// co-ownerships target already had cascade.
+	if _, err := tx.Exec(ctx, `DELETE FROM organizations WHERE id = $1`, sourceID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
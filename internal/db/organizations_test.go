@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/google/uuid"
@@ -150,3 +151,332 @@ func TestGetAllOrganizations(t *testing.T) {
 		t.Errorf("GetAllOrganizations() first org = %q, want %q", all[0].Name, "Alpha Org")
 	}
 }
+
+func TestSetOrganizationParent(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orgs := createTestOrgs(t, db, "nest-division", "nest-department", "nest-team")
+	division, department, team := orgs[0], orgs[1], orgs[2]
+
+	if err := db.SetOrganizationParent(ctx, department.ID, &division.ID); err != nil {
+		t.Fatalf("SetOrganizationParent(department) error = %v", err)
+	}
+	if err := db.SetOrganizationParent(ctx, team.ID, &department.ID); err != nil {
+		t.Fatalf("SetOrganizationParent(team) error = %v", err)
+	}
+
+	ancestors, err := db.GetOrganizationAncestors(ctx, team.ID)
+	if err != nil {
+		t.Fatalf("GetOrganizationAncestors() error = %v", err)
+	}
+	if len(ancestors) != 2 || ancestors[0].ID != department.ID || ancestors[1].ID != division.ID {
+		t.Errorf("GetOrganizationAncestors() = %+v, want department then division", ancestors)
+	}
+
+	for _, parent := range []*models.Organization{division, team} {
+		if err := db.SetOrganizationParent(ctx, division.ID, &parent.ID); !errors.Is(err, ErrOrgCycle) {
+			t.Errorf("SetOrganizationParent(division under %s) error = %v, want ErrOrgCycle", parent.Slug, err)
+		}
+	}
+	missing := uuid.New()
+	if err := db.SetOrganizationParent(ctx, team.ID, &missing); !errors.Is(err, ErrOrgNotFound) {
+		t.Errorf("SetOrganizationParent(missing parent) error = %v, want ErrOrgNotFound", err)
+	}
+
+	if err := db.SetOrganizationParent(ctx, team.ID, nil); err != nil {
+		t.Fatalf("SetOrganizationParent(nil) error = %v", err)
+	}
+	got, err := db.GetOrganizationByID(ctx, team.ID)
+	if err != nil {
+		t.Fatalf("GetOrganizationByID() error = %v", err)
+	}
+	if got.ParentID != nil {
+		t.Errorf("ParentID = %v, want nil", got.ParentID)
+	}
+}
+
+func TestNestedOrganizations_InheritedLinks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orgs := createTestOrgs(t, db, "inherit-division", "inherit-department", "inherit-team")
+	division, department, team := orgs[0], orgs[1], orgs[2]
+	if err := db.SetOrganizationParent(ctx, department.ID, &division.ID); err != nil {
+		t.Fatalf("SetOrganizationParent() error = %v", err)
+	}
+	if err := db.SetOrganizationParent(ctx, team.ID, &department.ID); err != nil {
+		t.Fatalf("SetOrganizationParent() error = %v", err)
+	}
+
+	member := &models.User{Sub: "inherit-member", Email: "member@example.com", Name: "Member"}
+	mod := &models.User{Sub: "inherit-mod", Email: "mod@example.com", Name: "Mod"}
+	for _, u := range []*models.User{member, mod} {
+		if err := db.UpsertUser(ctx, u); err != nil {
+			t.Fatalf("UpsertUser() error = %v", err)
+		}
+	}
+	if err := db.AddUserOrganization(ctx, member.ID, team.ID, models.OrgRoleMember); err != nil {
+		t.Fatalf("AddUserOrganization() error = %v", err)
+	}
+	if err := db.AddUserOrganization(ctx, mod.ID, division.ID, models.OrgRoleMod); err != nil {
+		t.Fatalf("AddUserOrganization() error = %v", err)
+	}
+
+	for _, org := range orgs {
+		link := &models.Link{Keyword: "inherit-" + org.Slug, URL: "https://example.com/" + org.Slug, Scope: models.ScopeOrg, OrganizationID: &org.ID, Status: models.StatusApproved}
+		if err := db.CreateLink(ctx, link); err != nil {
+			t.Fatalf("CreateLink() error = %v", err)
+		}
+	}
+	for _, org := range []*models.Organization{division, department} {
+		link := &models.Link{Keyword: "inherit-shared", URL: "https://example.com/shared-" + org.Slug, Scope: models.ScopeOrg, OrganizationID: &org.ID, Status: models.StatusApproved}
+		if err := db.CreateLink(ctx, link); err != nil {
+			t.Fatalf("CreateLink() error = %v", err)
+		}
+	}
+
+	// The nearest ancestor wins; links of every ancestor resolve.
+	resolved, err := db.ResolveKeywordForUser(ctx, &member.ID, &team.ID, "inherit-shared")
+	if err != nil || resolved.URL != "https://example.com/shared-inherit-department" {
+		t.Errorf("ResolveKeywordForUser(shared) = %+v, %v; want the department link", resolved, err)
+	}
+	if _, err := db.ResolveKeywordForUser(ctx, &member.ID, &team.ID, "inherit-inherit-division"); err != nil {
+		t.Errorf("ResolveKeywordForUser(division link) error = %v", err)
+	}
+	// Links of descendants are not visible upwards.
+	if _, err := db.ResolveKeywordForUser(ctx, &mod.ID, &division.ID, "inherit-inherit-team"); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("ResolveKeywordForUser(team link as division) error = %v, want ErrLinkNotFound", err)
+	}
+
+	total, err := db.CountApprovedLinks(ctx, "inherit-", nil, &team.ID, "org")
+	if err != nil {
+		t.Fatalf("CountApprovedLinks() error = %v", err)
+	}
+	if total != 5 {
+		t.Errorf("CountApprovedLinks() = %d, want 5 (own and inherited)", total)
+	}
+
+	// A division moderator moderates the organizations nested in it.
+	got, err := db.GetUserByID(ctx, mod.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	if !got.CanModerateOrg(team.ID) || !got.CanModerateOrg(department.ID) {
+		t.Errorf("division moderator cannot moderate nested organizations (sub-organizations %v)", got.SubOrganizationIDs)
+	}
+	ids, err := db.GetOrgModeratorIDs(ctx, team.ID)
+	if err != nil {
+		t.Fatalf("GetOrgModeratorIDs() error = %v", err)
+	}
+	if len(ids) != 1 || ids[0] != mod.ID {
+		t.Errorf("GetOrgModeratorIDs(team) = %v, want [%v]", ids, mod.ID)
+	}
+}
+
+func TestUpdateAndDeleteOrganization(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orgs := createTestOrgs(t, db, "admin-org-a", "admin-org-b")
+	a, b := orgs[0], orgs[1]
+
+	if err := db.UpdateOrganization(ctx, a.ID, "Platform", "platform"); err != nil {
+		t.Fatalf("UpdateOrganization() error = %v", err)
+	}
+	if err := db.UpdateOrganization(ctx, b.ID, "B", "platform"); !errors.Is(err, ErrDuplicateOrgSlug) {
+		t.Errorf("UpdateOrganization(taken slug) error = %v, want ErrDuplicateOrgSlug", err)
+	}
+
+	user := &models.User{Sub: "admin-org-user", Email: "user@example.com", Name: "User"}
+	if err := db.UpsertUser(ctx, user); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+	if err := db.AddUserOrganization(ctx, user.ID, a.ID, models.OrgRoleMod); err != nil {
+		t.Fatalf("AddUserOrganization() error = %v", err)
+	}
+
+	list, err := db.ListOrganizationsWithCounts(ctx)
+	if err != nil {
+		t.Fatalf("ListOrganizationsWithCounts() error = %v", err)
+	}
+	if len(list) != 2 || list[1].Name != "Platform" || list[1].MemberCount != 1 || list[0].MemberCount != 0 {
+		t.Errorf("ListOrganizationsWithCounts() = %+v, want B (empty) then Platform (1 member)", list)
+	}
+	members, err := db.ListOrganizationMembers(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("ListOrganizationMembers() error = %v", err)
+	}
+	if len(members) != 1 || members[0].UserID != user.ID || members[0].Role != models.OrgRoleMod || !members[0].Active {
+		t.Errorf("ListOrganizationMembers() = %+v, want the user as active org_mod", members)
+	}
+
+	if err := db.DeleteOrganization(ctx, a.ID); !errors.Is(err, ErrOrgNotEmpty) {
+		t.Errorf("DeleteOrganization(with members) error = %v, want ErrOrgNotEmpty", err)
+	}
+	if err := db.DeleteOrganization(ctx, b.ID); err != nil {
+		t.Fatalf("DeleteOrganization(empty) error = %v", err)
+	}
+	if _, err := db.GetOrganizationByID(ctx, b.ID); !errors.Is(err, ErrOrgNotFound) {
+		t.Errorf("GetOrganizationByID(deleted) error = %v, want ErrOrgNotFound", err)
+	}
+}
+
+func TestMergeOrganizations(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orgs := createTestOrgs(t, db, "merge-source", "merge-target", "merge-child")
+	source, target, child := orgs[0], orgs[1], orgs[2]
+	if err := db.SetOrganizationParent(ctx, child.ID, &source.ID); err != nil {
+		t.Fatalf("SetOrganizationParent() error = %v", err)
+	}
+
+	both := &models.User{Sub: "merge-both", Email: "both@example.com", Name: "Both"}
+	only := &models.User{Sub: "merge-only", Email: "only@example.com", Name: "Only"}
+	for _, u := range []*models.User{both, only} {
+		if err := db.UpsertUser(ctx, u); err != nil {
+			t.Fatalf("UpsertUser() error = %v", err)
+		}
+	}
+	if err := db.AddUserOrganization(ctx, both.ID, source.ID, models.OrgRoleMod); err != nil {
+		t.Fatalf("AddUserOrganization() error = %v", err)
+	}
+	if err := db.AddUserOrganization(ctx, both.ID, target.ID, models.OrgRoleMember); err != nil {
+		t.Fatalf("AddUserOrganization() error = %v", err)
+	}
+	if err := db.AddUserOrganization(ctx, only.ID, source.ID, models.OrgRoleMember); err != nil {
+		t.Fatalf("AddUserOrganization() error = %v", err)
+	}
+
+	fallback := &models.FallbackRedirect{OrganizationID: source.ID, Name: "Old wiki", URL: "https://wiki.example.com/"}
+	if err := db.CreateFallbackRedirect(ctx, fallback); err != nil {
+		t.Fatalf("CreateFallbackRedirect() error = %v", err)
+	}
+
+	for _, org := range []*models.Organization{source, target} {
+		link := &models.Link{Keyword: "merge-dup", URL: "https://example.com/" + org.Slug, Scope: models.ScopeOrg, OrganizationID: &org.ID, Status: models.StatusApproved}
+		if err := db.CreateLink(ctx, link); err != nil {
+			t.Fatalf("CreateLink() error = %v", err)
+		}
+	}
+	moved := &models.Link{Keyword: "merge-moved", URL: "https://example.com/moved", Scope: models.ScopeOrg, OrganizationID: &source.ID, Status: models.StatusApproved}
+	if err := db.CreateLink(ctx, moved); err != nil {
+		t.Fatalf("CreateLink() error = %v", err)
+	}
+	shared := &models.Link{Keyword: "merge-shared", URL: "https://example.com/shared", Scope: models.ScopeGlobal, Status: models.StatusApproved}
+	if err := db.CreateLink(ctx, shared); err != nil {
+		t.Fatalf("CreateLink() error = %v", err)
+	}
+	sourceOwner := models.LinkOwner{Kind: models.OwnerKindOrg, OrganizationID: &source.ID, Name: source.Slug}
+	targetOwner := models.LinkOwner{Kind: models.OwnerKindOrg, OrganizationID: &target.ID, Name: target.Slug}
+	if _, err := db.SetLinkOwners(ctx, moved.ID, both.ID, []models.LinkOwner{sourceOwner}); err != nil {
+		t.Fatalf("SetLinkOwners() error = %v", err)
+	}
+	if _, err := db.SetLinkOwners(ctx, shared.ID, both.ID, []models.LinkOwner{sourceOwner, targetOwner}); err != nil {
+		t.Fatalf("SetLinkOwners() error = %v", err)
+	}
+
+	if _, err := db.MergeOrganizations(ctx, source.ID, child.ID); !errors.Is(err, ErrOrgMergeTarget) {
+		t.Errorf("MergeOrganizations(into descendant) error = %v, want ErrOrgMergeTarget", err)
+	}
+
+	result, err := db.MergeOrganizations(ctx, source.ID, target.ID)
+	if !errors.Is(err, ErrOrgMergeConflict) {
+		t.Fatalf("MergeOrganizations(conflict) error = %v, want ErrOrgMergeConflict", err)
+	}
+	if len(result.ConflictingKeywords) != 1 || result.ConflictingKeywords[0] != "merge-dup" {
+		t.Errorf("ConflictingKeywords = %v, want [merge-dup]", result.ConflictingKeywords)
+	}
+
+	dup, err := db.GetApprovedOrgLinkByKeyword(ctx, "merge-dup", source.ID)
+	if err != nil {
+		t.Fatalf("GetApprovedOrgLinkByKeyword() error = %v", err)
+	}
+	if err := db.DeleteLink(ctx, dup.ID); err != nil {
+		t.Fatalf("DeleteLink() error = %v", err)
+	}
+
+	result, err = db.MergeOrganizations(ctx, source.ID, target.ID)
+	if err != nil {
+		t.Fatalf("MergeOrganizations() error = %v", err)
+	}
+	if result.LinksMoved != 1 || result.MembersMoved != 2 || result.FallbacksMoved != 1 {
+		t.Errorf("MergeOrganizations() = %+v, want 1 link, 2 members, 1 fallback moved", result)
+	}
+
+	if _, err := db.GetOrganizationByID(ctx, source.ID); !errors.Is(err, ErrOrgNotFound) {
+		t.Errorf("source still exists: %v", err)
+	}
+	link, err := db.GetLinkByID(ctx, moved.ID)
+	if err != nil {
+		t.Fatalf("GetLinkByID() error = %v", err)
+	}
+	if link.OrganizationID == nil || *link.OrganizationID != target.ID {
+		t.Errorf("link organization = %v, want target", link.OrganizationID)
+	}
+
+	// Source's co-ownerships pass to target, once per link.
+	for _, tc := range []struct {
+		link   *models.Link
+		events []string
+	}{
+		{moved, []string{models.OwnerRemoved + " " + source.Slug, models.OwnerAdded + " " + target.Slug}},
+		{shared, []string{models.OwnerRemoved + " " + source.Slug}},
+	} {
+		owners, err := db.GetLinkOwners(ctx, tc.link.ID)
+		if err != nil {
+			t.Fatalf("GetLinkOwners() error = %v", err)
+		}
+		if len(owners) != 1 || owners[0].OrganizationID == nil || *owners[0].OrganizationID != target.ID {
+			t.Errorf("%s owners = %+v, want only target", tc.link.Keyword, owners)
+		}
+		events, err := db.GetLinkOwnerEvents(ctx, tc.link.ID, 10)
+		if err != nil {
+			t.Fatalf("GetLinkOwnerEvents() error = %v", err)
+		}
+		recorded := map[string]bool{}
+		for _, e := range events {
+			if e.ActorID == nil {
+				recorded[e.Action+" "+e.OwnerName] = true
+			}
+		}
+		if len(recorded) != len(tc.events) {
+			t.Errorf("%s merge events = %v, want %v", tc.link.Keyword, recorded, tc.events)
+		}
+		for _, want := range tc.events {
+			if !recorded[want] {
+				t.Errorf("%s merge events = %v, missing %q", tc.link.Keyword, recorded, want)
+			}
+		}
+	}
+
+	got, err := db.GetOrganizationByID(ctx, child.ID)
+	if err != nil {
+		t.Fatalf("GetOrganizationByID() error = %v", err)
+	}
+	if got.ParentID == nil || *got.ParentID != target.ID {
+		t.Errorf("child parent = %v, want target", got.ParentID)
+	}
+
+	// The user in both keeps the higher role, with source's active
+	// organization replaced by target.
+	u, err := db.GetUserByID(ctx, both.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	if u.OrganizationID == nil || *u.OrganizationID != target.ID || u.Role != models.RoleOrgMod {
+		t.Errorf("merged member: org = %v, role = %q; want target and %q", u.OrganizationID, u.Role, models.RoleOrgMod)
+	}
+	memberships, err := db.ListUserOrganizations(ctx, only.ID)
+	if err != nil {
+		t.Fatalf("ListUserOrganizations() error = %v", err)
+	}
+	if len(memberships) != 1 || memberships[0].OrganizationID != target.ID || !memberships[0].Active {
+		t.Errorf("ListUserOrganizations() = %+v, want only target, active", memberships)
+	}
+}
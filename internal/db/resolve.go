@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -11,18 +12,72 @@ import (
 	"golinks/internal/models"
 )
 
+// Orders in which a user's organizations are consulted when more than one of
+// them has a link for the keyword (ORG_RESOLUTION_ORDER).
+const (
+	OrgOrderActive = "active" // the active organization, then the rest in the order joined
+	OrgOrderJoined = "joined" // the order the user joined them in
+	OrgOrderName   = "name"   // alphabetically by organization name
+)
+
+// orgOrderBy maps each resolution order to the ORDER BY terms that rank org
+// links in ResolveKeywordForUser.
+var orgOrderBy = map[string]string{
+	OrgOrderActive: "active_rank ASC, joined_at ASC",
+	OrgOrderJoined: "joined_at ASC",
+	OrgOrderName:   "org_name ASC",
+}
+
+// resolveChain pairs each organization user $1 belongs to with itself and its
+// ancestors; inherited links rank by how far up they come from.
+var resolveChain = `
+	WITH RECURSIVE chain AS (
+		SELECT organization_id AS member_org, organization_id AS org_id, 0 AS depth, created_at AS joined_at
+		FROM user_organizations
+		WHERE user_id = $1
+		UNION ALL
+		SELECT c.member_org, o.parent_id, c.depth + 1, c.joined_at
+		FROM chain c
+		JOIN organizations o ON o.id = c.org_id
+		WHERE o.parent_id IS NOT NULL AND c.depth < ` + strconv.Itoa(maxOrgDepth) + `
+	)`
+
+// SetOrgResolutionOrder sets the order ResolveKeywordForUser consults a
+// user's organizations in. The default is OrgOrderActive.
+func (d *DB) SetOrgResolutionOrder(order string) error {
+	if _, ok := orgOrderBy[order]; !ok {
+		return fmt.Errorf("unknown organization resolution order %q (want %s, %s or %s)", order, OrgOrderActive, OrgOrderJoined, OrgOrderName)
+	}
+	d.orgOrder = order
+	return nil
+}
+
+// orgOrderTerms returns the ORDER BY terms for the configured org resolution
+// order.
+func (d *DB) orgOrderTerms() string {
+	if orderBy, ok := orgOrderBy[d.orgOrder]; ok {
+		return orderBy
+	}
+	return orgOrderBy[OrgOrderActive]
+}
+
 // ResolveKeywordForUser resolves a keyword using the scope hierarchy:
-// personal (user_links) > org (links scope=org) > global (links scope=global).
+// personal (user_links) > org (links scope=org in any of the user's
+// organizations) > inherited (links of the organizations those are nested in,
+// nearest first) > global (links scope=global). When several of the user's
+// organizations define the keyword at the same level, the org resolution
+// order picks one; orgID is the user's active organization. Links hidden from
+// the user by their visibility are skipped, so a lower level can still match.
 // Returns the first matching link, or ErrLinkNotFound if none exists.
 func (d *DB) ResolveKeywordForUser(ctx context.Context, userID *uuid.UUID, orgID *uuid.UUID, keyword string) (*models.ResolvedLink, error) {
 	resolved := &models.ResolvedLink{}
 
 	if userID == nil {
-		// Unauthenticated: global links only
+		// Unauthenticated: public global links only
 		err := d.Pool.QueryRow(ctx, `
 			SELECT id, url, 'global'::text
 			FROM links
-			WHERE keyword = $1 AND scope = 'global' AND status = 'approved'
+			WHERE keyword = $1 AND scope = 'global' AND status = 'approved' AND visibility = 'public'
 			LIMIT 1
 		`, keyword).Scan(&resolved.ID, &resolved.URL, &resolved.Source)
 		if err != nil {
@@ -34,48 +89,31 @@ func (d *DB) ResolveKeywordForUser(ctx context.Context, userID *uuid.UUID, orgID
 		return resolved, nil
 	}
 
-	if orgID != nil {
-		// Authenticated with org: personal > org > global
-		err := d.Pool.QueryRow(ctx, `
-			SELECT id, url, source FROM (
-				SELECT id, url, 'personal'::text AS source, 1 AS priority
-				FROM user_links
-				WHERE user_id = $1 AND keyword = $3
-				UNION ALL
-				SELECT id, url, 'org'::text AS source, 2 AS priority
-				FROM links
-				WHERE keyword = $3 AND scope = 'org' AND organization_id = $2 AND status = 'approved'
-				UNION ALL
-				SELECT id, url, 'global'::text AS source, 3 AS priority
-				FROM links
-				WHERE keyword = $3 AND scope = 'global' AND status = 'approved'
-			) combined
-			ORDER BY priority ASC
-			LIMIT 1
-		`, userID, orgID, keyword).Scan(&resolved.ID, &resolved.URL, &resolved.Source)
-		if err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				return nil, ErrLinkNotFound
-			}
-			return nil, fmt.Errorf("failed to resolve keyword: %w", err)
-		}
-		return resolved, nil
-	}
-
-	// Authenticated without org: personal > global
-	err := d.Pool.QueryRow(ctx, `
+	// Authenticated: personal > org (by membership, then ancestors) > global.
+	err := d.Pool.QueryRow(ctx, resolveChain+`
 		SELECT id, url, source FROM (
-			SELECT id, url, 'personal'::text AS source, 1 AS priority
+			SELECT id, url, 'personal'::text AS source, 1 AS priority, 0 AS depth,
+			       0 AS active_rank, NULL::timestamptz AS joined_at, NULL::text AS org_name
 			FROM user_links
-			WHERE user_id = $1 AND keyword = $2
+			WHERE user_id = $1 AND keyword = $3
 			UNION ALL
-			SELECT id, url, 'global'::text AS source, 2 AS priority
+			SELECT l.id, l.url, 'org'::text AS source, 2 AS priority, c.depth,
+			       CASE WHEN c.member_org = $2 THEN 0 ELSE 1 END, c.joined_at, o.name
+			FROM chain c
+			JOIN links l ON l.organization_id = c.org_id
+			JOIN organizations o ON o.id = c.member_org
+			WHERE l.keyword = $3 AND l.scope = 'org' AND l.status = 'approved'
+				AND `+linkVisibleTo("l", "$1")+`
+			UNION ALL
+			SELECT id, url, 'global'::text AS source, 3 AS priority, 0,
+			       0, NULL::timestamptz, NULL::text
 			FROM links
-			WHERE keyword = $2 AND scope = 'global' AND status = 'approved'
+			WHERE keyword = $3 AND scope = 'global' AND status = 'approved'
+				AND `+linkVisibleTo("links", "$1")+`
 		) combined
-		ORDER BY priority ASC
+		ORDER BY priority ASC, depth ASC, `+d.orgOrderTerms()+`
 		LIMIT 1
-	`, userID, keyword).Scan(&resolved.ID, &resolved.URL, &resolved.Source)
+	`, userID, orgID, keyword).Scan(&resolved.ID, &resolved.URL, &resolved.Source)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrLinkNotFound
@@ -85,6 +123,112 @@ func (d *DB) ResolveKeywordForUser(ctx context.Context, userID *uuid.UUID, orgID
 	return resolved, nil
 }
 
+// ExplainKeywordForUser returns every link defined for keyword as
+// ResolveKeywordForUser weighs it for userID, whose active organization is
+// orgID: the user's personal link and all org and global links whatever their
+// status or organization. Candidates are in resolution order, with the one
+// ResolveKeywordForUser would return marked as the winner and the rest given
+// the reason they lost.
+func (d *DB) ExplainKeywordForUser(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, keyword string) ([]models.ResolveCandidate, error) {
+	// Each org link is ranked by its best route through chain, as the
+	// resolution query would reach it; in_chain is false when there is none.
+	rows, err := d.Pool.Query(ctx, resolveChain+`
+		SELECT id, source, url, status, organization_id, link_org_name, priority, depth, in_chain, visible, own FROM (
+			SELECT id, 'personal'::text AS source, url, 'approved'::text AS status,
+			       NULL::uuid AS organization_id, ''::text AS link_org_name, 1 AS priority, 0 AS depth,
+			       true AS in_chain, true AS visible, true AS own,
+			       0 AS active_rank, NULL::timestamptz AS joined_at, NULL::text AS org_name
+			FROM user_links
+			WHERE user_id = $1 AND keyword = $3
+			UNION ALL
+			SELECT l.id, l.scope, l.url, l.status,
+			       l.organization_id, COALESCE(lo.name, ''), CASE WHEN l.scope = 'org' THEN 2 ELSE 3 END, COALESCE(c.depth, 0),
+			       l.scope = 'global' OR c.depth IS NOT NULL, `+linkVisibleTo("l", "$1")+`,
+			       COALESCE(l.created_by = $1 OR l.submitted_by = $1, false),
+			       COALESCE(c.active_rank, 0), c.joined_at, c.org_name
+			FROM links l
+			LEFT JOIN organizations lo ON lo.id = l.organization_id
+			LEFT JOIN LATERAL (
+				SELECT c.depth, CASE WHEN c.member_org = $2 THEN 0 ELSE 1 END AS active_rank, c.joined_at, o.name AS org_name
+				FROM chain c
+				JOIN organizations o ON o.id = c.member_org
+				WHERE l.scope = 'org' AND c.org_id = l.organization_id
+				ORDER BY depth ASC, `+d.orgOrderTerms()+`
+				LIMIT 1
+			) c ON true
+			WHERE l.keyword = $3
+		) combined
+		ORDER BY (status = 'approved' AND in_chain AND visible) DESC, priority ASC, depth ASC, `+d.orgOrderTerms()+`, id
+	`, userID, orgID, keyword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain keyword: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := []models.ResolveCandidate{}
+	won := false
+	for rows.Next() {
+		var cand models.ResolveCandidate
+		var inChain, visible bool
+		if err := rows.Scan(&cand.ID, &cand.Source, &cand.URL, &cand.Status, &cand.OrganizationID, &cand.OrgName,
+			&cand.Priority, &cand.Depth, &inChain, &visible, &cand.Own); err != nil {
+			return nil, err
+		}
+		switch {
+		case cand.Status == models.StatusPending:
+			cand.LostReason = models.ResolveLostPending
+		case cand.Status == models.StatusRejected:
+			cand.LostReason = models.ResolveLostRejected
+		case !inChain:
+			cand.LostReason = models.ResolveLostWrongOrg
+		case !visible:
+			cand.LostReason = models.ResolveLostHidden
+		case won:
+			cand.LostReason = models.ResolveLostShadowed
+		default:
+			cand.Winner = true
+			won = true
+		}
+		candidates = append(candidates, cand)
+	}
+	return candidates, rows.Err()
+}
+
+// ExplainResolution explains how keyword resolves for user, including the
+// fallback redirect the user has chosen and, when no candidate wins, the URL
+// a browser would be sent to instead.
+func (d *DB) ExplainResolution(ctx context.Context, user *models.User, keyword string) (*models.ResolveExplanation, error) {
+	candidates, err := d.ExplainKeywordForUser(ctx, user.ID, user.OrganizationID, keyword)
+	if err != nil {
+		return nil, err
+	}
+
+	order := d.orgOrder
+	if _, ok := orgOrderBy[order]; !ok {
+		order = OrgOrderActive
+	}
+	e := &models.ResolveExplanation{
+		Keyword:        keyword,
+		UserID:         user.ID,
+		Email:          user.Email,
+		OrganizationID: user.OrganizationID,
+		OrgOrder:       order,
+		Candidates:     candidates,
+	}
+
+	if user.FallbackRedirectID != nil {
+		fb, err := d.GetFallbackRedirectByID(ctx, *user.FallbackRedirectID)
+		if err != nil && !errors.Is(err, ErrFallbackRedirectNotFound) {
+			return nil, err
+		}
+		e.Fallback = fb
+		if fb != nil && e.Winner() == nil {
+			e.FallbackURL = fb.URL + keyword
+		}
+	}
+	return e, nil
+}
+
 // IncrementResolvedLinkClickCount records a click for a resolved link. Writes
 // are buffered in memory and flushed in batches to reduce WAL write frequency.
 func (d *DB) IncrementResolvedLinkClickCount(_ context.Context, resolved *models.ResolvedLink, userID *uuid.UUID) error {
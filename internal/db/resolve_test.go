@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"golinks/internal/models"
+)
+
+func TestExplainResolution(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orgs := createTestOrgs(t, db, "explain-eng", "explain-sales", "explain-ops")
+	eng, sales, ops := orgs[0], orgs[1], orgs[2]
+
+	user := &models.User{Sub: "explain-user", Email: "explain@example.com", Name: "Explain"}
+	if err := db.UpsertUser(ctx, user); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+	for _, org := range []*models.Organization{eng, ops} {
+		if err := db.AddUserOrganization(ctx, user.ID, org.ID, models.OrgRoleMember); err != nil {
+			t.Fatalf("AddUserOrganization() error = %v", err)
+		}
+	}
+
+	links := []*models.Link{
+		{Keyword: "deploy", URL: "https://deploy.example.com", Scope: models.ScopeGlobal, Status: models.StatusApproved},
+		{Keyword: "deploy", URL: "https://eng-deploy.example.com", Scope: models.ScopeOrg, OrganizationID: &eng.ID, Status: models.StatusApproved},
+		{Keyword: "deploy", URL: "https://sales-deploy.example.com", Scope: models.ScopeOrg, OrganizationID: &sales.ID, Status: models.StatusApproved},
+		{Keyword: "deploy", URL: "https://next-deploy.example.com", Scope: models.ScopeOrg, OrganizationID: &ops.ID, Status: models.StatusPending},
+	}
+	for _, l := range links {
+		if err := db.CreateLink(ctx, l); err != nil {
+			t.Fatalf("CreateLink() error = %v", err)
+		}
+	}
+
+	got, err := db.GetUserByEmail(ctx, "EXPLAIN@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail() error = %v", err)
+	}
+
+	e, err := db.ExplainResolution(ctx, got, "deploy")
+	if err != nil {
+		t.Fatalf("ExplainResolution() error = %v", err)
+	}
+	if len(e.Candidates) != 4 {
+		t.Fatalf("ExplainResolution() returned %d candidates, want 4", len(e.Candidates))
+	}
+
+	// The winner matches what ResolveKeywordForUser returns.
+	resolved, err := db.ResolveKeywordForUser(ctx, &got.ID, got.OrganizationID, "deploy")
+	if err != nil {
+		t.Fatalf("ResolveKeywordForUser() error = %v", err)
+	}
+	winner := e.Winner()
+	if winner == nil || winner.ID != resolved.ID || winner.ID != links[1].ID {
+		t.Errorf("Winner() = %+v, want the eng link %s", winner, links[1].ID)
+	}
+
+	reasons := make(map[string]string)
+	for _, c := range e.Candidates {
+		reasons[c.URL] = c.LostReason
+	}
+	for url, want := range map[string]string{
+		links[0].URL: models.ResolveLostShadowed,
+		links[2].URL: models.ResolveLostWrongOrg,
+		links[3].URL: models.ResolveLostPending,
+	} {
+		if reasons[url] != want {
+			t.Errorf("LostReason for %s = %q, want %q", url, reasons[url], want)
+		}
+	}
+	if e.FallbackURL != "" {
+		t.Errorf("FallbackURL = %q for a resolving keyword, want empty", e.FallbackURL)
+	}
+
+	// A personal link takes over; a keyword with no candidates falls back.
+	personal := &models.UserLink{UserID: got.ID, Keyword: "deploy", URL: "https://my-deploy.example.com"}
+	if err := db.CreateUserLink(ctx, personal); err != nil {
+		t.Fatalf("CreateUserLink() error = %v", err)
+	}
+	fb := &models.FallbackRedirect{OrganizationID: eng.ID, Name: "Corp", URL: "https://go.corp.example.com/"}
+	if err := db.CreateFallbackRedirect(ctx, fb); err != nil {
+		t.Fatalf("CreateFallbackRedirect() error = %v", err)
+	}
+	if err := db.UpdateUserFallback(ctx, got.ID, &fb.ID); err != nil {
+		t.Fatalf("UpdateUserFallback() error = %v", err)
+	}
+	got.FallbackRedirectID = &fb.ID
+
+	e, err = db.ExplainResolution(ctx, got, "deploy")
+	if err != nil {
+		t.Fatalf("ExplainResolution() error = %v", err)
+	}
+	if winner := e.Winner(); winner == nil || winner.ID != personal.ID || winner.Source != "personal" {
+		t.Errorf("Winner() = %+v, want the personal link", winner)
+	}
+
+	e, err = db.ExplainResolution(ctx, got, "nothing")
+	if err != nil {
+		t.Fatalf("ExplainResolution() error = %v", err)
+	}
+	if len(e.Candidates) != 0 || e.FallbackURL != fb.URL+"nothing" {
+		t.Errorf("ExplainResolution(nothing) = %d candidates, fallback %q; want none and %q",
+			len(e.Candidates), e.FallbackURL, fb.URL+"nothing")
+	}
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"golinks/internal/models"
+)
+
+// RollupClickHistory moves hourly click_history buckets older than before into
+// click_history_daily, summing them per (link, UTC day). The delete and insert
+// run as a single statement so clicks are never lost or double counted.
+// Returns the number of hourly rows removed.
+func (d *DB) RollupClickHistory(ctx context.Context, before time.Time) (int64, error) {
+	var removed int64
+	err := d.Pool.QueryRow(ctx, `
+		WITH moved AS (
+			DELETE FROM click_history
+			WHERE hour_bucket < $1
+			RETURNING link_id, hour_bucket, click_count
+		),
+		rolled AS (
+			INSERT INTO click_history_daily (link_id, day, click_count)
+			SELECT link_id, (hour_bucket AT TIME ZONE 'UTC')::date, SUM(click_count)
+			FROM moved
+			GROUP BY 1, 2
+			ON CONFLICT (link_id, day) DO UPDATE
+			  SET click_count = click_history_daily.click_count + EXCLUDED.click_count
+			RETURNING 1
+		)
+		SELECT COUNT(*) FROM moved
+	`, before).Scan(&removed)
+	return removed, err
+}
+
+// PruneUserLinkClickHistory deletes hourly user_link_click_history buckets
+// older than before. Personal link history only feeds the /my-links
+// sparklines, so it is not rolled up.
+func (d *DB) PruneUserLinkClickHistory(ctx context.Context, before time.Time) (int64, error) {
+	result, err := d.Pool.Exec(ctx, `
+		DELETE FROM user_link_click_history
+		WHERE hour_bucket < $1
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// PruneNotFoundKeywordLookups deletes not_found keyword_lookups rows last seen
+// before the cutoff. Resolved and fallback rows are kept because they describe
+// keywords that exist.
+func (d *DB) PruneNotFoundKeywordLookups(ctx context.Context, before time.Time) (int64, error) {
+	result, err := d.Pool.Exec(ctx, `
+		DELETE FROM keyword_lookups
+		WHERE outcome = $1 AND last_seen_at < $2
+	`, models.OutcomeNotFound, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// PruneMissingKeywordLookups deletes per-organization missing keyword counts
+// last seen before the cutoff. Dismissals are kept.
+func (d *DB) PruneMissingKeywordLookups(ctx context.Context, before time.Time) (int64, error) {
+	result, err := d.Pool.Exec(ctx, `
+		DELETE FROM missing_keyword_lookups
+		WHERE last_seen_at < $1
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// CapNotFoundKeywordLookups keeps only the max most-requested not_found rows,
+// deleting the long tail of one-off typos. Ties are broken by recency.
+func (d *DB) CapNotFoundKeywordLookups(ctx context.Context, max int) (int64, error) {
+	result, err := d.Pool.Exec(ctx, `
+		DELETE FROM keyword_lookups
+		WHERE outcome = $1 AND keyword IN (
+			SELECT keyword FROM keyword_lookups
+			WHERE outcome = $1
+			ORDER BY count DESC, last_seen_at DESC
+			OFFSET $2
+		)
+	`, models.OutcomeNotFound, max)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// DeleteReadNotificationsBefore removes read notifications created before the cutoff.
+// Unread notifications are never pruned.
+func (d *DB) DeleteReadNotificationsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := d.Pool.Exec(ctx, `
+		DELETE FROM notifications
+		WHERE read = TRUE AND created_at < $1
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// DeleteRejectedLinksBefore purges rejected link submissions reviewed before the cutoff.
+func (d *DB) DeleteRejectedLinksBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := d.Pool.Exec(ctx, `
+		DELETE FROM links
+		WHERE status = $1 AND COALESCE(reviewed_at, updated_at) < $2
+	`, models.StatusRejected, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// DeleteReviewedEditRequestsBefore purges approved and rejected edit requests
+// reviewed before the cutoff. Pending requests are never touched.
+func (d *DB) DeleteReviewedEditRequestsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := d.Pool.Exec(ctx, `
+		DELETE FROM link_edit_requests
+		WHERE status <> $1 AND COALESCE(reviewed_at, created_at) < $2
+	`, models.StatusPending, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,249 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golinks/internal/models"
+)
+
+func TestRollupClickHistory(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	link := &models.Link{Keyword: "rollup-link", URL: "https://example.com", Scope: models.ScopeGlobal}
+	if err := db.CreateLink(ctx, link); err != nil {
+		t.Fatalf("CreateLink() error = %v", err)
+	}
+
+	day := time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC)
+	recent := time.Now().UTC().Truncate(time.Hour)
+	for _, b := range []struct {
+		hour  time.Time
+		count int
+	}{
+		{day.Add(1 * time.Hour), 3},
+		{day.Add(5 * time.Hour), 4},
+		{recent, 7},
+	} {
+		if _, err := db.Pool.Exec(ctx,
+			`INSERT INTO click_history (link_id, hour_bucket, click_count) VALUES ($1, $2, $3)`,
+			link.ID, b.hour, b.count,
+		); err != nil {
+			t.Fatalf("failed to seed click_history: %v", err)
+		}
+	}
+
+	removed, err := db.RollupClickHistory(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("RollupClickHistory() error = %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("RollupClickHistory() removed = %d, want 2", removed)
+	}
+
+	var daily int64
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT click_count FROM click_history_daily WHERE link_id = $1 AND day = $2`,
+		link.ID, day,
+	).Scan(&daily); err != nil {
+		t.Fatalf("failed to read click_history_daily: %v", err)
+	}
+	if daily != 7 {
+		t.Errorf("daily click_count = %d, want 7", daily)
+	}
+
+	// The recent hourly bucket must be untouched.
+	history, err := db.GetClickHistory24h(ctx, link.ID)
+	if err != nil {
+		t.Fatalf("GetClickHistory24h() error = %v", err)
+	}
+	if history[len(history)-1] != 7 {
+		t.Errorf("current hour = %d, want 7", history[len(history)-1])
+	}
+}
+
+func TestPruneNotFoundKeywordLookups(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	db.Pool.Exec(ctx, "DELETE FROM keyword_lookups")
+	defer db.Pool.Exec(ctx, "DELETE FROM keyword_lookups")
+
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	for _, kw := range []struct {
+		keyword, outcome string
+		seen             time.Time
+	}{
+		{"stale-typo", models.OutcomeNotFound, old},
+		{"fresh-typo", models.OutcomeNotFound, time.Now()},
+		{"stale-real", models.OutcomeResolved, old},
+	} {
+		if _, err := db.Pool.Exec(ctx,
+			`INSERT INTO keyword_lookups (keyword, outcome, count, last_seen_at) VALUES ($1, $2, 1, $3)`,
+			kw.keyword, kw.outcome, kw.seen,
+		); err != nil {
+			t.Fatalf("failed to seed keyword_lookups: %v", err)
+		}
+	}
+
+	removed, err := db.PruneNotFoundKeywordLookups(ctx, time.Now().Add(-90*24*time.Hour))
+	if err != nil {
+		t.Fatalf("PruneNotFoundKeywordLookups() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("PruneNotFoundKeywordLookups() removed = %d, want 1", removed)
+	}
+
+	lookups, err := db.GetTopKeywordLookups(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetTopKeywordLookups() error = %v", err)
+	}
+	if len(lookups) != 2 {
+		t.Errorf("remaining lookups = %d, want 2", len(lookups))
+	}
+}
+
+func TestCapNotFoundKeywordLookups(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	db.Pool.Exec(ctx, "DELETE FROM keyword_lookups")
+	defer db.Pool.Exec(ctx, "DELETE FROM keyword_lookups")
+
+	for i, kw := range []string{"popular", "middling", "rare"} {
+		if _, err := db.Pool.Exec(ctx,
+			`INSERT INTO keyword_lookups (keyword, outcome, count) VALUES ($1, $2, $3)`,
+			kw, models.OutcomeNotFound, 100-i*10,
+		); err != nil {
+			t.Fatalf("failed to seed keyword_lookups: %v", err)
+		}
+	}
+
+	removed, err := db.CapNotFoundKeywordLookups(ctx, 2)
+	if err != nil {
+		t.Fatalf("CapNotFoundKeywordLookups() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("CapNotFoundKeywordLookups() removed = %d, want 1", removed)
+	}
+
+	var exists bool
+	db.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM keyword_lookups WHERE keyword = 'rare')`).Scan(&exists)
+	if exists {
+		t.Error("least requested keyword should have been removed")
+	}
+}
+
+func TestDeleteReadNotificationsBefore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	user := &models.User{Sub: "retention-user", Email: "retention@example.com", Name: "Retention User"}
+	if err := db.UpsertUser(ctx, user); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	for _, n := range []struct {
+		read    bool
+		created time.Time
+	}{
+		{true, old},
+		{false, old},
+		{true, time.Now()},
+	} {
+		if _, err := db.Pool.Exec(ctx,
+			`INSERT INTO notifications (user_id, type, title, body, read, created_at) VALUES ($1, 'test', 't', 'b', $2, $3)`,
+			user.ID, n.read, n.created,
+		); err != nil {
+			t.Fatalf("failed to seed notifications: %v", err)
+		}
+	}
+
+	removed, err := db.DeleteReadNotificationsBefore(ctx, time.Now().Add(-30*24*time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteReadNotificationsBefore() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("DeleteReadNotificationsBefore() removed = %d, want 1", removed)
+	}
+
+	unread, err := db.CountUnreadNotifications(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("CountUnreadNotifications() error = %v", err)
+	}
+	if unread != 1 {
+		t.Errorf("unread notifications = %d, want 1", unread)
+	}
+}
+
+func TestDeleteRejectedLinksBefore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	rejected := &models.Link{Keyword: "old-rejected", URL: "https://example.com", Scope: models.ScopeGlobal, Status: models.StatusRejected}
+	pending := &models.Link{Keyword: "old-pending", URL: "https://example.com", Scope: models.ScopeGlobal, Status: models.StatusPending}
+	for _, l := range []*models.Link{rejected, pending} {
+		if err := db.CreateLink(ctx, l); err != nil {
+			t.Fatalf("CreateLink() error = %v", err)
+		}
+	}
+	db.Pool.Exec(ctx, `UPDATE links SET reviewed_at = NOW() - INTERVAL '120 days', updated_at = NOW() - INTERVAL '120 days'`)
+
+	removed, err := db.DeleteRejectedLinksBefore(ctx, time.Now().Add(-90*24*time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteRejectedLinksBefore() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("DeleteRejectedLinksBefore() removed = %d, want 1", removed)
+	}
+	if _, err := db.GetLinkByID(ctx, pending.ID); err != nil {
+		t.Errorf("pending link should survive, got error = %v", err)
+	}
+}
+
+func TestPruneUserLinkClickHistory(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	user := &models.User{Sub: "retention-ul-user", Email: "retention-ul@example.com", Name: "Retention UL"}
+	if err := db.UpsertUser(ctx, user); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+	link := &models.UserLink{UserID: user.ID, Keyword: "retention-ul", URL: "https://example.com"}
+	if err := db.CreateUserLink(ctx, link); err != nil {
+		t.Fatalf("CreateUserLink() error = %v", err)
+	}
+
+	for _, hour := range []time.Time{
+		time.Now().Add(-40 * 24 * time.Hour).Truncate(time.Hour),
+		time.Now().Truncate(time.Hour),
+	} {
+		if _, err := db.Pool.Exec(ctx,
+			`INSERT INTO user_link_click_history (user_link_id, hour_bucket, click_count) VALUES ($1, $2, 1)`,
+			link.ID, hour,
+		); err != nil {
+			t.Fatalf("failed to seed user_link_click_history: %v", err)
+		}
+	}
+
+	removed, err := db.PruneUserLinkClickHistory(ctx, time.Now().Add(-30*24*time.Hour))
+	if err != nil {
+		t.Fatalf("PruneUserLinkClickHistory() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("PruneUserLinkClickHistory() removed = %d, want 1", removed)
+	}
+}
@@ -0,0 +1,247 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"golinks/internal/models"
+)
+
+// SCIMUserFilter narrows ListSCIMUsers to exact attribute matches. Empty
+// fields match everything.
+type SCIMUserFilter struct {
+	Issuer     string // the issuer SCIM users are created under; "" matches every issuer
+	ExternalID string // matches sub
+	UserName   string // matches username
+}
+
+// ListSCIMUsers returns a page of users matching filter, ordered by creation,
+// with the total number of matches.
+func (d *DB) ListSCIMUsers(ctx context.Context, filter SCIMUserFilter, offset, limit int) ([]models.User, int, error) {
+	where := `WHERE ($1 = '' OR username = $1) AND ($2 = '' OR sub = $2) AND ($3 = '' OR issuer = $3)`
+
+	var total int
+	if err := d.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM users `+where,
+		filter.UserName, filter.ExternalID, filter.Issuer).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := d.Pool.Query(ctx, `SELECT `+userColumns+` FROM users `+where+` ORDER BY created_at, id OFFSET $4 LIMIT $5`,
+		filter.UserName, filter.ExternalID, filter.Issuer, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, *user)
+	}
+	return users, total, rows.Err()
+}
+
+// scimGroupColumns is the standard column list for scim_groups queries.
+const scimGroupColumns = `id, display_name, external_id, created_at, updated_at`
+
+func scanSCIMGroup(row pgx.Row) (*models.SCIMGroup, error) {
+	var g models.SCIMGroup
+	err := row.Scan(&g.ID, &g.DisplayName, &g.ExternalID, &g.CreatedAt, &g.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSCIMGroupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// CreateSCIMGroup inserts a group, filling in its ID and timestamps.
+func (d *DB) CreateSCIMGroup(ctx context.Context, group *models.SCIMGroup) error {
+	err := d.Pool.QueryRow(ctx, `
+		INSERT INTO scim_groups (display_name, external_id)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at
+	`, group.DisplayName, group.ExternalID).Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt)
+	return scimGroupWriteError(err)
+}
+
+// GetSCIMGroup retrieves a group with its members.
+func (d *DB) GetSCIMGroup(ctx context.Context, id uuid.UUID) (*models.SCIMGroup, error) {
+	group, err := scanSCIMGroup(d.Pool.QueryRow(ctx, `SELECT `+scimGroupColumns+` FROM scim_groups WHERE id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+	if group.Members, err = d.listSCIMGroupMembers(ctx, id); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// ListSCIMGroups returns a page of groups, optionally only the one named
+// displayName, with their members and the total number of matches.
+func (d *DB) ListSCIMGroups(ctx context.Context, displayName string, offset, limit int) ([]models.SCIMGroup, int, error) {
+	where := `WHERE ($1 = '' OR display_name = $1)`
+
+	var total int
+	if err := d.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM scim_groups `+where, displayName).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := d.Pool.Query(ctx, `SELECT `+scimGroupColumns+` FROM scim_groups `+where+` ORDER BY created_at, id OFFSET $2 LIMIT $3`,
+		displayName, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	var groups []models.SCIMGroup
+	for rows.Next() {
+		group, err := scanSCIMGroup(rows)
+		if err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		groups = append(groups, *group)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	for i := range groups {
+		if groups[i].Members, err = d.listSCIMGroupMembers(ctx, groups[i].ID); err != nil {
+			return nil, 0, err
+		}
+	}
+	return groups, total, nil
+}
+
+// UpdateSCIMGroup saves a group's display name and external ID.
+func (d *DB) UpdateSCIMGroup(ctx context.Context, group *models.SCIMGroup) error {
+	err := d.Pool.QueryRow(ctx, `
+		UPDATE scim_groups SET display_name = $1, external_id = $2, updated_at = NOW()
+		WHERE id = $3
+		RETURNING updated_at
+	`, group.DisplayName, group.ExternalID, group.ID).Scan(&group.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrSCIMGroupNotFound
+	}
+	return scimGroupWriteError(err)
+}
+
+// DeleteSCIMGroup deletes a group and its memberships.
+func (d *DB) DeleteSCIMGroup(ctx context.Context, id uuid.UUID) error {
+	tag, err := d.Pool.Exec(ctx, `DELETE FROM scim_groups WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSCIMGroupNotFound
+	}
+	return nil
+}
+
+// AddSCIMGroupMembers adds users to a group; existing members are ignored.
+func (d *DB) AddSCIMGroupMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	_, err := d.Pool.Exec(ctx, `
+		INSERT INTO scim_group_members (group_id, user_id)
+		SELECT $1, u.id FROM users u WHERE u.id = ANY($2)
+		ON CONFLICT DO NOTHING
+	`, groupID, userIDs)
+	return err
+}
+
+// RemoveSCIMGroupMembers removes users from a group.
+func (d *DB) RemoveSCIMGroupMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	_, err := d.Pool.Exec(ctx, `DELETE FROM scim_group_members WHERE group_id = $1 AND user_id = ANY($2)`, groupID, userIDs)
+	return err
+}
+
+// SetSCIMGroupMembers replaces a group's members.
+func (d *DB) SetSCIMGroupMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	if userIDs == nil {
+		// A nil slice is sent as NULL, which would match no member to remove.
+		userIDs = []uuid.UUID{}
+	}
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM scim_group_members WHERE group_id = $1 AND NOT (user_id = ANY($2))`, groupID, userIDs); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO scim_group_members (group_id, user_id)
+		SELECT $1, u.id FROM users u WHERE u.id = ANY($2)
+		ON CONFLICT DO NOTHING
+	`, groupID, userIDs); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListUserSCIMGroups returns the groups a user belongs to, without members.
+func (d *DB) ListUserSCIMGroups(ctx context.Context, userID uuid.UUID) ([]models.SCIMGroup, error) {
+	rows, err := d.Pool.Query(ctx, `
+		SELECT g.id, g.display_name, g.external_id, g.created_at, g.updated_at
+		FROM scim_groups g
+		JOIN scim_group_members m ON m.group_id = g.id
+		WHERE m.user_id = $1
+		ORDER BY g.display_name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []models.SCIMGroup
+	for rows.Next() {
+		group, err := scanSCIMGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, *group)
+	}
+	return groups, rows.Err()
+}
+
+func (d *DB) listSCIMGroupMembers(ctx context.Context, groupID uuid.UUID) ([]models.SCIMGroupMember, error) {
+	rows, err := d.Pool.Query(ctx, `
+		SELECT u.id, COALESCE(NULLIF(u.name, ''), u.email)
+		FROM scim_group_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.group_id = $1
+		ORDER BY u.name, u.id
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.SCIMGroupMember
+	for rows.Next() {
+		var m models.SCIMGroupMember
+		if err := rows.Scan(&m.UserID, &m.Display); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// scimGroupWriteError maps a display name collision to ErrDuplicateSCIMGroup.
+func scimGroupWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicateSCIMGroup
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"golinks/internal/models"
+)
+
+func TestSCIMUsers_FilterAndDeactivate(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	alice := &models.User{Issuer: "https://idp.example.com", Sub: "00u1", Username: "alice", Email: "alice@example.com", Name: "Alice"}
+	bob := &models.User{Issuer: "https://idp.example.com", Sub: "00u2", Username: "bob", Email: "bob@example.com", Name: "Bob"}
+	for _, u := range []*models.User{alice, bob} {
+		if err := db.UpsertUser(ctx, u); err != nil {
+			t.Fatalf("UpsertUser() error = %v", err)
+		}
+	}
+
+	users, total, err := db.ListSCIMUsers(ctx, SCIMUserFilter{}, 0, 10)
+	if err != nil {
+		t.Fatalf("ListSCIMUsers() error = %v", err)
+	}
+	if total != 2 || len(users) != 2 {
+		t.Errorf("ListSCIMUsers() = %d users of %d, want 2 of 2", len(users), total)
+	}
+
+	users, total, err = db.ListSCIMUsers(ctx, SCIMUserFilter{Issuer: "https://idp.example.com", ExternalID: "00u2"}, 0, 10)
+	if err != nil {
+		t.Fatalf("ListSCIMUsers(externalId) error = %v", err)
+	}
+	if total != 1 || users[0].ID != bob.ID {
+		t.Errorf("ListSCIMUsers(externalId) = %+v, want bob", users)
+	}
+
+	if err := db.DeactivateUser(ctx, alice.ID); err != nil {
+		t.Fatalf("DeactivateUser() error = %v", err)
+	}
+	got, err := db.GetUserByID(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	if !got.IsDeactivated() {
+		t.Error("user is not deactivated after DeactivateUser()")
+	}
+	if err := db.ReactivateUser(ctx, alice.ID); err != nil {
+		t.Fatalf("ReactivateUser() error = %v", err)
+	}
+	if got, _ = db.GetUserByID(ctx, alice.ID); got.IsDeactivated() {
+		t.Error("user is still deactivated after ReactivateUser()")
+	}
+}
+
+func TestSCIMGroups_Membership(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	alice := &models.User{Issuer: "https://idp.example.com", Sub: "00u1", Email: "alice@example.com", Name: "Alice"}
+	bob := &models.User{Issuer: "https://idp.example.com", Sub: "00u2", Email: "bob@example.com", Name: "Bob"}
+	for _, u := range []*models.User{alice, bob} {
+		if err := db.UpsertUser(ctx, u); err != nil {
+			t.Fatalf("UpsertUser() error = %v", err)
+		}
+	}
+
+	group := &models.SCIMGroup{DisplayName: "org:platform"}
+	if err := db.CreateSCIMGroup(ctx, group); err != nil {
+		t.Fatalf("CreateSCIMGroup() error = %v", err)
+	}
+	if err := db.CreateSCIMGroup(ctx, &models.SCIMGroup{DisplayName: "org:platform"}); !errors.Is(err, ErrDuplicateSCIMGroup) {
+		t.Errorf("CreateSCIMGroup(duplicate) error = %v, want ErrDuplicateSCIMGroup", err)
+	}
+
+	// Unknown user IDs are ignored.
+	if err := db.AddSCIMGroupMembers(ctx, group.ID, []uuid.UUID{alice.ID, bob.ID, uuid.New()}); err != nil {
+		t.Fatalf("AddSCIMGroupMembers() error = %v", err)
+	}
+	got, err := db.GetSCIMGroup(ctx, group.ID)
+	if err != nil {
+		t.Fatalf("GetSCIMGroup() error = %v", err)
+	}
+	if len(got.Members) != 2 {
+		t.Errorf("GetSCIMGroup() members = %+v, want alice and bob", got.Members)
+	}
+
+	if err := db.SetSCIMGroupMembers(ctx, group.ID, []uuid.UUID{bob.ID}); err != nil {
+		t.Fatalf("SetSCIMGroupMembers() error = %v", err)
+	}
+	groups, err := db.ListUserSCIMGroups(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("ListUserSCIMGroups() error = %v", err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("ListUserSCIMGroups(alice) = %+v, want none after SetSCIMGroupMembers", groups)
+	}
+
+	if err := db.RemoveSCIMGroupMembers(ctx, group.ID, []uuid.UUID{bob.ID}); err != nil {
+		t.Fatalf("RemoveSCIMGroupMembers() error = %v", err)
+	}
+	if got, _ = db.GetSCIMGroup(ctx, group.ID); len(got.Members) != 0 {
+		t.Errorf("members after RemoveSCIMGroupMembers() = %+v, want none", got.Members)
+	}
+
+	if err := db.DeleteSCIMGroup(ctx, group.ID); err != nil {
+		t.Fatalf("DeleteSCIMGroup() error = %v", err)
+	}
+	if _, err := db.GetSCIMGroup(ctx, group.ID); !errors.Is(err, ErrSCIMGroupNotFound) {
+		t.Errorf("GetSCIMGroup() after delete error = %v, want ErrSCIMGroupNotFound", err)
+	}
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"golinks/internal/models"
+)
+
+// linkLastUsed returns an SQL expression for the latest click on the link in
+// table or alias link: its newest hourly bucket, or the newest daily roll-up
+// once the hourly buckets have been pruned.
+func linkLastUsed(link string) string {
+	return `COALESCE(
+		(SELECT MAX(ch.hour_bucket) FROM click_history ch WHERE ch.link_id = ` + link + `.id),
+		(SELECT MAX(cd.day)::timestamp AT TIME ZONE 'UTC' FROM click_history_daily cd WHERE cd.link_id = ` + link + `.id)
+	)`
+}
+
+// shadowColumns selects a KeywordShadow from a winner w and a shadowed link s
+// that both expose id, scope, url, health_status, last_used, organization_id
+// and org_name.
+const shadowColumns = `w.keyword,
+	w.id, w.scope, w.url, w.health_status, w.last_used, w.organization_id, w.org_name,
+	s.id, s.scope, s.url, s.health_status, s.last_used, s.organization_id, s.org_name`
+
+// shadowOrder sorts shadows by keyword, then the winner's scope priority and
+// the winner, so each winner's rows are adjacent, then the shadowed link's
+// scope priority. Personal > org > global happens to be reverse alphabetical.
+const shadowOrder = `ORDER BY 1, 3 DESC, 2, 10 DESC`
+
+func scanShadows(rows pgx.Rows) ([]models.KeywordShadow, error) {
+	defer rows.Close()
+	shadows := []models.KeywordShadow{}
+	for rows.Next() {
+		var s models.KeywordShadow
+		w, l := &s.Winner, &s.Shadowed
+		if err := rows.Scan(&s.Keyword,
+			&w.ID, &w.Scope, &w.URL, &w.HealthStatus, &w.LastUsedAt, &w.OrganizationID, &w.OrgName,
+			&l.ID, &l.Scope, &l.URL, &l.HealthStatus, &l.LastUsedAt, &l.OrganizationID, &l.OrgName,
+		); err != nil {
+			return nil, err
+		}
+		shadows = append(shadows, s)
+	}
+	return shadows, rows.Err()
+}
+
+// GetUserShadows returns the keywords where, for userID, a higher-priority
+// scope hides a lower one: the user's personal links over org and global
+// links they can see, and links of their organizations (and the ones those
+// are nested in) over global links, where the user has no personal link of
+// their own for the keyword.
+func (d *DB) GetUserShadows(ctx context.Context, userID uuid.UUID) ([]models.KeywordShadow, error) {
+	rows, err := d.Pool.Query(ctx, `
+		WITH RECURSIVE chain AS (
+			SELECT organization_id AS org_id, 0 AS depth
+			FROM user_organizations
+			WHERE user_id = $1
+			UNION ALL
+			SELECT o.parent_id, c.depth + 1
+			FROM chain c
+			JOIN organizations o ON o.id = c.org_id
+			WHERE o.parent_id IS NOT NULL AND c.depth < `+strconv.Itoa(maxOrgDepth)+`
+		),
+		reachable AS (
+			SELECT l.id, l.keyword, l.scope, l.url, l.health_status, `+linkLastUsed("l")+` AS last_used,
+			       l.organization_id, COALESCE(o.name, '') AS org_name
+			FROM links l
+			LEFT JOIN organizations o ON o.id = l.organization_id
+			WHERE l.status = 'approved' AND `+linkVisibleTo("l", "$1")+`
+				AND (l.scope = 'global' OR (l.scope = 'org' AND l.organization_id IN (SELECT org_id FROM chain)))
+		),
+		personal AS (
+			SELECT ul.id, ul.keyword, 'personal'::text AS scope, ul.url, ul.health_status,
+			       (SELECT MAX(h.hour_bucket) FROM user_link_click_history h WHERE h.user_link_id = ul.id) AS last_used,
+			       NULL::uuid AS organization_id, ''::text AS org_name
+			FROM user_links ul
+			WHERE ul.user_id = $1
+		)
+		SELECT `+shadowColumns+`
+		FROM personal w
+		JOIN reachable s ON s.keyword = w.keyword
+		UNION ALL
+		SELECT `+shadowColumns+`
+		FROM reachable w
+		JOIN reachable s ON s.keyword = w.keyword AND s.scope = 'global'
+		WHERE w.scope = 'org' AND NOT EXISTS (SELECT 1 FROM personal p WHERE p.keyword = w.keyword)
+		`+shadowOrder, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanShadows(rows)
+}
+
+// GetOrgShadows returns approved org links that hide an approved global link
+// with the same keyword from the organization's members, for the
+// organization orgID and those nested under it, or for every organization
+// when orgID is nil. Global moderators see every shadowed global link; other
+// viewers only the ones visible to them.
+func (d *DB) GetOrgShadows(ctx context.Context, viewer *models.User, orgID *uuid.UUID) ([]models.KeywordShadow, error) {
+	var viewerID *uuid.UUID
+	if !viewer.IsGlobalMod() {
+		viewerID = &viewer.ID
+	}
+
+	rows, err := d.Pool.Query(ctx, `
+		WITH candidates AS (
+			SELECT l.id, l.keyword, l.scope, l.url, l.health_status, `+linkLastUsed("l")+` AS last_used,
+			       l.organization_id, COALESCE(o.name, '') AS org_name
+			FROM links l
+			LEFT JOIN organizations o ON o.id = l.organization_id
+			WHERE l.status = 'approved'
+				AND (l.scope = 'global' OR ($1::uuid IS NULL OR l.organization_id IN `+orgSubtree("$1::uuid")+`))
+				AND l.keyword IN (SELECT keyword FROM links WHERE scope = 'global' AND status = 'approved')
+		)
+		SELECT `+shadowColumns+`
+		FROM candidates w
+		JOIN candidates s ON s.keyword = w.keyword AND s.scope = 'global'
+		WHERE w.scope = 'org'
+			AND ($2::uuid IS NULL OR EXISTS (SELECT 1 FROM links sl WHERE sl.id = s.id AND `+linkVisibleTo("sl", "$2")+`))
+		`+shadowOrder, orgID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return scanShadows(rows)
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"golinks/internal/models"
+)
+
+func TestShadows(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orgs := createTestOrgs(t, db, "shadow-eng", "shadow-sales")
+	eng, sales := orgs[0], orgs[1]
+
+	user := &models.User{Sub: "shadow-user", Email: "shadow@example.com", Name: "Shadow"}
+	if err := db.UpsertUser(ctx, user); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+	if err := db.AddUserOrganization(ctx, user.ID, eng.ID, models.OrgRoleMember); err != nil {
+		t.Fatalf("AddUserOrganization() error = %v", err)
+	}
+
+	links := []*models.Link{
+		{Keyword: "wiki", URL: "https://wiki.example.com", Scope: models.ScopeGlobal, Status: models.StatusApproved},
+		{Keyword: "wiki", URL: "https://eng-wiki.example.com", Scope: models.ScopeOrg, OrganizationID: &eng.ID, Status: models.StatusApproved},
+		{Keyword: "wiki", URL: "https://sales-wiki.example.com", Scope: models.ScopeOrg, OrganizationID: &sales.ID, Status: models.StatusApproved},
+		{Keyword: "docs", URL: "https://docs.example.com", Scope: models.ScopeGlobal, Status: models.StatusApproved},
+		{Keyword: "solo", URL: "https://solo.example.com", Scope: models.ScopeGlobal, Status: models.StatusApproved},
+	}
+	for _, l := range links {
+		if err := db.CreateLink(ctx, l); err != nil {
+			t.Fatalf("CreateLink(%s) error = %v", l.Keyword, err)
+		}
+	}
+	global, engWiki := links[0], links[1]
+
+	// Without personal links, the eng wiki hides the global one; the sales
+	// wiki isn't reachable from the user's organizations.
+	shadows, err := db.GetUserShadows(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserShadows() error = %v", err)
+	}
+	if len(shadows) != 1 || shadows[0].Winner.ID != engWiki.ID || shadows[0].Shadowed.ID != global.ID {
+		t.Fatalf("GetUserShadows() = %+v, want the eng wiki over the global wiki", shadows)
+	}
+	if shadows[0].Winner.OrgName != eng.Name {
+		t.Errorf("winner OrgName = %q, want %q", shadows[0].Winner.OrgName, eng.Name)
+	}
+
+	// A personal link hides both reachable links, and takes over the org
+	// link's row for the keyword.
+	personal := &models.UserLink{UserID: user.ID, Keyword: "wiki", URL: "https://my-wiki.example.com"}
+	if err := db.CreateUserLink(ctx, personal); err != nil {
+		t.Fatalf("CreateUserLink() error = %v", err)
+	}
+	same := &models.UserLink{UserID: user.ID, Keyword: "docs", URL: "https://docs.example.com"}
+	if err := db.CreateUserLink(ctx, same); err != nil {
+		t.Fatalf("CreateUserLink() error = %v", err)
+	}
+
+	shadows, err = db.GetUserShadows(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserShadows() error = %v", err)
+	}
+	if len(shadows) != 3 {
+		t.Fatalf("GetUserShadows() returned %d shadows, want 3", len(shadows))
+	}
+	if shadows[0].Keyword != "docs" || !shadows[0].SameURL() {
+		t.Errorf("first shadow = %+v, want docs with the same URL", shadows[0])
+	}
+	for _, s := range shadows[1:] {
+		if s.Winner.ID != personal.ID || s.Winner.Scope != "personal" {
+			t.Errorf("wiki shadow winner = %+v, want the personal link", s.Winner)
+		}
+	}
+	if shadows[1].Shadowed.ID != engWiki.ID || shadows[2].Shadowed.ID != global.ID {
+		t.Errorf("wiki shadows hide %s then %s, want the org link then the global link",
+			shadows[1].Shadowed.ID, shadows[2].Shadowed.ID)
+	}
+
+	// A restricted global link the user can't see, hidden by an eng link.
+	restricted := []*models.Link{
+		{Keyword: "payroll", URL: "https://payroll.example.com", Scope: models.ScopeGlobal, Status: models.StatusApproved,
+			Visibility: models.VisibilityRestricted, AllowedUsers: []string{"hr@example.com"}},
+		{Keyword: "payroll", URL: "https://eng-payroll.example.com", Scope: models.ScopeOrg, OrganizationID: &eng.ID, Status: models.StatusApproved},
+	}
+	for _, l := range restricted {
+		if err := db.CreateLink(ctx, l); err != nil {
+			t.Fatalf("CreateLink(%s) error = %v", l.Keyword, err)
+		}
+	}
+
+	// Global moderators see every org link hiding a global one, or one org's.
+	globalMod := &models.User{Role: models.RoleGlobalMod}
+	all, err := db.GetOrgShadows(ctx, globalMod, nil)
+	if err != nil {
+		t.Fatalf("GetOrgShadows(nil) error = %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("GetOrgShadows(nil) returned %d shadows, want 3", len(all))
+	}
+
+	// Anyone else only sees the global links visible to them.
+	visible, err := db.GetOrgShadows(ctx, user, nil)
+	if err != nil {
+		t.Fatalf("GetOrgShadows(user) error = %v", err)
+	}
+	if len(visible) != 2 {
+		t.Errorf("GetOrgShadows(user) returned %d shadows, want 2", len(visible))
+	}
+	for _, s := range visible {
+		if s.Shadowed.ID == restricted[0].ID {
+			t.Errorf("GetOrgShadows(user) includes the restricted global link: %+v", s)
+		}
+	}
+
+	one, err := db.GetOrgShadows(ctx, globalMod, &sales.ID)
+	if err != nil {
+		t.Fatalf("GetOrgShadows(sales) error = %v", err)
+	}
+	if len(one) != 1 || one[0].Winner.OrganizationID == nil || *one[0].Winner.OrganizationID != sales.ID {
+		t.Errorf("GetOrgShadows(sales) = %+v, want the sales wiki", one)
+	}
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"golinks/internal/models"
+)
+
+// GetLinkDailyClicks returns per-day click totals for a link over the half-open
+// UTC day range [from, to). Rolled-up click_history_daily rows are combined with
+// hourly click_history buckets that have not been rolled up yet. Days without
+// clicks are omitted.
+func (d *DB) GetLinkDailyClicks(ctx context.Context, linkID uuid.UUID, from, to time.Time) ([]models.DailyClicks, error) {
+	rows, err := d.Pool.Query(ctx, `
+		WITH daily AS (
+			SELECT day, click_count
+			FROM click_history_daily
+			WHERE link_id = $1 AND day >= $2::date AND day < $3::date
+			UNION ALL
+			SELECT (hour_bucket AT TIME ZONE 'UTC')::date, click_count
+			FROM click_history
+			WHERE link_id = $1
+				AND hour_bucket >= ($2::date)::timestamp AT TIME ZONE 'UTC'
+				AND hour_bucket < ($3::date)::timestamp AT TIME ZONE 'UTC'
+		)
+		SELECT day, SUM(click_count)::bigint
+		FROM daily
+		GROUP BY day
+		ORDER BY day
+	`, linkID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.DailyClicks
+	for rows.Next() {
+		var dc models.DailyClicks
+		if err := rows.Scan(&dc.Day, &dc.Clicks); err != nil {
+			return nil, err
+		}
+		result = append(result, dc)
+	}
+	return result, rows.Err()
+}
+
+// GetTopLinksByClicks returns the approved links with the most clicks in the
+// UTC day range [from, to), together with their clicks in [prevFrom, from) for
+// trend comparison. scope: "all" = global + org, "global" = global only,
+// "org" = org only (requires orgID). Links without clicks in the current
+// period, and links hidden from viewerID, are omitted.
+func (d *DB) GetTopLinksByClicks(ctx context.Context, viewerID, orgID *uuid.UUID, scope string, prevFrom, from, to time.Time, limit int) ([]models.TopLinkStat, error) {
+	if scope == "" {
+		scope = "all"
+	}
+	rows, err := d.Pool.Query(ctx, `
+		WITH daily AS (
+			SELECT link_id, day, click_count
+			FROM click_history_daily
+			WHERE day >= $1::date AND day < $3::date
+			UNION ALL
+			SELECT link_id, (hour_bucket AT TIME ZONE 'UTC')::date, click_count
+			FROM click_history
+			WHERE hour_bucket >= ($1::date)::timestamp AT TIME ZONE 'UTC'
+				AND hour_bucket < ($3::date)::timestamp AT TIME ZONE 'UTC'
+		),
+		totals AS (
+			SELECT link_id,
+				COALESCE(SUM(click_count) FILTER (WHERE day >= $2::date), 0)::bigint AS clicks,
+				COALESCE(SUM(click_count) FILTER (WHERE day < $2::date), 0)::bigint AS previous
+			FROM daily
+			GROUP BY link_id
+		)
+		SELECT l.id, l.keyword, l.url, l.scope, l.organization_id, t.clicks, t.previous
+		FROM totals t
+		JOIN links l ON l.id = t.link_id
+		WHERE t.clicks > 0
+			AND l.status = $4
+			AND (
+				(l.scope = 'global' AND ($5 = 'all' OR $5 = 'global'))
+				OR (l.scope = 'org' AND $6::uuid IS NOT NULL AND l.organization_id = $6 AND ($5 = 'all' OR $5 = 'org'))
+			)
+			AND `+linkVisibleTo("l", "$8")+`
+		ORDER BY t.clicks DESC, l.keyword ASC
+		LIMIT $7
+	`, prevFrom, from, to, models.StatusApproved, scope, orgID, limit, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.TopLinkStat
+	for rows.Next() {
+		var s models.TopLinkStat
+		if err := rows.Scan(&s.LinkID, &s.Keyword, &s.URL, &s.Scope, &s.OrganizationID, &s.Clicks, &s.PreviousClicks); err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golinks/internal/models"
+)
+
+func TestGetLinkDailyClicks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	link := &models.Link{Keyword: "stats-link", URL: "https://example.com", Scope: models.ScopeGlobal}
+	if err := db.CreateLink(ctx, link); err != nil {
+		t.Fatalf("CreateLink() error = %v", err)
+	}
+
+	day := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	if _, err := db.Pool.Exec(ctx,
+		`INSERT INTO click_history_daily (link_id, day, click_count) VALUES ($1, $2, 10)`,
+		link.ID, day,
+	); err != nil {
+		t.Fatalf("failed to seed click_history_daily: %v", err)
+	}
+	for _, b := range []struct {
+		hour  time.Time
+		count int
+	}{
+		{day.Add(23 * time.Hour), 2}, // same day as the rolled-up row
+		{day.Add(26 * time.Hour), 5}, // next day
+		{day.AddDate(0, 0, 5), 100},  // outside the range
+	} {
+		if _, err := db.Pool.Exec(ctx,
+			`INSERT INTO click_history (link_id, hour_bucket, click_count) VALUES ($1, $2, $3)`,
+			link.ID, b.hour, b.count,
+		); err != nil {
+			t.Fatalf("failed to seed click_history: %v", err)
+		}
+	}
+
+	daily, err := db.GetLinkDailyClicks(ctx, link.ID, day, day.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("GetLinkDailyClicks() error = %v", err)
+	}
+	if len(daily) != 2 {
+		t.Fatalf("len(daily) = %d, want 2", len(daily))
+	}
+	if daily[0].Clicks != 12 || daily[1].Clicks != 5 {
+		t.Errorf("daily clicks = %d, %d, want 12, 5", daily[0].Clicks, daily[1].Clicks)
+	}
+}
+
+func TestGetTopLinksByClicks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	popular := &models.Link{Keyword: "top-popular", URL: "https://example.com/a", Scope: models.ScopeGlobal, Status: models.StatusApproved}
+	quiet := &models.Link{Keyword: "top-quiet", URL: "https://example.com/b", Scope: models.ScopeGlobal, Status: models.StatusApproved}
+	pending := &models.Link{Keyword: "top-pending", URL: "https://example.com/c", Scope: models.ScopeGlobal, Status: models.StatusPending}
+	for _, l := range []*models.Link{popular, quiet, pending} {
+		if err := db.CreateLink(ctx, l); err != nil {
+			t.Fatalf("CreateLink() error = %v", err)
+		}
+	}
+
+	to := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -7)
+	prevFrom := from.AddDate(0, 0, -7)
+	for _, c := range []struct {
+		link  *models.Link
+		day   time.Time
+		count int
+	}{
+		{popular, from, 20},
+		{popular, prevFrom, 10},
+		{quiet, from.AddDate(0, 0, 1), 3},
+		{pending, from, 50},
+	} {
+		if _, err := db.Pool.Exec(ctx,
+			`INSERT INTO click_history_daily (link_id, day, click_count) VALUES ($1, $2, $3)`,
+			c.link.ID, c.day, c.count,
+		); err != nil {
+			t.Fatalf("failed to seed click_history_daily: %v", err)
+		}
+	}
+
+	top, err := db.GetTopLinksByClicks(ctx, nil, nil, "global", prevFrom, from, to, 10)
+	if err != nil {
+		t.Fatalf("GetTopLinksByClicks() error = %v", err)
+	}
+	if len(top) != 2 {
+		t.Fatalf("len(top) = %d, want 2 (pending links excluded)", len(top))
+	}
+	if top[0].Keyword != "top-popular" || top[0].Clicks != 20 || top[0].PreviousClicks != 10 {
+		t.Errorf("top[0] = %+v, want top-popular with 20/10 clicks", top[0])
+	}
+	if top[1].Keyword != "top-quiet" {
+		t.Errorf("top[1].Keyword = %q, want top-quiet", top[1].Keyword)
+	}
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -143,6 +144,107 @@ func (d *DB) GetUserLinks(ctx context.Context, userID uuid.UUID) ([]models.UserL
 	return links, rows.Err()
 }
 
+// GetUserLinksByRecentUse retrieves all link overrides for a user, most
+// recently clicked first. Links without recorded clicks in
+// user_link_click_history sort last, alphabetically.
+func (d *DB) GetUserLinksByRecentUse(ctx context.Context, userID uuid.UUID) ([]models.UserLink, error) {
+	query := `
+		SELECT ul.id, ul.user_id, ul.keyword, ul.url, ul.description, ul.click_count, ul.created_at, ul.updated_at,
+		       ul.health_status, ul.health_checked_at, ul.health_error
+		FROM user_links ul
+		LEFT JOIN LATERAL (
+			SELECT MAX(hour_bucket) AS last_used
+			FROM user_link_click_history
+			WHERE user_link_id = ul.id
+		) h ON TRUE
+		WHERE ul.user_id = $1
+		ORDER BY h.last_used DESC NULLS LAST, ul.keyword ASC
+	`
+
+	rows, err := d.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.UserLink
+	for rows.Next() {
+		var link models.UserLink
+		if err := rows.Scan(
+			&link.ID,
+			&link.UserID,
+			&link.Keyword,
+			&link.URL,
+			&link.Description,
+			&link.ClickCount,
+			&link.CreatedAt,
+			&link.UpdatedAt,
+			&link.HealthStatus,
+			&link.HealthCheckedAt,
+			&link.HealthError,
+		); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// GetUserLinkClickHistoryBatch returns daily click histories for multiple
+// personal links over the last `days` UTC days (including today) in a single
+// query. Returns a map from user link ID to a slice of daily click counts
+// (oldest to newest).
+func (d *DB) GetUserLinkClickHistoryBatch(ctx context.Context, ids []uuid.UUID, days int) (map[uuid.UUID][]int, error) {
+	if len(ids) == 0 {
+		return make(map[uuid.UUID][]int), nil
+	}
+
+	query := `
+		WITH days AS (
+			SELECT generate_series(
+				(NOW() AT TIME ZONE 'UTC')::date - ($2::int - 1),
+				(NOW() AT TIME ZONE 'UTC')::date,
+				INTERVAL '1 day'
+			)::date AS day
+		),
+		targets AS (
+			SELECT unnest($1::uuid[]) AS user_link_id
+		),
+		daily AS (
+			SELECT user_link_id, (hour_bucket AT TIME ZONE 'UTC')::date AS day, SUM(click_count)::int AS clicks
+			FROM user_link_click_history
+			WHERE user_link_id = ANY($1::uuid[])
+				AND hour_bucket >= ((NOW() AT TIME ZONE 'UTC')::date - ($2::int - 1))::timestamp AT TIME ZONE 'UTC'
+			GROUP BY 1, 2
+		)
+		SELECT t.user_link_id, days.day, COALESCE(daily.clicks, 0)
+		FROM targets t
+		CROSS JOIN days
+		LEFT JOIN daily
+			ON daily.user_link_id = t.user_link_id AND daily.day = days.day
+		ORDER BY t.user_link_id, days.day
+	`
+
+	rows, err := d.Pool.Query(ctx, query, ids, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[uuid.UUID][]int, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		var day time.Time
+		var count int
+		if err := rows.Scan(&id, &day, &count); err != nil {
+			return nil, err
+		}
+		result[id] = append(result[id], count)
+	}
+	return result, rows.Err()
+}
+
 // UpdateUserLink updates a user's link override.
 func (d *DB) UpdateUserLink(ctx context.Context, link *models.UserLink) error {
 	query := `
@@ -172,6 +172,59 @@ func TestGetUserLinks(t *testing.T) {
 	}
 }
 
+func TestUserLinkClickHistory(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	user := &models.User{
+		Sub:   "history-userlink-sub",
+		Email: "history-userlink@example.com",
+		Name:  "History User",
+	}
+	if err := db.UpsertUser(ctx, user); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+
+	var links []*models.UserLink
+	for _, kw := range []string{"aaa-unused", "bbb-used"} {
+		link := &models.UserLink{UserID: user.ID, Keyword: kw, URL: "https://example.com/" + kw}
+		if err := db.CreateUserLink(ctx, link); err != nil {
+			t.Fatalf("CreateUserLink() error = %v", err)
+		}
+		links = append(links, link)
+	}
+	used := links[1]
+
+	// Clicks go through the write buffer into user_link_click_history.
+	db.buf.recordUserLinkClick(used.ID)
+	db.buf.recordUserLinkClick(used.ID)
+	db.flush(ctx)
+
+	history, err := db.GetUserLinkClickHistoryBatch(ctx, []uuid.UUID{used.ID, links[0].ID}, 7)
+	if err != nil {
+		t.Fatalf("GetUserLinkClickHistoryBatch() error = %v", err)
+	}
+	if len(history[used.ID]) != 7 {
+		t.Fatalf("history length = %d, want 7", len(history[used.ID]))
+	}
+	if today := history[used.ID][6]; today != 2 {
+		t.Errorf("today's clicks = %d, want 2", today)
+	}
+	if unused := history[links[0].ID][6]; unused != 0 {
+		t.Errorf("unused link clicks = %d, want 0", unused)
+	}
+
+	recent, err := db.GetUserLinksByRecentUse(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserLinksByRecentUse() error = %v", err)
+	}
+	if len(recent) != 2 || recent[0].Keyword != "bbb-used" {
+		t.Errorf("GetUserLinksByRecentUse() first link = %q, want %q", recent[0].Keyword, "bbb-used")
+	}
+}
+
 func TestUpdateUserLink(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
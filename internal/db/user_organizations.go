@@ -0,0 +1,214 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"golinks/internal/models"
+)
+
+// ListUserOrganizations lists a user's organization memberships, the active
+// organization first, then in the order they were joined.
+func (d *DB) ListUserOrganizations(ctx context.Context, userID uuid.UUID) ([]models.OrgMembership, error) {
+	query := `
+		SELECT o.id, o.name, o.slug, m.role, o.id = u.organization_id AS active, m.created_at
+		FROM user_organizations m
+		JOIN organizations o ON o.id = m.organization_id
+		JOIN users u ON u.id = m.user_id
+		WHERE m.user_id = $1
+		ORDER BY active DESC, m.created_at ASC, o.slug ASC
+	`
+
+	rows, err := d.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var memberships []models.OrgMembership
+	for rows.Next() {
+		var m models.OrgMembership
+		if err := rows.Scan(&m.OrganizationID, &m.Name, &m.Slug, &m.Role, &m.Active, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, m)
+	}
+
+	return memberships, rows.Err()
+}
+
+// AddUserOrganization adds a user to an organization with the given role
+// (member or org_mod), or changes their role if they already belong to it.
+// Users without an active organization get this one.
+func (d *DB) AddUserOrganization(ctx context.Context, userID, orgID uuid.UUID, role string) error {
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_organizations (user_id, organization_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, organization_id) DO UPDATE SET role = EXCLUDED.role
+	`, userID, orgID, role)
+	if err != nil {
+		return err
+	}
+	if err := syncActiveOrganization(ctx, tx, userID, nil); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RemoveUserOrganization removes a user from an organization. Leaving the
+// active organization switches to the earliest remaining membership, or to
+// none.
+func (d *DB) RemoveUserOrganization(ctx context.Context, userID, orgID uuid.UUID) error {
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM user_organizations WHERE user_id = $1 AND organization_id = $2`, userID, orgID)
+	if err != nil {
+		return err
+	}
+	if err := syncActiveOrganization(ctx, tx, userID, nil); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SetUserOrganizations makes orgIDs exactly the user's memberships, as when
+// an identity provider asserts them. New memberships get role; an empty role
+// adds them as members and keeps the role of existing ones. The active
+// organization is kept if the user still belongs to it, otherwise the first
+// of orgIDs becomes active.
+func (d *DB) SetUserOrganizations(ctx context.Context, userID uuid.UUID, orgIDs []uuid.UUID, role string) error {
+	if orgIDs == nil {
+		orgIDs = []uuid.UUID{} // a nil slice is sent as NULL, which ANY never matches
+	}
+
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM user_organizations WHERE user_id = $1 AND NOT (organization_id = ANY($2))
+	`, userID, orgIDs)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_organizations (user_id, organization_id, role)
+		SELECT $1, id, COALESCE(NULLIF($3, ''), 'member')
+		FROM (SELECT DISTINCT unnest($2::uuid[]) AS id) ids
+		ON CONFLICT (user_id, organization_id) DO UPDATE
+		SET role = CASE WHEN $3 = '' THEN user_organizations.role ELSE EXCLUDED.role END
+	`, userID, orgIDs, role)
+	if err != nil {
+		return err
+	}
+
+	var preferred *uuid.UUID
+	if len(orgIDs) > 0 {
+		preferred = &orgIDs[0]
+	}
+	if err := syncActiveOrganization(ctx, tx, userID, preferred); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SetActiveOrganization switches the organization a user creates links in
+// and moderates. It returns ErrNotOrgMember unless the user belongs to it.
+func (d *DB) SetActiveOrganization(ctx context.Context, userID, orgID uuid.UUID) error {
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var member bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM user_organizations WHERE user_id = $1 AND organization_id = $2)
+	`, userID, orgID).Scan(&member)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrNotOrgMember
+	}
+
+	if err := syncActiveOrganization(ctx, tx, userID, &orgID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// syncActiveOrganization points users.organization_id at one of the user's
+// memberships: preferred when the user belongs to it, else the current active
+// organization while they still belong to it, else the earliest joined (NULL
+// without memberships). Users and org moderators take their role from that
+// membership; other roles apply everywhere and are left alone.
+func syncActiveOrganization(ctx context.Context, tx pgx.Tx, userID uuid.UUID, preferred *uuid.UUID) error {
+	_, err := tx.Exec(ctx, `
+		WITH pick AS (
+			SELECT m.organization_id, m.role
+			FROM user_organizations m
+			JOIN users u ON u.id = m.user_id
+			WHERE m.user_id = $1
+			ORDER BY (m.organization_id = $2) IS TRUE DESC,
+			         (m.organization_id = u.organization_id) IS TRUE DESC,
+			         m.created_at ASC, m.organization_id ASC
+			LIMIT 1
+		)
+		UPDATE users u
+		SET organization_id = pick.organization_id,
+		    role = CASE
+		        WHEN u.role NOT IN ($3, $4) THEN u.role
+		        WHEN pick.role = $5 THEN $4
+		        ELSE $3
+		    END,
+		    updated_at = NOW()
+		FROM (SELECT 1) one
+		LEFT JOIN pick ON TRUE
+		WHERE u.id = $1
+	`, userID, preferred, models.RoleUser, models.RoleOrgMod, models.OrgRoleMod)
+	return err
+}
+
+// ListOrganizationMembers lists the members of an organization, moderators
+// first, then by name.
+func (d *DB) ListOrganizationMembers(ctx context.Context, orgID uuid.UUID) ([]models.OrgMember, error) {
+	query := `
+		SELECT u.id, u.name, u.email, m.role, u.organization_id IS NOT DISTINCT FROM m.organization_id, m.created_at
+		FROM user_organizations m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1
+		ORDER BY m.role = $2 DESC, u.name ASC, u.email ASC
+	`
+
+	rows, err := d.Pool.Query(ctx, query, orgID, models.OrgRoleMod)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.OrgMember
+	for rows.Next() {
+		var m models.OrgMember
+		if err := rows.Scan(&m.UserID, &m.Name, &m.Email, &m.Role, &m.Active, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+
+	return members, rows.Err()
+}
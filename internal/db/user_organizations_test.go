@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"golinks/internal/models"
+)
+
+func createTestOrgs(t *testing.T, db *DB, slugs ...string) []*models.Organization {
+	t.Helper()
+	orgs := make([]*models.Organization, len(slugs))
+	for i, slug := range slugs {
+		orgs[i] = &models.Organization{Name: slug, Slug: slug}
+		if err := db.CreateOrganization(context.Background(), orgs[i]); err != nil {
+			t.Fatalf("CreateOrganization() error = %v", err)
+		}
+	}
+	return orgs
+}
+
+func TestUserOrganizations(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orgs := createTestOrgs(t, db, "memb-platform", "memb-data", "memb-other")
+	platform, data, other := orgs[0], orgs[1], orgs[2]
+
+	user := &models.User{Sub: "memb-user", Email: "memb@example.com", Name: "Member"}
+	if err := db.UpsertUser(ctx, user); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+
+	if err := db.AddUserOrganization(ctx, user.ID, platform.ID, models.OrgRoleMember); err != nil {
+		t.Fatalf("AddUserOrganization() error = %v", err)
+	}
+	if err := db.AddUserOrganization(ctx, user.ID, data.ID, models.OrgRoleMod); err != nil {
+		t.Fatalf("AddUserOrganization() error = %v", err)
+	}
+
+	got, err := db.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	if got.OrganizationID == nil || *got.OrganizationID != platform.ID || got.Role != models.RoleUser {
+		t.Errorf("after joining: org = %v, role = %q; want platform and %q", got.OrganizationID, got.Role, models.RoleUser)
+	}
+
+	// Switching takes the role held in the organization switched to.
+	if err := db.SetActiveOrganization(ctx, user.ID, data.ID); err != nil {
+		t.Fatalf("SetActiveOrganization() error = %v", err)
+	}
+	got, _ = db.GetUserByID(ctx, user.ID)
+	if got.OrganizationID == nil || *got.OrganizationID != data.ID || got.Role != models.RoleOrgMod {
+		t.Errorf("after switching: org = %v, role = %q; want data and %q", got.OrganizationID, got.Role, models.RoleOrgMod)
+	}
+	if err := db.SetActiveOrganization(ctx, user.ID, other.ID); !errors.Is(err, ErrNotOrgMember) {
+		t.Errorf("SetActiveOrganization(non-member) error = %v, want ErrNotOrgMember", err)
+	}
+
+	memberships, err := db.ListUserOrganizations(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("ListUserOrganizations() error = %v", err)
+	}
+	if len(memberships) != 2 || memberships[0].OrganizationID != data.ID || !memberships[0].Active || memberships[1].Active {
+		t.Errorf("ListUserOrganizations() = %+v, want data (active) then platform", memberships)
+	}
+
+	// Leaving the active organization falls back to the remaining one.
+	if err := db.RemoveUserOrganization(ctx, user.ID, data.ID); err != nil {
+		t.Fatalf("RemoveUserOrganization() error = %v", err)
+	}
+	got, _ = db.GetUserByID(ctx, user.ID)
+	if got.OrganizationID == nil || *got.OrganizationID != platform.ID || got.Role != models.RoleUser {
+		t.Errorf("after leaving: org = %v, role = %q; want platform and %q", got.OrganizationID, got.Role, models.RoleUser)
+	}
+
+	// An identity provider's list replaces the memberships; the active
+	// organization is kept while the user still belongs to it.
+	if err := db.SetUserOrganizations(ctx, user.ID, []uuid.UUID{other.ID, platform.ID}, ""); err != nil {
+		t.Fatalf("SetUserOrganizations() error = %v", err)
+	}
+	got, _ = db.GetUserByID(ctx, user.ID)
+	if got.OrganizationID == nil || *got.OrganizationID != platform.ID {
+		t.Errorf("after sync: org = %v, want platform kept", got.OrganizationID)
+	}
+	if err := db.SetUserOrganizations(ctx, user.ID, []uuid.UUID{other.ID}, models.OrgRoleMod); err != nil {
+		t.Fatalf("SetUserOrganizations() error = %v", err)
+	}
+	got, _ = db.GetUserByID(ctx, user.ID)
+	if got.OrganizationID == nil || *got.OrganizationID != other.ID || got.Role != models.RoleOrgMod {
+		t.Errorf("after sync: org = %v, role = %q; want other and %q", got.OrganizationID, got.Role, models.RoleOrgMod)
+	}
+	if err := db.SetUserOrganizations(ctx, user.ID, nil, ""); err != nil {
+		t.Fatalf("SetUserOrganizations(nil) error = %v", err)
+	}
+	got, _ = db.GetUserByID(ctx, user.ID)
+	if got.OrganizationID != nil || got.Role != models.RoleUser {
+		t.Errorf("without memberships: org = %v, role = %q; want none and %q", got.OrganizationID, got.Role, models.RoleUser)
+	}
+}
+
+func TestResolveKeywordForUser_Memberships(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orgs := createTestOrgs(t, db, "resolve-zeta", "resolve-alpha", "resolve-outside")
+	zeta, alpha := orgs[0], orgs[1] // not a member of the third
+
+	user := &models.User{Sub: "resolve-member", Email: "resolve@example.com", Name: "Resolver"}
+	if err := db.UpsertUser(ctx, user); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+	for _, org := range []*models.Organization{zeta, alpha} {
+		if err := db.AddUserOrganization(ctx, user.ID, org.ID, models.OrgRoleMember); err != nil {
+			t.Fatalf("AddUserOrganization() error = %v", err)
+		}
+	}
+
+	for _, org := range orgs {
+		link := &models.Link{Keyword: "resolve-shared", URL: "https://example.com/" + org.Slug, Scope: models.ScopeOrg, OrganizationID: &org.ID, Status: models.StatusApproved}
+		if err := db.CreateLink(ctx, link); err != nil {
+			t.Fatalf("CreateLink() error = %v", err)
+		}
+	}
+	only := &models.Link{Keyword: "resolve-alpha-only", URL: "https://example.com/alpha-only", Scope: models.ScopeOrg, OrganizationID: &alpha.ID, Status: models.StatusApproved}
+	if err := db.CreateLink(ctx, only); err != nil {
+		t.Fatalf("CreateLink() error = %v", err)
+	}
+
+	// Links of any organization the user belongs to resolve.
+	resolved, err := db.ResolveKeywordForUser(ctx, &user.ID, &zeta.ID, "resolve-alpha-only")
+	if err != nil || resolved.Source != "org" {
+		t.Fatalf("ResolveKeywordForUser(other membership) = %+v, %v; want the alpha link", resolved, err)
+	}
+
+	tests := []struct {
+		order  string
+		active *uuid.UUID
+		want   string
+	}{
+		{OrgOrderActive, &alpha.ID, "https://example.com/resolve-alpha"},
+		{OrgOrderActive, &zeta.ID, "https://example.com/resolve-zeta"},
+		{OrgOrderJoined, &alpha.ID, "https://example.com/resolve-zeta"},
+		{OrgOrderName, &zeta.ID, "https://example.com/resolve-alpha"},
+	}
+	for _, tt := range tests {
+		if err := db.SetOrgResolutionOrder(tt.order); err != nil {
+			t.Fatalf("SetOrgResolutionOrder(%q) error = %v", tt.order, err)
+		}
+		resolved, err := db.ResolveKeywordForUser(ctx, &user.ID, tt.active, "resolve-shared")
+		if err != nil {
+			t.Fatalf("ResolveKeywordForUser() error = %v", err)
+		}
+		if resolved.URL != tt.want {
+			t.Errorf("order %q: resolved %s, want %s", tt.order, resolved.URL, tt.want)
+		}
+	}
+
+	if err := db.SetOrgResolutionOrder("random"); err == nil {
+		t.Error("SetOrgResolutionOrder(random) succeeded, want an error")
+	}
+}
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"golinks/internal/models"
+)
+
+// userSessionColumns is the standard column list for user_sessions queries.
+const userSessionColumns = `id, user_id, session_hash, ip_address, user_agent, created_at, last_seen_at,
+	expires_at, refreshed_at, revoked_at, COALESCE(oidc_sid, ''), COALESCE(refresh_token, '')`
+
+// HashSessionID returns the value stored for a session ID. Raw IDs are bearer
+// credentials and never touch the database.
+func HashSessionID(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])
+}
+
+func scanUserSession(row pgx.Row) (*models.UserSession, error) {
+	var s models.UserSession
+	err := row.Scan(&s.ID, &s.UserID, &s.SessionHash, &s.IPAddress, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt,
+		&s.ExpiresAt, &s.RefreshedAt, &s.RevokedAt, &s.OIDCSessionID, &s.RefreshToken)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CreateUserSession records a newly signed-in session ending at s.ExpiresAt.
+func (d *DB) CreateUserSession(ctx context.Context, s *models.UserSession) error {
+	query := `
+		INSERT INTO user_sessions (session_hash, user_id, ip_address, user_agent, expires_at, oidc_sid, refresh_token)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, last_seen_at
+	`
+	return d.Pool.QueryRow(ctx, query,
+		s.SessionHash, s.UserID, s.IPAddress, s.UserAgent, s.ExpiresAt,
+		nullIfEmpty(s.OIDCSessionID), nullIfEmpty(s.RefreshToken),
+	).Scan(&s.ID, &s.CreatedAt, &s.LastSeenAt)
+}
+
+// GetUserSessionByHash looks up a tracked session, revoked or not.
+func (d *DB) GetUserSessionByHash(ctx context.Context, hash string) (*models.UserSession, error) {
+	return scanUserSession(d.Pool.QueryRow(ctx,
+		`SELECT `+userSessionColumns+` FROM user_sessions WHERE session_hash = $1`, hash))
+}
+
+// TouchUserSession refreshes a session's last-seen time and IP address.
+func (d *DB) TouchUserSession(ctx context.Context, id uuid.UUID, ip string) error {
+	_, err := d.Pool.Exec(ctx, `
+		UPDATE user_sessions SET last_seen_at = NOW(), ip_address = $2
+		WHERE id = $1 AND revoked_at IS NULL
+	`, id, ip)
+	return err
+}
+
+// ListActiveUserSessions returns a user's unrevoked, unexpired sessions last
+// seen after lastSeenAfter (most recently used first), so sessions the store
+// has already dropped for inactivity are left out.
+func (d *DB) ListActiveUserSessions(ctx context.Context, userID uuid.UUID, lastSeenAfter time.Time) ([]models.UserSession, error) {
+	rows, err := d.Pool.Query(ctx, `
+		SELECT `+userSessionColumns+`
+		FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW() AND last_seen_at > $2
+		ORDER BY last_seen_at DESC
+	`, userID, lastSeenAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.UserSession
+	for rows.Next() {
+		s, err := scanUserSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *s)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeUserSession revokes one of a user's sessions. Returns
+// ErrUserSessionNotFound if the session doesn't belong to the user or is
+// already revoked.
+func (d *DB) RevokeUserSession(ctx context.Context, userID, id uuid.UUID) error {
+	tag, err := d.Pool.Exec(ctx, `
+		UPDATE user_sessions SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserSessionNotFound
+	}
+	return nil
+}
+
+// RevokeUserSessionByHash revokes the session with the given hash, e.g. on logout.
+func (d *DB) RevokeUserSessionByHash(ctx context.Context, hash string) error {
+	_, err := d.Pool.Exec(ctx, `
+		UPDATE user_sessions SET revoked_at = NOW()
+		WHERE session_hash = $1 AND revoked_at IS NULL
+	`, hash)
+	return err
+}
+
+// RevokeUserSessionsBySID revokes every session started from the identity
+// provider session sid at issuer, as named by a back-channel logout token.
+func (d *DB) RevokeUserSessionsBySID(ctx context.Context, issuer, sid string) (int64, error) {
+	tag, err := d.Pool.Exec(ctx, `
+		UPDATE user_sessions SET revoked_at = NOW()
+		WHERE oidc_sid = $2 AND revoked_at IS NULL
+		  AND user_id IN (SELECT id FROM users WHERE issuer = $1)
+	`, issuer, sid)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ClaimUserSessionRefresh marks a session as being refreshed, provided no
+// other request has done so since refreshedAt was read. Exactly one of several
+// concurrent requests (on any replica) wins, so a rotating refresh token is
+// redeemed once.
+func (d *DB) ClaimUserSessionRefresh(ctx context.Context, id uuid.UUID, refreshedAt *time.Time) (bool, error) {
+	tag, err := d.Pool.Exec(ctx, `
+		UPDATE user_sessions SET refreshed_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL AND refreshed_at IS NOT DISTINCT FROM $2
+	`, id, refreshedAt)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ExtendUserSession stores the refresh token returned by a successful refresh
+// and moves the session's end to expiresAt.
+func (d *DB) ExtendUserSession(ctx context.Context, id uuid.UUID, refreshToken string, expiresAt time.Time) error {
+	_, err := d.Pool.Exec(ctx, `
+		UPDATE user_sessions SET refresh_token = $2, expires_at = $3
+		WHERE id = $1 AND revoked_at IS NULL
+	`, id, nullIfEmpty(refreshToken), expiresAt)
+	return err
+}
+
+// RevokeAllUserSessions revokes every session of a user and returns how many
+// were still active.
+func (d *DB) RevokeAllUserSessions(ctx context.Context, userID uuid.UUID) (int64, error) {
+	tag, err := d.Pool.Exec(ctx, `
+		UPDATE user_sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteExpiredUserSessions removes sessions that ended before the cutoff.
+// Untracked sessions are rejected, so the cutoff must never be in the future.
+func (d *DB) DeleteExpiredUserSessions(ctx context.Context, before time.Time) (int64, error) {
+	result, err := d.Pool.Exec(ctx, `
+		DELETE FROM user_sessions
+		WHERE expires_at < $1
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
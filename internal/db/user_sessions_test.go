@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"golinks/internal/models"
+)
+
+func createTestSession(t *testing.T, db *DB, userID uuid.UUID, sessionID string) *models.UserSession {
+	t.Helper()
+	s := &models.UserSession{
+		UserID:      userID,
+		SessionHash: HashSessionID(sessionID),
+		IPAddress:   "192.0.2.10",
+		UserAgent:   "Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0",
+		ExpiresAt:   time.Now().Add(24 * time.Hour),
+	}
+	if err := db.CreateUserSession(context.Background(), s); err != nil {
+		t.Fatalf("CreateUserSession() error = %v", err)
+	}
+	return s
+}
+
+func TestUserSessions_CreateAndRevoke(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	user := &models.User{Sub: "session-sub", Email: "session@example.com", Name: "Session User"}
+	if err := db.UpsertUser(ctx, user); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+
+	laptop := createTestSession(t, db, user.ID, "laptop-session")
+	createTestSession(t, db, user.ID, "phone-session")
+
+	got, err := db.GetUserSessionByHash(ctx, HashSessionID("laptop-session"))
+	if err != nil {
+		t.Fatalf("GetUserSessionByHash() error = %v", err)
+	}
+	if got.ID != laptop.ID || got.IsRevoked() {
+		t.Errorf("GetUserSessionByHash() = %+v, want active session %s", got, laptop.ID)
+	}
+
+	since := time.Now().Add(-time.Hour)
+	active, err := db.ListActiveUserSessions(ctx, user.ID, since)
+	if err != nil {
+		t.Fatalf("ListActiveUserSessions() error = %v", err)
+	}
+	if len(active) != 2 {
+		t.Fatalf("ListActiveUserSessions() = %d sessions, want 2", len(active))
+	}
+
+	// Another user can't revoke the session.
+	if err := db.RevokeUserSession(ctx, uuid.New(), laptop.ID); !errors.Is(err, ErrUserSessionNotFound) {
+		t.Errorf("RevokeUserSession() by other user error = %v, want ErrUserSessionNotFound", err)
+	}
+	if err := db.RevokeUserSession(ctx, user.ID, laptop.ID); err != nil {
+		t.Fatalf("RevokeUserSession() error = %v", err)
+	}
+	got, _ = db.GetUserSessionByHash(ctx, HashSessionID("laptop-session"))
+	if !got.IsRevoked() {
+		t.Error("session not revoked")
+	}
+
+	revoked, err := db.RevokeAllUserSessions(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("RevokeAllUserSessions() error = %v", err)
+	}
+	if revoked != 1 {
+		t.Errorf("RevokeAllUserSessions() = %d, want 1", revoked)
+	}
+	active, _ = db.ListActiveUserSessions(ctx, user.ID, since)
+	if len(active) != 0 {
+		t.Errorf("ListActiveUserSessions() after revoke-all = %d sessions, want 0", len(active))
+	}
+}
+
+func TestUserSessions_NotFound(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := db.GetUserSessionByHash(context.Background(), HashSessionID("unknown")); !errors.Is(err, ErrUserSessionNotFound) {
+		t.Errorf("GetUserSessionByHash() error = %v, want ErrUserSessionNotFound", err)
+	}
+}
+
+func TestRevokeUserSessionsBySID(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	user := &models.User{Issuer: "https://idp.example.com", Sub: "sid-session-sub", Email: "sid@example.com", Name: "SID User"}
+	if err := db.UpsertUser(ctx, user); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+
+	s := &models.UserSession{
+		UserID:        user.ID,
+		SessionHash:   HashSessionID("sid-session"),
+		ExpiresAt:     time.Now().Add(time.Hour),
+		OIDCSessionID: "idp-sid-1",
+	}
+	if err := db.CreateUserSession(ctx, s); err != nil {
+		t.Fatalf("CreateUserSession() error = %v", err)
+	}
+	other := createTestSession(t, db, user.ID, "other-session")
+
+	// The same sid from another provider names a different session.
+	if revoked, _ := db.RevokeUserSessionsBySID(ctx, "https://other.example.com", "idp-sid-1"); revoked != 0 {
+		t.Errorf("RevokeUserSessionsBySID() for another issuer = %d, want 0", revoked)
+	}
+
+	revoked, err := db.RevokeUserSessionsBySID(ctx, "https://idp.example.com", "idp-sid-1")
+	if err != nil {
+		t.Fatalf("RevokeUserSessionsBySID() error = %v", err)
+	}
+	if revoked != 1 {
+		t.Errorf("RevokeUserSessionsBySID() = %d, want 1", revoked)
+	}
+	if got, _ := db.GetUserSessionByHash(ctx, HashSessionID("other-session")); got.ID != other.ID || got.IsRevoked() {
+		t.Error("session from another IdP session was revoked")
+	}
+}
+
+func TestClaimUserSessionRefresh(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	user := &models.User{Sub: "refresh-session-sub", Email: "refresh@example.com", Name: "Refresh User"}
+	if err := db.UpsertUser(ctx, user); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+	s := createTestSession(t, db, user.ID, "refresh-session")
+
+	// Two requests read the same state; only the first claim wins.
+	if ok, err := db.ClaimUserSessionRefresh(ctx, s.ID, nil); err != nil || !ok {
+		t.Fatalf("first ClaimUserSessionRefresh() = %v, %v; want true", ok, err)
+	}
+	if ok, _ := db.ClaimUserSessionRefresh(ctx, s.ID, nil); ok {
+		t.Error("second ClaimUserSessionRefresh() with stale state = true, want false")
+	}
+
+	expires := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	if err := db.ExtendUserSession(ctx, s.ID, "sealed-token", expires); err != nil {
+		t.Fatalf("ExtendUserSession() error = %v", err)
+	}
+	got, _ := db.GetUserSessionByHash(ctx, HashSessionID("refresh-session"))
+	if got.RefreshToken != "sealed-token" || !got.ExpiresAt.Equal(expires) || got.RefreshedAt == nil {
+		t.Errorf("after extend: token=%q expires=%v refreshed=%v", got.RefreshToken, got.ExpiresAt, got.RefreshedAt)
+	}
+}
+
+func TestDeleteExpiredUserSessions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	user := &models.User{Sub: "prune-session-sub", Email: "prune@example.com", Name: "Prune User"}
+	if err := db.UpsertUser(ctx, user); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+
+	old := createTestSession(t, db, user.ID, "old-session")
+	createTestSession(t, db, user.ID, "new-session")
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE user_sessions SET expires_at = NOW() - INTERVAL '2 days' WHERE id = $1`, old.ID,
+	); err != nil {
+		t.Fatalf("failed to expire session: %v", err)
+	}
+
+	removed, err := db.DeleteExpiredUserSessions(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteExpiredUserSessions() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("DeleteExpiredUserSessions() removed = %d, want 1", removed)
+	}
+	if _, err := db.GetUserSessionByHash(ctx, HashSessionID("new-session")); err != nil {
+		t.Errorf("live session was pruned: %v", err)
+	}
+}
@@ -3,6 +3,8 @@ package db
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -10,14 +12,20 @@ import (
 	"golinks/internal/models"
 )
 
-// userColumns is the standard column list for user queries.
-const userColumns = `id, sub, COALESCE(username, ''), email, name, picture, role, organization_id, fallback_redirect_id, created_at, updated_at, last_login_at`
+// userColumns is the standard column list for user queries. For org
+// moderators it ends with the descendants of their active organization, which
+// they moderate too.
+var userColumns = `id, issuer, sub, COALESCE(username, ''), email, name, picture, role, organization_id, fallback_redirect_id, created_at, updated_at, last_login_at, deactivated_at,
+	CASE WHEN role = 'org_mod' AND organization_id IS NOT NULL THEN
+		ARRAY(SELECT t.id FROM ` + orgSubtree("users.organization_id") + ` t WHERE t.id <> users.organization_id)
+	END`
 
 // scanUser scans a single row into a User struct.
 func scanUser(row pgx.Row) (*models.User, error) {
 	var user models.User
 	err := row.Scan(
 		&user.ID,
+		&user.Issuer,
 		&user.Sub,
 		&user.Username,
 		&user.Email,
@@ -29,6 +37,8 @@ func scanUser(row pgx.Row) (*models.User, error) {
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.LastLoginAt,
+		&user.DeactivatedAt,
+		&user.SubOrganizationIDs,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, ErrUserNotFound
@@ -39,21 +49,22 @@ func scanUser(row pgx.Row) (*models.User, error) {
 	return &user, nil
 }
 
-// UpsertUser creates or updates a user based on their OIDC subject.
+// UpsertUser creates or updates a user based on their OIDC issuer and subject.
 func (d *DB) UpsertUser(ctx context.Context, user *models.User) error {
 	query := `
-		INSERT INTO users (sub, username, email, name, picture, role, organization_id)
-		VALUES ($1, $2, $3, $4, $5, COALESCE($6, 'user'), $7)
-		ON CONFLICT (sub) DO UPDATE SET
+		INSERT INTO users (issuer, sub, username, email, name, picture, role, organization_id)
+		VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, 'user'), $8)
+		ON CONFLICT (issuer, sub) DO UPDATE SET
 			username = COALESCE(EXCLUDED.username, users.username),
 			email = EXCLUDED.email,
 			name = EXCLUDED.name,
 			picture = EXCLUDED.picture,
 			updated_at = NOW()
-		RETURNING id, role, organization_id, fallback_redirect_id, created_at, updated_at
+		RETURNING id, role, organization_id, fallback_redirect_id, created_at, updated_at, deactivated_at
 	`
 
 	return d.Pool.QueryRow(ctx, query,
+		user.Issuer,
 		user.Sub,
 		nullIfEmpty(user.Username),
 		user.Email,
@@ -61,7 +72,7 @@ func (d *DB) UpsertUser(ctx context.Context, user *models.User) error {
 		user.Picture,
 		nullIfEmpty(user.Role),
 		user.OrganizationID,
-	).Scan(&user.ID, &user.Role, &user.OrganizationID, &user.FallbackRedirectID, &user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.ID, &user.Role, &user.OrganizationID, &user.FallbackRedirectID, &user.CreatedAt, &user.UpdatedAt, &user.DeactivatedAt)
 }
 
 func nullIfEmpty(s string) any {
@@ -71,14 +82,30 @@ func nullIfEmpty(s string) any {
 	return s
 }
 
-// GetUserBySub retrieves a user by their OIDC subject identifier.
-func (d *DB) GetUserBySub(ctx context.Context, sub string) (*models.User, error) {
-	return scanUser(d.Pool.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE sub = $1`, sub))
+// GetUserBySub retrieves a user by their OIDC issuer and subject identifier.
+func (d *DB) GetUserBySub(ctx context.Context, issuer, sub string) (*models.User, error) {
+	return scanUser(d.Pool.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE issuer = $1 AND sub = $2`, issuer, sub))
 }
 
-// GetUserByUsername retrieves a user by their PKI username.
-func (d *DB) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
-	return scanUser(d.Pool.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE username = $1`, username))
+// AssignLegacyUserIssuer assigns users created before users were keyed by
+// issuer to the given provider. Users whose subject that provider has already
+// signed in separately are left alone. Returns the number of users assigned.
+func (d *DB) AssignLegacyUserIssuer(ctx context.Context, issuer string) (int64, error) {
+	tag, err := d.Pool.Exec(ctx, `
+		UPDATE users SET issuer = $1
+		WHERE issuer = ''
+		  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.issuer = $1 AND u.sub = users.sub)
+	`, issuer)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetUserByUsername retrieves a user by their username with an identity
+// provider. Usernames are only unique per issuer.
+func (d *DB) GetUserByUsername(ctx context.Context, issuer, username string) (*models.User, error) {
+	return scanUser(d.Pool.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE issuer = $1 AND username = $2`, issuer, username))
 }
 
 // GetUserByID retrieves a user by their UUID.
@@ -86,19 +113,74 @@ func (d *DB) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error
 	return scanUser(d.Pool.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id))
 }
 
-// UpdateUserRole updates a user's role (admin only).
+// GetUserByEmail retrieves the active user with an email address, ignoring
+// case. If several accounts share it, the one that signed in last wins.
+func (d *DB) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return scanUser(d.Pool.QueryRow(ctx, `
+		SELECT `+userColumns+` FROM users
+		WHERE LOWER(email) = LOWER($1) AND deactivated_at IS NULL
+		ORDER BY last_login_at DESC NULLS LAST
+		LIMIT 1
+	`, strings.TrimSpace(email)))
+}
+
+// UpdateUserRole updates a user's role (admin only). Setting user or org_mod
+// also sets their role in their active organization.
 func (d *DB) UpdateUserRole(ctx context.Context, userID uuid.UUID, role string) error {
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
 	query := `UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2`
-	_, err := d.Pool.Exec(ctx, query, role, userID)
-	return err
+	if _, err := tx.Exec(ctx, query, role, userID); err != nil {
+		return err
+	}
+	if err := setActiveMembershipRole(ctx, tx, userID, role); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 // UpdateUserRoleFromOIDC persists both the raw OIDC-mapped role and the resolved
 // final role for a user.  mappedRole is the group-derived value (admin | moderator | user);
 // finalRole is what actually goes into the role column (admin | global_mod | org_mod | user).
 func (d *DB) UpdateUserRoleFromOIDC(ctx context.Context, userID uuid.UUID, mappedRole, finalRole string) error {
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
 	query := `UPDATE users SET role = $1, oidc_mapped_role = $2, updated_at = NOW() WHERE id = $3`
-	_, err := d.Pool.Exec(ctx, query, finalRole, mappedRole, userID)
+	if _, err := tx.Exec(ctx, query, finalRole, mappedRole, userID); err != nil {
+		return err
+	}
+	if err := setActiveMembershipRole(ctx, tx, userID, finalRole); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// setActiveMembershipRole mirrors a user or org_mod role onto the user's
+// membership in their active organization, so switching away and back keeps
+// it. Other roles apply in every organization and leave memberships alone.
+func setActiveMembershipRole(ctx context.Context, tx pgx.Tx, userID uuid.UUID, role string) error {
+	var orgRole string
+	switch role {
+	case models.RoleUser:
+		orgRole = models.OrgRoleMember
+	case models.RoleOrgMod:
+		orgRole = models.OrgRoleMod
+	default:
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		UPDATE user_organizations m SET role = $2
+		FROM users u
+		WHERE u.id = $1 AND m.user_id = u.id AND m.organization_id = u.organization_id
+	`, userID, orgRole)
 	return err
 }
 
@@ -107,41 +189,132 @@ func (d *DB) UpdateUserRoleFromOIDC(ctx context.Context, userID uuid.UUID, mappe
 // is created so that users who were already mapped to the moderator role but
 // had not yet re-logged-in get the correct org_mod assignment immediately.
 func (d *DB) PromoteOrgModerators(ctx context.Context, orgID uuid.UUID) error {
-	query := `
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE user_organizations m SET role = 'org_mod'
+		FROM users u
+		WHERE m.user_id = u.id AND m.organization_id = $1 AND u.oidc_mapped_role = 'moderator'
+	`, orgID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
 		UPDATE users SET role = 'org_mod', updated_at = NOW()
 		WHERE organization_id = $1 AND oidc_mapped_role = 'moderator'
-	`
-	_, err := d.Pool.Exec(ctx, query, orgID)
-	return err
+	`, orgID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-// UpdateUserOrganization updates a user's organization membership.
+// UpdateUserOrganization makes orgID the user's active organization, adding
+// them to it as a member if they don't belong to it yet. A nil orgID removes
+// the user from all of their organizations.
 func (d *DB) UpdateUserOrganization(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) error {
-	query := `UPDATE users SET organization_id = $1, updated_at = NOW() WHERE id = $2`
-	_, err := d.Pool.Exec(ctx, query, orgID, userID)
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if orgID == nil {
+		_, err = tx.Exec(ctx, `DELETE FROM user_organizations WHERE user_id = $1`, userID)
+	} else {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO user_organizations (user_id, organization_id) VALUES ($1, $2)
+			ON CONFLICT (user_id, organization_id) DO NOTHING
+		`, userID, *orgID)
+	}
+	if err != nil {
+		return err
+	}
+	if err := syncActiveOrganization(ctx, tx, userID, orgID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeactivateUser marks a user deactivated, keeping their links and
+// attribution. Deactivating an already deactivated user keeps the original
+// time.
+func (d *DB) DeactivateUser(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE users SET deactivated_at = COALESCE(deactivated_at, NOW()), updated_at = NOW() WHERE id = $1`
+	_, err := d.Pool.Exec(ctx, query, userID)
 	return err
 }
 
-// DeleteUser deletes a user by ID.
-func (d *DB) DeleteUser(ctx context.Context, userID uuid.UUID) error {
-	query := `DELETE FROM users WHERE id = $1`
+// ReactivateUser lets a deactivated user sign in again.
+func (d *DB) ReactivateUser(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE users SET deactivated_at = NULL, updated_at = NOW() WHERE id = $1 AND deactivated_at IS NOT NULL`
 	_, err := d.Pool.Exec(ctx, query, userID)
 	return err
 }
 
+// DeleteUser deletes a user by ID. Users who still author links must be
+// offboarded first (see OffboardUser) so the links get a new owner; their
+// personal links, shares and requests are deleted with them, and their name
+// is cleared from links they submitted or reviewed.
+func (d *DB) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var authored int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM links
+		WHERE created_by = $1 OR (submitted_by = $1 AND status = $2)
+	`, userID, models.StatusPending).Scan(&authored)
+	if err != nil {
+		return err
+	}
+	if authored > 0 {
+		return ErrUserAuthorsLinks
+	}
+
+	for _, query := range []string{
+		`UPDATE links SET submitted_by = NULL WHERE submitted_by = $1`,
+		`UPDATE links SET reviewed_by = NULL WHERE reviewed_by = $1`,
+		`UPDATE link_edit_requests SET reviewed_by = NULL WHERE reviewed_by = $1`,
+	} {
+		if _, err := tx.Exec(ctx, query, userID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
 // UserWithOrg represents a user with their organization details.
 type UserWithOrg struct {
 	models.User
-	OrganizationName string
-	OrganizationSlug string
+	OrganizationName   string
+	OrganizationSlug   string
+	OtherOrganizations []string // names of the user's other organizations, in the order joined
 }
 
 // GetAllUsersWithOrgs retrieves all users with their organization info.
 func (d *DB) GetAllUsersWithOrgs(ctx context.Context) ([]UserWithOrg, error) {
 	query := `
-		SELECT u.id, u.sub, COALESCE(u.username, ''), u.email, u.name, u.picture,
-			   u.role, u.organization_id, u.fallback_redirect_id, u.created_at, u.updated_at, u.last_login_at,
-			   COALESCE(o.name, ''), COALESCE(o.slug, '')
+		SELECT u.id, u.issuer, u.sub, COALESCE(u.username, ''), u.email, u.name, u.picture,
+			   u.role, u.organization_id, u.fallback_redirect_id, u.created_at, u.updated_at, u.last_login_at, u.deactivated_at,
+			   COALESCE(o.name, ''), COALESCE(o.slug, ''),
+			   ARRAY(
+				   SELECT mo.name FROM user_organizations m
+				   JOIN organizations mo ON mo.id = m.organization_id
+				   WHERE m.user_id = u.id AND m.organization_id IS DISTINCT FROM u.organization_id
+				   ORDER BY m.created_at
+			   )
 		FROM users u
 		LEFT JOIN organizations o ON u.organization_id = o.id
 		ORDER BY u.name ASC, u.email ASC
@@ -157,9 +330,9 @@ func (d *DB) GetAllUsersWithOrgs(ctx context.Context) ([]UserWithOrg, error) {
 	for rows.Next() {
 		var u UserWithOrg
 		if err := rows.Scan(
-			&u.ID, &u.Sub, &u.Username, &u.Email, &u.Name, &u.Picture,
-			&u.Role, &u.OrganizationID, &u.FallbackRedirectID, &u.CreatedAt, &u.UpdatedAt, &u.LastLoginAt,
-			&u.OrganizationName, &u.OrganizationSlug,
+			&u.ID, &u.Issuer, &u.Sub, &u.Username, &u.Email, &u.Name, &u.Picture,
+			&u.Role, &u.OrganizationID, &u.FallbackRedirectID, &u.CreatedAt, &u.UpdatedAt, &u.LastLoginAt, &u.DeactivatedAt,
+			&u.OrganizationName, &u.OrganizationSlug, &u.OtherOrganizations,
 		); err != nil {
 			return nil, err
 		}
@@ -176,12 +349,55 @@ func (d *DB) UpdateUserLastLogin(ctx context.Context, userID uuid.UUID) error {
 	return err
 }
 
+// SetUserGroups stores the groups claim from the user's latest sign-in, which
+// decides what restricted links they can see.
+func (d *DB) SetUserGroups(ctx context.Context, userID uuid.UUID, groups []string) error {
+	if groups == nil {
+		groups = []string{}
+	}
+	_, err := d.Pool.Exec(ctx, `UPDATE users SET groups = $1 WHERE id = $2`, groups, userID)
+	return err
+}
+
+// ResolveUserEmails returns the IDs of the active users with the given emails,
+// matched case-insensitively, skipping blanks and duplicates. It returns an
+// error wrapping ErrUserNotFound naming the first email that matches no one.
+func (d *DB) ResolveUserEmails(ctx context.Context, emails []string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+		var id uuid.UUID
+		err := d.Pool.QueryRow(ctx, `
+			SELECT id FROM users
+			WHERE LOWER(email) = LOWER($1) AND deactivated_at IS NULL
+			ORDER BY last_login_at DESC NULLS LAST
+			LIMIT 1
+		`, email).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrUserNotFound, email)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
 // SearchUsers searches users by name or email, excluding the requesting user.
 func (d *DB) SearchUsers(ctx context.Context, query string, excludeID uuid.UUID, limit int) ([]models.User, error) {
 	q := `
 		SELECT id, COALESCE(username, ''), email, name, sub
 		FROM users
 		WHERE id != $1
+		  AND deactivated_at IS NULL
 		  AND (
 		    name ILIKE '%' || $2 || '%'
 		    OR email ILIKE '%' || $2 || '%'
@@ -244,14 +460,15 @@ func (d *DB) GetGlobalModeratorEmails(ctx context.Context) ([]string, error) {
 }
 
 // GetOrgModeratorEmails returns email addresses for moderators of a specific organization.
-// Includes admins, global mods, and org mods for that org.
+// Includes admins, global mods, and org mods for that org or an organization
+// it is nested in, whichever organization they have active.
 func (d *DB) GetOrgModeratorEmails(ctx context.Context, orgID uuid.UUID) ([]string, error) {
 	query := `
 		SELECT DISTINCT email FROM users
 		WHERE email != '' AND email IS NOT NULL
 		AND (
 			role IN ('admin', 'global_mod')
-			OR (role = 'org_mod' AND organization_id = $1)
+			OR (role IN ('user', 'org_mod') AND ` + orgModMembership + `)
 		)
 	`
 
@@ -273,6 +490,14 @@ func (d *DB) GetOrgModeratorEmails(ctx context.Context, orgID uuid.UUID) ([]stri
 	return emails, rows.Err()
 }
 
+// orgModMembership matches users who are org moderators in organization $1
+// or in one of the organizations it is nested in.
+var orgModMembership = `EXISTS (
+	SELECT 1 FROM user_organizations m
+	WHERE m.user_id = users.id AND m.role = 'org_mod'
+	  AND m.organization_id IN (SELECT id FROM ` + orgLineage("$1") + ` lineage)
+)`
+
 // GetGlobalModeratorIDs returns IDs of all global_mod and admin users.
 func (d *DB) GetGlobalModeratorIDs(ctx context.Context) ([]uuid.UUID, error) {
 	rows, err := d.Pool.Query(ctx, `SELECT id FROM users WHERE role IN ('admin', 'global_mod')`)
@@ -297,7 +522,7 @@ func (d *DB) GetOrgModeratorIDs(ctx context.Context, orgID uuid.UUID) ([]uuid.UU
 	query := `
 		SELECT id FROM users
 		WHERE role IN ('admin', 'global_mod')
-		   OR (role = 'org_mod' AND organization_id = $1)
+		   OR (role IN ('user', 'org_mod') AND ` + orgModMembership + `)
 	`
 	rows, err := d.Pool.Query(ctx, query, orgID)
 	if err != nil {
@@ -316,12 +541,14 @@ func (d *DB) GetOrgModeratorIDs(ctx context.Context, orgID uuid.UUID) ([]uuid.UU
 	return ids, rows.Err()
 }
 
-// GetUserCountByOrg returns user count grouped by organization.
+// GetUserCountByOrg returns member count grouped by organization. Users in
+// several organizations count in each; "none" counts users in none.
 func (d *DB) GetUserCountByOrg(ctx context.Context) (map[string]int, error) {
 	query := `
 		SELECT COALESCE(o.slug, 'none'), COUNT(u.id)
 		FROM users u
-		LEFT JOIN organizations o ON u.organization_id = o.id
+		LEFT JOIN user_organizations m ON m.user_id = u.id
+		LEFT JOIN organizations o ON m.organization_id = o.id
 		GROUP BY o.slug
 		ORDER BY COUNT(u.id) DESC
 	`
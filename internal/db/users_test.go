@@ -65,7 +65,7 @@ func TestUpsertUser_Update(t *testing.T) {
 	}
 
 	// Verify update
-	fetched, err := db.GetUserBySub(ctx, "update-sub-123")
+	fetched, err := db.GetUserBySub(ctx, "", "update-sub-123")
 	if err != nil {
 		t.Fatalf("GetUserBySub() error = %v", err)
 	}
@@ -94,7 +94,7 @@ func TestGetUserBySub(t *testing.T) {
 	}
 
 	// Find by sub
-	found, err := db.GetUserBySub(ctx, "get-sub-123")
+	found, err := db.GetUserBySub(ctx, "", "get-sub-123")
 	if err != nil {
 		t.Fatalf("GetUserBySub() error = %v", err)
 	}
@@ -103,12 +103,65 @@ func TestGetUserBySub(t *testing.T) {
 	}
 
 	// Not found
-	_, err = db.GetUserBySub(ctx, "non-existent")
+	_, err = db.GetUserBySub(ctx, "", "non-existent")
 	if err != ErrUserNotFound {
 		t.Errorf("GetUserBySub() error = %v, want ErrUserNotFound", err)
 	}
 }
 
+func TestGetUserBySub_SameSubDifferentIssuers(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	corp := &models.User{Issuer: "https://corp.example.com", Sub: "shared-sub", Email: "jane@corp.example.com", Name: "Jane Corp"}
+	acme := &models.User{Issuer: "https://acme.example.com", Sub: "shared-sub", Email: "jane@acme.example.com", Name: "Jane Acme"}
+	for _, u := range []*models.User{corp, acme} {
+		if err := db.UpsertUser(ctx, u); err != nil {
+			t.Fatalf("UpsertUser(%s) error = %v", u.Issuer, err)
+		}
+	}
+	if corp.ID == acme.ID {
+		t.Fatal("users with the same sub from different issuers were merged")
+	}
+
+	found, err := db.GetUserBySub(ctx, "https://acme.example.com", "shared-sub")
+	if err != nil {
+		t.Fatalf("GetUserBySub() error = %v", err)
+	}
+	if found.ID != acme.ID || found.Email != "jane@acme.example.com" {
+		t.Errorf("GetUserBySub() = %s (%s), want %s", found.ID, found.Email, acme.ID)
+	}
+	if _, err := db.GetUserBySub(ctx, "https://other.example.com", "shared-sub"); err != ErrUserNotFound {
+		t.Errorf("GetUserBySub() for unknown issuer error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestAssignLegacyUserIssuer(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	legacy := &models.User{Sub: "legacy-sub", Email: "legacy@example.com", Name: "Legacy User"}
+	if err := db.UpsertUser(ctx, legacy); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+
+	assigned, err := db.AssignLegacyUserIssuer(ctx, "https://corp.example.com")
+	if err != nil {
+		t.Fatalf("AssignLegacyUserIssuer() error = %v", err)
+	}
+	if assigned != 1 {
+		t.Errorf("AssignLegacyUserIssuer() = %d, want 1", assigned)
+	}
+	found, err := db.GetUserBySub(ctx, "https://corp.example.com", "legacy-sub")
+	if err != nil || found.ID != legacy.ID {
+		t.Errorf("GetUserBySub() after assignment = %v, %v; want user %s", found, err, legacy.ID)
+	}
+}
+
 func TestGetUserByID(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -155,13 +208,35 @@ func TestGetUserByUsername(t *testing.T) {
 		t.Fatalf("UpsertUser() error = %v", err)
 	}
 
-	found, err := db.GetUserByUsername(ctx, "testuser")
+	// Another provider may have a user with the same username.
+	other := &models.User{
+		Issuer:   "https://other.example.com",
+		Sub:      "username-sub-456",
+		Username: "testuser",
+		Email:    "other@example.com",
+		Name:     "Other User",
+	}
+	if err := db.UpsertUser(ctx, other); err != nil {
+		t.Fatalf("UpsertUser(same username, other issuer) error = %v", err)
+	}
+
+	found, err := db.GetUserByUsername(ctx, "", "testuser")
 	if err != nil {
 		t.Fatalf("GetUserByUsername() error = %v", err)
 	}
 	if found.Sub != "username-sub-123" {
 		t.Errorf("GetUserByUsername() sub = %q, want %q", found.Sub, "username-sub-123")
 	}
+	found, err = db.GetUserByUsername(ctx, other.Issuer, "testuser")
+	if err != nil {
+		t.Fatalf("GetUserByUsername(other issuer) error = %v", err)
+	}
+	if found.Sub != "username-sub-456" {
+		t.Errorf("GetUserByUsername(other issuer) sub = %q, want %q", found.Sub, "username-sub-456")
+	}
+	if _, err := db.GetUserByUsername(ctx, "https://unknown.example.com", "testuser"); err != ErrUserNotFound {
+		t.Errorf("GetUserByUsername(unknown issuer) error = %v, want ErrUserNotFound", err)
+	}
 }
 
 func TestUpdateUserRole(t *testing.T) {
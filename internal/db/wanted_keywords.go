@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"golinks/internal/models"
+)
+
+// RecordMissingKeyword counts a lookup for a keyword that does not exist,
+// attributed to the requester's organization (nil for none). The write is
+// buffered in memory and flushed to the database in batches.
+func (d *DB) RecordMissingKeyword(_ context.Context, keyword string, orgID *uuid.UUID) {
+	key := uuid.Nil
+	if orgID != nil {
+		key = *orgID
+	}
+	d.buf.recordMissingKeyword(keyword, key)
+}
+
+// GetWantedKeywords returns the most requested keywords that do not exist,
+// most requested first. When allOrgs is true demand is summed across every
+// organization; otherwise only lookups attributed to orgID (or to users
+// without an organization when orgID is nil) are counted.
+//
+// Keywords that now exist as a pending or approved global link, or as an org
+// link in orgID, are excluded, as are keywords dismissed globally or for orgID.
+func (d *DB) GetWantedKeywords(ctx context.Context, orgID *uuid.UUID, allOrgs bool, limit int) ([]models.WantedKeyword, error) {
+	rows, err := d.Pool.Query(ctx, `
+		SELECT m.keyword, SUM(m.count)::bigint, MAX(m.last_seen_at)
+		FROM missing_keyword_lookups m
+		WHERE ($2 OR m.organization_id IS NOT DISTINCT FROM $1::uuid)
+			AND NOT EXISTS (
+				SELECT 1 FROM links l
+				WHERE l.keyword = m.keyword
+					AND l.status IN ($4, $5)
+					AND (l.scope = 'global' OR (l.scope = 'org' AND l.organization_id = $1::uuid))
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM dismissed_wanted_keywords dk
+				WHERE dk.keyword = m.keyword
+					AND (dk.organization_id IS NULL OR dk.organization_id = $1::uuid)
+			)
+		GROUP BY m.keyword
+		ORDER BY SUM(m.count) DESC, MAX(m.last_seen_at) DESC, m.keyword ASC
+		LIMIT $3
+	`, orgID, allOrgs, limit, models.StatusApproved, models.StatusPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var wanted []models.WantedKeyword
+	for rows.Next() {
+		var w models.WantedKeyword
+		if err := rows.Scan(&w.Keyword, &w.Count, &w.LastSeenAt); err != nil {
+			return nil, err
+		}
+		wanted = append(wanted, w)
+	}
+	return wanted, rows.Err()
+}
+
+// DismissWantedKeyword permanently hides a keyword from the "most wanted"
+// report, everywhere when orgID is nil or only for that organization.
+// Dismissing an already dismissed keyword is a no-op.
+func (d *DB) DismissWantedKeyword(ctx context.Context, keyword string, orgID *uuid.UUID, dismissedBy uuid.UUID) error {
+	_, err := d.Pool.Exec(ctx, `
+		INSERT INTO dismissed_wanted_keywords (keyword, organization_id, dismissed_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (keyword, COALESCE(organization_id, '00000000-0000-0000-0000-000000000000'::uuid)) DO NOTHING
+	`, keyword, orgID, dismissedBy)
+	return err
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"golinks/internal/models"
+)
+
+func TestGetWantedKeywords(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	db.Pool.Exec(ctx, "DELETE FROM missing_keyword_lookups")
+	db.Pool.Exec(ctx, "DELETE FROM dismissed_wanted_keywords")
+	defer db.Pool.Exec(ctx, "DELETE FROM missing_keyword_lookups")
+	defer db.Pool.Exec(ctx, "DELETE FROM dismissed_wanted_keywords")
+
+	org := &models.Organization{Name: "Wanted Org", Slug: "wanted-org"}
+	if err := db.CreateOrganization(ctx, org); err != nil {
+		t.Fatalf("CreateOrganization() error = %v", err)
+	}
+	mod := &models.User{Sub: "wanted-mod", Email: "wanted-mod@example.com", Name: "Wanted Mod"}
+	if err := db.UpsertUser(ctx, mod); err != nil {
+		t.Fatalf("UpsertUser() error = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		db.RecordMissingKeyword(ctx, "popular", &org.ID)
+	}
+	db.RecordMissingKeyword(ctx, "typo", &org.ID)
+	db.RecordMissingKeyword(ctx, "exists-now", &org.ID)
+	db.RecordMissingKeyword(ctx, "elsewhere", nil)
+	db.flush(ctx)
+
+	// A keyword that has since been created no longer shows up.
+	link := &models.Link{Keyword: "exists-now", URL: "https://example.com", Scope: models.ScopeGlobal, Status: models.StatusApproved}
+	if err := db.CreateLink(ctx, link); err != nil {
+		t.Fatalf("CreateLink() error = %v", err)
+	}
+
+	wanted, err := db.GetWantedKeywords(ctx, &org.ID, false, 10)
+	if err != nil {
+		t.Fatalf("GetWantedKeywords() error = %v", err)
+	}
+	if len(wanted) != 2 {
+		t.Fatalf("GetWantedKeywords() returned %d keywords, want 2", len(wanted))
+	}
+	if wanted[0].Keyword != "popular" || wanted[0].Count != 3 {
+		t.Errorf("first wanted = %+v, want popular with 3 requests", wanted[0])
+	}
+
+	// Dismissed keywords are hidden for the org.
+	if err := db.DismissWantedKeyword(ctx, "typo", &org.ID, mod.ID); err != nil {
+		t.Fatalf("DismissWantedKeyword() error = %v", err)
+	}
+	if err := db.DismissWantedKeyword(ctx, "typo", &org.ID, mod.ID); err != nil {
+		t.Fatalf("DismissWantedKeyword() twice error = %v", err)
+	}
+	wanted, _ = db.GetWantedKeywords(ctx, &org.ID, false, 10)
+	if len(wanted) != 1 {
+		t.Errorf("after dismissal got %d keywords, want 1", len(wanted))
+	}
+
+	// Across all orgs, the org-level dismissal does not apply.
+	all, err := db.GetWantedKeywords(ctx, nil, true, 10)
+	if err != nil {
+		t.Fatalf("GetWantedKeywords(all) error = %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("GetWantedKeywords(all) returned %d keywords, want 3", len(all))
+	}
+}
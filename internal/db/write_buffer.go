@@ -8,9 +8,12 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+
+	"golinks/internal/metrics"
 )
 
-// historyKey identifies a (link, hour) bucket in click_history.
+// historyKey identifies a (link, hour) bucket in click_history, or a
+// (user link, hour) bucket in user_link_click_history.
 type historyKey struct {
 	linkID     uuid.UUID
 	hourBucket time.Time
@@ -22,22 +25,33 @@ type kwLookupKey struct {
 	outcome string
 }
 
+// missingKey identifies a (keyword, organization) pair in
+// missing_keyword_lookups. uuid.Nil stands for "no organization".
+type missingKey struct {
+	keyword string
+	orgID   uuid.UUID
+}
+
 // writeBuffer accumulates hot counter increments in memory and flushes them
 // to the database in batches, dramatically reducing WAL write amplification.
 type writeBuffer struct {
-	mu             sync.Mutex
-	linkClicks     map[uuid.UUID]int64   // delta for links.click_count
-	userLinkClicks map[uuid.UUID]int64   // delta for user_links.click_count (by ID)
-	historyClicks  map[historyKey]int64  // delta for click_history.click_count
-	kwLookups      map[kwLookupKey]int64 // delta for keyword_lookups.count
+	mu                sync.Mutex
+	linkClicks        map[uuid.UUID]int64   // delta for links.click_count
+	userLinkClicks    map[uuid.UUID]int64   // delta for user_links.click_count (by ID)
+	historyClicks     map[historyKey]int64  // delta for click_history.click_count
+	userHistoryClicks map[historyKey]int64  // delta for user_link_click_history.click_count
+	kwLookups         map[kwLookupKey]int64 // delta for keyword_lookups.count
+	missingKeywords   map[missingKey]int64  // delta for missing_keyword_lookups.count
 }
 
 func newWriteBuffer() *writeBuffer {
 	return &writeBuffer{
-		linkClicks:     make(map[uuid.UUID]int64),
-		userLinkClicks: make(map[uuid.UUID]int64),
-		historyClicks:  make(map[historyKey]int64),
-		kwLookups:      make(map[kwLookupKey]int64),
+		linkClicks:        make(map[uuid.UUID]int64),
+		userLinkClicks:    make(map[uuid.UUID]int64),
+		historyClicks:     make(map[historyKey]int64),
+		userHistoryClicks: make(map[historyKey]int64),
+		kwLookups:         make(map[kwLookupKey]int64),
+		missingKeywords:   make(map[missingKey]int64),
 	}
 }
 
@@ -50,8 +64,10 @@ func (b *writeBuffer) recordLinkClick(id uuid.UUID) {
 }
 
 func (b *writeBuffer) recordUserLinkClick(id uuid.UUID) {
+	hour := time.Now().UTC().Truncate(time.Hour)
 	b.mu.Lock()
 	b.userLinkClicks[id]++
+	b.userHistoryClicks[historyKey{id, hour}]++
 	b.mu.Unlock()
 }
 
@@ -61,6 +77,20 @@ func (b *writeBuffer) recordKeywordLookup(keyword, outcome string) {
 	b.mu.Unlock()
 }
 
+func (b *writeBuffer) recordMissingKeyword(keyword string, orgID uuid.UUID) {
+	b.mu.Lock()
+	b.missingKeywords[missingKey{keyword, orgID}]++
+	b.mu.Unlock()
+}
+
+// depth returns the number of distinct entries waiting to be flushed.
+func (b *writeBuffer) depth() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.linkClicks) + len(b.userLinkClicks) + len(b.historyClicks) +
+		len(b.userHistoryClicks) + len(b.kwLookups) + len(b.missingKeywords)
+}
+
 // swap atomically drains all pending writes and returns them for flushing.
 // The buffer is reset to empty maps immediately, so new writes during flush
 // are accumulated for the next cycle.
@@ -68,25 +98,35 @@ func (b *writeBuffer) swap() (
 	links map[uuid.UUID]int64,
 	userLinks map[uuid.UUID]int64,
 	history map[historyKey]int64,
+	userHistory map[historyKey]int64,
 	kw map[kwLookupKey]int64,
+	missing map[missingKey]int64,
 ) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	links, b.linkClicks = b.linkClicks, make(map[uuid.UUID]int64)
 	userLinks, b.userLinkClicks = b.userLinkClicks, make(map[uuid.UUID]int64)
 	history, b.historyClicks = b.historyClicks, make(map[historyKey]int64)
+	userHistory, b.userHistoryClicks = b.userHistoryClicks, make(map[historyKey]int64)
 	kw, b.kwLookups = b.kwLookups, make(map[kwLookupKey]int64)
+	missing, b.missingKeywords = b.missingKeywords, make(map[missingKey]int64)
 	return
 }
 
 func (d *DB) flush(ctx context.Context) {
-	links, userLinks, history, kw := d.buf.swap()
+	links, userLinks, history, userHistory, kw, missing := d.buf.swap()
 
-	total := len(links) + len(userLinks) + len(history) + len(kw)
+	total := len(links) + len(userLinks) + len(history) + len(userHistory) + len(kw) + len(missing)
 	if total == 0 {
 		return
 	}
 
+	start := time.Now()
+	defer func() {
+		metrics.WriteBufferFlushDuration.Observe(time.Since(start).Seconds())
+		metrics.WriteBufferFlushedEntries.Set(float64(total))
+	}()
+
 	batch := &pgx.Batch{}
 
 	for id, delta := range links {
@@ -110,6 +150,15 @@ func (d *DB) flush(ctx context.Context) {
 			k.linkID, k.hourBucket, delta,
 		)
 	}
+	for k, delta := range userHistory {
+		batch.Queue(`
+			INSERT INTO user_link_click_history (user_link_id, hour_bucket, click_count)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_link_id, hour_bucket) DO UPDATE
+			  SET click_count = user_link_click_history.click_count + EXCLUDED.click_count`,
+			k.linkID, k.hourBucket, delta,
+		)
+	}
 	for k, delta := range kw {
 		batch.Queue(`
 			INSERT INTO keyword_lookups (keyword, outcome, count, last_seen_at)
@@ -121,6 +170,21 @@ func (d *DB) flush(ctx context.Context) {
 		)
 	}
 
+	for k, delta := range missing {
+		var orgID *uuid.UUID
+		if k.orgID != uuid.Nil {
+			orgID = &k.orgID
+		}
+		batch.Queue(`
+			INSERT INTO missing_keyword_lookups (keyword, organization_id, count, last_seen_at)
+			VALUES ($1, $2, $3, NOW())
+			ON CONFLICT (keyword, COALESCE(organization_id, '00000000-0000-0000-0000-000000000000'::uuid)) DO UPDATE
+			  SET count        = missing_keyword_lookups.count + EXCLUDED.count,
+			      last_seen_at = NOW()`,
+			k.keyword, orgID, delta,
+		)
+	}
+
 	results := d.Pool.SendBatch(ctx, batch)
 	defer results.Close()
 
@@ -134,10 +198,18 @@ func (d *DB) flush(ctx context.Context) {
 		"links", len(links),
 		"user_links", len(userLinks),
 		"history", len(history),
+		"user_link_history", len(userHistory),
 		"keyword_lookups", len(kw),
+		"missing_keywords", len(missing),
 	)
 }
 
+// WriteBufferDepth returns the number of buffered counter entries not yet
+// flushed to the database.
+func (d *DB) WriteBufferDepth() int {
+	return d.buf.depth()
+}
+
 // StartWriteBuffer starts a background goroutine that flushes buffered writes
 // to the database at the given interval. Call FlushWriteBuffer before closing.
 func (d *DB) StartWriteBuffer(ctx context.Context, interval time.Duration) {
@@ -1,6 +1,7 @@
 package email
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"log/slog"
@@ -8,8 +9,12 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"golinks/internal/config"
+	"golinks/internal/tracing"
 )
 
 // Service handles sending email notifications.
@@ -49,6 +54,27 @@ func (s *Service) Send(to []string, subject, htmlBody, textBody string) error {
 		return nil
 	}
 
+	// Sends run detached from the triggering request, so each is its own trace.
+	_, span := tracing.Tracer().Start(context.Background(), "smtp send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("smtp.host", s.cfg.SMTPHost),
+			attribute.String("smtp.tls", s.cfg.SMTPTLS),
+			attribute.Int("smtp.recipients", len(to)),
+		),
+	)
+	defer span.End()
+
+	err := s.send(to, subject, htmlBody, textBody)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// send builds the MIME message and delivers it using the configured TLS mode.
+func (s *Service) send(to []string, subject, htmlBody, textBody string) error {
 	// Build the email message
 	from := s.cfg.SMTPFrom
 	if s.cfg.SMTPFromName != "" {
@@ -1,10 +1,14 @@
 package email
 
 import (
+	"net"
 	"strings"
 	"testing"
 
+	"go.opentelemetry.io/otel/codes"
+
 	"golinks/internal/config"
+	"golinks/internal/testutil"
 )
 
 func TestNewService(t *testing.T) {
@@ -104,6 +108,41 @@ func TestService_Send_NoRecipients(t *testing.T) {
 	}
 }
 
+func TestService_Send_RecordsSpan(t *testing.T) {
+	exporter := testutil.InMemoryTracer(t)
+
+	// Reserve a port and close it so the connection is refused immediately.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	svc := NewService(&config.Config{
+		SMTPEnabled: true,
+		SMTPHost:    "127.0.0.1",
+		SMTPPort:    port,
+		SMTPFrom:    "noreply@example.com",
+		SMTPTLS:     "none",
+	})
+
+	if err := svc.Send([]string{"test@example.com"}, "Test", "", "Text"); err == nil {
+		t.Fatal("Send() to a closed port should fail")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	if spans[0].Name != "smtp send" {
+		t.Errorf("span name = %q, want %q", spans[0].Name, "smtp send")
+	}
+	if spans[0].Status.Code != codes.Error {
+		t.Errorf("span status = %v, want Error", spans[0].Status.Code)
+	}
+}
+
 func TestService_BuildMessage(t *testing.T) {
 	cfg := &config.Config{
 		SMTPEnabled:  true,
@@ -3,6 +3,9 @@ package email
 import (
 	"context"
 	"log"
+	"slices"
+
+	"github.com/google/uuid"
 
 	"golinks/internal/config"
 	"golinks/internal/db"
@@ -41,8 +44,9 @@ func (n *Notifier) NotifyModeratorsLinkSubmitted(ctx context.Context, link *mode
 		// Global links: notify global mods and admins
 		emails, err = n.db.GetGlobalModeratorEmails(ctx)
 	} else if link.Scope == models.ScopeOrg && link.OrganizationID != nil {
-		// Org links: notify org mods, global mods, and admins
-		emails, err = n.db.GetOrgModeratorEmails(ctx, *link.OrganizationID)
+		// Org links: notify org mods, global mods, and admins, and the
+		// addresses in the organization's settings
+		emails, err = n.orgSubmissionEmails(ctx, *link.OrganizationID)
 	} else {
 		// Personal links don't need moderation
 		return
@@ -61,6 +65,30 @@ func (n *Notifier) NotifyModeratorsLinkSubmitted(ctx context.Context, link *mode
 	n.service.SendAsync(emails, subject, htmlBody, textBody)
 }
 
+// orgSubmissionEmails returns who hears about submissions to an
+// organization: its moderators unless its settings turn them off, plus the
+// settings' notification addresses.
+func (n *Notifier) orgSubmissionEmails(ctx context.Context, orgID uuid.UUID) ([]string, error) {
+	settings, err := n.db.GetOrgSettings(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []string
+	if settings.NotifyModerators {
+		emails, err = n.db.GetOrgModeratorEmails(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range settings.NotifyEmails {
+		if !slices.Contains(emails, e) {
+			emails = append(emails, e)
+		}
+	}
+	return emails, nil
+}
+
 // NotifyUserLinkApproved notifies a user when their link is approved.
 func (n *Notifier) NotifyUserLinkApproved(ctx context.Context, link *models.Link, approver *models.User) {
 	if !n.service.IsEnabled() || !n.cfg.EmailNotifyUserOnApproval {
@@ -185,7 +213,7 @@ func (n *Notifier) NotifyModeratorsEditSuggested(ctx context.Context, link *mode
 	if link.Scope == models.ScopeGlobal {
 		emails, err = n.db.GetGlobalModeratorEmails(ctx)
 	} else if link.Scope == models.ScopeOrg && link.OrganizationID != nil {
-		emails, err = n.db.GetOrgModeratorEmails(ctx, *link.OrganizationID)
+		emails, err = n.orgSubmissionEmails(ctx, *link.OrganizationID)
 	} else {
 		return
 	}
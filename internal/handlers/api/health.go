@@ -61,7 +61,7 @@ func (h *HealthHandler) CheckLink(c fiber.Ctx) error {
 		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch link")
 	}
 
-	if !canManageLink(user, link) {
+	if !canManageLink(user, link, isLinkOwner(c, h.db, user, link)) {
 		return jsonError(c, fiber.StatusForbidden, "you do not have permission to check this link")
 	}
 
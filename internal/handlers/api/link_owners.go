@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"golinks/internal/db"
+	"golinks/internal/models"
+)
+
+// Owners returns a link's co-owners and its recent owner changes to anyone
+// who can see the link.
+func (h *LinkHandler) Owners(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return jsonError(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid link id")
+	}
+
+	link, err := h.db.GetLinkByID(c.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrLinkNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "link not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch link")
+	}
+
+	if !canManageLink(user, link, isLinkOwner(c, h.db, user, link)) && !h.linkVisibleTo(c, link, user) {
+		return jsonError(c, fiber.StatusNotFound, "link not found")
+	}
+
+	return h.renderOwners(c, link, nil)
+}
+
+// UpdateOwners replaces a link's co-owners with the users (emails), groups
+// and orgs (slugs) in the request body. Moderators of the link and its
+// current owners can change them; replacing the list transfers ownership.
+func (h *LinkHandler) UpdateOwners(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return jsonError(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid link id")
+	}
+
+	link, err := h.db.GetLinkByID(c.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrLinkNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "link not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch link")
+	}
+
+	if !isLinkOwner(c, h.db, user, link) && !canModerate(user, link) {
+		return jsonError(c, fiber.StatusForbidden, "you do not have permission to change the owners of this link")
+	}
+	if link.Status != models.StatusApproved {
+		return jsonError(c, fiber.StatusBadRequest, "only approved links have owners")
+	}
+
+	var body struct {
+		Users  []string `json:"users"`
+		Groups []string `json:"groups"`
+		Orgs   []string `json:"orgs"`
+	}
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid request body")
+	}
+
+	owners, err := h.db.ResolveLinkOwners(c.Context(), body.Users, body.Groups, body.Orgs)
+	if errors.Is(err, db.ErrOwnerNotFound) {
+		return jsonError(c, fiber.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to resolve owners")
+	}
+
+	events, err := h.db.SetLinkOwners(c.Context(), link.ID, user.ID, owners)
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to update owners")
+	}
+	if events == nil {
+		events = []models.LinkOwnerEvent{}
+	}
+	for _, e := range events {
+		slog.InfoContext(c.Context(), "link owner changed", "link_id", link.ID, "keyword", link.Keyword,
+			"actor_id", user.ID, "action", e.Action, "owner_kind", e.OwnerKind, "owner", e.OwnerName)
+	}
+
+	return h.renderOwners(c, link, events)
+}
+
+// renderOwners responds with the link's owners and owner history, plus the
+// changes just made when changes is non-nil.
+func (h *LinkHandler) renderOwners(c fiber.Ctx, link *models.Link, changes []models.LinkOwnerEvent) error {
+	owners, err := h.db.GetLinkOwners(c.Context(), link.ID)
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch owners")
+	}
+	history, err := h.db.GetLinkOwnerEvents(c.Context(), link.ID, 50)
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch owners")
+	}
+
+	data := fiber.Map{
+		"owners":  owners,
+		"history": history,
+	}
+	if changes != nil {
+		data["changes"] = changes
+	}
+	return jsonSuccess(c, data)
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"golinks/internal/db"
+	"golinks/internal/models"
+)
+
+// Retire demotes an approved org or global link into personal links for the
+// users (emails) in the request body, then deletes the link. Without a users
+// list the link's author, submitter and co-owners receive it. Moderators of
+// the link only.
+func (h *LinkHandler) Retire(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return jsonError(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid link id")
+	}
+
+	link, err := h.db.GetLinkByID(c.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrLinkNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "link not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch link")
+	}
+
+	if !h.cfg.EnablePersonalLinks {
+		return jsonError(c, fiber.StatusBadRequest, "personal links are not enabled")
+	}
+	if !canModerate(user, link) {
+		return jsonError(c, fiber.StatusForbidden, "you do not have permission to retire this link")
+	}
+	if link.Status != models.StatusApproved {
+		return jsonError(c, fiber.StatusBadRequest, "only approved links can be retired")
+	}
+
+	var body struct {
+		Users []string `json:"users"`
+	}
+	if len(c.Body()) > 0 {
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			return jsonError(c, fiber.StatusBadRequest, "invalid request body")
+		}
+	}
+	if body.Users == nil {
+		body.Users, err = h.db.GetLinkInterestedUserEmails(c.Context(), link.ID)
+		if err != nil {
+			return jsonError(c, fiber.StatusInternalServerError, "failed to fetch interested users")
+		}
+	}
+
+	userIDs, err := h.db.ResolveUserEmails(c.Context(), body.Users)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return jsonError(c, fiber.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to resolve users")
+	}
+	if len(userIDs) == 0 {
+		return jsonError(c, fiber.StatusBadRequest, "no users to receive the link; delete it instead")
+	}
+
+	created, err := h.db.RetireLink(c.Context(), link.ID, userIDs)
+	if err != nil {
+		if errors.Is(err, db.ErrLinkNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "link not found or no longer approved")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to retire link")
+	}
+
+	ns := make([]models.Notification, 0, len(created))
+	for _, uid := range created {
+		ns = append(ns, models.Notification{
+			UserID:    uid,
+			Type:      models.NotifTypeLinkRetired,
+			Title:     "Link moved to your personal links",
+			Body:      fmt.Sprintf(`"%s" was retired and is now one of your personal links`, link.Keyword),
+			ActionURL: "/my-links",
+		})
+	}
+	if len(ns) > 0 {
+		_ = h.db.CreateNotifications(c.Context(), ns)
+	}
+	slog.InfoContext(c.Context(), "link retired to personal links", "link_id", link.ID, "keyword", link.Keyword,
+		"actor_id", user.ID, "personal_links", len(created))
+
+	return jsonSuccess(c, fiber.Map{
+		"keyword": link.Keyword,
+		"users":   created,
+	})
+}
@@ -36,8 +36,13 @@ func (h *LinkHandler) List(c fiber.Ctx) error {
 		orgID = user.OrganizationID
 	}
 
+	var viewerID *uuid.UUID
+	if user != nil {
+		viewerID = &user.ID
+	}
+
 	query := c.Query("q", "")
-	links, err := h.db.SearchApprovedLinks(c.Context(), query, orgID, "all", 100, 0)
+	links, err := h.db.SearchApprovedLinks(c.Context(), query, viewerID, orgID, "all", 100, 0)
 	if err != nil {
 		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch links")
 	}
@@ -45,8 +50,11 @@ func (h *LinkHandler) List(c fiber.Ctx) error {
 	return jsonSuccess(c, links)
 }
 
-// Get returns a single link by ID.
+// Get returns a single link by ID. Links hidden from the user by their
+// visibility are reported as not found.
 func (h *LinkHandler) Get(c fiber.Ctx) error {
+	user, _ := c.Locals("user").(*models.User)
+
 	id, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return jsonError(c, fiber.StatusBadRequest, "invalid link id")
@@ -60,6 +68,20 @@ func (h *LinkHandler) Get(c fiber.Ctx) error {
 		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch link")
 	}
 
+	if user == nil || !canManageLink(user, link, isLinkOwner(c, h.db, user, link)) {
+		var viewerID *uuid.UUID
+		if user != nil {
+			viewerID = &user.ID
+		}
+		visible, err := h.db.LinkVisibleTo(c.Context(), link.ID, viewerID)
+		if err != nil {
+			return jsonError(c, fiber.StatusInternalServerError, "failed to fetch link")
+		}
+		if !visible {
+			return jsonError(c, fiber.StatusNotFound, "link not found")
+		}
+	}
+
 	return jsonSuccess(c, link)
 }
 
@@ -176,6 +198,17 @@ func (h *LinkHandler) createOrgLink(c fiber.Ctx, user *models.User, keyword, url
 		orgID = user.OrganizationID
 	}
 
+	settings, err := h.db.GetOrgSettings(c.Context(), *orgID)
+	if err != nil {
+		if errors.Is(err, db.ErrOrgNotFound) {
+			return jsonError(c, fiber.StatusBadRequest, "organization not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to load organization settings")
+	}
+	if ok, msg := settings.ValidateLink(keyword, url); !ok {
+		return jsonError(c, fiber.StatusBadRequest, msg)
+	}
+
 	link := &models.Link{
 		Keyword:        keyword,
 		URL:            url,
@@ -184,8 +217,11 @@ func (h *LinkHandler) createOrgLink(c fiber.Ctx, user *models.User, keyword, url
 		OrganizationID: orgID,
 		Reason:         reason,
 	}
+	if err := bodyVisibility(c, link); err != nil {
+		return jsonError(c, fiber.StatusBadRequest, err.Error())
+	}
 
-	if user.IsAdmin() || user.CanModerateOrg(*orgID) {
+	if user.IsAdmin() || user.CanModerateOrg(*orgID) || !settings.RequireModeration {
 		link.CreatedBy = &user.ID
 		link.Status = models.StatusApproved
 		if err := h.db.CreateLink(c.Context(), link); err != nil {
@@ -228,6 +264,9 @@ func (h *LinkHandler) createGlobalLink(c fiber.Ctx, user *models.User, keyword,
 		Scope:       models.ScopeGlobal,
 		Reason:      reason,
 	}
+	if err := bodyVisibility(c, link); err != nil {
+		return jsonError(c, fiber.StatusBadRequest, err.Error())
+	}
 
 	if user.IsGlobalMod() {
 		link.CreatedBy = &user.ID
@@ -264,7 +303,25 @@ func (h *LinkHandler) createGlobalLink(c fiber.Ctx, user *models.User, keyword,
 	})
 }
 
-// Update updates a link's URL and description (moderators only).
+// bodyVisibility sets who can see a new link from the visibility,
+// allowed_groups and allowed_users fields of the request body.
+func bodyVisibility(c fiber.Ctx, link *models.Link) error {
+	var body struct {
+		Visibility    string   `json:"visibility"`
+		AllowedGroups []string `json:"allowed_groups"`
+		AllowedUsers  []string `json:"allowed_users"`
+	}
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return errors.New("invalid request body")
+	}
+	link.Visibility = body.Visibility
+	link.AllowedGroups = body.AllowedGroups
+	link.AllowedUsers = body.AllowedUsers
+	return link.NormalizeVisibility()
+}
+
+// Update updates a link's URL and description, and optionally who can see
+// it (moderators only).
 func (h *LinkHandler) Update(c fiber.Ctx) error {
 	user, ok := c.Locals("user").(*models.User)
 	if !ok {
@@ -284,13 +341,16 @@ func (h *LinkHandler) Update(c fiber.Ctx) error {
 		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch link")
 	}
 
-	if !canManageLink(user, link) {
+	if !canManageLink(user, link, isLinkOwner(c, h.db, user, link)) {
 		return jsonError(c, fiber.StatusForbidden, "you do not have permission to edit this link")
 	}
 
 	var body struct {
-		URL         string `json:"url"`
-		Description string `json:"description"`
+		URL           string    `json:"url"`
+		Description   string    `json:"description"`
+		Visibility    *string   `json:"visibility"`
+		AllowedGroups *[]string `json:"allowed_groups"`
+		AllowedUsers  *[]string `json:"allowed_users"`
 	}
 	if err := json.Unmarshal(c.Body(), &body); err != nil {
 		return jsonError(c, fiber.StatusBadRequest, "invalid request body")
@@ -304,11 +364,44 @@ func (h *LinkHandler) Update(c fiber.Ctx) error {
 		return jsonError(c, fiber.StatusBadRequest, msg)
 	}
 
+	// The organization's settings bind edits as they do creation, for
+	// moderators and co-owners alike
+	if link.Scope == models.ScopeOrg && link.OrganizationID != nil {
+		settings, err := h.db.GetOrgSettings(c.Context(), *link.OrganizationID)
+		if err != nil {
+			return jsonError(c, fiber.StatusInternalServerError, "failed to load organization settings")
+		}
+		if ok, msg := settings.ValidateLink(link.Keyword, body.URL); !ok {
+			return jsonError(c, fiber.StatusBadRequest, msg)
+		}
+	}
+
+	changeVisibility := body.Visibility != nil || body.AllowedGroups != nil || body.AllowedUsers != nil
+	if body.Visibility != nil {
+		link.Visibility = *body.Visibility
+	}
+	if body.AllowedGroups != nil {
+		link.AllowedGroups = *body.AllowedGroups
+	}
+	if body.AllowedUsers != nil {
+		link.AllowedUsers = *body.AllowedUsers
+	}
+	if changeVisibility {
+		if err := link.NormalizeVisibility(); err != nil {
+			return jsonError(c, fiber.StatusBadRequest, err.Error())
+		}
+	}
+
 	link.URL = body.URL
 	link.Description = body.Description
 	if err := h.db.UpdateLinkAndResetHealth(c.Context(), link); err != nil {
 		return jsonError(c, fiber.StatusInternalServerError, "failed to update link")
 	}
+	if changeVisibility {
+		if err := h.db.UpdateLinkVisibility(c.Context(), link); err != nil {
+			return jsonError(c, fiber.StatusInternalServerError, "failed to update link")
+		}
+	}
 
 	return jsonSuccess(c, link)
 }
@@ -384,13 +477,13 @@ func (h *LinkHandler) CheckKeyword(c fiber.Ctx) error {
 		}
 	case "org":
 		if user != nil && user.OrganizationID != nil {
-			_, err := h.db.GetApprovedOrgLinkByKeyword(c.Context(), keyword, *user.OrganizationID)
-			exists = err == nil
+			link, err := h.db.GetApprovedOrgLinkByKeyword(c.Context(), keyword, *user.OrganizationID)
+			exists = err == nil && h.linkVisibleTo(c, link, user)
 			conflictType = "organization"
 		}
 	case "global":
-		_, err := h.db.GetApprovedGlobalLinkByKeyword(c.Context(), keyword)
-		exists = err == nil
+		link, err := h.db.GetApprovedGlobalLinkByKeyword(c.Context(), keyword)
+		exists = err == nil && h.linkVisibleTo(c, link, user)
 		conflictType = "global"
 	}
 
@@ -401,8 +494,21 @@ func (h *LinkHandler) CheckKeyword(c fiber.Ctx) error {
 	return jsonSuccess(c, resp)
 }
 
-// canManageLink checks if a user can manage a specific link.
-func canManageLink(user *models.User, link *models.Link) bool {
+// linkVisibleTo reports whether the user (nil when signed out) may learn that
+// link exists. Hidden links are treated as missing so their keywords don't
+// leak; creating one still fails as a duplicate.
+func (h *LinkHandler) linkVisibleTo(c fiber.Ctx, link *models.Link, user *models.User) bool {
+	var viewerID *uuid.UUID
+	if user != nil {
+		viewerID = &user.ID
+	}
+	visible, err := h.db.LinkVisibleTo(c.Context(), link.ID, viewerID)
+	return err == nil && visible
+}
+
+// canManageLink checks if a user can manage a specific link. owner is whether
+// the user co-owns it (see isLinkOwner).
+func canManageLink(user *models.User, link *models.Link, owner bool) bool {
 	if user.IsAdmin() {
 		return true
 	}
@@ -414,10 +520,17 @@ func canManageLink(user *models.User, link *models.Link) bool {
 			return true
 		}
 	}
-	// Users can manage links they authored
+	// Users can manage links they authored or co-own
 	if link.CreatedBy != nil && *link.CreatedBy == user.ID {
 		return true
 	}
-	return false
+	return owner
+}
+
+// isLinkOwner reports whether user co-owns link. Lookup errors count as not
+// owning it.
+func isLinkOwner(c fiber.Ctx, database *db.DB, user *models.User, link *models.Link) bool {
+	owned, err := database.IsLinkOwner(c.Context(), link.ID, user.ID)
+	return err == nil && owned
 }
 
@@ -3,6 +3,8 @@ package api
 import (
 	"encoding/json"
 	"errors"
+	"log/slog"
+	"slices"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
@@ -64,9 +66,20 @@ func (h *ModerationHandler) ListPending(c fiber.Ctx) error {
 		orgPending = []models.Link{}
 	}
 
+	// Usage evidence for links proposed from personal links, keyed by link ID
+	pendingIDs := make([]uuid.UUID, 0, len(globalPending)+len(orgPending))
+	for _, l := range slices.Concat(globalPending, orgPending) {
+		pendingIDs = append(pendingIDs, l.ID)
+	}
+	promotions, err := h.db.GetLinkPromotions(c.Context(), pendingIDs)
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch pending links")
+	}
+
 	return jsonSuccess(c, fiber.Map{
-		"global": globalPending,
-		"org":    orgPending,
+		"global":     globalPending,
+		"org":        orgPending,
+		"promotions": promotions,
 	})
 }
 
@@ -101,6 +114,11 @@ func (h *ModerationHandler) Approve(c fiber.Ctx) error {
 		return jsonError(c, fiber.StatusInternalServerError, "failed to approve link")
 	}
 
+	// A link proposed from a personal link takes over its click history
+	if _, err := h.db.ApplyLinkPromotion(c.Context(), link.ID); err != nil {
+		slog.ErrorContext(c.Context(), "failed to apply link promotion", "link_id", link.ID, "error", err)
+	}
+
 	if h.notifier != nil {
 		h.notifier.NotifyUserLinkApproved(c.Context(), link, user)
 	}
@@ -0,0 +1,526 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"golinks/internal/config"
+	"golinks/internal/db"
+	"golinks/internal/models"
+	"golinks/internal/validation"
+)
+
+// OrgHandler handles organization management via JSON API.
+type OrgHandler struct {
+	db  *db.DB
+	cfg *config.Config
+}
+
+// NewOrgHandler creates a new API organization handler.
+func NewOrgHandler(database *db.DB, cfg *config.Config) *OrgHandler {
+	return &OrgHandler{db: database, cfg: cfg}
+}
+
+// List returns all organizations with their member and link counts (admin only).
+func (h *OrgHandler) List(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	orgs, err := h.db.ListOrganizationsWithCounts(c.Context())
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch organizations")
+	}
+	if orgs == nil {
+		orgs = []db.OrgWithCounts{}
+	}
+	return jsonSuccess(c, orgs)
+}
+
+// Get returns an organization with its member and link counts (admin only).
+func (h *OrgHandler) Get(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid organization id")
+	}
+
+	org, err := h.db.GetOrganizationWithCounts(c.Context(), orgID)
+	if err != nil {
+		if errors.Is(err, db.ErrOrgNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "organization not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch organization")
+	}
+	return jsonSuccess(c, org)
+}
+
+// Update renames an organization or changes its slug (admin only). Omitted
+// fields keep their value.
+func (h *OrgHandler) Update(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid organization id")
+	}
+
+	var body struct {
+		Name *string `json:"name"`
+		Slug *string `json:"slug"`
+	}
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid request body")
+	}
+
+	org, err := h.db.GetOrganizationByID(c.Context(), orgID)
+	if err != nil {
+		if errors.Is(err, db.ErrOrgNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "organization not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch organization")
+	}
+	if body.Name != nil {
+		org.Name = strings.TrimSpace(*body.Name)
+	}
+	if body.Slug != nil {
+		org.Slug = strings.TrimSpace(*body.Slug)
+	}
+	if org.Name == "" || org.Slug == "" {
+		return jsonError(c, fiber.StatusBadRequest, "name and slug must not be empty")
+	}
+
+	if err := h.db.UpdateOrganization(c.Context(), orgID, org.Name, org.Slug); err != nil {
+		switch {
+		case errors.Is(err, db.ErrOrgNotFound):
+			return jsonError(c, fiber.StatusNotFound, "organization not found")
+		case errors.Is(err, db.ErrDuplicateOrgSlug):
+			return jsonError(c, fiber.StatusConflict, err.Error())
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to update organization")
+	}
+
+	return h.Get(c)
+}
+
+// Delete deletes an organization without members or links (admin only).
+func (h *OrgHandler) Delete(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid organization id")
+	}
+
+	if err := h.db.DeleteOrganization(c.Context(), orgID); err != nil {
+		switch {
+		case errors.Is(err, db.ErrOrgNotFound):
+			return jsonError(c, fiber.StatusNotFound, "organization not found")
+		case errors.Is(err, db.ErrOrgNotEmpty):
+			return jsonError(c, fiber.StatusConflict, "organization still has members or links; merge it into another instead")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to delete organization")
+	}
+
+	return jsonSuccess(c, fiber.Map{
+		"message": "organization deleted",
+	})
+}
+
+// Merge folds the organization into the one given as "into" and deletes it
+// (admin only). Links, members, fallback redirects and nested organizations
+// move over; keywords both organizations use are a 409 listing them.
+func (h *OrgHandler) Merge(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	sourceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid organization id")
+	}
+
+	var body struct {
+		Into string `json:"into"`
+	}
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid request body")
+	}
+	targetID, err := uuid.Parse(body.Into)
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "into must be an organization id")
+	}
+
+	result, err := h.db.MergeOrganizations(c.Context(), sourceID, targetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrOrgNotFound):
+			return jsonError(c, fiber.StatusNotFound, "organization not found")
+		case errors.Is(err, db.ErrOrgMergeTarget):
+			return jsonError(c, fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, db.ErrOrgMergeConflict):
+			return jsonError(c, fiber.StatusConflict, err.Error()+": "+strings.Join(result.ConflictingKeywords, ", "))
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to merge organizations")
+	}
+
+	return jsonSuccess(c, result)
+}
+
+// ListMembers returns an organization's members with their role in it (admin only).
+func (h *OrgHandler) ListMembers(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid organization id")
+	}
+	if _, err := h.db.GetOrganizationByID(c.Context(), orgID); err != nil {
+		if errors.Is(err, db.ErrOrgNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "organization not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch organization")
+	}
+
+	members, err := h.db.ListOrganizationMembers(c.Context(), orgID)
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch members")
+	}
+	if members == nil {
+		members = []models.OrgMember{}
+	}
+	return jsonSuccess(c, members)
+}
+
+// SetMember adds a user to the organization or changes their role in it,
+// e.g. to make them an org moderator (admin only). The body is
+// {"role": "member"} or {"role": "org_mod"}; an empty body means member.
+func (h *OrgHandler) SetMember(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid organization id")
+	}
+	userID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid user id")
+	}
+
+	var body struct {
+		Role string `json:"role"`
+	}
+	if len(c.Body()) > 0 {
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			return jsonError(c, fiber.StatusBadRequest, "invalid request body")
+		}
+	}
+	switch body.Role {
+	case "":
+		body.Role = models.OrgRoleMember
+	case models.OrgRoleMember, models.OrgRoleMod:
+	default:
+		return jsonError(c, fiber.StatusBadRequest, "role must be member or org_mod")
+	}
+
+	if _, err := h.db.GetOrganizationByID(c.Context(), orgID); err != nil {
+		if errors.Is(err, db.ErrOrgNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "organization not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch organization")
+	}
+	if _, err := h.db.GetUserByID(c.Context(), userID); err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "user not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch user")
+	}
+
+	if err := h.db.AddUserOrganization(c.Context(), userID, orgID, body.Role); err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to save membership")
+	}
+
+	return jsonSuccess(c, fiber.Map{
+		"message": "organization membership saved",
+	})
+}
+
+// RemoveMember removes a user from the organization (admin only).
+func (h *OrgHandler) RemoveMember(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid organization id")
+	}
+	userID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid user id")
+	}
+
+	if err := h.db.RemoveUserOrganization(c.Context(), userID, orgID); err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to remove membership")
+	}
+
+	return jsonSuccess(c, fiber.Map{
+		"message": "organization membership removed",
+	})
+}
+
+// SetParent nests an organization under another, or makes it top level when
+// parent_id is null or empty (admin only). Nesting an organization under
+// itself or one of its descendants is rejected.
+func (h *OrgHandler) SetParent(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid organization id")
+	}
+
+	var body struct {
+		ParentID *string `json:"parent_id"`
+	}
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid request body")
+	}
+
+	var parentID *uuid.UUID
+	if body.ParentID != nil && *body.ParentID != "" {
+		id, err := uuid.Parse(*body.ParentID)
+		if err != nil {
+			return jsonError(c, fiber.StatusBadRequest, "invalid parent_id")
+		}
+		parentID = &id
+	}
+
+	if err := h.db.SetOrganizationParent(c.Context(), orgID, parentID); err != nil {
+		switch {
+		case errors.Is(err, db.ErrOrgNotFound):
+			return jsonError(c, fiber.StatusNotFound, "organization not found")
+		case errors.Is(err, db.ErrOrgCycle):
+			return jsonError(c, fiber.StatusConflict, err.Error())
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to update organization")
+	}
+
+	org, err := h.db.GetOrganizationByID(c.Context(), orgID)
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch organization")
+	}
+	return jsonSuccess(c, org)
+}
+
+// GetSettings returns an organization's settings (its moderators and admins).
+func (h *OrgHandler) GetSettings(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return jsonError(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid organization id")
+	}
+	if !user.IsAdmin() && !user.CanModerateOrg(orgID) {
+		return jsonError(c, fiber.StatusForbidden, "moderator access required")
+	}
+
+	settings, err := h.db.GetOrgSettings(c.Context(), orgID)
+	if err != nil {
+		if errors.Is(err, db.ErrOrgNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "organization not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch organization settings")
+	}
+	return jsonSuccess(c, settings)
+}
+
+// UpdateSettings changes an organization's settings (its moderators and
+// admins). Omitted fields keep their value.
+func (h *OrgHandler) UpdateSettings(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return jsonError(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid organization id")
+	}
+	if !user.IsAdmin() && !user.CanModerateOrg(orgID) {
+		return jsonError(c, fiber.StatusForbidden, "moderator access required")
+	}
+
+	var body struct {
+		RequireModeration *bool     `json:"require_moderation"`
+		AllowedDomains    *[]string `json:"allowed_domains"`
+		KeywordPrefix     *string   `json:"keyword_prefix"`
+		KeywordPattern    *string   `json:"keyword_pattern"`
+		NotifyModerators  *bool     `json:"notify_moderators"`
+		NotifyEmails      *[]string `json:"notify_emails"`
+	}
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid request body")
+	}
+
+	settings, err := h.db.GetOrgSettings(c.Context(), orgID)
+	if err != nil {
+		if errors.Is(err, db.ErrOrgNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "organization not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch organization settings")
+	}
+	if body.RequireModeration != nil {
+		settings.RequireModeration = *body.RequireModeration
+	}
+	if body.AllowedDomains != nil {
+		settings.AllowedDomains = *body.AllowedDomains
+	}
+	if body.KeywordPrefix != nil {
+		settings.KeywordPrefix = *body.KeywordPrefix
+	}
+	if body.KeywordPattern != nil {
+		settings.KeywordPattern = *body.KeywordPattern
+	}
+	if body.NotifyModerators != nil {
+		settings.NotifyModerators = *body.NotifyModerators
+	}
+	if body.NotifyEmails != nil {
+		settings.NotifyEmails = *body.NotifyEmails
+	}
+	if err := settings.Normalize(); err != nil {
+		return jsonError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := h.db.SaveOrgSettings(c.Context(), settings, user.ID); err != nil {
+		if errors.Is(err, db.ErrOrgNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "organization not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to update organization settings")
+	}
+	return jsonSuccess(c, settings)
+}
+
+// GetBranding returns the branding an organization has set itself (its
+// moderators and admins).
+func (h *OrgHandler) GetBranding(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return jsonError(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid organization id")
+	}
+	if !user.IsAdmin() && !user.CanModerateOrg(orgID) {
+		return jsonError(c, fiber.StatusForbidden, "moderator access required")
+	}
+
+	branding, err := h.db.GetOrgBranding(c.Context(), orgID)
+	if err != nil {
+		if errors.Is(err, db.ErrOrgNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "organization not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch organization branding")
+	}
+	return jsonSuccess(c, branding)
+}
+
+// UpdateBranding changes an organization's branding (its moderators and
+// admins). Omitted fields keep their value; empty strings clear them.
+func (h *OrgHandler) UpdateBranding(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return jsonError(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid organization id")
+	}
+	if !user.IsAdmin() && !user.CanModerateOrg(orgID) {
+		return jsonError(c, fiber.StatusForbidden, "moderator access required")
+	}
+
+	var body struct {
+		SiteTitle       *string `json:"site_title"`
+		LogoURL         *string `json:"logo_url"`
+		AccentColor     *string `json:"accent_color"`
+		BannerText      *string `json:"banner_text"`
+		BannerTextColor *string `json:"banner_text_color"`
+		BannerBGColor   *string `json:"banner_bg_color"`
+	}
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid request body")
+	}
+
+	branding, err := h.db.GetOrgBranding(c.Context(), orgID)
+	if err != nil {
+		if errors.Is(err, db.ErrOrgNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "organization not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch organization branding")
+	}
+	if body.SiteTitle != nil {
+		branding.SiteTitle = *body.SiteTitle
+	}
+	if body.LogoURL != nil {
+		branding.LogoURL = *body.LogoURL
+	}
+	if body.AccentColor != nil {
+		branding.AccentColor = *body.AccentColor
+	}
+	if body.BannerText != nil {
+		branding.BannerText = *body.BannerText
+	}
+	if body.BannerTextColor != nil {
+		branding.BannerTextColor = *body.BannerTextColor
+	}
+	if body.BannerBGColor != nil {
+		branding.BannerBGColor = *body.BannerBGColor
+	}
+	if err := branding.Normalize(); err != nil {
+		return jsonError(c, fiber.StatusBadRequest, err.Error())
+	}
+	if err := validation.ValidateOrgBranding(branding, h.cfg.CSPImgSources); err != nil {
+		return jsonError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := h.db.SaveOrgBranding(c.Context(), branding, user.ID); err != nil {
+		if errors.Is(err, db.ErrOrgNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "organization not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to update organization branding")
+	}
+	return jsonSuccess(c, branding)
+}
@@ -54,3 +54,46 @@ func (h *ResolveHandler) Resolve(c fiber.Ctx) error {
 		Source:  resolved.Source,
 	})
 }
+
+// Explain lists every candidate link for a keyword in resolution order, marks
+// the one that wins for the caller and says why the others lost. Admins may
+// pass user=<id or email> to explain the resolution for another user; other
+// callers only see candidates they could already know about.
+func (h *ResolveHandler) Explain(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return jsonError(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	keyword := validation.NormalizeKeyword(c.Params("keyword"))
+	if !validation.ValidateKeyword(keyword) {
+		return jsonError(c, fiber.StatusBadRequest, "invalid keyword")
+	}
+
+	target := user
+	if ref := c.Query("user"); ref != "" {
+		if !user.IsAdmin() {
+			return jsonError(c, fiber.StatusForbidden, "admin access required to explain for another user")
+		}
+		var err error
+		if id, parseErr := uuid.Parse(ref); parseErr == nil {
+			target, err = h.db.GetUserByID(c.Context(), id)
+		} else {
+			target, err = h.db.GetUserByEmail(c.Context(), ref)
+		}
+		if errors.Is(err, db.ErrUserNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "user not found")
+		}
+		if err != nil {
+			return jsonError(c, fiber.StatusInternalServerError, "failed to fetch user")
+		}
+	}
+
+	explanation, err := h.db.ExplainResolution(c.Context(), target, keyword)
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to explain keyword")
+	}
+	explanation.RestrictTo(user)
+
+	return jsonSuccess(c, explanation)
+}
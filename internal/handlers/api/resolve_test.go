@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"golinks/internal/config"
+	"golinks/internal/db"
+	"golinks/internal/models"
+	"golinks/internal/testutil"
+)
+
+// explainAs explains "payroll" through the API signed in as user, for the
+// user given by target when set.
+func explainAs(t *testing.T, h *ResolveHandler, user *models.User, target string) *models.ResolveExplanation {
+	t.Helper()
+	app := fiber.New()
+	app.Use(func(c fiber.Ctx) error {
+		c.Locals("user", user)
+		return c.Next()
+	})
+	app.Get("/api/v1/resolve/:keyword/explain", h.Explain)
+
+	path := "/api/v1/resolve/payroll/explain"
+	if target != "" {
+		path += "?user=" + target
+	}
+	resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+	if err != nil {
+		t.Fatalf("GET %s error = %v", path, err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("GET %s = %d: %s", path, resp.StatusCode, body)
+	}
+	var out struct {
+		Data models.ResolveExplanation `json:"data"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		t.Fatalf("decoding %s: %v", body, err)
+	}
+	return &out.Data
+}
+
+func TestExplainHidesRestrictedCandidates(t *testing.T) {
+	if os.Getenv("TEST_DATABASE_URL") == "" && os.Getenv("RUN_INTEGRATION_TESTS") == "" {
+		t.Skip("Skipping integration test: TEST_DATABASE_URL not set")
+	}
+	database, cleanup := testutil.TestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orgs := make(map[string]*models.Organization)
+	for _, slug := range []string{"explain-api-eng", "explain-api-finance", "explain-api-ops", "explain-api-legal"} {
+		orgs[slug] = &models.Organization{Name: slug, Slug: slug}
+		if err := database.CreateOrganization(ctx, orgs[slug]); err != nil {
+			t.Fatalf("CreateOrganization() error = %v", err)
+		}
+	}
+
+	viewer := &models.User{Sub: "explain-api-viewer", Email: "viewer@example.com", Name: "Viewer"}
+	hr := &models.User{Sub: "explain-api-hr", Email: "hr@example.com", Name: "HR"}
+	admin := &models.User{Sub: "explain-api-admin", Email: "admin@example.com", Name: "Admin", Role: models.RoleAdmin}
+	for _, u := range []*models.User{viewer, hr, admin} {
+		if err := database.UpsertUser(ctx, u); err != nil {
+			t.Fatalf("UpsertUser() error = %v", err)
+		}
+	}
+	if err := database.AddUserOrganization(ctx, viewer.ID, orgs["explain-api-eng"].ID, models.OrgRoleMember); err != nil {
+		t.Fatalf("AddUserOrganization() error = %v", err)
+	}
+	viewer = reloadUser(t, database, viewer)
+
+	orgLink := func(slug, url, status, visibility string, createdBy *models.User) *models.Link {
+		return &models.Link{Keyword: "payroll", URL: url, Scope: models.ScopeOrg, OrganizationID: &orgs[slug].ID,
+			Status: status, Visibility: visibility, AllowedUsers: []string{"hr@example.com"}, CreatedBy: &createdBy.ID}
+	}
+	global := &models.Link{Keyword: "payroll", URL: "https://payroll.example.com", Scope: models.ScopeGlobal, Status: models.StatusApproved, CreatedBy: &hr.ID}
+	restricted := orgLink("explain-api-eng", "https://hr-payroll.example.com", models.StatusApproved, models.VisibilityRestricted, hr)
+	otherOrg := orgLink("explain-api-finance", "https://finance-payroll.example.com", models.StatusApproved, models.VisibilityPublic, hr)
+	othersPending := orgLink("explain-api-ops", "https://ops-payroll.example.com", models.StatusPending, models.VisibilityPublic, hr)
+	ownPending := orgLink("explain-api-legal", "https://legal-payroll.example.com", models.StatusPending, models.VisibilityPublic, viewer)
+	for _, l := range []*models.Link{global, restricted, otherOrg, othersPending, ownPending} {
+		if err := database.CreateLink(ctx, l); err != nil {
+			t.Fatalf("CreateLink() error = %v", err)
+		}
+	}
+
+	h := NewResolveHandler(database, &config.Config{})
+
+	// The viewer isn't on the restricted link's allow-list: it, the other
+	// organization's link and someone else's suggestion are left out.
+	e := explainAs(t, h, viewer, "")
+	got := make(map[string]bool)
+	for _, c := range e.Candidates {
+		got[c.ID.String()] = true
+	}
+	if len(e.Candidates) != 2 || !got[global.ID.String()] || !got[ownPending.ID.String()] {
+		t.Errorf("non-admin candidates = %+v, want the global link and their own pending link", e.Candidates)
+	}
+	if w := e.Winner(); w == nil || w.ID != global.ID {
+		t.Errorf("Winner() = %+v, want the global link", w)
+	}
+
+	// Admins explaining for the viewer see every candidate.
+	e = explainAs(t, h, reloadUser(t, database, admin), viewer.ID.String())
+	if len(e.Candidates) != 5 {
+		t.Errorf("admin sees %d candidates, want 5", len(e.Candidates))
+	}
+}
+
+func reloadUser(t *testing.T, database *db.DB, u *models.User) *models.User {
+	t.Helper()
+	got, err := database.GetUserByID(context.Background(), u.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	return got
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"golinks/internal/config"
+	"golinks/internal/db"
+	"golinks/internal/models"
+)
+
+// ShadowHandler serves the scope shadowing report via JSON API.
+type ShadowHandler struct {
+	db  *db.DB
+	cfg *config.Config
+}
+
+// NewShadowHandler creates a new API shadowing report handler.
+func NewShadowHandler(database *db.DB, cfg *config.Config) *ShadowHandler {
+	return &ShadowHandler{db: database, cfg: cfg}
+}
+
+// List returns the keywords where a higher-priority scope hides a lower one.
+// scope=mine (the default) reports the caller's personal links and their
+// organizations' links; moderators may pass scope=orgs for org links hiding
+// global ones across the organizations they moderate, narrowed with org=<id>.
+func (h *ShadowHandler) List(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return jsonError(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	var shadows []models.KeywordShadow
+	var err error
+	switch c.Query("scope", "mine") {
+	case "mine":
+		shadows, err = h.db.GetUserShadows(c.Context(), user.ID)
+	case "orgs":
+		orgID, ok := user.ModerationScope()
+		if !ok {
+			return jsonError(c, fiber.StatusForbidden, "moderator access required for scope=orgs")
+		}
+		if s := c.Query("org"); s != "" {
+			id, err := uuid.Parse(s)
+			if err != nil {
+				return jsonError(c, fiber.StatusBadRequest, "invalid organization id")
+			}
+			if !user.CanModerateOrg(id) {
+				return jsonError(c, fiber.StatusForbidden, "you do not moderate this organization")
+			}
+			orgID = &id
+		}
+		shadows, err = h.db.GetOrgShadows(c.Context(), user, orgID)
+	default:
+		return jsonError(c, fiber.StatusBadRequest, "scope must be mine or orgs")
+	}
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch shadowed keywords")
+	}
+
+	return jsonSuccess(c, shadows)
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"golinks/internal/analytics"
+	"golinks/internal/config"
+	"golinks/internal/db"
+	"golinks/internal/models"
+)
+
+// StatsHandler serves click analytics via JSON API.
+type StatsHandler struct {
+	db  *db.DB
+	cfg *config.Config
+}
+
+// NewStatsHandler creates a new API stats handler.
+func NewStatsHandler(database *db.DB, cfg *config.Config) *StatsHandler {
+	return &StatsHandler{db: database, cfg: cfg}
+}
+
+// LinkStats returns click totals, trend and a time series for a single link.
+func (h *StatsHandler) LinkStats(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return jsonError(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid link id")
+	}
+
+	link, err := h.db.GetLinkByID(c.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrLinkNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "link not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch link")
+	}
+	if !analytics.CanView(user, link) {
+		return jsonError(c, fiber.StatusNotFound, "link not found")
+	}
+	if !canModerate(user, link) {
+		visible, err := h.db.LinkVisibleTo(c.Context(), link.ID, &user.ID)
+		if err != nil {
+			return jsonError(c, fiber.StatusInternalServerError, "failed to fetch link")
+		}
+		if !visible {
+			return jsonError(c, fiber.StatusNotFound, "link not found")
+		}
+	}
+
+	w, err := analytics.NewWindow(c.Query("range"), c.Query("granularity"), time.Now())
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	stats, err := analytics.ForLink(c.Context(), h.db, link, w)
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch stats")
+	}
+
+	return jsonSuccess(c, stats)
+}
+
+// Top returns the most clicked links for a scope. Pass format=csv to download
+// the leaderboard as CSV instead of JSON.
+func (h *StatsHandler) Top(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return jsonError(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	scope := c.Query("scope", "all")
+	orgID, err := analytics.OrgForScope(user, scope, c.Query("org_id"))
+	if err != nil {
+		if errors.Is(err, analytics.ErrForbiddenOrg) {
+			return jsonError(c, fiber.StatusForbidden, err.Error())
+		}
+		return jsonError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	w, err := analytics.NewWindow(c.Query("range"), "", time.Now())
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		return jsonError(c, fiber.StatusBadRequest, "limit must be between 1 and 100")
+	}
+
+	top, err := analytics.TopLinks(c.Context(), h.db, &user.ID, orgID, scope, w, limit)
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch stats")
+	}
+
+	if c.Query("format") == "csv" {
+		orgs, err := h.db.GetAllOrganizations(c.Context())
+		if err != nil {
+			return jsonError(c, fiber.StatusInternalServerError, "failed to fetch organizations")
+		}
+		orgNames := make(map[string]string, len(orgs))
+		for _, o := range orgs {
+			orgNames[o.ID.String()] = o.Name
+		}
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="top-links-`+scope+`-`+w.Range+`.csv"`)
+		return analytics.WriteTopLinksCSV(c.Response().BodyWriter(), top, orgNames)
+	}
+
+	if top == nil {
+		top = []models.TopLinkStat{}
+	}
+	return jsonSuccess(c, fiber.Map{
+		"scope":           scope,
+		"organization_id": orgID,
+		"range":           w.Range,
+		"from":            w.From,
+		"to":              w.To,
+		"links":           top,
+	})
+}
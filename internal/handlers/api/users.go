@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
@@ -9,6 +10,7 @@ import (
 
 	"golinks/internal/config"
 	"golinks/internal/db"
+	"golinks/internal/middleware"
 	"golinks/internal/models"
 )
 
@@ -37,6 +39,7 @@ func (h *UserHandler) List(c fiber.Ctx) error {
 
 	type userResponse struct {
 		ID               uuid.UUID  `json:"id"`
+		Issuer           string     `json:"issuer"`
 		Sub              string     `json:"sub"`
 		Username         string     `json:"username"`
 		Email            string     `json:"email"`
@@ -47,12 +50,15 @@ func (h *UserHandler) List(c fiber.Ctx) error {
 		OrganizationSlug string     `json:"organization_slug"`
 		CreatedAt        time.Time  `json:"created_at"`
 		UpdatedAt        time.Time  `json:"updated_at"`
+		LastLoginAt      *time.Time `json:"last_login_at"`
+		DeactivatedAt    *time.Time `json:"deactivated_at"`
 	}
 
 	resp := make([]userResponse, len(users))
 	for i, u := range users {
 		resp[i] = userResponse{
 			ID:               u.ID,
+			Issuer:           u.Issuer,
 			Sub:              u.Sub,
 			Username:         u.Username,
 			Email:            u.Email,
@@ -63,6 +69,8 @@ func (h *UserHandler) List(c fiber.Ctx) error {
 			OrganizationSlug: u.OrganizationSlug,
 			CreatedAt:        u.CreatedAt,
 			UpdatedAt:        u.UpdatedAt,
+			LastLoginAt:      u.LastLoginAt,
+			DeactivatedAt:    u.DeactivatedAt,
 		}
 	}
 
@@ -115,7 +123,8 @@ func (h *UserHandler) UpdateRole(c fiber.Ctx) error {
 	})
 }
 
-// UpdateOrg updates a user's organization (admin only).
+// UpdateOrg sets a user's active organization, adding them to it if needed;
+// a null organization_id removes them from all organizations (admin only).
 func (h *UserHandler) UpdateOrg(c fiber.Ctx) error {
 	currentUser, ok := c.Locals("user").(*models.User)
 	if !ok || !currentUser.IsAdmin() {
@@ -152,6 +161,113 @@ func (h *UserHandler) UpdateOrg(c fiber.Ctx) error {
 	})
 }
 
+// ListOrgs returns a user's organization memberships (admin, or the user
+// themselves).
+func (h *UserHandler) ListOrgs(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return jsonError(c, fiber.StatusUnauthorized, "authentication required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid user id")
+	}
+	if userID != currentUser.ID && !currentUser.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	memberships, err := h.db.ListUserOrganizations(c.Context(), userID)
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch organizations")
+	}
+	if memberships == nil {
+		memberships = []models.OrgMembership{}
+	}
+
+	return jsonSuccess(c, memberships)
+}
+
+// AddOrg adds a user to an organization, or changes their role in it
+// (admin only).
+func (h *UserHandler) AddOrg(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok || !currentUser.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid user id")
+	}
+	orgID, err := uuid.Parse(c.Params("orgId"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid organization id")
+	}
+
+	var body struct {
+		Role string `json:"role"`
+	}
+	if len(c.Body()) > 0 {
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			return jsonError(c, fiber.StatusBadRequest, "invalid request body")
+		}
+	}
+	switch body.Role {
+	case "":
+		body.Role = models.OrgRoleMember
+	case models.OrgRoleMember, models.OrgRoleMod:
+	default:
+		return jsonError(c, fiber.StatusBadRequest, "role must be member or org_mod")
+	}
+
+	if _, err := h.db.GetUserByID(c.Context(), userID); err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "user not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch user")
+	}
+	if _, err := h.db.GetOrganizationByID(c.Context(), orgID); err != nil {
+		if errors.Is(err, db.ErrOrgNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "organization not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch organization")
+	}
+
+	if err := h.db.AddUserOrganization(c.Context(), userID, orgID, body.Role); err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to add organization")
+	}
+
+	return jsonSuccess(c, fiber.Map{
+		"message": "organization membership saved",
+	})
+}
+
+// RemoveOrg removes a user from an organization (admin only).
+func (h *UserHandler) RemoveOrg(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok || !currentUser.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid user id")
+	}
+	orgID, err := uuid.Parse(c.Params("orgId"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid organization id")
+	}
+
+	if err := h.db.RemoveUserOrganization(c.Context(), userID, orgID); err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to remove organization")
+	}
+
+	return jsonSuccess(c, fiber.Map{
+		"message": "organization membership removed",
+	})
+}
+
 // Delete removes a user (admin only).
 func (h *UserHandler) Delete(c fiber.Ctx) error {
 	currentUser, ok := c.Locals("user").(*models.User)
@@ -169,6 +285,9 @@ func (h *UserHandler) Delete(c fiber.Ctx) error {
 	}
 
 	if err := h.db.DeleteUser(c.Context(), userID); err != nil {
+		if errors.Is(err, db.ErrUserAuthorsLinks) {
+			return jsonError(c, fiber.StatusConflict, err.Error())
+		}
 		return jsonError(c, fiber.StatusInternalServerError, "failed to delete user")
 	}
 
@@ -176,3 +295,198 @@ func (h *UserHandler) Delete(c fiber.Ctx) error {
 		"message": "user deleted successfully",
 	})
 }
+
+// Deactivate blocks a user from signing in and revokes their sessions,
+// keeping their links and attribution (admin only).
+func (h *UserHandler) Deactivate(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok || !currentUser.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid user id")
+	}
+	if userID == currentUser.ID {
+		return jsonError(c, fiber.StatusBadRequest, "cannot deactivate your own account")
+	}
+
+	if err := h.db.DeactivateUser(c.Context(), userID); err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to deactivate user")
+	}
+	if _, err := h.db.RevokeAllUserSessions(c.Context(), userID); err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to revoke sessions")
+	}
+
+	return jsonSuccess(c, fiber.Map{
+		"message": "user deactivated successfully",
+	})
+}
+
+// Reactivate lets a deactivated user sign in again (admin only).
+func (h *UserHandler) Reactivate(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok || !currentUser.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid user id")
+	}
+
+	if err := h.db.ReactivateUser(c.Context(), userID); err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to reactivate user")
+	}
+
+	return jsonSuccess(c, fiber.Map{
+		"message": "user reactivated successfully",
+	})
+}
+
+// Offboard deactivates a departing user, hands their links to another user
+// (transfer_to) or the organization (transfer_to omitted), and submits the
+// chosen personal links for approval (admin only).
+func (h *UserHandler) Offboard(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok || !currentUser.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid user id")
+	}
+	if userID == currentUser.ID {
+		return jsonError(c, fiber.StatusBadRequest, "cannot offboard your own account")
+	}
+
+	var body struct {
+		TransferTo      *uuid.UUID  `json:"transfer_to"`
+		SubmitUserLinks []uuid.UUID `json:"submit_user_links"`
+		Reason          string      `json:"reason"`
+	}
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid request body")
+	}
+
+	target, err := h.db.GetUserByID(c.Context(), userID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return jsonError(c, fiber.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch user")
+	}
+
+	if body.TransferTo != nil {
+		if *body.TransferTo == userID {
+			return jsonError(c, fiber.StatusBadRequest, "cannot transfer links to the departing user")
+		}
+		to, err := h.db.GetUserByID(c.Context(), *body.TransferTo)
+		if errors.Is(err, db.ErrUserNotFound) {
+			return jsonError(c, fiber.StatusBadRequest, "transfer user not found")
+		}
+		if err != nil {
+			return jsonError(c, fiber.StatusInternalServerError, "failed to fetch transfer user")
+		}
+		if to.IsDeactivated() {
+			return jsonError(c, fiber.StatusBadRequest, "cannot transfer links to a deactivated user")
+		}
+	}
+	if body.Reason == "" {
+		name := target.Name
+		if name == "" {
+			name = target.Email
+		}
+		body.Reason = "Submitted from " + name + "'s personal links when they were offboarded"
+	}
+
+	result, err := h.db.OffboardUser(c.Context(), userID, db.OffboardOptions{
+		TransferTo:      body.TransferTo,
+		SubmitUserLinks: body.SubmitUserLinks,
+		Reason:          body.Reason,
+	})
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to offboard user")
+	}
+	if _, err := h.db.RevokeAllUserSessions(c.Context(), userID); err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to revoke sessions")
+	}
+
+	return jsonSuccess(c, result)
+}
+
+// ListSessions returns a user's active sessions (admin only).
+func (h *UserHandler) ListSessions(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok || !currentUser.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid user id")
+	}
+
+	sessions, err := h.db.ListActiveUserSessions(c.Context(), userID, middleware.SessionLastSeenAfter(time.Now()))
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch sessions")
+	}
+	if sessions == nil {
+		sessions = []models.UserSession{}
+	}
+
+	return jsonSuccess(c, sessions)
+}
+
+// RevokeSession signs out one of a user's sessions (admin only).
+func (h *UserHandler) RevokeSession(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok || !currentUser.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid user id")
+	}
+	sessionID, err := uuid.Parse(c.Params("sessionId"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid session id")
+	}
+
+	if err := h.db.RevokeUserSession(c.Context(), userID, sessionID); err != nil {
+		if errors.Is(err, db.ErrUserSessionNotFound) {
+			return jsonError(c, fiber.StatusNotFound, "session not found")
+		}
+		return jsonError(c, fiber.StatusInternalServerError, "failed to revoke session")
+	}
+
+	return jsonSuccess(c, fiber.Map{
+		"message": "session revoked successfully",
+	})
+}
+
+// RevokeAllSessions signs a user out of every session (admin only).
+func (h *UserHandler) RevokeAllSessions(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok || !currentUser.IsAdmin() {
+		return jsonError(c, fiber.StatusForbidden, "admin access required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return jsonError(c, fiber.StatusBadRequest, "invalid user id")
+	}
+
+	revoked, err := h.db.RevokeAllUserSessions(c.Context(), userID)
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to revoke sessions")
+	}
+
+	return jsonSuccess(c, fiber.Map{
+		"message": "sessions revoked successfully",
+		"revoked": revoked,
+	})
+}
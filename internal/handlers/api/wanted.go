@@ -0,0 +1,83 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+
+	"golinks/internal/config"
+	"golinks/internal/db"
+	"golinks/internal/models"
+	"golinks/internal/validation"
+)
+
+// WantedHandler serves the "most wanted" missing keywords report via JSON API.
+type WantedHandler struct {
+	db  *db.DB
+	cfg *config.Config
+}
+
+// NewWantedHandler creates a new API wanted keywords handler.
+func NewWantedHandler(database *db.DB, cfg *config.Config) *WantedHandler {
+	return &WantedHandler{db: database, cfg: cfg}
+}
+
+// List returns the most requested keywords that do not exist for the caller's
+// organization. Global moderators may pass scope=all to sum demand across orgs.
+func (h *WantedHandler) List(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return jsonError(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	allOrgs := false
+	switch c.Query("scope", "org") {
+	case "org":
+	case "all":
+		if !user.IsGlobalMod() {
+			return jsonError(c, fiber.StatusForbidden, "global moderator access required for scope=all")
+		}
+		allOrgs = true
+	default:
+		return jsonError(c, fiber.StatusBadRequest, "scope must be org or all")
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		return jsonError(c, fiber.StatusBadRequest, "limit must be between 1 and 500")
+	}
+
+	wanted, err := h.db.GetWantedKeywords(c.Context(), user.OrganizationID, allOrgs, limit)
+	if err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to fetch wanted keywords")
+	}
+	if wanted == nil {
+		wanted = []models.WantedKeyword{}
+	}
+
+	return jsonSuccess(c, wanted)
+}
+
+// Dismiss permanently hides a keyword from the report (moderators only).
+func (h *WantedHandler) Dismiss(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return jsonError(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	orgID, ok := user.ModerationScope()
+	if !ok {
+		return jsonError(c, fiber.StatusForbidden, "moderator access required")
+	}
+
+	keyword := validation.NormalizeKeyword(c.Params("keyword"))
+	if !validation.ValidateKeyword(keyword) {
+		return jsonError(c, fiber.StatusBadRequest, "invalid keyword")
+	}
+
+	if err := h.db.DismissWantedKeyword(c.Context(), keyword, orgID, user.ID); err != nil {
+		return jsonError(c, fiber.StatusInternalServerError, "failed to dismiss keyword")
+	}
+
+	return jsonSuccess(c, fiber.Map{"keyword": keyword, "dismissed": true})
+}
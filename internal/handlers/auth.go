@@ -4,10 +4,12 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"fmt"
 	"log"
 	"log/slog"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/gofiber/fiber/v3"
@@ -16,45 +18,72 @@ import (
 
 	"golinks/internal/config"
 	"golinks/internal/db"
+	"golinks/internal/identity"
+	"golinks/internal/middleware"
 	"golinks/internal/models"
 	"golinks/internal/oidchealth"
 	"golinks/internal/validation"
 )
 
+// maxUserAgentLength caps the user agent recorded for a session.
+const maxUserAgentLength = 512
+
 const callbackFailureNotice = "Sign-in didn't complete — the auth server did not return a token. Please try again in a moment."
 
-// AuthHandler handles OIDC authentication flows.
+// AuthHandler handles OIDC authentication flows for every configured provider.
 type AuthHandler struct {
-	provider       *oidc.Provider
-	oauth2Config   oauth2.Config
-	verifier       *oidc.IDTokenVerifier
-	db             *db.DB
-	cfg            *config.Config
-	oidcProbe      *oidchealth.Probe
-	endSessionURL  string // RP-initiated logout endpoint from discovery (empty if unsupported)
+	clients   []*oidcClient // in OIDC_PROVIDERS order; the first is the primary provider
+	db        *db.DB
+	cfg       *config.Config
+	oidcProbe *oidchealth.Probe
+}
+
+// oidcClient is the discovered state for one identity provider.
+type oidcClient struct {
+	cfg           *config.OIDCProvider
+	provider      *oidc.Provider
+	oauth2Config  oauth2.Config
+	verifier      *oidc.IDTokenVerifier
+	endSessionURL string // RP-initiated logout endpoint from discovery (empty if unsupported)
 }
 
-// NewAuthHandler creates a new auth handler with OIDC configuration.
+// NewAuthHandler creates a new auth handler, running discovery against every
+// configured OIDC provider.
 func NewAuthHandler(ctx context.Context, cfg *config.Config, database *db.DB, oidcProbe *oidchealth.Probe) (*AuthHandler, error) {
-	provider, err := oidc.NewProvider(ctx, cfg.OIDCIssuer)
+	h := &AuthHandler{db: database, cfg: cfg, oidcProbe: oidcProbe}
+	for i := range cfg.OIDCProviders {
+		client, err := newOIDCClient(ctx, cfg, &cfg.OIDCProviders[i])
+		if err != nil {
+			return nil, fmt.Errorf("oidc provider %q: %w", cfg.OIDCProviders[i].Name, err)
+		}
+		h.clients = append(h.clients, client)
+	}
+	return h, nil
+}
+
+func newOIDCClient(ctx context.Context, cfg *config.Config, p *config.OIDCProvider) (*oidcClient, error) {
+	provider, err := oidc.NewProvider(ctx, p.Issuer)
 	if err != nil {
 		return nil, err
 	}
 
 	scopes := []string{oidc.ScopeOpenID, "profile", "email"}
-	if cfg.HasGroupRoleMapping() {
+	if p.HasGroupRoleMapping() {
 		scopes = append(scopes, "groups")
 	}
+	if cfg.OIDCOfflineAccess {
+		scopes = append(scopes, oidc.ScopeOfflineAccess)
+	}
 
 	oauth2Config := oauth2.Config{
-		ClientID:     cfg.OIDCClientID,
-		ClientSecret: cfg.OIDCClientSecret,
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
 		RedirectURL:  cfg.OIDCRedirectURL,
 		Endpoint:     provider.Endpoint(),
 		Scopes:       scopes,
 	}
 
-	verifier := provider.Verifier(&oidc.Config{ClientID: cfg.OIDCClientID})
+	verifier := provider.Verifier(&oidc.Config{ClientID: p.ClientID})
 
 	// Pull end_session_endpoint from discovery metadata (not exposed by Endpoint()).
 	// Optional per the spec; left empty if the provider doesn't advertise it.
@@ -63,19 +92,60 @@ func NewAuthHandler(ctx context.Context, cfg *config.Config, database *db.DB, oi
 	}
 	_ = provider.Claims(&providerExtra)
 
-	return &AuthHandler{
+	return &oidcClient{
+		cfg:           p,
 		provider:      provider,
 		oauth2Config:  oauth2Config,
 		verifier:      verifier,
-		db:            database,
-		cfg:           cfg,
-		oidcProbe:     oidcProbe,
 		endSessionURL: providerExtra.EndSessionEndpoint,
 	}, nil
 }
 
-// Login initiates the OIDC login flow.
+// clientByName returns the provider with the given name, or nil.
+func (h *AuthHandler) clientByName(name string) *oidcClient {
+	for _, client := range h.clients {
+		if client.cfg.Name == name {
+			return client
+		}
+	}
+	return nil
+}
+
+// clientByIssuer returns the provider users of the given issuer sign in with,
+// or nil. An empty issuer means the primary provider.
+func (h *AuthHandler) clientByIssuer(issuer string) *oidcClient {
+	if issuer == "" && len(h.clients) > 0 {
+		return h.clients[0]
+	}
+	for _, client := range h.clients {
+		if client.cfg.Issuer == issuer {
+			return client
+		}
+	}
+	return nil
+}
+
+// LoginPage renders the provider chooser.
+func (h *AuthHandler) LoginPage(c fiber.Ctx) error {
+	return c.Render("login", MergeBranding(c, fiber.Map{
+		"Title":     "Sign In",
+		"Providers": h.cfg.OIDCProviders,
+	}, h.cfg))
+}
+
+// Login initiates the OIDC login flow with the provider named by the
+// "provider" query parameter. Without one, a single provider is used directly
+// and several send the user to the /login chooser.
 func (h *AuthHandler) Login(c fiber.Ctx) error {
+	name := c.Query("provider")
+	if name == "" && len(h.clients) == 1 {
+		name = h.clients[0].cfg.Name
+	}
+	client := h.clientByName(name)
+	if client == nil {
+		return c.Redirect().To("/login")
+	}
+
 	state := generateState()
 
 	sess := session.FromContext(c)
@@ -83,11 +153,12 @@ func (h *AuthHandler) Login(c fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "session not available")
 	}
 	sess.Set("oauth_state", state)
+	sess.Set("oauth_provider", client.cfg.Name)
 
 	verifier := oauth2.GenerateVerifier()
 	sess.Set("pkce_verifier", verifier)
 
-	url := h.oauth2Config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+	url := client.oauth2Config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
 	return c.Redirect().To(url)
 }
 
@@ -105,6 +176,14 @@ func (h *AuthHandler) Callback(c fiber.Ctx) error {
 	}
 	sess.Delete("oauth_state")
 
+	// The code can only be redeemed with the provider the login started at.
+	providerName, _ := sess.Get("oauth_provider").(string)
+	sess.Delete("oauth_provider")
+	client := h.clientByName(providerName)
+	if client == nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid state")
+	}
+
 	// Retrieve PKCE verifier
 	verifier, _ := sess.Get("pkce_verifier").(string)
 	sess.Delete("pkce_verifier")
@@ -114,7 +193,7 @@ func (h *AuthHandler) Callback(c fiber.Ctx) error {
 	if verifier != "" {
 		exchangeOpts = append(exchangeOpts, oauth2.VerifierOption(verifier))
 	}
-	oauth2Token, err := h.oauth2Config.Exchange(c.Context(), c.Query("code"), exchangeOpts...)
+	oauth2Token, err := client.oauth2Config.Exchange(c.Context(), c.Query("code"), exchangeOpts...)
 	if err != nil {
 		return h.recoverFromCallbackFailure(c, sess)
 	}
@@ -125,7 +204,7 @@ func (h *AuthHandler) Callback(c fiber.Ctx) error {
 		return h.recoverFromCallbackFailure(c, sess)
 	}
 
-	idToken, err := h.verifier.Verify(c.Context(), rawIDToken)
+	idToken, err := client.verifier.Verify(c.Context(), rawIDToken)
 	if err != nil {
 		return h.recoverFromCallbackFailure(c, sess)
 	}
@@ -136,14 +215,17 @@ func (h *AuthHandler) Callback(c fiber.Ctx) error {
 		return err
 	}
 
+	// The IdP session ID lets back-channel logout find this session.
+	oidcSessionID, _ := claimsMap["sid"].(string)
+
 	// Preserve groups from the ID token before merging userinfo claims.
 	// Many providers (Keycloak, Azure AD, etc.) include groups only in the
 	// ID token, not the userinfo endpoint response.
-	idTokenGroups := extractGroups(claimsMap, h.cfg.OIDCGroupsClaim)
+	idTokenGroups := identity.ExtractGroups(claimsMap, client.cfg.GroupsClaim)
 
 	// Also fetch userinfo endpoint to get additional claims (email, org, etc.)
 	// Some OIDC providers only include minimal claims in the ID token
-	userInfo, err := h.provider.UserInfo(c.Context(), oauth2.StaticTokenSource(oauth2Token))
+	userInfo, err := client.provider.UserInfo(c.Context(), oauth2.StaticTokenSource(oauth2Token))
 	if err == nil {
 		var userInfoClaims map[string]any
 		if err := userInfo.Claims(&userInfoClaims); err == nil {
@@ -161,74 +243,17 @@ func (h *AuthHandler) Callback(c fiber.Ctx) error {
 		log.Printf("OIDC claims received: %v", claimsMap)
 	}
 
-	// Extract standard claims
-	sub, _ := claimsMap["sub"].(string)
-	email, _ := claimsMap["email"].(string)
-	name, _ := claimsMap["name"].(string)
-	picture, _ := claimsMap["picture"].(string)
-	username, _ := claimsMap["preferred_username"].(string)
-
-	// Upsert user first
-	user := &models.User{
-		Sub:      sub,
-		Username: username,
-		Email:    email,
-		Name:     name,
-		Picture:  picture,
-	}
-	if err := h.db.UpsertUser(c.Context(), user); err != nil {
+	user, err := identity.SyncUser(c.Context(), h.db, client.cfg, claimsMap, idTokenGroups, h.cfg.IsDev())
+	if err != nil {
 		return err
 	}
-
-	// Handle organization claim if configured
-	if h.cfg.OIDCOrgClaim != "" {
-		if orgValue, ok := claimsMap[h.cfg.OIDCOrgClaim]; ok {
-			var orgSlug string
-			switch v := orgValue.(type) {
-			case string:
-				orgSlug = v
-			case []any:
-				// If it's an array, take the first value
-				if len(v) > 0 {
-					orgSlug, _ = v[0].(string)
-				}
-			}
-
-			if orgSlug != "" {
-				org, created, err := h.db.GetOrCreateOrganization(c.Context(), orgSlug)
-				if err == nil {
-					h.db.UpdateUserOrganization(c.Context(), user.ID, &org.ID)
-					user.OrganizationID = &org.ID
-
-					// New org + active group mapping → promote any existing users
-					// in this org who were previously mapped to moderator
-					if created && h.cfg.HasGroupRoleMapping() {
-						if promErr := h.db.PromoteOrgModerators(c.Context(), org.ID); promErr != nil {
-							log.Printf("Warning: failed to promote org moderators for new org %s: %v", orgSlug, promErr)
-						}
-					}
-				}
-			}
-		}
-	}
-
-	// Apply OIDC group-based role mapping when configured.
-	// Admin > moderator > user.  Moderator-mapped users become org_mod when they
-	// belong to an organisation, global_mod otherwise.
-	if h.cfg.HasGroupRoleMapping() {
-		groups := extractGroups(claimsMap, h.cfg.OIDCGroupsClaim)
-		// Fall back to ID token groups if the userinfo merge overwrote them
-		if len(groups) == 0 {
-			groups = idTokenGroups
-		}
-		if len(groups) == 0 && h.cfg.IsDev() {
-			log.Printf("Warning: OIDC group role mapping is configured but no groups found in claim '%s'", h.cfg.OIDCGroupsClaim)
-		}
-		mappedRole := resolveRoleFromGroups(groups, h.cfg)
-		finalRole := finalRoleFromMapped(mappedRole, user.OrganizationID != nil)
-		if err := h.db.UpdateUserRoleFromOIDC(c.Context(), user.ID, mappedRole, finalRole); err != nil {
-			log.Printf("Warning: failed to update role from OIDC groups for user %s: %v", sub, err)
-		}
+	if user.IsDeactivated() {
+		slog.Info("sign-in refused for deactivated user", "user_id", user.ID)
+		sess.Destroy()
+		return c.Status(fiber.StatusForbidden).Render("not_found", MergeBranding(c, fiber.Map{
+			"Title":  "Account Deactivated",
+			"Notice": "Your account has been deactivated. Contact an administrator if you need access again.",
+		}, h.cfg))
 	}
 
 	// Stamp last_login_at so admins can see recent sign-in activity on the
@@ -240,12 +265,38 @@ func (h *AuthHandler) Callback(c fiber.Ctx) error {
 	// Store session and regenerate ID to prevent session fixation.
 	// Stash the raw ID token so Logout can use it as the id_token_hint for
 	// RP-initiated logout against the OIDC provider.
-	sess.Set("user_sub", sub)
+	sess.Set("user_issuer", user.Issuer)
+	sess.Set("user_sub", user.Sub)
 	sess.Set("id_token", rawIDToken)
+	sess.Set(middleware.SessionTrackedKey, true)
 	if err := sess.Regenerate(); err != nil {
 		slog.Error("failed to regenerate session", "error", err)
 	}
 
+	// Keep the refresh token only when sessions are refreshed; without one
+	// the session ends after middleware.SessionLifetime.
+	var refreshToken string
+	if h.cfg.OIDCRefreshMins > 0 {
+		if refreshToken, err = h.sealRefreshToken(oauth2Token.RefreshToken); err != nil {
+			return err
+		}
+	}
+
+	// Register the session so it shows up on /profile and can be revoked
+	// remotely; the auth middleware rejects sessions that aren't registered.
+	if err := h.db.CreateUserSession(c.Context(), &models.UserSession{
+		UserID:        user.ID,
+		SessionHash:   db.HashSessionID(sess.ID()),
+		IPAddress:     c.IP(),
+		UserAgent:     truncate(c.Get(fiber.HeaderUserAgent), maxUserAgentLength),
+		ExpiresAt:     time.Now().Add(middleware.SessionLifetime),
+		OIDCSessionID: oidcSessionID,
+		RefreshToken:  refreshToken,
+	}); err != nil {
+		sess.Destroy()
+		return err
+	}
+
 	// Redirect to original URL if stored, otherwise home.
 	// Validate that the redirect is a safe relative path to prevent open redirects.
 	redirectURL := "/"
@@ -263,7 +314,7 @@ func (h *AuthHandler) Callback(c fiber.Ctx) error {
 // Reached from the auth middleware when the OIDC probe reports the issuer
 // is unreachable, so users aren't bounced to a dead login URL.
 func (h *AuthHandler) Unavailable(c fiber.Ctx) error {
-	return c.Status(fiber.StatusServiceUnavailable).Render("not_found", MergeBranding(fiber.Map{
+	return c.Status(fiber.StatusServiceUnavailable).Render("not_found", MergeBranding(c, fiber.Map{
 		"Title":  "Sign-in Unavailable",
 		"Notice": "Sign-in is temporarily unavailable. Global keywords still work — try /go/<keyword> directly. Please retry the sign-in in a few minutes.",
 	}, h.cfg))
@@ -271,19 +322,24 @@ func (h *AuthHandler) Unavailable(c fiber.Ctx) error {
 
 // Logout clears the user session and, when the provider supports it, performs
 // RP-initiated logout so the OIDC server forgets the user too. Falls back to a
-// local-only logout when the provider doesn't advertise end_session_endpoint
-// or the issuer is currently unreachable.
+// local-only logout when OIDC_RP_LOGOUT is disabled, the provider doesn't
+// advertise end_session_endpoint or the issuer is currently unreachable.
 func (h *AuthHandler) Logout(c fiber.Ctx) error {
 	sess := session.FromContext(c)
-	var idToken string
+	var idToken, issuer string
 	if sess != nil {
 		if t, ok := sess.Get("id_token").(string); ok {
 			idToken = t
 		}
+		issuer, _ = sess.Get("user_issuer").(string)
+		if err := h.db.RevokeUserSessionByHash(c.Context(), db.HashSessionID(sess.ID())); err != nil {
+			slog.Warn("failed to revoke session on logout", "error", err)
+		}
 		sess.Destroy()
 	}
 
-	if h.endSessionURL == "" || (h.oidcProbe != nil && !h.oidcProbe.IsHealthy()) {
+	client := h.clientByIssuer(issuer)
+	if !h.cfg.OIDCRPLogout || client == nil || client.endSessionURL == "" || (h.oidcProbe != nil && !h.oidcProbe.IsHealthy()) {
 		return c.Redirect().To("/")
 	}
 
@@ -291,10 +347,10 @@ func (h *AuthHandler) Logout(c fiber.Ctx) error {
 	if idToken != "" {
 		q.Set("id_token_hint", idToken)
 	}
-	q.Set("client_id", h.cfg.OIDCClientID)
+	q.Set("client_id", client.cfg.ClientID)
 	q.Set("post_logout_redirect_uri", h.cfg.BaseURL+"/")
 
-	return c.Redirect().To(h.endSessionURL + "?" + q.Encode())
+	return c.Redirect().To(client.endSessionURL + "?" + q.Encode())
 }
 
 // recoverFromCallbackFailure handles OIDC callback errors by attempting to
@@ -314,8 +370,8 @@ func (h *AuthHandler) recoverFromCallbackFailure(c fiber.Ctx, sess *session.Midd
 		if err == nil {
 			return c.Redirect().To(resolved.URL)
 		}
-		suggestions, _ := h.db.GetSimilarKeywords(c.Context(), keyword, nil, 5)
-		return c.Status(fiber.StatusNotFound).Render("not_found", MergeBranding(fiber.Map{
+		suggestions, _ := h.db.GetSimilarKeywords(c.Context(), keyword, nil, nil, 5)
+		return c.Status(fiber.StatusNotFound).Render("not_found", MergeBranding(c, fiber.Map{
 			"Title":       "Not Found",
 			"Keyword":     keyword,
 			"Suggestions": suggestions,
@@ -323,7 +379,7 @@ func (h *AuthHandler) recoverFromCallbackFailure(c fiber.Ctx, sess *session.Midd
 		}, h.cfg))
 	}
 
-	return c.Status(fiber.StatusBadGateway).Render("error", MergeBranding(fiber.Map{
+	return c.Status(fiber.StatusBadGateway).Render("error", MergeBranding(c, fiber.Map{
 		"Title":      "Authentication Failed",
 		"Message":    callbackFailureNotice,
 		"StatusCode": fiber.StatusBadGateway,
@@ -378,67 +434,3 @@ func isSafeRedirect(url string) bool {
 	}
 	return true
 }
-
-// extractGroups pulls a string slice out of a claims map value that may be
-// a []any (most providers) or a bare string.
-func extractGroups(claimsMap map[string]any, claimName string) []string {
-	val, ok := claimsMap[claimName]
-	if !ok {
-		return nil
-	}
-	switch v := val.(type) {
-	case []any:
-		groups := make([]string, 0, len(v))
-		for _, item := range v {
-			if s, ok := item.(string); ok {
-				groups = append(groups, s)
-			}
-		}
-		return groups
-	case string:
-		if v != "" {
-			return []string{v}
-		}
-	}
-	return nil
-}
-
-// resolveRoleFromGroups returns the highest role implied by the user's OIDC
-// groups: "admin", "moderator", or "user".  This is the intermediate value —
-// the final DB role is determined by finalRoleFromMapped.
-func resolveRoleFromGroups(groups []string, cfg *config.Config) string {
-	groupSet := make(map[string]struct{}, len(groups))
-	for _, g := range groups {
-		groupSet[g] = struct{}{}
-	}
-
-	for _, ag := range cfg.OIDCAdminGroups {
-		if _, ok := groupSet[ag]; ok {
-			return "admin"
-		}
-	}
-	for _, mg := range cfg.OIDCModeratorGroups {
-		if _, ok := groupSet[mg]; ok {
-			return "moderator"
-		}
-	}
-	return "user"
-}
-
-// finalRoleFromMapped converts the intermediate mapped role into the actual
-// role constant stored in the database.  Moderator-mapped users become org_mod
-// when they belong to an organisation (scoped to that org's keywords only) or
-// global_mod when they do not.
-func finalRoleFromMapped(mappedRole string, hasOrg bool) string {
-	switch mappedRole {
-	case "admin":
-		return models.RoleAdmin
-	case "moderator":
-		if hasOrg {
-			return models.RoleOrgMod
-		}
-		return models.RoleGlobalMod
-	default:
-		return models.RoleUser
-	}
-}
@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"html/template"
+	"log/slog"
 
 	"github.com/gofiber/fiber/v3"
 
 	"golinks/internal/config"
+	"golinks/internal/db"
+	"golinks/internal/middleware"
+	"golinks/internal/models"
 )
 
 // BrandingData contains site branding information for templates.
@@ -14,6 +18,7 @@ type BrandingData struct {
 	SiteTagline              string
 	SiteFooter               string
 	SiteLogoURL              string
+	AccentColor              string // replaces the brand color when set (per-organization only)
 	EnableAnimatedBackground bool
 	BannerText               string
 	BannerTextColor          string
@@ -34,20 +39,65 @@ func GetBrandingData(cfg *config.Config) BrandingData {
 	}
 }
 
-// MergeBranding adds branding data to a fiber.Map for template rendering.
-// Pass c.Path() as the currentPath argument to enable server-side nav active state.
-func MergeBranding(data fiber.Map, cfg *config.Config, currentPath ...string) fiber.Map {
+// brandingDB looks up organization branding for MergeBranding; nil until
+// SetBrandingDB is called, leaving everyone with the site-wide branding.
+var brandingDB *db.DB
+
+// SetBrandingDB enables per-organization branding in MergeBranding.
+func SetBrandingDB(database *db.DB) {
+	brandingDB = database
+}
+
+// viewerBranding returns the site-wide branding with the overrides of the
+// viewer's active organization (or those it inherits) applied.
+func viewerBranding(c fiber.Ctx, cfg *config.Config) BrandingData {
 	branding := GetBrandingData(cfg)
+	user, _ := c.Locals("user").(*models.User)
+	if brandingDB == nil || user == nil || user.OrganizationID == nil {
+		return branding
+	}
+
+	org, err := brandingDB.GetEffectiveOrgBranding(c.Context(), *user.OrganizationID)
+	if err != nil {
+		slog.Warn("failed to load organization branding", "organization_id", *user.OrganizationID, "error", err)
+		return branding
+	}
+	if org.SiteTitle != "" {
+		branding.SiteTitle = org.SiteTitle
+	}
+	if org.LogoURL != "" {
+		branding.SiteLogoURL = org.LogoURL
+	}
+	branding.AccentColor = org.AccentColor
+	if org.BannerText != "" {
+		branding.BannerText = org.BannerText
+	}
+	if org.BannerTextColor != "" {
+		branding.BannerTextColor = org.BannerTextColor
+	}
+	if org.BannerBGColor != "" {
+		branding.BannerBGColor = org.BannerBGColor
+	}
+	return branding
+}
+
+// MergeBranding adds branding data, the current path (for server-side nav
+// active state) and the request's CSP nonce to a fiber.Map for template
+// rendering. Signed-in members of an organization with its own branding see
+// that instead of the site-wide values. Inline <script> and <style> elements
+// must carry nonce="{{.CSPNonce}}".
+func MergeBranding(c fiber.Ctx, data fiber.Map, cfg *config.Config) fiber.Map {
+	branding := viewerBranding(c, cfg)
 	data["SiteTitle"] = branding.SiteTitle
 	data["SiteTagline"] = branding.SiteTagline
 	data["SiteFooter"] = template.HTML(branding.SiteFooter) // nolint:gosec — operator-configured value
 	data["SiteLogoURL"] = branding.SiteLogoURL
+	data["AccentColor"] = branding.AccentColor
 	data["EnableAnimatedBackground"] = branding.EnableAnimatedBackground
 	data["BannerText"] = branding.BannerText
 	data["BannerTextColor"] = branding.BannerTextColor
 	data["BannerBGColor"] = branding.BannerBGColor
-	if len(currentPath) > 0 {
-		data["CurrentPath"] = currentPath[0]
-	}
+	data["CurrentPath"] = c.Path()
+	data["CSPNonce"] = middleware.CSPNonce(c)
 	return data
 }
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"golinks/internal/metrics"
+)
+
+// maxCSPReportsPerRequest caps how many reports of a Reporting API batch are
+// processed, so one request can't flood the logs.
+const maxCSPReportsPerRequest = 20
+
+// maxCSPFieldLen truncates logged report fields.
+const maxCSPFieldLen = 256
+
+// cspDirectives are the directive names counted individually; anything else
+// is counted as "other" to keep the metric's cardinality bounded.
+var cspDirectives = map[string]bool{
+	"default-src": true, "script-src": true, "script-src-elem": true, "script-src-attr": true,
+	"style-src": true, "style-src-elem": true, "style-src-attr": true, "img-src": true,
+	"font-src": true, "connect-src": true, "object-src": true, "base-uri": true,
+	"frame-src": true, "frame-ancestors": true, "form-action": true, "media-src": true,
+	"worker-src": true, "manifest-src": true,
+}
+
+// cspViolation is a violation report normalized from either report format.
+type cspViolation struct {
+	DocumentURI string
+	Directive   string
+	BlockedURI  string
+	SourceFile  string
+	LineNumber  int
+	Disposition string
+	Sample      string
+}
+
+// legacyCSPReport is the report-uri body (application/csp-report).
+type legacyCSPReport struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		BlockedURI         string `json:"blocked-uri"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		Disposition        string `json:"disposition"`
+		ScriptSample       string `json:"script-sample"`
+	} `json:"csp-report"`
+}
+
+// reportingAPIReport is one entry of a Reporting API batch
+// (application/reports+json).
+type reportingAPIReport struct {
+	Type string `json:"type"`
+	Body struct {
+		DocumentURL        string `json:"documentURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		BlockedURL         string `json:"blockedURL"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		Disposition        string `json:"disposition"`
+		Sample             string `json:"sample"`
+	} `json:"body"`
+}
+
+// CSPReport collects Content-Security-Policy violation reports. Both the
+// legacy report-uri format and Reporting API batches are accepted; each
+// violation is logged and counted in golinks_csp_violations_total.
+func CSPReport(c fiber.Ctx) error {
+	violations, err := parseCSPReports(c.Body())
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid CSP report")
+	}
+
+	for _, v := range violations {
+		directive := v.Directive
+		if !cspDirectives[directive] {
+			directive = "other"
+		}
+		disposition := v.Disposition
+		if disposition != "enforce" && disposition != "report" {
+			disposition = "other"
+		}
+		metrics.CSPViolations.WithLabelValues(directive, disposition).Inc()
+
+		slog.WarnContext(c.Context(), "csp violation",
+			"directive", truncate(v.Directive, maxCSPFieldLen),
+			"disposition", disposition,
+			"document_uri", truncate(v.DocumentURI, maxCSPFieldLen),
+			"blocked_uri", truncate(v.BlockedURI, maxCSPFieldLen),
+			"source_file", truncate(v.SourceFile, maxCSPFieldLen),
+			"line", v.LineNumber,
+			"sample", truncate(v.Sample, maxCSPFieldLen),
+			"ip", c.IP(),
+		)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// parseCSPReports decodes a report body in either format.
+func parseCSPReports(body []byte) ([]cspViolation, error) {
+	body = []byte(strings.TrimSpace(string(body)))
+	if len(body) > 0 && body[0] == '[' {
+		var batch []reportingAPIReport
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		var violations []cspViolation
+		for _, r := range batch {
+			if r.Type != "csp-violation" {
+				continue
+			}
+			if len(violations) == maxCSPReportsPerRequest {
+				break
+			}
+			violations = append(violations, cspViolation{
+				DocumentURI: r.Body.DocumentURL,
+				Directive:   r.Body.EffectiveDirective,
+				BlockedURI:  r.Body.BlockedURL,
+				SourceFile:  r.Body.SourceFile,
+				LineNumber:  r.Body.LineNumber,
+				Disposition: r.Body.Disposition,
+				Sample:      r.Body.Sample,
+			})
+		}
+		return violations, nil
+	}
+
+	var legacy legacyCSPReport
+	if err := json.Unmarshal(body, &legacy); err != nil {
+		return nil, err
+	}
+	r := legacy.Report
+	directive := r.EffectiveDirective
+	if directive == "" {
+		// Older browsers only send violated-directive, which may include sources.
+		directive, _, _ = strings.Cut(r.ViolatedDirective, " ")
+	}
+	if directive == "" {
+		return nil, nil
+	}
+	return []cspViolation{{
+		DocumentURI: r.DocumentURI,
+		Directive:   directive,
+		BlockedURI:  r.BlockedURI,
+		SourceFile:  r.SourceFile,
+		LineNumber:  r.LineNumber,
+		Disposition: r.Disposition,
+		Sample:      r.ScriptSample,
+	}}, nil
+}
+
+// truncate shortens s to at most n bytes.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"golinks/internal/metrics"
+)
+
+func postCSPReport(t *testing.T, contentType, body string) int {
+	t.Helper()
+	app := fiber.New()
+	app.Post("/csp-report", CSPReport)
+
+	req := httptest.NewRequest("POST", "/csp-report", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("POST /csp-report error = %v", err)
+	}
+	return resp.StatusCode
+}
+
+func cspViolationCount(directive, disposition string) float64 {
+	return testutil.ToFloat64(metrics.CSPViolations.WithLabelValues(directive, disposition))
+}
+
+func TestCSPReport_LegacyFormat(t *testing.T) {
+	before := cspViolationCount("script-src-elem", "enforce")
+
+	status := postCSPReport(t, "application/csp-report", `{"csp-report":{
+		"document-uri":"https://links.example.com/",
+		"violated-directive":"script-src-elem",
+		"effective-directive":"script-src-elem",
+		"blocked-uri":"inline",
+		"disposition":"enforce"}}`)
+	if status != fiber.StatusNoContent {
+		t.Errorf("status = %d, want 204", status)
+	}
+	if got := cspViolationCount("script-src-elem", "enforce") - before; got != 1 {
+		t.Errorf("violations counted = %v, want 1", got)
+	}
+}
+
+func TestCSPReport_LegacyViolatedDirectiveOnly(t *testing.T) {
+	before := cspViolationCount("img-src", "report")
+
+	postCSPReport(t, "application/csp-report", `{"csp-report":{
+		"violated-directive":"img-src 'self' data:",
+		"blocked-uri":"https://cdn.example.com/logo.png",
+		"disposition":"report"}}`)
+	if got := cspViolationCount("img-src", "report") - before; got != 1 {
+		t.Errorf("violations counted = %v, want 1", got)
+	}
+}
+
+func TestCSPReport_ReportingAPIBatch(t *testing.T) {
+	beforeStyle := cspViolationCount("style-src-attr", "enforce")
+	beforeOther := cspViolationCount("other", "other")
+
+	status := postCSPReport(t, "application/reports+json", `[
+		{"type":"csp-violation","body":{"effectiveDirective":"style-src-attr","disposition":"enforce"}},
+		{"type":"csp-violation","body":{"effectiveDirective":"made-up-directive","disposition":"bogus"}},
+		{"type":"deprecation","body":{}}
+	]`)
+	if status != fiber.StatusNoContent {
+		t.Errorf("status = %d, want 204", status)
+	}
+	if got := cspViolationCount("style-src-attr", "enforce") - beforeStyle; got != 1 {
+		t.Errorf("style-src-attr violations = %v, want 1", got)
+	}
+	// Unknown directives and dispositions collapse into "other".
+	if got := cspViolationCount("other", "other") - beforeOther; got != 1 {
+		t.Errorf("other violations = %v, want 1", got)
+	}
+}
+
+func TestCSPReport_Malformed(t *testing.T) {
+	if status := postCSPReport(t, "application/csp-report", `{not json`); status != fiber.StatusBadRequest {
+		t.Errorf("status = %d, want 400", status)
+	}
+}
@@ -46,11 +46,11 @@ func (h *FallbackRedirectHandler) List(c fiber.Ctx) error {
 		data = append(data, orgWithFallbacks{Org: org, Fallbacks: fallbacks})
 	}
 
-	return c.Render("fallback_redirects", MergeBranding(fiber.Map{
+	return c.Render("fallback_redirects", MergeBranding(c, fiber.Map{
 		"User":             user,
 		"Orgs":             orgs,
 		"OrgWithFallbacks": data,
-	}, h.cfg, c.Path()))
+	}, h.cfg))
 }
 
 // Create creates a new fallback redirect option (admin only).
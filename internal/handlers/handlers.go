@@ -5,8 +5,11 @@ import (
 	"strconv"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
 
+	"golinks/internal/db"
 	"golinks/internal/email"
+	"golinks/internal/models"
 )
 
 // PageInfo holds computed pagination state for templates.
@@ -86,3 +89,19 @@ func htmxError(c fiber.Ctx, message string) error {
 		`<div class="p-3 rounded-lg bg-red-50 dark:bg-red-900/30 text-red-700 dark:text-red-300 text-sm">` + html.EscapeString(message) + `</div>`,
 	)
 }
+
+// viewerID returns the user ID link visibility is checked against: nil for
+// signed-out visitors.
+func viewerID(user *models.User) *uuid.UUID {
+	if user == nil {
+		return nil
+	}
+	return &user.ID
+}
+
+// isLinkOwner reports whether user co-owns link. Lookup errors count as not
+// owning it.
+func isLinkOwner(c fiber.Ctx, database *db.DB, user *models.User, link *models.Link) bool {
+	owned, err := database.IsLinkOwner(c.Context(), link.ID, user.ID)
+	return err == nil && owned
+}
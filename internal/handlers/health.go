@@ -63,7 +63,7 @@ func (h *HealthHandler) CheckLink(c fiber.Ctx) error {
 	}
 
 	// Check permissions
-	if !canManageLink(user, link) {
+	if !canManageLink(user, link, isLinkOwner(c, h.db, user, link)) {
 		return fiber.NewError(fiber.StatusForbidden, "you do not have permission to check this link")
 	}
 
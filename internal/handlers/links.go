@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -38,13 +39,13 @@ func NewLinkHandler(database *db.DB, cfg *config.Config) *LinkHandler {
 func (h *LinkHandler) Index(c fiber.Ctx) error {
 	user, _ := c.Locals("user").(*models.User)
 
-	data := MergeBranding(fiber.Map{
+	data := MergeBranding(c, fiber.Map{
 		"User":                 user,
 		"EnableRandomKeywords": h.cfg.EnableRandomKeywords,
 		"EnablePersonalLinks":  h.cfg.EnablePersonalLinks,
 		"EnableOrgLinks":       h.cfg.EnableOrgLinks,
 		"IsSimpleMode":         h.cfg.IsSimpleMode(),
-	}, h.cfg, c.Path())
+	}, h.cfg)
 
 	// Fetch top used, newest, and random keywords
 	var orgID *uuid.UUID
@@ -53,7 +54,7 @@ func (h *LinkHandler) Index(c fiber.Ctx) error {
 	}
 
 	// Top used links (global/org only, no personal) with 24h sparkline data
-	topUsed, err := h.db.GetTopApprovedLinks(c.Context(), orgID, 5)
+	topUsed, err := h.db.GetTopApprovedLinks(c.Context(), viewerID(user), orgID, 5)
 	if err == nil {
 		// Fetch all click histories in a single batch query
 		ids := make([]uuid.UUID, len(topUsed))
@@ -78,13 +79,13 @@ func (h *LinkHandler) Index(c fiber.Ctx) error {
 	}
 
 	// Newest links
-	newestLinks, err := h.db.GetNewestApprovedLinks(c.Context(), orgID, 5)
+	newestLinks, err := h.db.GetNewestApprovedLinks(c.Context(), viewerID(user), orgID, 5)
 	if err == nil {
 		data["NewestLinks"] = newestLinks
 	}
 
 	// Discover section always shows 5 random keywords; EnableRandomKeywords only gates the /random endpoint and button.
-	randomLinks, err := h.db.GetRandomApprovedLinks(c.Context(), orgID, 5)
+	randomLinks, err := h.db.GetRandomApprovedLinks(c.Context(), viewerID(user), orgID, 5)
 	if err == nil {
 		data["RandomLinks"] = randomLinks
 	}
@@ -107,17 +108,17 @@ func (h *LinkHandler) Search(c fiber.Ctx) error {
 	if user != nil && h.cfg.EnablePersonalLinks {
 		links, err = h.db.SearchLinksForUser(c.Context(), query, user.ID, orgID, 50)
 	} else {
-		links, err = h.db.SearchApprovedLinks(c.Context(), query, orgID, "all", 50, 0)
+		links, err = h.db.SearchApprovedLinks(c.Context(), query, viewerID(user), orgID, "all", 50, 0)
 	}
 	if err != nil {
 		return err
 	}
 
-	return c.Render("search", MergeBranding(fiber.Map{
+	return c.Render("search", MergeBranding(c, fiber.Map{
 		"Links": links,
 		"Query": query,
 		"User":  user,
-	}, h.cfg, c.Path()))
+	}, h.cfg))
 }
 
 // Suggest returns autocomplete suggestions for HTMX.
@@ -138,7 +139,7 @@ func (h *LinkHandler) Suggest(c fiber.Ctx) error {
 	if user != nil && h.cfg.EnablePersonalLinks {
 		links, err = h.db.SearchLinksForUser(c.Context(), query, user.ID, orgID, 5)
 	} else {
-		links, err = h.db.SearchApprovedLinks(c.Context(), query, orgID, "all", 5, 0)
+		links, err = h.db.SearchApprovedLinks(c.Context(), query, viewerID(user), orgID, "all", 5, 0)
 	}
 	if err != nil {
 		return err
@@ -200,11 +201,11 @@ func (h *LinkHandler) Browse(c fiber.Ctx) error {
 			return err
 		}
 	} else {
-		links, err = h.db.SearchApprovedLinks(c.Context(), query, orgID, scope, perPage, offset)
+		links, err = h.db.SearchApprovedLinks(c.Context(), query, viewerID(user), orgID, scope, perPage, offset)
 		if err != nil {
 			return err
 		}
-		total, err = h.db.CountApprovedLinks(c.Context(), query, orgID, scope)
+		total, err = h.db.CountApprovedLinks(c.Context(), query, viewerID(user), orgID, scope)
 		if err != nil {
 			return err
 		}
@@ -217,11 +218,23 @@ func (h *LinkHandler) Browse(c fiber.Ctx) error {
 		}
 	}
 
+	// Org links can come from the organizations the user's is nested in;
+	// those are labeled with the ancestor they are inherited from.
+	inheritedFrom := make(map[string]string)
+	if orgID != nil {
+		if ancestors, err := h.db.GetOrganizationAncestors(c.Context(), *orgID); err == nil {
+			for _, org := range ancestors {
+				inheritedFrom[org.ID.String()] = org.Name
+			}
+		}
+	}
+
 	pag := buildPagination(page, perPage, total)
 	data := fiber.Map{
 		"Links":               links,
 		"User":                user,
 		"OrgNames":            orgNames,
+		"InheritedFrom":       inheritedFrom,
 		"Query":               query,
 		"ScopeFilter":         scope,
 		"EnableOrgLinks":      h.cfg.EnableOrgLinks,
@@ -233,7 +246,7 @@ func (h *LinkHandler) Browse(c fiber.Ctx) error {
 		return c.Render("partials/links_list", data, "")
 	}
 
-	return c.Render("browse", MergeBranding(data, h.cfg, c.Path()))
+	return c.Render("browse", MergeBranding(c, data, h.cfg))
 }
 
 // New renders the create link form.
@@ -262,7 +275,7 @@ func (h *LinkHandler) New(c fiber.Ctx) error {
 		}
 	}
 
-	return c.Render("new", MergeBranding(data, h.cfg, c.Path()))
+	return c.Render("new", MergeBranding(c, data, h.cfg))
 }
 
 // splitKeywords splits a comma-separated keyword string into normalized, unique keywords.
@@ -425,6 +438,16 @@ func (h *LinkHandler) saveLinkForKeyword(c fiber.Ctx, user *models.User, keyword
 			}
 			orgID = user.OrganizationID
 		}
+		settings, err := h.db.GetOrgSettings(c.Context(), *orgID)
+		if err != nil {
+			if errors.Is(err, db.ErrOrgNotFound) {
+				return "organization not found"
+			}
+			return err.Error()
+		}
+		if ok, msg := settings.ValidateLink(keyword, url); !ok {
+			return msg
+		}
 		link := &models.Link{
 			Keyword:        keyword,
 			URL:            url,
@@ -433,7 +456,10 @@ func (h *LinkHandler) saveLinkForKeyword(c fiber.Ctx, user *models.User, keyword
 			OrganizationID: orgID,
 			Reason:         reason,
 		}
-		if user.IsAdmin() || user.CanModerateOrg(*orgID) {
+		if err := formVisibility(c, link); err != nil {
+			return err.Error()
+		}
+		if user.IsAdmin() || user.CanModerateOrg(*orgID) || !settings.RequireModeration {
 			link.CreatedBy = &user.ID
 			link.Status = models.StatusApproved
 			if err := h.db.CreateLink(c.Context(), link); err != nil {
@@ -458,7 +484,7 @@ func (h *LinkHandler) saveLinkForKeyword(c fiber.Ctx, user *models.User, keyword
 			}
 			if link.OrganizationID != nil {
 				if modIDs, err := h.db.GetOrgModeratorIDs(c.Context(), *link.OrganizationID); err == nil {
-					h.fanOutSubmissionNotifications(c, modIDs, link)
+					fanOutSubmissionNotifications(c, h.db, modIDs, link)
 				}
 			}
 		}
@@ -471,6 +497,9 @@ func (h *LinkHandler) saveLinkForKeyword(c fiber.Ctx, user *models.User, keyword
 			Scope:       models.ScopeGlobal,
 			Reason:      reason,
 		}
+		if err := formVisibility(c, link); err != nil {
+			return err.Error()
+		}
 		if user.IsGlobalMod() {
 			link.CreatedBy = &user.ID
 			link.Status = models.StatusApproved
@@ -495,7 +524,7 @@ func (h *LinkHandler) saveLinkForKeyword(c fiber.Ctx, user *models.User, keyword
 				go Notifier.NotifyModeratorsLinkSubmitted(c.Context(), link, user)
 			}
 			if modIDs, err := h.db.GetGlobalModeratorIDs(c.Context()); err == nil {
-				h.fanOutSubmissionNotifications(c, modIDs, link)
+				fanOutSubmissionNotifications(c, h.db, modIDs, link)
 			}
 		}
 		return ""
@@ -504,6 +533,16 @@ func (h *LinkHandler) saveLinkForKeyword(c fiber.Ctx, user *models.User, keyword
 	}
 }
 
+// formVisibility sets who can see a global or org link from the form's
+// visibility, allowed_groups and allowed_users fields. The lists take one
+// entry per line or comma-separated.
+func formVisibility(c fiber.Ctx, link *models.Link) error {
+	link.Visibility = c.FormValue("visibility")
+	link.AllowedGroups = splitList(c.FormValue("allowed_groups"))
+	link.AllowedUsers = splitList(c.FormValue("allowed_users"))
+	return link.NormalizeVisibility()
+}
+
 // createPersonalLink creates a personal link (user_links table).
 func (h *LinkHandler) createPersonalLink(c fiber.Ctx, user *models.User, keyword, url, description string) error {
 	userLink := &models.UserLink{
@@ -551,6 +590,19 @@ func (h *LinkHandler) createOrgLink(c fiber.Ctx, user *models.User, keyword, url
 		orgID = user.OrganizationID
 	}
 
+	// The organization's settings restrict keywords and destinations for
+	// everyone, moderators included
+	settings, err := h.db.GetOrgSettings(c.Context(), *orgID)
+	if err != nil {
+		if errors.Is(err, db.ErrOrgNotFound) {
+			return htmxError(c, "Organization not found")
+		}
+		return err
+	}
+	if ok, msg := settings.ValidateLink(keyword, url); !ok {
+		return htmxError(c, msg)
+	}
+
 	link := &models.Link{
 		Keyword:        keyword,
 		URL:            url,
@@ -559,9 +611,13 @@ func (h *LinkHandler) createOrgLink(c fiber.Ctx, user *models.User, keyword, url
 		OrganizationID: orgID,
 		Reason:         reason,
 	}
+	if err := formVisibility(c, link); err != nil {
+		return htmxError(c, err.Error())
+	}
 
-	// Admins and org mods can create links directly, others need approval
-	if user.IsAdmin() || user.CanModerateOrg(*orgID) {
+	// Admins and org mods can create links directly, as can everyone in
+	// organizations that don't require moderation; others need approval
+	if user.IsAdmin() || user.CanModerateOrg(*orgID) || !settings.RequireModeration {
 		link.CreatedBy = &user.ID
 		link.Status = models.StatusApproved
 		if err := h.db.CreateLink(c.Context(), link); err != nil {
@@ -595,7 +651,7 @@ func (h *LinkHandler) createOrgLink(c fiber.Ctx, user *models.User, keyword, url
 
 	// Fan out in-app notifications to org moderators
 	if modIDs, err := h.db.GetOrgModeratorIDs(c.Context(), *orgID); err == nil {
-		h.fanOutSubmissionNotifications(c, modIDs, link)
+		fanOutSubmissionNotifications(c, h.db, modIDs, link)
 	}
 
 	return c.Render("partials/form_success", fiber.Map{
@@ -614,6 +670,9 @@ func (h *LinkHandler) createGlobalLink(c fiber.Ctx, user *models.User, keyword,
 		Scope:       models.ScopeGlobal,
 		Reason:      reason,
 	}
+	if err := formVisibility(c, link); err != nil {
+		return htmxError(c, err.Error())
+	}
 
 	// Global mods can create links directly, others need approval
 	if user.IsGlobalMod() {
@@ -650,7 +709,7 @@ func (h *LinkHandler) createGlobalLink(c fiber.Ctx, user *models.User, keyword,
 
 	// Fan out in-app notifications to global moderators
 	if modIDs, err := h.db.GetGlobalModeratorIDs(c.Context()); err == nil {
-		h.fanOutSubmissionNotifications(c, modIDs, link)
+		fanOutSubmissionNotifications(c, h.db, modIDs, link)
 	}
 
 	return c.Render("partials/form_success", fiber.Map{
@@ -817,6 +876,13 @@ func (h *LinkHandler) SubmitSuggestEdit(c fiber.Ctx) error {
 		} else if linkCopy.Scope == models.ScopeOrg && linkCopy.OrganizationID != nil {
 			modIDs, _ = h.db.GetOrgModeratorIDs(ctx, *linkCopy.OrganizationID)
 		}
+		// Co-owners review suggestions for their link too.
+		ownerIDs, _ := h.db.GetLinkOwnerUserIDs(ctx, linkCopy.ID)
+		for _, id := range ownerIDs {
+			if !slices.Contains(modIDs, id) {
+				modIDs = append(modIDs, id)
+			}
+		}
 		h.fanOutEditSuggestionNotifications(ctx, modIDs, linkCopy, userCopy)
 		if Notifier != nil {
 			Notifier.NotifyModeratorsEditSuggested(ctx, linkCopy, userCopy, newURL, newDescription, reason)
@@ -874,14 +940,14 @@ func (h *LinkHandler) CheckKeyword(c fiber.Ctx) error {
 	case "org":
 		// Check org links
 		if user != nil && user.OrganizationID != nil {
-			_, err := h.db.GetApprovedOrgLinkByKeyword(c.Context(), keyword, *user.OrganizationID)
-			exists = err == nil
+			link, err := h.db.GetApprovedOrgLinkByKeyword(c.Context(), keyword, *user.OrganizationID)
+			exists = err == nil && h.linkVisibleTo(c, link, user)
 			conflictType = "organization"
 		}
 	case "global":
 		// Check global links
-		_, err := h.db.GetApprovedGlobalLinkByKeyword(c.Context(), keyword)
-		exists = err == nil
+		link, err := h.db.GetApprovedGlobalLinkByKeyword(c.Context(), keyword)
+		exists = err == nil && h.linkVisibleTo(c, link, user)
 		conflictType = "global"
 	}
 
@@ -897,6 +963,14 @@ func (h *LinkHandler) CheckKeyword(c fiber.Ctx) error {
 	return c.SendString("")
 }
 
+// linkVisibleTo reports whether the user (nil when signed out) may learn that
+// link exists. Hidden links are treated as missing so their keywords don't
+// leak; creating one still fails as a duplicate.
+func (h *LinkHandler) linkVisibleTo(c fiber.Ctx, link *models.Link, user *models.User) bool {
+	visible, err := h.db.LinkVisibleTo(c.Context(), link.ID, viewerID(user))
+	return err == nil && visible
+}
+
 // fanOutEditSuggestionNotifications creates in-app bell notifications for moderators when an edit is suggested.
 func (h *LinkHandler) fanOutEditSuggestionNotifications(ctx context.Context, modIDs []uuid.UUID, link *models.Link, requester *models.User) {
 	if len(modIDs) == 0 {
@@ -917,7 +991,7 @@ func (h *LinkHandler) fanOutEditSuggestionNotifications(ctx context.Context, mod
 }
 
 // fanOutSubmissionNotifications creates in-app notifications for a list of moderators.
-func (h *LinkHandler) fanOutSubmissionNotifications(c fiber.Ctx, modIDs []uuid.UUID, link *models.Link) {
+func fanOutSubmissionNotifications(c fiber.Ctx, database *db.DB, modIDs []uuid.UUID, link *models.Link) {
 	if len(modIDs) == 0 {
 		return
 	}
@@ -932,5 +1006,5 @@ func (h *LinkHandler) fanOutSubmissionNotifications(c fiber.Ctx, modIDs []uuid.U
 			LinkID:    &link.ID,
 		})
 	}
-	_ = h.db.CreateNotifications(c.Context(), ns)
+	_ = database.CreateNotifications(c.Context(), ns)
 }
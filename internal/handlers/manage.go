@@ -3,6 +3,8 @@ package handlers
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"strings"
 
 	"github.com/gofiber/fiber/v3"
@@ -89,6 +91,10 @@ func (h *ManageHandler) Index(c fiber.Ctx) error {
 	if pendingEdits == nil {
 		pendingEdits = make(map[string]bool)
 	}
+	owned, _ := h.db.GetOwnedLinkIDs(c.Context(), user.ID, linkIDs)
+	if owned == nil {
+		owned = make(map[string]bool)
+	}
 
 	data := fiber.Map{
 		"Links":        links,
@@ -100,6 +106,7 @@ func (h *ManageHandler) Index(c fiber.Ctx) error {
 		"OrgColors":    orgColors,
 		"IsModerator":  isModerator,
 		"PendingEdits": pendingEdits,
+		"Owned":        owned,
 		"Pagination":   buildPagination(page, perPage, total),
 	}
 
@@ -107,7 +114,7 @@ func (h *ManageHandler) Index(c fiber.Ctx) error {
 		return c.Render("partials/manage_links_list", data, "")
 	}
 
-	return c.Render("manage", MergeBranding(data, h.cfg, c.Path()))
+	return c.Render("manage", MergeBranding(c, data, h.cfg))
 }
 
 // Edit renders the inline edit form for a link.
@@ -132,28 +139,76 @@ func (h *ManageHandler) Edit(c fiber.Ctx) error {
 	}
 
 	// Check permissions
-	if !canManageLink(user, link) {
+	owner := isLinkOwner(c, h.db, user, link)
+	if !canManageLink(user, link, owner) {
 		return fiber.NewError(fiber.StatusForbidden, "you do not have permission to manage this link")
 	}
 
-	return c.Render("partials/manage_edit_form", fiber.Map{
+	data, err := h.editFormData(c, user, link, owner)
+	if err != nil {
+		return err
+	}
+	return c.Render("partials/manage_edit_form", data, "")
+}
+
+// editFormData returns the template data for the inline edit form. Owners
+// and moderators of the link also get its owners and their recent changes.
+func (h *ManageHandler) editFormData(c fiber.Ctx, user *models.User, link *models.Link, owner bool) (fiber.Map, error) {
+	data := fiber.Map{
 		"Link":        link,
 		"User":        user,
 		"IsModerator": user.IsOrgMod(),
-	}, "")
+		"IsOwner":     owner,
+	}
+	if link.Status != models.StatusApproved || (!owner && !canModerate(user, link)) {
+		return data, nil
+	}
+	owners, err := h.db.GetLinkOwners(c.Context(), link.ID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := h.db.GetLinkOwnerEvents(c.Context(), link.ID, 10)
+	if err != nil {
+		return nil, err
+	}
+	data["CanChangeOwners"] = true
+	data["Owners"] = owners
+	data["OwnerEvents"] = events
+
+	if h.cfg.EnablePersonalLinks && canModerate(user, link) {
+		emails, err := h.db.GetLinkInterestedUserEmails(c.Context(), link.ID)
+		if err != nil {
+			return nil, err
+		}
+		data["CanRetire"] = true
+		data["RetireEmails"] = emails
+	}
+	return data, nil
+}
+
+// orgLinkRuleViolation checks a new URL for link against its organization's
+// settings, returning the broken rule's message, or "" when the URL is allowed
+// or link isn't an org link. The settings bind everyone who edits the link,
+// moderators and co-owners included, as they do on create.
+func orgLinkRuleViolation(ctx context.Context, database *db.DB, link *models.Link, url string) (string, error) {
+	if link.Scope != models.ScopeOrg || link.OrganizationID == nil {
+		return "", nil
+	}
+	settings, err := database.GetOrgSettings(ctx, *link.OrganizationID)
+	if err != nil {
+		return "", err
+	}
+	_, msg := settings.ValidateLink(link.Keyword, url)
+	return msg, nil
 }
 
-// Update saves changes to a link (moderators only — direct edit).
+// Update saves changes to a link (moderators and co-owners only — direct edit).
 func (h *ManageHandler) Update(c fiber.Ctx) error {
 	user, ok := c.Locals("user").(*models.User)
 	if !ok {
 		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
 	}
 
-	if !user.IsOrgMod() {
-		return fiber.NewError(fiber.StatusForbidden, "you do not have management permissions")
-	}
-
 	idStr := c.Params("id")
 	linkID, err := uuid.Parse(idStr)
 	if err != nil {
@@ -169,7 +224,11 @@ func (h *ManageHandler) Update(c fiber.Ctx) error {
 	}
 
 	// Check permissions
-	if !canManageLink(user, link) {
+	owner := isLinkOwner(c, h.db, user, link)
+	if !user.IsOrgMod() && !owner {
+		return fiber.NewError(fiber.StatusForbidden, "you do not have management permissions")
+	}
+	if !canManageLink(user, link, owner) {
 		return fiber.NewError(fiber.StatusForbidden, "you do not have permission to manage this link")
 	}
 
@@ -186,6 +245,16 @@ func (h *ManageHandler) Update(c fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, msg)
 	}
 
+	if err := formVisibility(c, link); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if msg, err := orgLinkRuleViolation(c.Context(), h.db, link, newURL); err != nil {
+		return err
+	} else if msg != "" {
+		return fiber.NewError(fiber.StatusBadRequest, msg)
+	}
+
 	// Update link
 	link.URL = newURL
 	link.Description = newDescription
@@ -194,6 +263,9 @@ func (h *ManageHandler) Update(c fiber.Ctx) error {
 	if err := h.db.UpdateLinkAndResetHealth(c.Context(), link); err != nil {
 		return err
 	}
+	if err := h.db.UpdateLinkVisibility(c.Context(), link); err != nil {
+		return err
+	}
 
 	orgNames, orgColors := h.buildOrgMaps(c.Context())
 
@@ -202,7 +274,8 @@ func (h *ManageHandler) Update(c fiber.Ctx) error {
 		"User":        user,
 		"OrgNames":    orgNames,
 		"OrgColors":   orgColors,
-		"IsModerator": true,
+		"IsModerator": user.IsOrgMod(),
+		"IsOwner":     owner,
 	}, "")
 }
 
@@ -227,7 +300,7 @@ func (h *ManageHandler) RequestEdit(c fiber.Ctx) error {
 		return err
 	}
 
-	if !canManageLink(user, link) {
+	if !canManageLink(user, link, isLinkOwner(c, h.db, user, link)) {
 		return htmxError(c, "You do not have permission to edit this link")
 	}
 
@@ -296,7 +369,7 @@ func (h *ManageHandler) RequestDeletion(c fiber.Ctx) error {
 		return err
 	}
 
-	if !canManageLink(user, link) {
+	if !canManageLink(user, link, isLinkOwner(c, h.db, user, link)) {
 		return htmxError(c, "You do not have permission to manage this link")
 	}
 
@@ -335,8 +408,156 @@ func (h *ManageHandler) RequestDeletion(c fiber.Ctx) error {
 	}, "")
 }
 
-// canManageLink checks if a user can manage a specific link.
-func canManageLink(user *models.User, link *models.Link) bool {
+// UpdateOwners replaces the co-owners of an approved link from the owner_users
+// (emails), owner_groups and owner_orgs (slugs) form values and re-renders
+// the owners section of the edit form. Moderators of the link and its current
+// owners can change them; replacing the list is how ownership is transferred.
+func (h *ManageHandler) UpdateOwners(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	linkID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return htmxError(c, "Invalid link ID")
+	}
+
+	link, err := h.db.GetLinkByID(c.Context(), linkID)
+	if err != nil {
+		if errors.Is(err, db.ErrLinkNotFound) {
+			return htmxError(c, "Link not found")
+		}
+		return err
+	}
+
+	owner := isLinkOwner(c, h.db, user, link)
+	if !owner && !canModerate(user, link) {
+		return htmxError(c, "You do not have permission to change the owners of this link")
+	}
+	if link.Status != models.StatusApproved {
+		return htmxError(c, "Only approved links have owners")
+	}
+
+	owners, err := h.db.ResolveLinkOwners(c.Context(),
+		splitList(c.FormValue("owner_users")),
+		splitList(c.FormValue("owner_groups")),
+		splitList(c.FormValue("owner_orgs")))
+	if errors.Is(err, db.ErrOwnerNotFound) {
+		data, dataErr := h.editFormData(c, user, link, owner)
+		if dataErr != nil {
+			return dataErr
+		}
+		data["OwnersError"] = err.Error()
+		return c.Render("partials/link_owners", data, "")
+	}
+	if err != nil {
+		return err
+	}
+
+	events, err := h.db.SetLinkOwners(c.Context(), link.ID, user.ID, owners)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		slog.InfoContext(c.Context(), "link owner changed", "link_id", link.ID, "keyword", link.Keyword,
+			"actor_id", user.ID, "action", e.Action, "owner_kind", e.OwnerKind, "owner", e.OwnerName)
+	}
+
+	// The change may have handed the link to someone else.
+	data, err := h.editFormData(c, user, link, isLinkOwner(c, h.db, user, link))
+	if err != nil {
+		return err
+	}
+	if data["CanChangeOwners"] == true {
+		data["OwnersMessage"] = "Owners updated"
+	} else {
+		data["OwnersMessage"] = "Owners updated. You no longer own this link."
+	}
+	return c.Render("partials/link_owners", data, "")
+}
+
+// Retire demotes an approved org or global link into personal links for the
+// users listed by email (moderators only), then deletes the link. Users who
+// already have a personal link for the keyword keep theirs.
+func (h *ManageHandler) Retire(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	linkID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return htmxError(c, "Invalid link ID")
+	}
+
+	link, err := h.db.GetLinkByID(c.Context(), linkID)
+	if err != nil {
+		if errors.Is(err, db.ErrLinkNotFound) {
+			return htmxError(c, "Link not found")
+		}
+		return err
+	}
+
+	if !h.cfg.EnablePersonalLinks {
+		return htmxError(c, "Personal links are not enabled")
+	}
+	if !canModerate(user, link) {
+		return htmxError(c, "You do not have permission to retire this link")
+	}
+	if link.Status != models.StatusApproved {
+		return htmxError(c, "Only approved links can be retired")
+	}
+
+	userIDs, err := h.db.ResolveUserEmails(c.Context(), splitList(c.FormValue("retire_users")))
+	if errors.Is(err, db.ErrUserNotFound) {
+		return htmxError(c, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		return htmxError(c, "List at least one user to receive the link, or delete it instead")
+	}
+
+	created, err := h.db.RetireLink(c.Context(), link.ID, userIDs)
+	if err != nil {
+		if errors.Is(err, db.ErrLinkNotFound) {
+			return htmxError(c, "Link not found or no longer approved")
+		}
+		return err
+	}
+	notifyLinkRetired(c, h.db, link, created)
+	slog.InfoContext(c.Context(), "link retired to personal links", "link_id", link.ID, "keyword", link.Keyword,
+		"actor_id", user.ID, "personal_links", len(created))
+
+	return c.Render("partials/form_success", fiber.Map{
+		"Message": fmt.Sprintf("Retired go/%s into %d personal links.", link.Keyword, len(created)),
+	}, "")
+}
+
+// notifyLinkRetired tells the users who received a personal copy of a retired
+// link.
+func notifyLinkRetired(c fiber.Ctx, database *db.DB, link *models.Link, userIDs []uuid.UUID) {
+	if len(userIDs) == 0 {
+		return
+	}
+	ns := make([]models.Notification, 0, len(userIDs))
+	for _, id := range userIDs {
+		ns = append(ns, models.Notification{
+			UserID:    id,
+			Type:      models.NotifTypeLinkRetired,
+			Title:     "Link moved to your personal links",
+			Body:      fmt.Sprintf(`"%s" was retired and is now one of your personal links`, link.Keyword),
+			ActionURL: "/my-links",
+		})
+	}
+	_ = database.CreateNotifications(c.Context(), ns)
+}
+
+// canManageLink checks if a user can manage a specific link. owner is whether
+// the user co-owns it (see isLinkOwner).
+func canManageLink(user *models.User, link *models.Link, owner bool) bool {
 	// Admins can manage anything
 	if user.IsAdmin() {
 		return true
@@ -359,10 +580,9 @@ func canManageLink(user *models.User, link *models.Link) bool {
 		}
 	}
 
-	// Users can manage links they authored
+	// Users can manage links they authored or co-own
 	if link.CreatedBy != nil && *link.CreatedBy == user.ID {
 		return true
 	}
-
-	return false
+	return owner
 }
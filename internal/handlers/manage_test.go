@@ -18,6 +18,7 @@ func TestCanManageLink(t *testing.T) {
 		name     string
 		user     *models.User
 		link     *models.Link
+		owner    bool
 		expected bool
 	}{
 		{
@@ -86,11 +87,25 @@ func TestCanManageLink(t *testing.T) {
 			link:     &models.Link{Scope: models.ScopeOrg, OrganizationID: &orgID},
 			expected: false,
 		},
+		{
+			name:     "co-owner can manage global link",
+			user:     &models.User{ID: userID, Role: models.RoleUser},
+			link:     &models.Link{Scope: models.ScopeGlobal, CreatedBy: &otherUserID},
+			owner:    true,
+			expected: true,
+		},
+		{
+			name:     "co-owner can manage link in another org",
+			user:     &models.User{ID: userID, Role: models.RoleOrgMod, OrganizationID: &otherOrgID},
+			link:     &models.Link{Scope: models.ScopeOrg, OrganizationID: &orgID},
+			owner:    true,
+			expected: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := canManageLink(tt.user, tt.link); got != tt.expected {
+			if got := canManageLink(tt.user, tt.link, tt.owner); got != tt.expected {
 				t.Errorf("canManageLink() = %v, want %v", got, tt.expected)
 			}
 		})
@@ -3,6 +3,8 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
@@ -32,9 +34,17 @@ func (h *ModerationHandler) Index(c fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
 	}
 
-	// Check if user has moderation permissions
+	// Link co-owners without moderation permissions review edit suggestions
+	// for the links they own.
 	if !user.IsOrgMod() {
-		return fiber.NewError(fiber.StatusForbidden, "you do not have moderation permissions")
+		editRequests, err := h.db.GetPendingEditRequests(c.Context(), user)
+		if err != nil {
+			return err
+		}
+		return c.Render("moderation", MergeBranding(c, fiber.Map{
+			"User":         user,
+			"EditRequests": editRequests,
+		}, h.cfg))
 	}
 
 	var globalPending, orgPending []models.Link
@@ -69,6 +79,16 @@ func (h *ModerationHandler) Index(c fiber.Ctx) error {
 		return err
 	}
 
+	// Usage evidence for links proposed from personal links
+	pendingIDs := make([]uuid.UUID, 0, len(globalPending)+len(orgPending))
+	for _, l := range slices.Concat(globalPending, orgPending) {
+		pendingIDs = append(pendingIDs, l.ID)
+	}
+	promotions, err := h.db.GetLinkPromotions(c.Context(), pendingIDs)
+	if err != nil {
+		return err
+	}
+
 	// Build a map of org IDs to names for the template
 	orgNames := make(map[string]string)
 	if len(orgPending) > 0 || len(deletionRequests) > 0 {
@@ -80,14 +100,15 @@ func (h *ModerationHandler) Index(c fiber.Ctx) error {
 		}
 	}
 
-	return c.Render("moderation", MergeBranding(fiber.Map{
+	return c.Render("moderation", MergeBranding(c, fiber.Map{
 		"User":             user,
 		"GlobalPending":    globalPending,
 		"OrgPending":       orgPending,
 		"DeletionRequests": deletionRequests,
 		"EditRequests":     editRequests,
 		"OrgNames":         orgNames,
-	}, h.cfg, c.Path()))
+		"Promotions":       promotions,
+	}, h.cfg))
 }
 
 // Approve approves a pending link.
@@ -124,6 +145,11 @@ func (h *ModerationHandler) Approve(c fiber.Ctx) error {
 		return err
 	}
 
+	// A link proposed from a personal link takes over its click history
+	if _, err := h.db.ApplyLinkPromotion(c.Context(), link.ID); err != nil {
+		slog.ErrorContext(c.Context(), "failed to apply link promotion", "link_id", link.ID, "error", err)
+	}
+
 	// Remove pending-review notifications from all moderators' feeds
 	_ = h.db.DeleteNotificationsForLink(c.Context(), link.ID, models.NotifTypeLinkSubmitted)
 
@@ -296,10 +322,6 @@ func (h *ModerationHandler) ApproveEdit(c fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
 	}
 
-	if !user.IsOrgMod() {
-		return fiber.NewError(fiber.StatusForbidden, "you do not have moderation permissions")
-	}
-
 	idStr := c.Params("id")
 	reqID, err := uuid.Parse(idStr)
 	if err != nil {
@@ -314,6 +336,27 @@ func (h *ModerationHandler) ApproveEdit(c fiber.Ctx) error {
 		return err
 	}
 
+	canReview, err := h.canReviewEdit(c, user, editReq)
+	if err != nil {
+		return err
+	}
+	if !canReview {
+		return fiber.NewError(fiber.StatusForbidden, "you do not have permission to review this edit request")
+	}
+
+	link, err := h.db.GetLinkByID(c.Context(), editReq.LinkID)
+	if err != nil {
+		if errors.Is(err, db.ErrLinkNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "link not found")
+		}
+		return err
+	}
+	if msg, err := orgLinkRuleViolation(c.Context(), h.db, link, editReq.URL); err != nil {
+		return err
+	} else if msg != "" {
+		return fiber.NewError(fiber.StatusBadRequest, msg)
+	}
+
 	if err := h.db.ApproveEditRequest(c.Context(), reqID, user.ID); err != nil {
 		if errors.Is(err, db.ErrEditRequestNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "edit request not found or already processed")
@@ -334,10 +377,6 @@ func (h *ModerationHandler) RejectEdit(c fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
 	}
 
-	if !user.IsOrgMod() {
-		return fiber.NewError(fiber.StatusForbidden, "you do not have moderation permissions")
-	}
-
 	idStr := c.Params("id")
 	reqID, err := uuid.Parse(idStr)
 	if err != nil {
@@ -352,6 +391,14 @@ func (h *ModerationHandler) RejectEdit(c fiber.Ctx) error {
 		return err
 	}
 
+	canReview, err := h.canReviewEdit(c, user, editReq)
+	if err != nil {
+		return err
+	}
+	if !canReview {
+		return fiber.NewError(fiber.StatusForbidden, "you do not have permission to review this edit request")
+	}
+
 	if err := h.db.RejectEditRequest(c.Context(), reqID, user.ID); err != nil {
 		if errors.Is(err, db.ErrEditRequestNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "edit request not found or already processed")
@@ -365,6 +412,19 @@ func (h *ModerationHandler) RejectEdit(c fiber.Ctx) error {
 	}, "")
 }
 
+// canReviewEdit reports whether user can approve or reject an edit request:
+// moderators and co-owners of the link can.
+func (h *ModerationHandler) canReviewEdit(c fiber.Ctx, user *models.User, req *models.LinkEditRequest) (bool, error) {
+	if user.IsOrgMod() {
+		return true, nil
+	}
+	owned, err := h.db.IsLinkOwner(c.Context(), req.LinkID, user.ID)
+	if errors.Is(err, db.ErrLinkNotFound) {
+		return false, nil
+	}
+	return owned, err
+}
+
 // canModerate checks if a user can moderate a specific link.
 func canModerate(user *models.User, link *models.Link) bool {
 	// Admins and global mods can moderate anything (global and org links)
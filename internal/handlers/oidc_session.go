@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gofiber/fiber/v3"
+	"golang.org/x/oauth2"
+
+	"golinks/internal/db"
+	"golinks/internal/identity"
+	"golinks/internal/middleware"
+	"golinks/internal/models"
+)
+
+// backChannelLogoutEvent is the event a logout token must carry (OpenID
+// Connect Back-Channel Logout 1.0, section 2.4).
+const backChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+// refreshTokenKeyLabel separates the refresh token sealing key from other
+// uses of SESSION_SECRET.
+const refreshTokenKeyLabel = "golinks refresh token v1:"
+
+// RefreshSession implements middleware.SessionRefresher. It redeems the
+// session's refresh token, re-reads the user's claims from the provider and
+// re-syncs their profile, organization and role. A refused refresh token, an
+// unreadable sealed token or a token for a different subject reject the
+// session; anything else is reported as a transient failure.
+func (h *AuthHandler) RefreshSession(ctx context.Context, user *models.User, sealedToken string) (*models.User, string, error) {
+	client := h.clientByIssuer(user.Issuer)
+	if client == nil {
+		return nil, "", fmt.Errorf("%w: provider %q is no longer configured", middleware.ErrSessionRefreshRejected, user.Issuer)
+	}
+	refreshToken, err := h.openRefreshToken(sealedToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", middleware.ErrSessionRefreshRejected, err)
+	}
+
+	token, err := client.oauth2Config.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}).Token()
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && retrieveErr.ErrorCode == "invalid_grant" {
+			return nil, "", fmt.Errorf("%w: %v", middleware.ErrSessionRefreshRejected, err)
+		}
+		return nil, "", err
+	}
+
+	// Providers may or may not issue a new ID token on refresh; when they do
+	// it must verify and name the same subject.
+	claimsMap := make(map[string]any)
+	if rawIDToken, ok := token.Extra("id_token").(string); ok {
+		idToken, err := client.verifier.Verify(ctx, rawIDToken)
+		if err != nil {
+			return nil, "", fmt.Errorf("verify refreshed id token: %w", err)
+		}
+		if err := idToken.Claims(&claimsMap); err != nil {
+			return nil, "", err
+		}
+	}
+	idTokenGroups := identity.ExtractGroups(claimsMap, client.cfg.GroupsClaim)
+
+	// The userinfo response carries the profile; without it the user's
+	// details would be blanked, so its failure is treated as transient.
+	userInfo, err := client.provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+	if err != nil {
+		return nil, "", fmt.Errorf("fetch userinfo: %w", err)
+	}
+	var userInfoClaims map[string]any
+	if err := userInfo.Claims(&userInfoClaims); err != nil {
+		return nil, "", err
+	}
+	for k, v := range userInfoClaims {
+		claimsMap[k] = v
+	}
+
+	if sub, _ := claimsMap["sub"].(string); sub != user.Sub {
+		return nil, "", fmt.Errorf("%w: subject changed", middleware.ErrSessionRefreshRejected)
+	}
+
+	synced, err := identity.SyncUser(ctx, h.db, client.cfg, claimsMap, idTokenGroups, h.cfg.IsDev())
+	if err != nil {
+		return nil, "", err
+	}
+	refreshed, err := h.db.GetUserByID(ctx, synced.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sealed, err := h.sealRefreshToken(token.RefreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+	return refreshed, sealed, nil
+}
+
+// BackChannelLogout handles OpenID Connect back-channel logout: a provider
+// POSTs a signed logout_token naming an IdP session (sid) or a user (sub),
+// and the matching sessions are revoked. Sessions signed in from that sid are
+// revoked; a token with only a sub signs the user out everywhere. The token
+// is accepted from whichever configured provider it verifies against.
+func (h *AuthHandler) BackChannelLogout(c fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	rawToken := c.FormValue("logout_token")
+	if rawToken == "" {
+		return backChannelLogoutError(c, "missing logout_token")
+	}
+	var idToken *oidc.IDToken
+	var err error
+	for _, client := range h.clients {
+		if idToken, err = client.verifier.Verify(c.Context(), rawToken); err == nil {
+			break
+		}
+	}
+	if idToken == nil {
+		slog.WarnContext(c.Context(), "back-channel logout token rejected", "error", err)
+		return backChannelLogoutError(c, "invalid logout_token")
+	}
+	var claims logoutTokenClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return backChannelLogoutError(c, "invalid logout_token")
+	}
+	if err := claims.validate(); err != nil {
+		return backChannelLogoutError(c, err.Error())
+	}
+
+	var revoked int64
+	if claims.SID != "" {
+		revoked, err = h.db.RevokeUserSessionsBySID(c.Context(), idToken.Issuer, claims.SID)
+	} else {
+		var user *models.User
+		user, err = h.db.GetUserBySub(c.Context(), idToken.Issuer, claims.Sub)
+		if errors.Is(err, db.ErrUserNotFound) {
+			return c.SendStatus(fiber.StatusOK)
+		}
+		if err == nil {
+			revoked, err = h.db.RevokeAllUserSessions(c.Context(), user.ID)
+		}
+	}
+	if err != nil {
+		slog.ErrorContext(c.Context(), "back-channel logout failed", "issuer", idToken.Issuer, "sub", claims.Sub, "sid", claims.SID, "error", err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	slog.InfoContext(c.Context(), "back-channel logout", "issuer", idToken.Issuer, "sub", claims.Sub, "sid", claims.SID, "revoked", revoked)
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// logoutTokenClaims are the logout token claims beyond those checked by the
+// ID token verifier (issuer, audience, expiry, signature).
+type logoutTokenClaims struct {
+	Sub    string         `json:"sub"`
+	SID    string         `json:"sid"`
+	Events map[string]any `json:"events"`
+	Nonce  *string        `json:"nonce"`
+}
+
+// validate applies the logout token rules: the back-channel logout event must
+// be present, at least one of sub and sid must be set, and a nonce is
+// forbidden so an ID token can't be replayed as a logout token.
+func (l logoutTokenClaims) validate() error {
+	if _, ok := l.Events[backChannelLogoutEvent]; !ok {
+		return errors.New("logout_token is missing the back-channel logout event")
+	}
+	if l.Sub == "" && l.SID == "" {
+		return errors.New("logout_token must contain sub or sid")
+	}
+	if l.Nonce != nil {
+		return errors.New("logout_token must not contain a nonce")
+	}
+	return nil
+}
+
+func backChannelLogoutError(c fiber.Ctx, description string) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":             "invalid_request",
+		"error_description": description,
+	})
+}
+
+// sealRefreshToken encrypts a refresh token for storage so a database dump
+// doesn't hand out long-lived provider credentials. Empty tokens stay empty.
+func (h *AuthHandler) sealRefreshToken(token string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+	aead, err := h.refreshTokenAEAD()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(token), nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// openRefreshToken decrypts a token sealed by sealRefreshToken. It fails once
+// SESSION_SECRET has been rotated.
+func (h *AuthHandler) openRefreshToken(sealed string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	aead, err := h.refreshTokenAEAD()
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("sealed refresh token too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	token, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(token), nil
+}
+
+func (h *AuthHandler) refreshTokenAEAD() (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(refreshTokenKeyLabel + h.cfg.SessionSecret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
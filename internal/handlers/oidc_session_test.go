@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"testing"
+
+	"golinks/internal/config"
+)
+
+func TestRefreshTokenSealRoundTrip(t *testing.T) {
+	h := &AuthHandler{cfg: &config.Config{SessionSecret: "a-session-secret-of-at-least-32-chars"}}
+
+	sealed, err := h.sealRefreshToken("refresh-token-value")
+	if err != nil {
+		t.Fatalf("sealRefreshToken() error = %v", err)
+	}
+	if sealed == "refresh-token-value" {
+		t.Fatal("sealRefreshToken() stored the token in the clear")
+	}
+	got, err := h.openRefreshToken(sealed)
+	if err != nil {
+		t.Fatalf("openRefreshToken() error = %v", err)
+	}
+	if got != "refresh-token-value" {
+		t.Errorf("openRefreshToken() = %q, want %q", got, "refresh-token-value")
+	}
+
+	if empty, _ := h.sealRefreshToken(""); empty != "" {
+		t.Errorf("sealRefreshToken(\"\") = %q, want empty", empty)
+	}
+
+	// A rotated SESSION_SECRET can't open old tokens.
+	rotated := &AuthHandler{cfg: &config.Config{SessionSecret: "another-session-secret-of-32-chars!!"}}
+	if _, err := rotated.openRefreshToken(sealed); err == nil {
+		t.Error("openRefreshToken() with rotated secret succeeded, want error")
+	}
+	if _, err := h.openRefreshToken("not-a-sealed-token"); err == nil {
+		t.Error("openRefreshToken() of garbage succeeded, want error")
+	}
+}
+
+func TestLogoutTokenClaimsValidate(t *testing.T) {
+	event := map[string]any{backChannelLogoutEvent: map[string]any{}}
+	nonce := "n-0S6_WzA2Mj"
+
+	tests := []struct {
+		name    string
+		claims  logoutTokenClaims
+		wantErr bool
+	}{
+		{"sid only", logoutTokenClaims{SID: "08a5019c", Events: event}, false},
+		{"sub only", logoutTokenClaims{Sub: "248289761001", Events: event}, false},
+		{"sub and sid", logoutTokenClaims{Sub: "248289761001", SID: "08a5019c", Events: event}, false},
+		{"missing event", logoutTokenClaims{SID: "08a5019c"}, true},
+		{"other event", logoutTokenClaims{SID: "08a5019c", Events: map[string]any{"urn:example:event": map[string]any{}}}, true},
+		{"neither sub nor sid", logoutTokenClaims{Events: event}, true},
+		{"nonce present", logoutTokenClaims{SID: "08a5019c", Events: event, Nonce: &nonce}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.claims.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"golinks/internal/config"
+	"golinks/internal/db"
+	"golinks/internal/models"
+	"golinks/internal/validation"
+)
+
+// OrgHandler handles admin management of organizations and the settings
+// page their moderators share with admins.
+type OrgHandler struct {
+	db  *db.DB
+	cfg *config.Config
+}
+
+// NewOrgHandler creates a new organization handler.
+func NewOrgHandler(database *db.DB, cfg *config.Config) *OrgHandler {
+	return &OrgHandler{db: database, cfg: cfg}
+}
+
+// List renders the organization management page (admin only).
+func (h *OrgHandler) List(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	orgs, err := h.db.ListOrganizationsWithCounts(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return c.Render("orgs", MergeBranding(c, fiber.Map{
+		"User": user,
+		"Orgs": orgs,
+	}, h.cfg))
+}
+
+// Show renders the page for editing, merging and staffing one organization
+// (admin only).
+func (h *OrgHandler) Show(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid organization ID")
+	}
+
+	org, err := h.db.GetOrganizationWithCounts(c.Context(), orgID)
+	if errors.Is(err, db.ErrOrgNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "organization not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	// Every other organization can be merged into or become the parent; the
+	// database rejects choices that would nest it under itself.
+	all, err := h.db.GetAllOrganizations(c.Context())
+	if err != nil {
+		return err
+	}
+	var others []models.Organization
+	for _, o := range all {
+		if o.ID != orgID {
+			others = append(others, o)
+		}
+	}
+
+	members, err := h.membersData(c, orgID)
+	if err != nil {
+		return err
+	}
+
+	data := MergeBranding(c, fiber.Map{
+		"User":      user,
+		"Org":       org,
+		"OtherOrgs": others,
+	}, h.cfg)
+	for k, v := range members {
+		data[k] = v
+	}
+	return c.Render("org_detail", data)
+}
+
+// Update saves an organization's name, slug and parent (admin only).
+func (h *OrgHandler) Update(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return htmxError(c, "Invalid organization ID")
+	}
+
+	name := strings.TrimSpace(c.FormValue("name"))
+	slug := strings.TrimSpace(c.FormValue("slug"))
+	if name == "" || slug == "" {
+		return htmxError(c, "Name and slug are required")
+	}
+	var parentID *uuid.UUID
+	if v := c.FormValue("parent_id"); v != "" && v != "none" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return htmxError(c, "Invalid parent organization")
+		}
+		parentID = &id
+	}
+
+	if err := h.db.UpdateOrganization(c.Context(), orgID, name, slug); err != nil {
+		if errors.Is(err, db.ErrDuplicateOrgSlug) || errors.Is(err, db.ErrOrgNotFound) {
+			return htmxError(c, err.Error())
+		}
+		return htmxError(c, "Failed to update organization: "+err.Error())
+	}
+	if err := h.db.SetOrganizationParent(c.Context(), orgID, parentID); err != nil {
+		if errors.Is(err, db.ErrOrgCycle) || errors.Is(err, db.ErrOrgNotFound) {
+			return htmxError(c, err.Error())
+		}
+		return htmxError(c, "Failed to update organization: "+err.Error())
+	}
+
+	c.Set("HX-Refresh", "true")
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Merge folds the organization into another and deletes it (admin only).
+func (h *OrgHandler) Merge(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	sourceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return htmxError(c, "Invalid organization ID")
+	}
+	targetID, err := uuid.Parse(c.FormValue("into"))
+	if err != nil {
+		return htmxError(c, "Choose the organization to merge into")
+	}
+
+	result, err := h.db.MergeOrganizations(c.Context(), sourceID, targetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrOrgMergeConflict):
+			return htmxError(c, "Both organizations have links for: "+strings.Join(result.ConflictingKeywords, ", ")+". Rename or delete one of each first.")
+		case errors.Is(err, db.ErrOrgMergeTarget), errors.Is(err, db.ErrOrgNotFound):
+			return htmxError(c, err.Error())
+		}
+		return htmxError(c, "Failed to merge organizations: "+err.Error())
+	}
+
+	c.Set("HX-Redirect", "/admin/orgs/"+targetID.String())
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Delete deletes an organization without members or links (admin only).
+func (h *OrgHandler) Delete(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid organization ID")
+	}
+
+	if err := h.db.DeleteOrganization(c.Context(), orgID); err != nil {
+		switch {
+		case errors.Is(err, db.ErrOrgNotFound):
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		case errors.Is(err, db.ErrOrgNotEmpty):
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		return err
+	}
+
+	// Return empty string to remove the row from the DOM
+	return c.SendString("")
+}
+
+// AddMember adds a user to the organization with the chosen role (admin only).
+func (h *OrgHandler) AddMember(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid organization ID")
+	}
+	userID, err := uuid.Parse(c.FormValue("user_id"))
+	if err != nil {
+		return h.renderMembers(c, orgID, "Choose a user to add")
+	}
+
+	return h.saveMember(c, orgID, userID, c.FormValue("role"))
+}
+
+// UpdateMemberRole makes a member an org moderator or a plain member (admin only).
+func (h *OrgHandler) UpdateMemberRole(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid organization ID")
+	}
+	userID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid user ID")
+	}
+
+	return h.saveMember(c, orgID, userID, c.FormValue("role"))
+}
+
+// RemoveMember removes a user from the organization (admin only).
+func (h *OrgHandler) RemoveMember(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid organization ID")
+	}
+	userID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid user ID")
+	}
+
+	if err := h.db.RemoveUserOrganization(c.Context(), userID, orgID); err != nil {
+		return h.renderMembers(c, orgID, "Failed to remove member: "+err.Error())
+	}
+	return h.renderMembers(c, orgID, "")
+}
+
+// saveMember adds or updates a membership and re-renders the member list.
+func (h *OrgHandler) saveMember(c fiber.Ctx, orgID, userID uuid.UUID, role string) error {
+	if role != models.OrgRoleMember && role != models.OrgRoleMod {
+		return h.renderMembers(c, orgID, "Role must be member or org_mod")
+	}
+	if _, err := h.db.GetUserByID(c.Context(), userID); err != nil {
+		return h.renderMembers(c, orgID, "User not found")
+	}
+	if err := h.db.AddUserOrganization(c.Context(), userID, orgID, role); err != nil {
+		return h.renderMembers(c, orgID, "Failed to save member: "+err.Error())
+	}
+	return h.renderMembers(c, orgID, "")
+}
+
+// renderMembers re-renders the member list partial, with an optional error.
+func (h *OrgHandler) renderMembers(c fiber.Ctx, orgID uuid.UUID, errMsg string) error {
+	data, err := h.membersData(c, orgID)
+	if err != nil {
+		return err
+	}
+	data["Error"] = errMsg
+	return c.Render("partials/org_members", data, "")
+}
+
+// membersData loads an organization's members and the users who could join.
+func (h *OrgHandler) membersData(c fiber.Ctx, orgID uuid.UUID) (fiber.Map, error) {
+	members, err := h.db.ListOrganizationMembers(c.Context(), orgID)
+	if err != nil {
+		return nil, err
+	}
+	users, err := h.db.GetAllUsersWithOrgs(c.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	isMember := make(map[uuid.UUID]bool, len(members))
+	for _, m := range members {
+		isMember[m.UserID] = true
+	}
+	var candidates []db.UserWithOrg
+	for _, u := range users {
+		if !isMember[u.ID] && !u.IsDeactivated() {
+			candidates = append(candidates, u)
+		}
+	}
+
+	return fiber.Map{
+		"OrgID":      orgID,
+		"Members":    members,
+		"Candidates": candidates,
+		"OrgRoles":   []string{models.OrgRoleMember, models.OrgRoleMod},
+	}, nil
+}
+
+// Settings renders an organization's link rules, notification routing and
+// branding (its moderators and admins).
+func (h *OrgHandler) Settings(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid organization ID")
+	}
+	if !user.IsAdmin() && !user.CanModerateOrg(orgID) {
+		return fiber.NewError(fiber.StatusForbidden, "moderator access required")
+	}
+
+	org, err := h.db.GetOrganizationByID(c.Context(), orgID)
+	if errors.Is(err, db.ErrOrgNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "organization not found")
+	}
+	if err != nil {
+		return err
+	}
+	settings, err := h.db.GetOrgSettings(c.Context(), orgID)
+	if err != nil {
+		return err
+	}
+	branding, err := h.db.GetOrgBranding(c.Context(), orgID)
+	if err != nil {
+		return err
+	}
+
+	return c.Render("org_settings", MergeBranding(c, fiber.Map{
+		"User":     user,
+		"Org":      org,
+		"Settings": settings,
+		"Branding": branding,
+	}, h.cfg))
+}
+
+// SaveSettings stores an organization's settings from the settings form (its
+// moderators and admins).
+func (h *OrgHandler) SaveSettings(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return htmxError(c, "Invalid organization ID")
+	}
+	if !user.IsAdmin() && !user.CanModerateOrg(orgID) {
+		return fiber.NewError(fiber.StatusForbidden, "moderator access required")
+	}
+
+	settings := &models.OrgSettings{
+		OrganizationID:    orgID,
+		RequireModeration: c.FormValue("require_moderation") != "",
+		AllowedDomains:    splitList(c.FormValue("allowed_domains")),
+		KeywordPrefix:     c.FormValue("keyword_prefix"),
+		KeywordPattern:    c.FormValue("keyword_pattern"),
+		NotifyModerators:  c.FormValue("notify_moderators") != "",
+		NotifyEmails:      splitList(c.FormValue("notify_emails")),
+	}
+	if err := settings.Normalize(); err != nil {
+		return htmxError(c, err.Error())
+	}
+
+	if err := h.db.SaveOrgSettings(c.Context(), settings, user.ID); err != nil {
+		if errors.Is(err, db.ErrOrgNotFound) {
+			return htmxError(c, err.Error())
+		}
+		return htmxError(c, "Failed to save settings: "+err.Error())
+	}
+
+	return c.Render("partials/form_success", fiber.Map{
+		"Message": "Settings saved.",
+	}, "")
+}
+
+// SaveBranding stores the title, logo, accent color and banner the
+// organization's members see (its moderators and admins).
+func (h *OrgHandler) SaveBranding(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return htmxError(c, "Invalid organization ID")
+	}
+	if !user.IsAdmin() && !user.CanModerateOrg(orgID) {
+		return fiber.NewError(fiber.StatusForbidden, "moderator access required")
+	}
+
+	branding := &models.OrgBranding{
+		OrganizationID:  orgID,
+		SiteTitle:       c.FormValue("site_title"),
+		LogoURL:         c.FormValue("logo_url"),
+		AccentColor:     c.FormValue("accent_color"),
+		BannerText:      c.FormValue("banner_text"),
+		BannerTextColor: c.FormValue("banner_text_color"),
+		BannerBGColor:   c.FormValue("banner_bg_color"),
+	}
+	if err := branding.Normalize(); err != nil {
+		return htmxError(c, err.Error())
+	}
+	if err := validation.ValidateOrgBranding(branding, h.cfg.CSPImgSources); err != nil {
+		return htmxError(c, err.Error())
+	}
+
+	if err := h.db.SaveOrgBranding(c.Context(), branding, user.ID); err != nil {
+		if errors.Is(err, db.ErrOrgNotFound) {
+			return htmxError(c, err.Error())
+		}
+		return htmxError(c, "Failed to save branding: "+err.Error())
+	}
+
+	// Reload so members see the new branding straight away
+	c.Set("HX-Refresh", "true")
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// splitList splits a textarea of values separated by newlines or commas.
+func splitList(raw string) []string {
+	return strings.FieldsFunc(raw, func(r rune) bool {
+		return r == '\n' || r == '\r' || r == ','
+	})
+}
@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"errors"
+	"time"
+
 	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/session"
 	"github.com/google/uuid"
 
 	"golinks/internal/config"
 	"golinks/internal/db"
+	"golinks/internal/middleware"
 	"golinks/internal/models"
 )
 
@@ -32,9 +37,16 @@ func (h *ProfileHandler) Show(c fiber.Ctx) error {
 		return err
 	}
 
+	sessions, err := activeSessions(c, h.db, user.ID)
+	if err != nil {
+		return err
+	}
+
 	data := fiber.Map{
-		"User":  user,
-		"Links": links,
+		"User":        user,
+		"Links":       links,
+		"Sessions":    sessions,
+		"SessionsURL": "/profile/sessions",
 	}
 
 	// Load fallback redirect options if user belongs to an org
@@ -45,7 +57,7 @@ func (h *ProfileHandler) Show(c fiber.Ctx) error {
 		}
 	}
 
-	return c.Render("profile", MergeBranding(data, h.cfg, c.Path()))
+	return c.Render("profile", MergeBranding(c, data, h.cfg))
 }
 
 // UpdateFallbackPreference updates the user's fallback redirect preference.
@@ -93,3 +105,109 @@ func (h *ProfileHandler) UpdateFallbackPreference(c fiber.Ctx) error {
 		"SavedMessage":    true,
 	}, "")
 }
+
+// OrgSwitcher renders the navbar's active-organization switcher for users in
+// more than one organization, and nothing for everyone else.
+func (h *ProfileHandler) OrgSwitcher(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	memberships, err := h.db.ListUserOrganizations(c.Context(), user.ID)
+	if err != nil || len(memberships) < 2 {
+		return c.SendString("")
+	}
+	return c.Render("partials/org_switcher", fiber.Map{
+		"Memberships": memberships,
+	}, "")
+}
+
+// SwitchOrg changes the user's active organization: the one new links are
+// created in and, for org moderators, the one they moderate. The page is
+// reloaded so everything scoped to the organization follows.
+func (h *ProfileHandler) SwitchOrg(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	orgID, err := uuid.Parse(c.FormValue("organization_id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid organization ID")
+	}
+
+	if err := h.db.SetActiveOrganization(c.Context(), user.ID, orgID); err != nil {
+		if errors.Is(err, db.ErrNotOrgMember) {
+			return fiber.NewError(fiber.StatusForbidden, "you are not a member of this organization")
+		}
+		return err
+	}
+
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Refresh", "true")
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+	return c.Redirect().To("/")
+}
+
+// RevokeSession signs one of the user's other sessions out.
+func (h *ProfileHandler) RevokeSession(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return htmxError(c, "Invalid session ID")
+	}
+
+	if err := h.db.RevokeUserSession(c.Context(), user.ID, id); err != nil && !errors.Is(err, db.ErrUserSessionNotFound) {
+		return htmxError(c, "Failed to sign out session")
+	}
+
+	sessions, err := activeSessions(c, h.db, user.ID)
+	if err != nil {
+		return err
+	}
+	return c.Render("partials/session_list", fiber.Map{
+		"Sessions":    sessions,
+		"SessionsURL": "/profile/sessions",
+	}, "")
+}
+
+// RevokeAllSessions signs the user out everywhere, including this browser,
+// then finishes with a regular logout.
+func (h *ProfileHandler) RevokeAllSessions(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	if _, err := h.db.RevokeAllUserSessions(c.Context(), user.ID); err != nil {
+		return htmxError(c, "Failed to sign out sessions")
+	}
+
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", "/auth/logout")
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+	return c.Redirect().To("/auth/logout")
+}
+
+// activeSessions lists a user's live sessions, marking the one making the
+// request.
+func activeSessions(c fiber.Ctx, database *db.DB, userID uuid.UUID) ([]models.UserSession, error) {
+	sessions, err := database.ListActiveUserSessions(c.Context(), userID, middleware.SessionLastSeenAfter(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	if sess := session.FromContext(c); sess != nil {
+		current := db.HashSessionID(sess.ID())
+		for i := range sessions {
+			sessions[i].Current = sessions[i].SessionHash == current
+		}
+	}
+	return sessions, nil
+}
@@ -45,7 +45,7 @@ func (h *RedirectHandler) Redirect(c fiber.Ctx) error {
 			})
 		}
 		user, _ := c.Locals("user").(*models.User)
-		return c.Status(fiber.StatusBadRequest).Render("error", MergeBranding(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).Render("error", MergeBranding(c, fiber.Map{
 			"Title":   "Invalid Keyword",
 			"Message": "The keyword contains invalid characters.",
 			"User":    user,
@@ -81,6 +81,7 @@ func (h *RedirectHandler) Redirect(c fiber.Ctx) error {
 		if errors.Is(err, db.ErrLinkNotFound) {
 			if wantsJSON {
 				metrics.RecordKeywordLookup(keyword, models.OutcomeNotFound)
+				h.db.RecordMissingKeyword(c.Context(), keyword, orgID)
 				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 					"status": "error",
 					"error":  "keyword not found",
@@ -91,10 +92,12 @@ func (h *RedirectHandler) Redirect(c fiber.Ctx) error {
 				fb, fbErr := h.db.GetFallbackRedirectByID(c.Context(), *user.FallbackRedirectID)
 				if fbErr == nil {
 					metrics.RecordKeywordLookup(keyword, models.OutcomeFallback)
+					h.db.RecordMissingKeyword(c.Context(), keyword, orgID)
 					return c.Redirect().To(fb.URL + keyword)
 				}
 			}
 			metrics.RecordKeywordLookup(keyword, models.OutcomeNotFound)
+			h.db.RecordMissingKeyword(c.Context(), keyword, orgID)
 
 			// Load fallback options for authenticated org members
 			var fallbackOptions []models.FallbackRedirect
@@ -103,8 +106,8 @@ func (h *RedirectHandler) Redirect(c fiber.Ctx) error {
 			}
 
 			// Look up similar keywords for "did you mean?" suggestions
-			suggestions, _ := h.db.GetSimilarKeywords(c.Context(), keyword, orgID, 5)
-			return c.Status(fiber.StatusNotFound).Render("not_found", MergeBranding(fiber.Map{
+			suggestions, _ := h.db.GetSimilarKeywords(c.Context(), keyword, userID, orgID, 5)
+			return c.Status(fiber.StatusNotFound).Render("not_found", MergeBranding(c, fiber.Map{
 				"Title":           "Not Found",
 				"Keyword":         keyword,
 				"Suggestions":     suggestions,
@@ -139,12 +142,12 @@ func (h *RedirectHandler) Redirect(c fiber.Ctx) error {
 }
 
 // actorForClick returns a stable per-request identifier used to deduplicate
-// click counts. Priority: OIDC sub > session ID > real client IP.
+// click counts. Priority: user ID > session ID > real client IP.
 // Fresh sessions (no cookie sent by client) are treated as IP-based to avoid
 // every cookieless request creating a unique actor and bypassing dedup.
 func actorForClick(c fiber.Ctx, user *models.User) string {
-	if user != nil && user.Sub != "" {
-		return "user:" + user.Sub
+	if user != nil {
+		return "user:" + user.ID.String()
 	}
 	if sess := session.FromContext(c); sess != nil && !sess.Fresh() {
 		if id := sess.ID(); id != "" {
@@ -175,10 +178,10 @@ func (h *RedirectHandler) Random(c fiber.Ctx) error {
 		orgID = user.OrganizationID
 	}
 
-	link, err := h.db.GetRandomApprovedLink(c.Context(), orgID)
+	link, err := h.db.GetRandomApprovedLink(c.Context(), viewerID(user), orgID)
 	if err != nil {
 		if errors.Is(err, db.ErrLinkNotFound) {
-			return c.Status(fiber.StatusNotFound).Render("error", MergeBranding(fiber.Map{
+			return c.Status(fiber.StatusNotFound).Render("error", MergeBranding(c, fiber.Map{
 				"Title":   "No Links",
 				"Message": "There are no links available.",
 				"User":    user,
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"golinks/internal/config"
+	"golinks/internal/db"
+	"golinks/internal/models"
+	"golinks/internal/validation"
+)
+
+// ResolveExplainHandler renders the admin page explaining how a keyword
+// resolves for a user.
+type ResolveExplainHandler struct {
+	db  *db.DB
+	cfg *config.Config
+}
+
+// NewResolveExplainHandler creates a new resolution explain handler.
+func NewResolveExplainHandler(database *db.DB, cfg *config.Config) *ResolveExplainHandler {
+	return &ResolveExplainHandler{db: database, cfg: cfg}
+}
+
+// Show renders the explain form and, once a keyword is given, every candidate
+// link for it in resolution order for the user given by id or email (the
+// admin themselves when blank), with the winner and why the others lost.
+func (h *ResolveExplainHandler) Show(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || !user.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	keyword := validation.NormalizeKeyword(c.Query("keyword"))
+	ref := c.Query("user")
+	data := fiber.Map{
+		"User":    user,
+		"Keyword": keyword,
+		"Target":  ref,
+	}
+
+	if keyword != "" {
+		if !validation.ValidateKeyword(keyword) {
+			data["Error"] = "The keyword contains invalid characters."
+			return c.Render("resolve_explain", MergeBranding(c, data, h.cfg))
+		}
+
+		target := user
+		if ref != "" {
+			var err error
+			if id, parseErr := uuid.Parse(ref); parseErr == nil {
+				target, err = h.db.GetUserByID(c.Context(), id)
+			} else {
+				target, err = h.db.GetUserByEmail(c.Context(), ref)
+			}
+			if errors.Is(err, db.ErrUserNotFound) {
+				data["Error"] = "No active user matches " + ref + "."
+				return c.Render("resolve_explain", MergeBranding(c, data, h.cfg))
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		explanation, err := h.db.ExplainResolution(c.Context(), target, keyword)
+		if err != nil {
+			return err
+		}
+		explanation.RestrictTo(user)
+		data["Explanation"] = explanation
+		data["Winner"] = explanation.Winner()
+		if target.OrganizationID != nil {
+			if org, err := h.db.GetOrganizationByID(c.Context(), *target.OrganizationID); err == nil {
+				data["ActiveOrg"] = org
+			}
+		}
+	}
+
+	return c.Render("resolve_explain", MergeBranding(c, data, h.cfg))
+}
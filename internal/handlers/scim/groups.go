@@ -0,0 +1,445 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"golinks/internal/db"
+	"golinks/internal/identity"
+	"golinks/internal/models"
+)
+
+// group is the SCIM Group resource.
+type group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []ref    `json:"members,omitempty"`
+	Meta        *meta    `json:"meta,omitempty"`
+}
+
+// toGroup renders a group as a SCIM resource.
+func (h *Handler) toGroup(g *models.SCIMGroup, withMembers bool) group {
+	out := group{
+		Schemas:     []string{schemaGroup},
+		ID:          g.ID.String(),
+		ExternalID:  g.ExternalID,
+		DisplayName: g.DisplayName,
+		Meta: &meta{
+			ResourceType: "Group",
+			Created:      g.CreatedAt,
+			LastModified: g.UpdatedAt,
+			Location:     h.baseURL + "/Groups/" + g.ID.String(),
+		},
+	}
+	if withMembers {
+		for _, m := range g.Members {
+			out.Members = append(out.Members, ref{Value: m.UserID.String(), Display: m.Display, Ref: h.baseURL + "/Users/" + m.UserID.String()})
+		}
+	}
+	return out
+}
+
+// withMembers reports whether members should be returned; Entra ID asks for
+// them to be left out with excludedAttributes=members.
+func withMembers(c fiber.Ctx) bool {
+	for _, attr := range strings.Split(c.Query("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attr), "members") {
+			return false
+		}
+	}
+	return true
+}
+
+// ListGroups lists groups, optionally filtered by displayName.
+func (h *Handler) ListGroups(c fiber.Ctx) error {
+	var displayName string
+	if f := c.Query("filter"); f != "" {
+		attr, value, err := parseFilter(f)
+		if err != nil {
+			return writeError(c, err)
+		}
+		if stripSchema(attr, schemaGroup) != "displayname" {
+			return scimError(c, fiber.StatusBadRequest, "invalidFilter", "groups can only be filtered by displayName")
+		}
+		if value == "" {
+			return c.JSON(listResponse([]group{}, 0, 1), contentType)
+		}
+		displayName = value
+	}
+
+	startIndex, offset, limit := pagination(c)
+	groups, total, err := h.store.ListSCIMGroups(c.Context(), displayName, offset, limit)
+	if err != nil {
+		slog.ErrorContext(c.Context(), "scim: failed to list groups", "error", err)
+		return writeError(c, err)
+	}
+	members := withMembers(c)
+	resources := make([]group, 0, len(groups))
+	for i := range groups {
+		resources = append(resources, h.toGroup(&groups[i], members))
+	}
+	return c.JSON(listResponse(resources, total, startIndex), contentType)
+}
+
+// GetGroup returns one group.
+func (h *Handler) GetGroup(c fiber.Ctx) error {
+	g, err := h.findGroup(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(h.toGroup(g, withMembers(c)), contentType)
+}
+
+// CreateGroup creates a group with its initial members and maps them onto
+// the group's organization or role.
+func (h *Handler) CreateGroup(c fiber.Ctx) error {
+	var in group
+	if err := decode(c.Body(), &in); err != nil {
+		return writeError(c, err)
+	}
+	if in.DisplayName == "" {
+		return scimError(c, fiber.StatusBadRequest, "invalidValue", "displayName is required")
+	}
+	ids, err := refIDs(in.Members)
+	if err != nil {
+		return writeError(c, err)
+	}
+	ctx := c.Context()
+
+	g := &models.SCIMGroup{DisplayName: in.DisplayName, ExternalID: in.ExternalID}
+	if err := h.store.CreateSCIMGroup(ctx, g); err != nil {
+		return writeError(c, groupWriteError(err))
+	}
+	if len(ids) > 0 {
+		if err := h.store.AddSCIMGroupMembers(ctx, g.ID, ids); err != nil {
+			return writeError(c, err)
+		}
+	}
+	h.applyGroups(ctx, ids, "")
+	slog.InfoContext(ctx, "scim: group created", "group_id", g.ID, "display_name", g.DisplayName, "members", len(ids))
+
+	if g, err = h.store.GetSCIMGroup(ctx, g.ID); err != nil {
+		return writeError(c, err)
+	}
+	out := h.toGroup(g, true)
+	c.Set(fiber.HeaderLocation, out.Meta.Location)
+	return c.Status(fiber.StatusCreated).JSON(out, contentType)
+}
+
+// ReplaceGroup replaces a group's name and members.
+func (h *Handler) ReplaceGroup(c fiber.Ctx) error {
+	g, err := h.findGroup(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	var in group
+	if err := decode(c.Body(), &in); err != nil {
+		return writeError(c, err)
+	}
+	if in.DisplayName == "" {
+		return scimError(c, fiber.StatusBadRequest, "invalidValue", "displayName is required")
+	}
+	ids, err := refIDs(in.Members)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return h.updateGroup(c, g, func(ctx context.Context, g *models.SCIMGroup) error {
+		g.DisplayName, g.ExternalID = in.DisplayName, in.ExternalID
+		return h.store.SetSCIMGroupMembers(ctx, g.ID, ids)
+	})
+}
+
+// PatchGroup applies PATCH operations to a group, typically adding and
+// removing members.
+func (h *Handler) PatchGroup(c fiber.Ctx) error {
+	g, err := h.findGroup(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	req, err := decodePatch(c.Body())
+	if err != nil {
+		return writeError(c, err)
+	}
+	return h.updateGroup(c, g, func(ctx context.Context, g *models.SCIMGroup) error {
+		for _, op := range req.Operations {
+			if err := h.applyGroupOp(ctx, g, op); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// updateGroup applies change to a group, saves its name and re-maps everyone
+// who was or now is a member.
+func (h *Handler) updateGroup(c fiber.Ctx, g *models.SCIMGroup, change func(context.Context, *models.SCIMGroup) error) error {
+	ctx := c.Context()
+	oldName := g.DisplayName
+	affected := memberIDs(g)
+
+	if err := change(ctx, g); err != nil {
+		return writeError(c, err)
+	}
+	if g.DisplayName == "" {
+		return scimError(c, fiber.StatusBadRequest, "invalidValue", "displayName is required")
+	}
+	if err := h.store.UpdateSCIMGroup(ctx, g); err != nil {
+		return writeError(c, groupWriteError(err))
+	}
+
+	updated, err := h.store.GetSCIMGroup(ctx, g.ID)
+	if err != nil {
+		return writeError(c, err)
+	}
+	for _, id := range memberIDs(updated) {
+		if !slices.Contains(affected, id) {
+			affected = append(affected, id)
+		}
+	}
+	h.applyGroups(ctx, affected, oldName)
+	return c.JSON(h.toGroup(updated, true), contentType)
+}
+
+// DeleteGroup deletes a group; its former members lose the organization or
+// role it gave them.
+func (h *Handler) DeleteGroup(c fiber.Ctx) error {
+	g, err := h.findGroup(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	ctx := c.Context()
+	if err := h.store.DeleteSCIMGroup(ctx, g.ID); err != nil {
+		return writeError(c, groupWriteError(err))
+	}
+	h.applyGroups(ctx, memberIDs(g), g.DisplayName)
+	slog.InfoContext(ctx, "scim: group deleted", "group_id", g.ID, "display_name", g.DisplayName)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// findGroup loads the group named by the :id parameter.
+func (h *Handler) findGroup(c fiber.Ctx) (*models.SCIMGroup, error) {
+	id, ok := parseID(c)
+	if !ok {
+		return nil, groupWriteError(db.ErrSCIMGroupNotFound)
+	}
+	g, err := h.store.GetSCIMGroup(c.Context(), id)
+	if err != nil {
+		return nil, groupWriteError(err)
+	}
+	return g, nil
+}
+
+// memberPathRe matches the member filter path used to remove one member:
+// members[value eq "<id>"].
+var memberPathRe = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+"([^"]+)"\s*\]$`)
+
+// applyGroupOp applies one PATCH operation to a group: changes to members
+// are written straight away, name changes are saved by updateGroup.
+func (h *Handler) applyGroupOp(ctx context.Context, g *models.SCIMGroup, op patchOp) error {
+	kind, err := op.kind()
+	if err != nil {
+		return err
+	}
+	if op.Path == "" {
+		if kind == "remove" {
+			return &scimErr{status: fiber.StatusBadRequest, scimType: "noTarget", detail: "remove requires a path"}
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &attrs); err != nil {
+			return invalidValue("value must be an object when path is omitted")
+		}
+		for attr, value := range attrs {
+			if err := h.applyGroupOp(ctx, g, patchOp{Op: kind, Path: attr, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	path := stripSchema(op.Path, schemaGroup)
+	if m := memberPathRe.FindStringSubmatch(path); m != nil {
+		if kind != "remove" {
+			return invalidValue("only remove is supported on a member filter")
+		}
+		id, err := uuid.Parse(m[1])
+		if err != nil {
+			return nil
+		}
+		return h.store.RemoveSCIMGroupMembers(ctx, g.ID, []uuid.UUID{id})
+	}
+
+	switch strings.ToLower(path) {
+	case "displayname":
+		if kind == "remove" {
+			return invalidValue("displayName can't be removed")
+		}
+		return stringValue(op.Value, &g.DisplayName)
+	case "externalid":
+		g.ExternalID = ""
+		if kind == "remove" {
+			return nil
+		}
+		return stringValue(op.Value, &g.ExternalID)
+	case "members":
+		var ids []uuid.UUID
+		if len(op.Value) > 0 {
+			var refs []ref
+			if err := json.Unmarshal(op.Value, &refs); err != nil {
+				return invalidValue("members must be an array")
+			}
+			if ids, err = refIDs(refs); err != nil {
+				return err
+			}
+		}
+		switch {
+		case kind == "add":
+			return h.store.AddSCIMGroupMembers(ctx, g.ID, ids)
+		case kind == "replace":
+			return h.store.SetSCIMGroupMembers(ctx, g.ID, ids)
+		case len(op.Value) == 0:
+			// remove without a value removes every member.
+			return h.store.SetSCIMGroupMembers(ctx, g.ID, nil)
+		default:
+			return h.store.RemoveSCIMGroupMembers(ctx, g.ID, ids)
+		}
+	}
+	return nil
+}
+
+// applyGroups re-maps users onto organizations and roles after their group
+// memberships changed. left is the name a group had before it was renamed,
+// lost members or was deleted. Failures are logged: the memberships are
+// already saved and are re-applied on the next change.
+func (h *Handler) applyGroups(ctx context.Context, userIDs []uuid.UUID, left string) {
+	for _, id := range userIDs {
+		if err := h.applyUserGroups(ctx, id, left); err != nil && !errors.Is(err, db.ErrUserNotFound) {
+			slog.ErrorContext(ctx, "scim: failed to apply group memberships", "user_id", id, "error", err)
+		}
+	}
+}
+
+// applyUserGroups maps a user's groups onto their organizations and role.
+// A group named SCIM_ORG_GROUP_PREFIX+slug makes members members of that
+// organization (created if needed); leaving it leaves the organization.
+// Memberships added another way are kept. The provider's admin and
+// moderator groups set the role the way an OIDC sign-in does, moderators
+// moderating every organization their groups put them in.
+func (h *Handler) applyUserGroups(ctx context.Context, userID uuid.UUID, left string) error {
+	groups, err := h.store.ListUserSCIMGroups(ctx, userID)
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(groups))
+	var orgSlugs []string
+	for i, g := range groups {
+		names[i] = g.DisplayName
+		if slug, ok := h.orgSlug(g.DisplayName); ok {
+			orgSlugs = append(orgSlugs, slug)
+		}
+	}
+
+	memberships, err := h.store.ListUserOrganizations(ctx, userID)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]models.OrgMembership, len(memberships))
+	for _, m := range memberships {
+		current[m.Slug] = m
+	}
+
+	mapRoles := h.provider.HasGroupRoleMapping()
+	var mappedRole string
+	orgRole := models.OrgRoleMember
+	if mapRoles {
+		mappedRole = identity.ResolveRoleFromGroups(names, h.provider)
+		if mappedRole == "moderator" {
+			orgRole = models.OrgRoleMod
+		}
+	}
+	for _, slug := range orgSlugs {
+		if m, ok := current[slug]; ok && (!mapRoles || m.Role == orgRole) {
+			continue
+		}
+		org, _, err := h.store.GetOrCreateOrganization(ctx, slug)
+		if err != nil {
+			return err
+		}
+		if err := h.store.AddUserOrganization(ctx, userID, org.ID, orgRole); err != nil {
+			return err
+		}
+	}
+	if slug, ok := h.orgSlug(left); ok && !slices.Contains(orgSlugs, slug) {
+		if m, ok := current[slug]; ok {
+			if err := h.store.RemoveUserOrganization(ctx, userID, m.OrganizationID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if mapRoles {
+		u, err := h.store.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		role := identity.FinalRoleFromMapped(mappedRole, u.OrganizationID != nil)
+		if role != u.Role {
+			if err := h.store.UpdateUserRole(ctx, userID, role); err != nil {
+				return err
+			}
+			slog.InfoContext(ctx, "scim: user role mapped from groups", "user_id", userID, "role", role)
+		}
+	}
+	return nil
+}
+
+// orgSlug returns the organization slug a group name maps to.
+func (h *Handler) orgSlug(groupName string) (string, bool) {
+	if h.orgGroupPrefix == "" {
+		return "", false
+	}
+	slug, ok := strings.CutPrefix(groupName, h.orgGroupPrefix)
+	return slug, ok && slug != ""
+}
+
+// refIDs parses member references; members must be user IDs.
+func refIDs(refs []ref) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(refs))
+	for _, r := range refs {
+		id, err := uuid.Parse(r.Value)
+		if err != nil {
+			return nil, invalidValue("member " + r.Value + " is not a user id")
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func memberIDs(g *models.SCIMGroup) []uuid.UUID {
+	ids := make([]uuid.UUID, len(g.Members))
+	for i, m := range g.Members {
+		ids[i] = m.UserID
+	}
+	return ids
+}
+
+// groupWriteError maps group store errors to SCIM errors.
+func groupWriteError(err error) error {
+	switch {
+	case errors.Is(err, db.ErrSCIMGroupNotFound):
+		return &scimErr{status: fiber.StatusNotFound, detail: "group not found"}
+	case errors.Is(err, db.ErrDuplicateSCIMGroup):
+		return &scimErr{status: fiber.StatusConflict, scimType: "uniqueness", detail: "a group with this displayName already exists"}
+	default:
+		return err
+	}
+}
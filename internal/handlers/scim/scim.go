@@ -0,0 +1,322 @@
+// Package scim implements a SCIM 2.0 (RFC 7643/7644) provisioning endpoint
+// for users and groups. Identity providers push users ahead of their first
+// sign-in and deactivate leavers; group memberships map users onto
+// organizations and roles.
+package scim
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"golinks/internal/config"
+	"golinks/internal/db"
+	"golinks/internal/models"
+)
+
+const (
+	schemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	schemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	schemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	schemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	schemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	schemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	schemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+
+	// contentType is the SCIM media type (RFC 7644, section 3.1).
+	contentType = "application/scim+json"
+
+	// defaultCount and maxCount bound list pages.
+	defaultCount = 100
+	maxCount     = 200
+)
+
+// Store is the persistence the SCIM endpoint needs; *db.DB implements it.
+type Store interface {
+	UpsertUser(ctx context.Context, user *models.User) error
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetUserBySub(ctx context.Context, issuer, sub string) (*models.User, error)
+	ListSCIMUsers(ctx context.Context, filter db.SCIMUserFilter, offset, limit int) ([]models.User, int, error)
+	DeactivateUser(ctx context.Context, userID uuid.UUID) error
+	ReactivateUser(ctx context.Context, userID uuid.UUID) error
+	RevokeAllUserSessions(ctx context.Context, userID uuid.UUID) (int64, error)
+	UpdateUserRole(ctx context.Context, userID uuid.UUID, role string) error
+
+	ListUserOrganizations(ctx context.Context, userID uuid.UUID) ([]models.OrgMembership, error)
+	AddUserOrganization(ctx context.Context, userID, orgID uuid.UUID, role string) error
+	RemoveUserOrganization(ctx context.Context, userID, orgID uuid.UUID) error
+
+	GetOrCreateOrganization(ctx context.Context, slug string) (*models.Organization, bool, error)
+
+	CreateSCIMGroup(ctx context.Context, group *models.SCIMGroup) error
+	GetSCIMGroup(ctx context.Context, id uuid.UUID) (*models.SCIMGroup, error)
+	ListSCIMGroups(ctx context.Context, displayName string, offset, limit int) ([]models.SCIMGroup, int, error)
+	UpdateSCIMGroup(ctx context.Context, group *models.SCIMGroup) error
+	DeleteSCIMGroup(ctx context.Context, id uuid.UUID) error
+	AddSCIMGroupMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error
+	RemoveSCIMGroupMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error
+	SetSCIMGroupMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error
+	ListUserSCIMGroups(ctx context.Context, userID uuid.UUID) ([]models.SCIMGroup, error)
+}
+
+// Handler serves /scim/v2.
+type Handler struct {
+	store          Store
+	provider       *config.OIDCProvider
+	orgGroupPrefix string
+	baseURL        string
+	tokenHash      [sha256.Size]byte
+}
+
+// NewHandler creates a SCIM handler authenticating clients with
+// SCIM_BEARER_TOKEN.
+func NewHandler(store Store, cfg *config.Config) *Handler {
+	return &Handler{
+		store:          store,
+		provider:       cfg.SCIMProvider(),
+		orgGroupPrefix: cfg.SCIMOrgGroupPrefix,
+		baseURL:        strings.TrimRight(cfg.BaseURL, "/") + "/scim/v2",
+		tokenHash:      sha256.Sum256([]byte(cfg.SCIMBearerToken)),
+	}
+}
+
+// Register mounts the SCIM routes, all behind RequireToken, on r.
+func (h *Handler) Register(r fiber.Router) {
+	r.Use(h.RequireToken)
+
+	r.Get("/ServiceProviderConfig", h.ServiceProviderConfig)
+	r.Get("/ResourceTypes", h.ResourceTypes)
+
+	r.Get("/Users", h.ListUsers)
+	r.Post("/Users", h.CreateUser)
+	r.Get("/Users/:id", h.GetUser)
+	r.Put("/Users/:id", h.ReplaceUser)
+	r.Patch("/Users/:id", h.PatchUser)
+	r.Delete("/Users/:id", h.DeleteUser)
+
+	r.Get("/Groups", h.ListGroups)
+	r.Post("/Groups", h.CreateGroup)
+	r.Get("/Groups/:id", h.GetGroup)
+	r.Put("/Groups/:id", h.ReplaceGroup)
+	r.Patch("/Groups/:id", h.PatchGroup)
+	r.Delete("/Groups/:id", h.DeleteGroup)
+}
+
+// RequireToken rejects requests without the configured bearer token.
+func (h *Handler) RequireToken(c fiber.Ctx) error {
+	auth := c.Get(fiber.HeaderAuthorization)
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		sum := sha256.Sum256([]byte(auth[7:]))
+		if subtle.ConstantTimeCompare(sum[:], h.tokenHash[:]) == 1 {
+			return c.Next()
+		}
+	}
+	c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="scim"`)
+	return scimError(c, fiber.StatusUnauthorized, "", "invalid or missing bearer token")
+}
+
+// ServiceProviderConfig describes the supported SCIM features.
+func (h *Handler) ServiceProviderConfig(c fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"schemas":        []string{schemaServiceProviderConfig},
+		"patch":          fiber.Map{"supported": true},
+		"bulk":           fiber.Map{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         fiber.Map{"supported": true, "maxResults": maxCount},
+		"changePassword": fiber.Map{"supported": false},
+		"sort":           fiber.Map{"supported": false},
+		"etag":           fiber.Map{"supported": false},
+		"authenticationSchemes": []fiber.Map{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "Authentication with the SCIM_BEARER_TOKEN shared secret",
+			"primary":     true,
+		}},
+		"meta": fiber.Map{"resourceType": "ServiceProviderConfig", "location": h.baseURL + "/ServiceProviderConfig"},
+	}, contentType)
+}
+
+// ResourceTypes lists the User and Group resources.
+func (h *Handler) ResourceTypes(c fiber.Ctx) error {
+	types := []fiber.Map{
+		{
+			"schemas":  []string{schemaResourceType},
+			"id":       "User",
+			"name":     "User",
+			"endpoint": "/Users",
+			"schema":   schemaUser,
+			"meta":     fiber.Map{"resourceType": "ResourceType", "location": h.baseURL + "/ResourceTypes/User"},
+		},
+		{
+			"schemas":  []string{schemaResourceType},
+			"id":       "Group",
+			"name":     "Group",
+			"endpoint": "/Groups",
+			"schema":   schemaGroup,
+			"meta":     fiber.Map{"resourceType": "ResourceType", "location": h.baseURL + "/ResourceTypes/Group"},
+		},
+	}
+	return c.JSON(listResponse(types, len(types), 1), contentType)
+}
+
+// meta is the SCIM resource metadata.
+type meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+// ref points at another resource, e.g. a group member.
+type ref struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// patchRequest is a PATCH body (RFC 7644, section 3.5.2).
+type patchRequest struct {
+	Schemas    []string  `json:"schemas"`
+	Operations []patchOp `json:"Operations"`
+}
+
+type patchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// kind returns the lower-cased operation; some clients send "Replace".
+func (op patchOp) kind() (string, error) {
+	switch kind := strings.ToLower(op.Op); kind {
+	case "add", "replace", "remove":
+		return kind, nil
+	default:
+		return "", &scimErr{status: fiber.StatusBadRequest, scimType: "invalidSyntax", detail: "unsupported patch op " + strconv.Quote(op.Op)}
+	}
+}
+
+// scimErr is a SCIM error response raised while applying a request.
+type scimErr struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e *scimErr) Error() string { return e.detail }
+
+func invalidValue(detail string) error {
+	return &scimErr{status: fiber.StatusBadRequest, scimType: "invalidValue", detail: detail}
+}
+
+// scimError writes a SCIM error response (RFC 7644, section 3.12).
+func scimError(c fiber.Ctx, status int, scimType, detail string) error {
+	body := fiber.Map{
+		"schemas": []string{schemaError},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	return c.Status(status).JSON(body, contentType)
+}
+
+// writeError reports err as a SCIM error: a *scimErr as itself, anything
+// else as an internal error.
+func writeError(c fiber.Ctx, err error) error {
+	var se *scimErr
+	if errors.As(err, &se) {
+		return scimError(c, se.status, se.scimType, se.detail)
+	}
+	return scimError(c, fiber.StatusInternalServerError, "", "internal error")
+}
+
+func listResponse[T any](resources []T, total, startIndex int) fiber.Map {
+	if resources == nil {
+		resources = []T{}
+	}
+	return fiber.Map{
+		"schemas":      []string{schemaListResponse},
+		"totalResults": total,
+		"startIndex":   startIndex,
+		"itemsPerPage": len(resources),
+		"Resources":    resources,
+	}
+}
+
+// pagination reads startIndex (1-based) and count, returning the offset and
+// limit to query with.
+func pagination(c fiber.Ctx) (startIndex, offset, limit int) {
+	startIndex = fiber.Query[int](c, "startIndex", 1)
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	limit = fiber.Query[int](c, "count", defaultCount)
+	if limit < 0 {
+		limit = 0
+	}
+	if limit > maxCount {
+		limit = maxCount
+	}
+	return startIndex, startIndex - 1, limit
+}
+
+// filterRe matches the one filter form supported: attribute eq "value".
+var filterRe = regexp.MustCompile(`(?i)^\s*([a-z][a-z0-9._:-]*)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// parseFilter parses an `attribute eq "value"` filter. The attribute is
+// returned lower-cased, since SCIM attribute names are case-insensitive.
+func parseFilter(filter string) (attr, value string, err error) {
+	m := filterRe.FindStringSubmatch(filter)
+	if m == nil {
+		return "", "", &scimErr{status: fiber.StatusBadRequest, scimType: "invalidFilter", detail: `only filters of the form attribute eq "value" are supported`}
+	}
+	if err := json.Unmarshal([]byte(`"`+m[2]+`"`), &value); err != nil {
+		return "", "", &scimErr{status: fiber.StatusBadRequest, scimType: "invalidFilter", detail: "invalid filter value"}
+	}
+	return strings.ToLower(m[1]), value, nil
+}
+
+// parseID parses a resource ID; malformed IDs can't name a resource.
+func parseID(c fiber.Ctx) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Params("id"))
+	return id, err == nil
+}
+
+// stripSchema removes a schema URN prefix from an attribute path, e.g.
+// "urn:ietf:params:scim:schemas:core:2.0:User:userName".
+func stripSchema(path, schema string) string {
+	if len(path) > len(schema) && strings.EqualFold(path[:len(schema)+1], schema+":") {
+		return path[len(schema)+1:]
+	}
+	return path
+}
+
+// decode unmarshals a request body, reporting malformed JSON as invalidSyntax.
+func decode(body []byte, v any) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return &scimErr{status: fiber.StatusBadRequest, scimType: "invalidSyntax", detail: "malformed request body"}
+	}
+	return nil
+}
+
+// decodePatch unmarshals a PATCH body.
+func decodePatch(body []byte) (*patchRequest, error) {
+	var req patchRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if len(req.Operations) == 0 {
+		return nil, &scimErr{status: fiber.StatusBadRequest, scimType: "invalidSyntax", detail: "no patch operations"}
+	}
+	return &req, nil
+}
@@ -0,0 +1,594 @@
+package scim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"golinks/internal/config"
+	"golinks/internal/db"
+	"golinks/internal/models"
+)
+
+const (
+	testToken  = "scim-test-token-that-is-long-enough"
+	testIssuer = "https://idp.example.com"
+)
+
+// memStore is an in-memory Store.
+type memStore struct {
+	users   []*models.User
+	orgs    []*models.Organization
+	groups  []*models.SCIMGroup
+	members map[uuid.UUID][]uuid.UUID // group -> users
+	revoked map[uuid.UUID]int
+
+	memberships map[uuid.UUID][]models.OrgMembership // user -> organizations
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		members:     make(map[uuid.UUID][]uuid.UUID),
+		revoked:     make(map[uuid.UUID]int),
+		memberships: make(map[uuid.UUID][]models.OrgMembership),
+	}
+}
+
+func (s *memStore) UpsertUser(_ context.Context, user *models.User) error {
+	for _, u := range s.users {
+		if u.Issuer == user.Issuer && u.Sub == user.Sub {
+			u.Username, u.Email, u.Name, u.Picture = user.Username, user.Email, user.Name, user.Picture
+			u.UpdatedAt = time.Now()
+			*user = *u
+			return nil
+		}
+	}
+	user.ID = uuid.New()
+	user.Role = models.RoleUser
+	user.CreatedAt, user.UpdatedAt = time.Now(), time.Now()
+	stored := *user
+	s.users = append(s.users, &stored)
+	return nil
+}
+
+func (s *memStore) user(id uuid.UUID) *models.User {
+	for _, u := range s.users {
+		if u.ID == id {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *memStore) GetUserByID(_ context.Context, id uuid.UUID) (*models.User, error) {
+	if u := s.user(id); u != nil {
+		copied := *u
+		return &copied, nil
+	}
+	return nil, db.ErrUserNotFound
+}
+
+func (s *memStore) GetUserBySub(_ context.Context, issuer, sub string) (*models.User, error) {
+	for _, u := range s.users {
+		if u.Issuer == issuer && u.Sub == sub {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, db.ErrUserNotFound
+}
+
+func (s *memStore) ListSCIMUsers(_ context.Context, filter db.SCIMUserFilter, offset, limit int) ([]models.User, int, error) {
+	var matched []models.User
+	for _, u := range s.users {
+		if filter.UserName != "" && u.Username != filter.UserName {
+			continue
+		}
+		if filter.ExternalID != "" && u.Sub != filter.ExternalID {
+			continue
+		}
+		if filter.Issuer != "" && u.Issuer != filter.Issuer {
+			continue
+		}
+		matched = append(matched, *u)
+	}
+	total := len(matched)
+	matched = matched[min(offset, total):]
+	return matched[:min(limit, len(matched))], total, nil
+}
+
+func (s *memStore) DeactivateUser(_ context.Context, id uuid.UUID) error {
+	if u := s.user(id); u != nil && u.DeactivatedAt == nil {
+		now := time.Now()
+		u.DeactivatedAt = &now
+	}
+	return nil
+}
+
+func (s *memStore) ReactivateUser(_ context.Context, id uuid.UUID) error {
+	if u := s.user(id); u != nil {
+		u.DeactivatedAt = nil
+	}
+	return nil
+}
+
+func (s *memStore) RevokeAllUserSessions(_ context.Context, id uuid.UUID) (int64, error) {
+	s.revoked[id]++
+	return 1, nil
+}
+
+func (s *memStore) UpdateUserRole(_ context.Context, id uuid.UUID, role string) error {
+	s.user(id).Role = role
+	return nil
+}
+
+func (s *memStore) ListUserOrganizations(_ context.Context, id uuid.UUID) ([]models.OrgMembership, error) {
+	return s.memberships[id], nil
+}
+
+func (s *memStore) AddUserOrganization(_ context.Context, id, orgID uuid.UUID, role string) error {
+	u := s.user(id)
+	if u.OrganizationID == nil {
+		u.OrganizationID = &orgID
+	}
+	for i, m := range s.memberships[id] {
+		if m.OrganizationID == orgID {
+			s.memberships[id][i].Role = role
+			return nil
+		}
+	}
+	for _, o := range s.orgs {
+		if o.ID == orgID {
+			s.memberships[id] = append(s.memberships[id], models.OrgMembership{OrganizationID: orgID, Name: o.Name, Slug: o.Slug, Role: role, JoinedAt: time.Now()})
+		}
+	}
+	return nil
+}
+
+func (s *memStore) RemoveUserOrganization(_ context.Context, id, orgID uuid.UUID) error {
+	u := s.user(id)
+	s.memberships[id] = slices.DeleteFunc(s.memberships[id], func(m models.OrgMembership) bool { return m.OrganizationID == orgID })
+	if u.OrganizationID != nil && *u.OrganizationID == orgID {
+		u.OrganizationID = nil
+		if len(s.memberships[id]) > 0 {
+			u.OrganizationID = &s.memberships[id][0].OrganizationID
+		}
+	}
+	return nil
+}
+
+func (s *memStore) GetOrCreateOrganization(_ context.Context, slug string) (*models.Organization, bool, error) {
+	for _, o := range s.orgs {
+		if o.Slug == slug {
+			return o, false, nil
+		}
+	}
+	org := &models.Organization{ID: uuid.New(), Name: slug, Slug: slug}
+	s.orgs = append(s.orgs, org)
+	return org, true, nil
+}
+
+func (s *memStore) org(slug string) *models.Organization {
+	org, _, _ := s.GetOrCreateOrganization(context.Background(), slug)
+	return org
+}
+
+func (s *memStore) CreateSCIMGroup(_ context.Context, group *models.SCIMGroup) error {
+	for _, g := range s.groups {
+		if g.DisplayName == group.DisplayName {
+			return db.ErrDuplicateSCIMGroup
+		}
+	}
+	group.ID = uuid.New()
+	group.CreatedAt, group.UpdatedAt = time.Now(), time.Now()
+	stored := *group
+	s.groups = append(s.groups, &stored)
+	return nil
+}
+
+func (s *memStore) withMembers(g *models.SCIMGroup) *models.SCIMGroup {
+	copied := *g
+	copied.Members = nil
+	for _, id := range s.members[g.ID] {
+		copied.Members = append(copied.Members, models.SCIMGroupMember{UserID: id, Display: s.user(id).Name})
+	}
+	return &copied
+}
+
+func (s *memStore) GetSCIMGroup(_ context.Context, id uuid.UUID) (*models.SCIMGroup, error) {
+	for _, g := range s.groups {
+		if g.ID == id {
+			return s.withMembers(g), nil
+		}
+	}
+	return nil, db.ErrSCIMGroupNotFound
+}
+
+func (s *memStore) ListSCIMGroups(_ context.Context, displayName string, offset, limit int) ([]models.SCIMGroup, int, error) {
+	var matched []models.SCIMGroup
+	for _, g := range s.groups {
+		if displayName == "" || g.DisplayName == displayName {
+			matched = append(matched, *s.withMembers(g))
+		}
+	}
+	total := len(matched)
+	matched = matched[min(offset, total):]
+	return matched[:min(limit, len(matched))], total, nil
+}
+
+func (s *memStore) UpdateSCIMGroup(_ context.Context, group *models.SCIMGroup) error {
+	var target *models.SCIMGroup
+	for _, g := range s.groups {
+		if g.DisplayName == group.DisplayName && g.ID != group.ID {
+			return db.ErrDuplicateSCIMGroup
+		}
+		if g.ID == group.ID {
+			target = g
+		}
+	}
+	if target == nil {
+		return db.ErrSCIMGroupNotFound
+	}
+	target.DisplayName, target.ExternalID = group.DisplayName, group.ExternalID
+	return nil
+}
+
+func (s *memStore) DeleteSCIMGroup(_ context.Context, id uuid.UUID) error {
+	for i, g := range s.groups {
+		if g.ID == id {
+			s.groups = slices.Delete(s.groups, i, i+1)
+			delete(s.members, id)
+			return nil
+		}
+	}
+	return db.ErrSCIMGroupNotFound
+}
+
+func (s *memStore) AddSCIMGroupMembers(_ context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	for _, id := range userIDs {
+		if s.user(id) != nil && !slices.Contains(s.members[groupID], id) {
+			s.members[groupID] = append(s.members[groupID], id)
+		}
+	}
+	return nil
+}
+
+func (s *memStore) RemoveSCIMGroupMembers(_ context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	s.members[groupID] = slices.DeleteFunc(s.members[groupID], func(id uuid.UUID) bool {
+		return slices.Contains(userIDs, id)
+	})
+	return nil
+}
+
+func (s *memStore) SetSCIMGroupMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	s.members[groupID] = nil
+	return s.AddSCIMGroupMembers(ctx, groupID, userIDs)
+}
+
+func (s *memStore) ListUserSCIMGroups(_ context.Context, userID uuid.UUID) ([]models.SCIMGroup, error) {
+	var groups []models.SCIMGroup
+	for _, g := range s.groups {
+		if slices.Contains(s.members[g.ID], userID) {
+			groups = append(groups, *g)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].DisplayName < groups[j].DisplayName })
+	return groups, nil
+}
+
+// scimClient is a minimal SCIM client driving the handler in-process.
+type scimClient struct {
+	t     *testing.T
+	app   *fiber.App
+	token string
+}
+
+func newTestClient(t *testing.T, store *memStore) *scimClient {
+	t.Helper()
+	cfg := &config.Config{
+		BaseURL:            "https://go.example.com",
+		SCIMBearerToken:    testToken,
+		SCIMOrgGroupPrefix: "org:",
+		OIDCProviders: []config.OIDCProvider{{
+			Name:            "default",
+			Issuer:          testIssuer,
+			AdminGroups:     []string{"golinks-admins"},
+			ModeratorGroups: []string{"golinks-mods"},
+		}},
+	}
+	app := fiber.New()
+	NewHandler(store, cfg).Register(app.Group("/scim/v2"))
+	return &scimClient{t: t, app: app, token: testToken}
+}
+
+// do sends a request and decodes a JSON response into out, returning the
+// status code.
+func (c *scimClient) do(method, path string, body, out any) int {
+	c.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, "/scim/v2"+path, reader)
+	req.Header.Set("Content-Type", contentType)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.app.Test(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			c.t.Fatalf("%s %s: decode response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func patchBody(ops ...map[string]any) map[string]any {
+	return map[string]any{"schemas": []string{schemaPatchOp}, "Operations": ops}
+}
+
+func TestSCIMRequiresBearerToken(t *testing.T) {
+	client := newTestClient(t, newMemStore())
+
+	for _, token := range []string{"", "wrong-token"} {
+		client.token = token
+		var errResp map[string]any
+		if status := client.do("GET", "/Users", nil, &errResp); status != fiber.StatusUnauthorized {
+			t.Errorf("token %q: status = %d, want 401", token, status)
+		}
+		if errResp["status"] != "401" {
+			t.Errorf("token %q: error body = %v, want a SCIM error", token, errResp)
+		}
+	}
+
+	client.token = testToken
+	if status := client.do("GET", "/ServiceProviderConfig", nil, nil); status != fiber.StatusOK {
+		t.Errorf("ServiceProviderConfig status = %d, want 200", status)
+	}
+}
+
+func TestSCIMUserLifecycle(t *testing.T) {
+	store := newMemStore()
+	client := newTestClient(t, store)
+
+	var created user
+	status := client.do("POST", "/Users", map[string]any{
+		"schemas":    []string{schemaUser},
+		"userName":   "alice@example.com",
+		"externalId": "00u1",
+		"name":       map[string]any{"givenName": "Alice", "familyName": "Smith"},
+		"emails":     []map[string]any{{"value": "alice@example.com", "primary": true}},
+	}, &created)
+	if status != fiber.StatusCreated {
+		t.Fatalf("create status = %d, want 201", status)
+	}
+	id := uuid.MustParse(created.ID)
+	stored := store.user(id)
+	if stored.Issuer != testIssuer || stored.Sub != "00u1" || stored.Name != "Alice Smith" || stored.Email != "alice@example.com" {
+		t.Errorf("stored user = %+v, want issuer/sub/name/email from the request", stored)
+	}
+	if created.Active == nil || !*created.Active {
+		t.Errorf("created user active = %v, want true", created.Active)
+	}
+
+	var list struct {
+		TotalResults int    `json:"totalResults"`
+		Resources    []user `json:"Resources"`
+	}
+	if status := client.do("GET", `/Users?filter=userName+eq+%22alice%40example.com%22`, nil, &list); status != fiber.StatusOK {
+		t.Fatalf("filter status = %d, want 200", status)
+	}
+	if list.TotalResults != 1 || list.Resources[0].ID != created.ID {
+		t.Errorf("filter by userName = %+v, want alice", list)
+	}
+
+	if status := client.do("POST", "/Users", map[string]any{"userName": "alice@example.com", "externalId": "00u2"}, nil); status != fiber.StatusConflict {
+		t.Errorf("duplicate userName status = %d, want 409", status)
+	}
+
+	// Entra ID sends booleans as strings.
+	var patched user
+	if status := client.do("PATCH", "/Users/"+created.ID, patchBody(map[string]any{"op": "Replace", "path": "active", "value": "False"}), &patched); status != fiber.StatusOK {
+		t.Fatalf("deactivate status = %d, want 200", status)
+	}
+	if patched.Active == nil || *patched.Active || !store.user(id).IsDeactivated() || store.revoked[id] == 0 {
+		t.Errorf("after active=False: active = %v, deactivated = %v, revoked = %d; want deactivated with sessions revoked",
+			patched.Active, store.user(id).IsDeactivated(), store.revoked[id])
+	}
+
+	if status := client.do("PATCH", "/Users/"+created.ID, patchBody(map[string]any{
+		"op":    "replace",
+		"value": map[string]any{"active": true, "displayName": "Alice S."},
+	}), &patched); status != fiber.StatusOK {
+		t.Fatalf("reactivate status = %d, want 200", status)
+	}
+	if store.user(id).IsDeactivated() || store.user(id).Name != "Alice S." {
+		t.Errorf("after reactivate: %+v, want active and renamed", store.user(id))
+	}
+
+	if status := client.do("PUT", "/Users/"+created.ID, map[string]any{
+		"userName": "alice.smith@example.com",
+		"emails":   []map[string]any{{"value": "alice.smith@example.com"}},
+	}, nil); status != fiber.StatusOK {
+		t.Fatalf("replace status = %d, want 200", status)
+	}
+	if u := store.user(id); u.Username != "alice.smith@example.com" || u.Email != "alice.smith@example.com" || u.Sub != "00u1" {
+		t.Errorf("after replace: %+v, want new userName and email with the same sub", u)
+	}
+
+	if status := client.do("DELETE", "/Users/"+created.ID, nil, nil); status != fiber.StatusNoContent {
+		t.Fatalf("delete status = %d, want 204", status)
+	}
+	if !store.user(id).IsDeactivated() {
+		t.Error("deleted user is not deactivated")
+	}
+
+	if status := client.do("GET", "/Users/"+uuid.NewString(), nil, nil); status != fiber.StatusNotFound {
+		t.Errorf("unknown user status = %d, want 404", status)
+	}
+}
+
+func TestSCIMCreateUserAdoptsSignedInUser(t *testing.T) {
+	store := newMemStore()
+	existing := &models.User{Issuer: testIssuer, Sub: "00u9", Name: "Bob"}
+	store.UpsertUser(context.Background(), existing)
+	client := newTestClient(t, store)
+
+	var created user
+	if status := client.do("POST", "/Users", map[string]any{"userName": "bob", "externalId": "00u9", "displayName": "Bob Jones"}, &created); status != fiber.StatusCreated {
+		t.Fatalf("create status = %d, want 201", status)
+	}
+	if created.ID != existing.ID.String() || len(store.users) != 1 {
+		t.Errorf("created %s with %d users, want the existing user %s adopted", created.ID, len(store.users), existing.ID)
+	}
+}
+
+func TestSCIMGroupsMapOrganizationsAndRoles(t *testing.T) {
+	store := newMemStore()
+	client := newTestClient(t, store)
+
+	var alice user
+	client.do("POST", "/Users", map[string]any{"userName": "alice", "externalId": "00u1"}, &alice)
+	id := uuid.MustParse(alice.ID)
+	members := []map[string]any{{"value": alice.ID}}
+
+	var orgGroup group
+	if status := client.do("POST", "/Groups", map[string]any{"displayName": "org:platform", "members": members}, &orgGroup); status != fiber.StatusCreated {
+		t.Fatalf("create org group status = %d, want 201", status)
+	}
+	platform := store.org("platform")
+	if got := store.user(id).OrganizationID; got == nil || *got != platform.ID {
+		t.Fatalf("organization = %v, want platform %s", got, platform.ID)
+	}
+
+	var modGroup group
+	if status := client.do("POST", "/Groups", map[string]any{"displayName": "golinks-mods", "members": members}, &modGroup); status != fiber.StatusCreated {
+		t.Fatalf("create mod group status = %d, want 201", status)
+	}
+	if role := store.user(id).Role; role != models.RoleOrgMod {
+		t.Errorf("role in an org and the moderator group = %q, want %q", role, models.RoleOrgMod)
+	}
+
+	var listed user
+	client.do("GET", "/Users/"+alice.ID, nil, &listed)
+	if len(listed.Groups) != 2 {
+		t.Errorf("user groups = %+v, want both groups", listed.Groups)
+	}
+
+	if status := client.do("POST", "/Groups", map[string]any{"displayName": "golinks-mods"}, nil); status != fiber.StatusConflict {
+		t.Errorf("duplicate group status = %d, want 409", status)
+	}
+
+	// Leaving the org group leaves the organization; moderators without one
+	// moderate globally, as with OIDC group mapping.
+	path := `members[value eq "` + alice.ID + `"]`
+	if status := client.do("PATCH", "/Groups/"+orgGroup.ID, patchBody(map[string]any{"op": "remove", "path": path}), nil); status != fiber.StatusOK {
+		t.Fatalf("remove member status = %d, want 200", status)
+	}
+	if u := store.user(id); u.OrganizationID != nil || u.Role != models.RoleGlobalMod {
+		t.Errorf("after leaving org group: org = %v, role = %q; want no org and %q", u.OrganizationID, u.Role, models.RoleGlobalMod)
+	}
+
+	if status := client.do("PATCH", "/Groups/"+orgGroup.ID, patchBody(map[string]any{"op": "add", "path": "members", "value": members}), nil); status != fiber.StatusOK {
+		t.Fatalf("add member status = %d, want 200", status)
+	}
+	if got := store.user(id).OrganizationID; got == nil || *got != platform.ID {
+		t.Errorf("organization after re-adding = %v, want platform", got)
+	}
+
+	if status := client.do("DELETE", "/Groups/"+modGroup.ID, nil, nil); status != fiber.StatusNoContent {
+		t.Fatalf("delete group status = %d, want 204", status)
+	}
+	if role := store.user(id).Role; role != models.RoleUser {
+		t.Errorf("role after moderator group deleted = %q, want %q", role, models.RoleUser)
+	}
+
+	var groups struct {
+		TotalResults int     `json:"totalResults"`
+		Resources    []group `json:"Resources"`
+	}
+	client.do("GET", `/Groups?filter=displayName+eq+%22org%3Aplatform%22&excludedAttributes=members`, nil, &groups)
+	if groups.TotalResults != 1 || groups.Resources[0].ID != orgGroup.ID || len(groups.Resources[0].Members) != 0 {
+		t.Errorf("filtered groups = %+v, want org:platform without members", groups)
+	}
+}
+
+func TestSCIMOrgGroupsAddMemberships(t *testing.T) {
+	store := newMemStore()
+	client := newTestClient(t, store)
+
+	var bob user
+	client.do("POST", "/Users", map[string]any{"userName": "bob", "externalId": "00u2"}, &bob)
+	id := uuid.MustParse(bob.ID)
+	members := []map[string]any{{"value": bob.ID}}
+
+	var platformGroup group
+	client.do("POST", "/Groups", map[string]any{"displayName": "org:platform", "members": members}, &platformGroup)
+	client.do("POST", "/Groups", map[string]any{"displayName": "org:data", "members": members}, nil)
+	client.do("POST", "/Groups", map[string]any{"displayName": "golinks-mods", "members": members}, nil)
+
+	platform, data := store.org("platform"), store.org("data")
+	memberships := store.memberships[id]
+	if len(memberships) != 2 {
+		t.Fatalf("memberships = %+v, want platform and data", memberships)
+	}
+	for _, m := range memberships {
+		if m.Role != models.OrgRoleMod {
+			t.Errorf("role in %s = %q, want %q", m.Slug, m.Role, models.OrgRoleMod)
+		}
+	}
+	if got := store.user(id).OrganizationID; got == nil || *got != platform.ID {
+		t.Errorf("active organization = %v, want the first joined, platform", got)
+	}
+
+	// Leaving the active organization's group activates the other one.
+	path := `members[value eq "` + bob.ID + `"]`
+	client.do("PATCH", "/Groups/"+platformGroup.ID, patchBody(map[string]any{"op": "remove", "path": path}), nil)
+	if u := store.user(id); u.OrganizationID == nil || *u.OrganizationID != data.ID || u.Role != models.RoleOrgMod {
+		t.Errorf("after leaving platform: org = %v, role = %q; want data and %q", u.OrganizationID, u.Role, models.RoleOrgMod)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		filter    string
+		attr      string
+		value     string
+		wantError bool
+	}{
+		{`userName eq "alice@example.com"`, "username", "alice@example.com", false},
+		{`externalId EQ "00u1"`, "externalid", "00u1", false},
+		{`displayName eq "Team \"A\""`, "displayname", `Team "A"`, false},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "bob"`, strings.ToLower(schemaUser) + ":username", "bob", false},
+		{`userName sw "al"`, "", "", true},
+		{`userName eq "a" and active eq true`, "", "", true},
+	}
+	for _, tt := range tests {
+		attr, value, err := parseFilter(tt.filter)
+		if (err != nil) != tt.wantError {
+			t.Errorf("parseFilter(%q) error = %v, wantError %v", tt.filter, err, tt.wantError)
+			continue
+		}
+		if attr != tt.attr || value != tt.value {
+			t.Errorf("parseFilter(%q) = %q, %q; want %q, %q", tt.filter, attr, value, tt.attr, tt.value)
+		}
+	}
+}
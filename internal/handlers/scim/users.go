@@ -0,0 +1,478 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"golinks/internal/db"
+	"golinks/internal/models"
+)
+
+// user is the SCIM User resource.
+type user struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	UserName    string   `json:"userName"`
+	Name        *name    `json:"name,omitempty"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Emails      []email  `json:"emails,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+	Groups      []ref    `json:"groups,omitempty"`
+	Meta        *meta    `json:"meta,omitempty"`
+}
+
+type name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// displayName is the name stored for the user: displayName, else the
+// formatted or given and family name, else the userName.
+func (u *user) displayName() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if u.Name != nil {
+		if u.Name.Formatted != "" {
+			return u.Name.Formatted
+		}
+		if full := strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName); full != "" {
+			return full
+		}
+	}
+	return u.UserName
+}
+
+// email is the primary email, else the first one, else the userName when it
+// is an address.
+func (u *user) email() string {
+	for _, e := range u.Emails {
+		if e.Primary && e.Value != "" {
+			return e.Value
+		}
+	}
+	for _, e := range u.Emails {
+		if e.Value != "" {
+			return e.Value
+		}
+	}
+	if strings.Contains(u.UserName, "@") {
+		return u.UserName
+	}
+	return ""
+}
+
+// toUser renders a user with their groups as a SCIM resource.
+func (h *Handler) toUser(u *models.User, groups []models.SCIMGroup) user {
+	active := !u.IsDeactivated()
+	out := user{
+		Schemas:     []string{schemaUser},
+		ID:          u.ID.String(),
+		ExternalID:  u.Sub,
+		UserName:    u.Username,
+		DisplayName: u.Name,
+		Active:      &active,
+		Meta: &meta{
+			ResourceType: "User",
+			Created:      u.CreatedAt,
+			LastModified: u.UpdatedAt,
+			Location:     h.baseURL + "/Users/" + u.ID.String(),
+		},
+	}
+	if u.Name != "" {
+		out.Name = &name{Formatted: u.Name}
+	}
+	if u.Email != "" {
+		out.Emails = []email{{Value: u.Email, Type: "work", Primary: true}}
+	}
+	for _, g := range groups {
+		out.Groups = append(out.Groups, ref{Value: g.ID.String(), Display: g.DisplayName, Ref: h.baseURL + "/Groups/" + g.ID.String()})
+	}
+	return out
+}
+
+// loadUser renders the stored user as a SCIM resource.
+func (h *Handler) loadUser(ctx context.Context, id uuid.UUID) (user, error) {
+	u, err := h.store.GetUserByID(ctx, id)
+	if err != nil {
+		return user{}, err
+	}
+	groups, err := h.store.ListUserSCIMGroups(ctx, id)
+	if err != nil {
+		return user{}, err
+	}
+	return h.toUser(u, groups), nil
+}
+
+// ListUsers lists users, optionally filtered by userName or externalId.
+func (h *Handler) ListUsers(c fiber.Ctx) error {
+	var filter db.SCIMUserFilter
+	if f := c.Query("filter"); f != "" {
+		attr, value, err := parseFilter(f)
+		if err != nil {
+			return writeError(c, err)
+		}
+		switch stripSchema(attr, schemaUser) {
+		case "username":
+			filter.Issuer, filter.UserName = h.provider.Issuer, value
+		case "externalid":
+			filter.Issuer, filter.ExternalID = h.provider.Issuer, value
+		default:
+			return scimError(c, fiber.StatusBadRequest, "invalidFilter", "users can only be filtered by userName or externalId")
+		}
+		// An empty value would match everything.
+		if value == "" {
+			return c.JSON(listResponse([]user{}, 0, 1), contentType)
+		}
+	}
+
+	startIndex, offset, limit := pagination(c)
+	users, total, err := h.store.ListSCIMUsers(c.Context(), filter, offset, limit)
+	if err != nil {
+		slog.ErrorContext(c.Context(), "scim: failed to list users", "error", err)
+		return writeError(c, err)
+	}
+	resources := make([]user, 0, len(users))
+	for i := range users {
+		groups, err := h.store.ListUserSCIMGroups(c.Context(), users[i].ID)
+		if err != nil {
+			return writeError(c, err)
+		}
+		resources = append(resources, h.toUser(&users[i], groups))
+	}
+	return c.JSON(listResponse(resources, total, startIndex), contentType)
+}
+
+// GetUser returns one user.
+func (h *Handler) GetUser(c fiber.Ctx) error {
+	id, ok := parseID(c)
+	if !ok {
+		return userNotFound(c)
+	}
+	out, err := h.loadUser(c.Context(), id)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return userNotFound(c)
+	}
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(out, contentType)
+}
+
+// CreateUser provisions a user keyed by the SCIM issuer and the externalId,
+// or the userName without one. A user that already signed in with that
+// identity is adopted rather than rejected.
+func (h *Handler) CreateUser(c fiber.Ctx) error {
+	var in user
+	if err := decode(c.Body(), &in); err != nil {
+		return writeError(c, err)
+	}
+	if in.UserName == "" {
+		return scimError(c, fiber.StatusBadRequest, "invalidValue", "userName is required")
+	}
+	ctx := c.Context()
+
+	sub := in.ExternalID
+	if sub == "" {
+		sub = in.UserName
+	}
+	existing, err := h.store.GetUserBySub(ctx, h.provider.Issuer, sub)
+	if err != nil && !errors.Is(err, db.ErrUserNotFound) {
+		return writeError(c, err)
+	}
+	u := existing
+	if u == nil {
+		u = &models.User{Issuer: h.provider.Issuer, Sub: sub}
+	}
+	if err := h.saveUser(ctx, u, &in); err != nil {
+		return writeError(c, err)
+	}
+	slog.InfoContext(ctx, "scim: user provisioned", "user_id", u.ID, "user_name", in.UserName, "adopted", existing != nil)
+
+	out, err := h.loadUser(ctx, u.ID)
+	if err != nil {
+		return writeError(c, err)
+	}
+	c.Set(fiber.HeaderLocation, out.Meta.Location)
+	return c.Status(fiber.StatusCreated).JSON(out, contentType)
+}
+
+// ReplaceUser replaces a user's attributes. The externalId is the user's
+// identity and can't change.
+func (h *Handler) ReplaceUser(c fiber.Ctx) error {
+	id, ok := parseID(c)
+	if !ok {
+		return userNotFound(c)
+	}
+	var in user
+	if err := decode(c.Body(), &in); err != nil {
+		return writeError(c, err)
+	}
+	if in.UserName == "" {
+		return scimError(c, fiber.StatusBadRequest, "invalidValue", "userName is required")
+	}
+	return h.updateUser(c, id, func(user) (user, error) {
+		return in, nil
+	})
+}
+
+// PatchUser applies PATCH operations to a user.
+func (h *Handler) PatchUser(c fiber.Ctx) error {
+	id, ok := parseID(c)
+	if !ok {
+		return userNotFound(c)
+	}
+	req, err := decodePatch(c.Body())
+	if err != nil {
+		return writeError(c, err)
+	}
+	return h.updateUser(c, id, func(u user) (user, error) {
+		for _, op := range req.Operations {
+			if err := applyUserOp(&u, op); err != nil {
+				return u, err
+			}
+		}
+		return u, nil
+	})
+}
+
+// updateUser loads a user, changes its SCIM form and saves the result.
+func (h *Handler) updateUser(c fiber.Ctx, id uuid.UUID, change func(current user) (user, error)) error {
+	ctx := c.Context()
+	u, err := h.store.GetUserByID(ctx, id)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return userNotFound(c)
+	}
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	in, err := change(h.toUser(u, nil))
+	if err != nil {
+		return writeError(c, err)
+	}
+	if in.UserName == "" {
+		return scimError(c, fiber.StatusBadRequest, "invalidValue", "userName is required")
+	}
+	if err := h.saveUser(ctx, u, &in); err != nil {
+		return writeError(c, err)
+	}
+
+	out, err := h.loadUser(ctx, id)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(out, contentType)
+}
+
+// DeleteUser deactivates a user. Their links and attribution stay; they can
+// no longer sign in and their sessions are revoked.
+func (h *Handler) DeleteUser(c fiber.Ctx) error {
+	id, ok := parseID(c)
+	if !ok {
+		return userNotFound(c)
+	}
+	u, err := h.store.GetUserByID(c.Context(), id)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return userNotFound(c)
+	}
+	if err != nil {
+		return writeError(c, err)
+	}
+	if err := h.setActive(c.Context(), u, false); err != nil {
+		return writeError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// saveUser writes in's profile and active state onto u, creating u when it
+// has no ID yet. The userName must not belong to another user of the same
+// identity provider.
+func (h *Handler) saveUser(ctx context.Context, u *models.User, in *user) error {
+	taken, _, err := h.store.ListSCIMUsers(ctx, db.SCIMUserFilter{Issuer: u.Issuer, UserName: in.UserName}, 0, 1)
+	if err != nil {
+		return err
+	}
+	if len(taken) > 0 && taken[0].ID != u.ID {
+		return &scimErr{status: fiber.StatusConflict, scimType: "uniqueness", detail: "userName is already in use"}
+	}
+
+	u.Username = in.UserName
+	u.Name = in.displayName()
+	u.Email = in.email()
+	if err := h.store.UpsertUser(ctx, u); err != nil {
+		return err
+	}
+	if in.Active != nil {
+		return h.setActive(ctx, u, *in.Active)
+	}
+	return nil
+}
+
+// setActive deactivates or reactivates a user. Deactivation revokes the
+// user's sessions so they are signed out immediately.
+func (h *Handler) setActive(ctx context.Context, u *models.User, active bool) error {
+	if active {
+		if !u.IsDeactivated() {
+			return nil
+		}
+		slog.InfoContext(ctx, "scim: user reactivated", "user_id", u.ID)
+		return h.store.ReactivateUser(ctx, u.ID)
+	}
+	if err := h.store.DeactivateUser(ctx, u.ID); err != nil {
+		return err
+	}
+	revoked, err := h.store.RevokeAllUserSessions(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	if !u.IsDeactivated() {
+		slog.InfoContext(ctx, "scim: user deactivated", "user_id", u.ID, "sessions_revoked", revoked)
+	}
+	return nil
+}
+
+func userNotFound(c fiber.Ctx) error {
+	return scimError(c, fiber.StatusNotFound, "", "user not found")
+}
+
+// applyUserOp applies one PATCH operation to u. Attributes golinks doesn't
+// store are ignored so clients pushing their full mapping keep working.
+func applyUserOp(u *user, op patchOp) error {
+	kind, err := op.kind()
+	if err != nil {
+		return err
+	}
+	if op.Path == "" {
+		if kind == "remove" {
+			return &scimErr{status: fiber.StatusBadRequest, scimType: "noTarget", detail: "remove requires a path"}
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &attrs); err != nil {
+			return invalidValue("value must be an object when path is omitted")
+		}
+		for attr, value := range attrs {
+			if err := setUserAttr(u, attr, value, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return setUserAttr(u, op.Path, op.Value, kind == "remove")
+}
+
+// setUserAttr sets, or with remove clears, the attribute at path.
+func setUserAttr(u *user, path string, value json.RawMessage, remove bool) error {
+	path = strings.ToLower(stripSchema(path, schemaUser))
+	switch {
+	case path == "active":
+		if remove {
+			return invalidValue("active can't be removed")
+		}
+		active, err := boolValue(value)
+		if err != nil {
+			return err
+		}
+		u.Active = &active
+	case path == "username":
+		if remove {
+			return invalidValue("userName can't be removed")
+		}
+		return stringValue(value, &u.UserName)
+	case path == "displayname":
+		if remove {
+			u.DisplayName = ""
+			return nil
+		}
+		return stringValue(value, &u.DisplayName)
+	case path == "name":
+		u.Name = nil
+		if !remove {
+			u.Name = &name{}
+			if err := json.Unmarshal(value, u.Name); err != nil {
+				return invalidValue("name must be an object")
+			}
+		}
+		// The stored name is derived from displayName first.
+		u.DisplayName = ""
+	case strings.HasPrefix(path, "name."):
+		if u.Name == nil {
+			u.Name = &name{}
+		}
+		u.DisplayName = ""
+		var field *string
+		switch path {
+		case "name.formatted":
+			field = &u.Name.Formatted
+		case "name.givenname":
+			field, u.Name.Formatted = &u.Name.GivenName, ""
+		case "name.familyname":
+			field, u.Name.Formatted = &u.Name.FamilyName, ""
+		default:
+			return nil
+		}
+		if remove {
+			*field = ""
+			return nil
+		}
+		return stringValue(value, field)
+	case path == "emails":
+		u.Emails = nil
+		if !remove {
+			if err := json.Unmarshal(value, &u.Emails); err != nil {
+				return invalidValue("emails must be an array")
+			}
+		}
+	case strings.HasPrefix(path, "emails["):
+		// e.g. emails[type eq "work"].value: golinks keeps one address.
+		u.Emails = nil
+		if !remove {
+			var address string
+			if err := stringValue(value, &address); err != nil {
+				return err
+			}
+			u.Emails = []email{{Value: address, Primary: true}}
+		}
+	}
+	return nil
+}
+
+// stringValue decodes a JSON string into dst.
+func stringValue(value json.RawMessage, dst *string) error {
+	if err := json.Unmarshal(value, dst); err != nil {
+		return invalidValue("expected a string value")
+	}
+	return nil
+}
+
+// boolValue decodes a JSON boolean; some clients send "True" or "False".
+func boolValue(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		switch strings.ToLower(s) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, invalidValue("expected a boolean value")
+}
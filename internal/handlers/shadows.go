@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"golinks/internal/config"
+	"golinks/internal/db"
+	"golinks/internal/models"
+)
+
+// shadowViewOrgs switches the shadowing report to the moderators' view of org
+// links hiding global ones.
+const shadowViewOrgs = "orgs"
+
+// ShadowHandler renders the report of keywords where a higher-priority scope
+// hides a lower one.
+type ShadowHandler struct {
+	db  *db.DB
+	cfg *config.Config
+}
+
+// NewShadowHandler creates a new shadowing report handler.
+func NewShadowHandler(database *db.DB, cfg *config.Config) *ShadowHandler {
+	return &ShadowHandler{db: database, cfg: cfg}
+}
+
+// shadowGroup is a link that wins resolution for its keyword together with
+// the lower-priority links it hides.
+type shadowGroup struct {
+	Keyword  string
+	Winner   models.ShadowSide
+	Shadowed []models.ShadowSide
+	SameURL  bool // every hidden link points where the winner does
+	CanDrop  bool // the viewer may delete the winner
+}
+
+// groupShadows folds shadows, which arrive with each winner's rows adjacent,
+// into one group per winner.
+func groupShadows(user *models.User, shadows []models.KeywordShadow) []shadowGroup {
+	var groups []shadowGroup
+	for _, s := range shadows {
+		if n := len(groups); n > 0 && groups[n-1].Winner.ID == s.Winner.ID {
+			groups[n-1].Shadowed = append(groups[n-1].Shadowed, s.Shadowed)
+			groups[n-1].SameURL = groups[n-1].SameURL && s.SameURL()
+			continue
+		}
+		canDrop := s.Winner.Scope == "personal" ||
+			(s.Winner.OrganizationID != nil && user.CanModerateOrg(*s.Winner.OrganizationID))
+		groups = append(groups, shadowGroup{
+			Keyword:  s.Keyword,
+			Winner:   s.Winner,
+			Shadowed: []models.ShadowSide{s.Shadowed},
+			SameURL:  s.SameURL(),
+			CanDrop:  canDrop,
+		})
+	}
+	return groups
+}
+
+// List renders the viewer's own shadowing report: their personal links that
+// override org or global links, and their organizations' links that override
+// global ones. Moderators can switch to view=orgs for org links hiding global
+// links across the organizations they moderate, narrowed with org=<id>.
+func (h *ShadowHandler) List(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	scope, canModerate := user.ModerationScope()
+	data := fiber.Map{
+		"User":        user,
+		"CanModerate": canModerate,
+	}
+
+	if c.Query("view") == shadowViewOrgs && canModerate {
+		if s := c.Query("org"); s != "" {
+			orgID, err := uuid.Parse(s)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "invalid organization id")
+			}
+			if !user.CanModerateOrg(orgID) {
+				return fiber.NewError(fiber.StatusForbidden, "you do not moderate this organization")
+			}
+			scope = &orgID
+		}
+
+		shadows, err := h.db.GetOrgShadows(c.Context(), user, scope)
+		if err != nil {
+			return err
+		}
+
+		allOrgs, err := h.db.GetAllOrganizations(c.Context())
+		if err != nil {
+			return err
+		}
+		var orgs []models.Organization
+		for _, org := range allOrgs {
+			if user.CanModerateOrg(org.ID) {
+				orgs = append(orgs, org)
+			}
+		}
+
+		data["View"] = shadowViewOrgs
+		data["Groups"] = groupShadows(user, shadows)
+		data["Orgs"] = orgs
+		data["OrgFilter"] = c.Query("org")
+		return c.Render("shadows", MergeBranding(c, data, h.cfg))
+	}
+
+	shadows, err := h.db.GetUserShadows(c.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+	var personal, org []shadowGroup
+	for _, g := range groupShadows(user, shadows) {
+		if g.Winner.Scope == "personal" {
+			g.CanDrop = h.cfg.EnablePersonalLinks
+			personal = append(personal, g)
+		} else {
+			org = append(org, g)
+		}
+	}
+
+	data["PersonalGroups"] = personal
+	data["OrgGroups"] = org
+	return c.Render("shadows", MergeBranding(c, data, h.cfg))
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+
+	"golinks/internal/models"
+)
+
+func TestGroupShadows(t *testing.T) {
+	orgID := uuid.New()
+	otherOrgID := uuid.New()
+
+	personal := models.ShadowSide{ID: uuid.New(), Scope: "personal", URL: "https://mine.example.com"}
+	orgLink := models.ShadowSide{ID: uuid.New(), Scope: "org", URL: "https://org.example.com", OrganizationID: &orgID}
+	otherOrgLink := models.ShadowSide{ID: uuid.New(), Scope: "org", URL: "https://global.example.com", OrganizationID: &otherOrgID}
+	global := models.ShadowSide{ID: uuid.New(), Scope: "global", URL: "https://global.example.com"}
+
+	shadows := []models.KeywordShadow{
+		{Keyword: "wiki", Winner: personal, Shadowed: orgLink},
+		{Keyword: "wiki", Winner: personal, Shadowed: global},
+		{Keyword: "docs", Winner: orgLink, Shadowed: global},
+		{Keyword: "home", Winner: otherOrgLink, Shadowed: global},
+	}
+
+	mod := &models.User{Role: models.RoleOrgMod, OrganizationID: &orgID}
+	groups := groupShadows(mod, shadows)
+	if len(groups) != 3 {
+		t.Fatalf("groupShadows() returned %d groups, want 3", len(groups))
+	}
+
+	tests := []struct {
+		keyword  string
+		shadowed int
+		sameURL  bool
+		canDrop  bool
+	}{
+		{"wiki", 2, false, true},
+		{"docs", 1, false, true},
+		{"home", 1, true, false},
+	}
+	for i, tt := range tests {
+		g := groups[i]
+		if g.Keyword != tt.keyword || len(g.Shadowed) != tt.shadowed || g.SameURL != tt.sameURL || g.CanDrop != tt.canDrop {
+			t.Errorf("group %d = %s hiding %d, sameURL %v, canDrop %v; want %s hiding %d, sameURL %v, canDrop %v",
+				i, g.Keyword, len(g.Shadowed), g.SameURL, g.CanDrop, tt.keyword, tt.shadowed, tt.sameURL, tt.canDrop)
+		}
+	}
+
+	// Regular users can only drop their own overrides.
+	groups = groupShadows(&models.User{Role: models.RoleUser}, shadows)
+	if !groups[0].CanDrop || groups[1].CanDrop {
+		t.Errorf("regular user CanDrop = %v, %v; want true, false", groups[0].CanDrop, groups[1].CanDrop)
+	}
+}
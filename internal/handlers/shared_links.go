@@ -272,7 +272,7 @@ func (h *SharedLinkHandler) renderAcceptDeclineResponse(c fiber.Ctx, userID uuid
 	}
 
 	// Render updated personal links list
-	links, err := h.db.GetUserLinks(c.Context(), userID)
+	links, err := loadUserLinkCards(c.Context(), h.db, userID, "")
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"golinks/internal/analytics"
+	"golinks/internal/config"
+	"golinks/internal/db"
+	"golinks/internal/models"
+)
+
+// StatsHandler renders click analytics pages.
+type StatsHandler struct {
+	db  *db.DB
+	cfg *config.Config
+}
+
+// NewStatsHandler creates a new stats handler.
+func NewStatsHandler(database *db.DB, cfg *config.Config) *StatsHandler {
+	return &StatsHandler{db: database, cfg: cfg}
+}
+
+// LinkStats renders the analytics page for a single link.
+func (h *StatsHandler) LinkStats(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid link ID")
+	}
+
+	link, err := h.db.GetLinkByID(c.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrLinkNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "link not found")
+		}
+		return err
+	}
+	if !analytics.CanView(user, link) {
+		return fiber.NewError(fiber.StatusNotFound, "link not found")
+	}
+	if !canModerate(user, link) {
+		visible, err := h.db.LinkVisibleTo(c.Context(), link.ID, &user.ID)
+		if err != nil {
+			return err
+		}
+		if !visible {
+			return fiber.NewError(fiber.StatusNotFound, "link not found")
+		}
+	}
+
+	w, err := analytics.NewWindow(c.Query("range"), c.Query("granularity"), time.Now())
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	stats, err := analytics.ForLink(c.Context(), h.db, link, w)
+	if err != nil {
+		return err
+	}
+
+	return c.Render("link_stats", MergeBranding(c, fiber.Map{
+		"User":        user,
+		"Link":        link,
+		"Stats":       stats,
+		"Ranges":      analytics.Ranges,
+		"SeriesData":  seriesData(stats.Series),
+		"SeriesStart": stats.From.Format("2006-01-02"),
+	}, h.cfg))
+}
+
+// Top renders the top-N leaderboards for global links and the user's org.
+func (h *StatsHandler) Top(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	scope := c.Query("scope", models.ScopeGlobal)
+	orgID, err := analytics.OrgForScope(user, scope, c.Query("org_id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	w, err := analytics.NewWindow(c.Query("range"), "", time.Now())
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	top, err := analytics.TopLinks(c.Context(), h.db, &user.ID, orgID, scope, w, parseTopLimit(c))
+	if err != nil {
+		return err
+	}
+
+	// Admins and global moderators can switch between organizations.
+	var orgs []models.Organization
+	if user.IsGlobalMod() {
+		orgs, err = h.db.GetAllOrganizations(c.Context())
+		if err != nil {
+			return err
+		}
+	}
+
+	selectedOrg := ""
+	if orgID != nil {
+		selectedOrg = orgID.String()
+	}
+
+	return c.Render("stats", MergeBranding(c, fiber.Map{
+		"User":        user,
+		"Top":         top,
+		"Scope":       scope,
+		"Range":       w.Range,
+		"Ranges":      analytics.Ranges,
+		"Orgs":        orgs,
+		"SelectedOrg": selectedOrg,
+	}, h.cfg))
+}
+
+// TopCSV downloads a top-N leaderboard as CSV.
+func (h *StatsHandler) TopCSV(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	scope := c.Query("scope", models.ScopeGlobal)
+	orgID, err := analytics.OrgForScope(user, scope, c.Query("org_id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	w, err := analytics.NewWindow(c.Query("range"), "", time.Now())
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	top, err := analytics.TopLinks(c.Context(), h.db, &user.ID, orgID, scope, w, parseTopLimit(c))
+	if err != nil {
+		return err
+	}
+
+	orgNames, err := h.orgNames(c)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="top-links-`+scope+`-`+w.Range+`.csv"`)
+	return analytics.WriteTopLinksCSV(c.Response().BodyWriter(), top, orgNames)
+}
+
+// orgNames maps organization IDs to display names for CSV export.
+func (h *StatsHandler) orgNames(c fiber.Ctx) (map[string]string, error) {
+	orgs, err := h.db.GetAllOrganizations(c.Context())
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(orgs))
+	for _, o := range orgs {
+		names[o.ID.String()] = o.Name
+	}
+	return names, nil
+}
+
+// parseTopLimit reads the leaderboard size from the query string (default 20, max 100).
+func parseTopLimit(c fiber.Ctx) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit < 1 {
+		return 20
+	}
+	if limit > 100 {
+		return 100
+	}
+	return limit
+}
+
+// seriesData formats click counts as a comma-separated list for the chart.
+func seriesData(series []models.ClickPoint) string {
+	parts := make([]string, len(series))
+	for i, p := range series {
+		parts[i] = strconv.FormatInt(p.Clicks, 10)
+	}
+	return strings.Join(parts, ",")
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v3"
@@ -14,6 +16,61 @@ import (
 	"golinks/internal/validation"
 )
 
+// userLinkSparklineDays is the number of days covered by /my-links sparklines.
+const userLinkSparklineDays = 14
+
+// userLinkSortRecent orders personal links by most recent click instead of keyword.
+const userLinkSortRecent = "recent"
+
+// userLinkWithSparkline wraps a UserLink with pre-computed sparkline data for /my-links.
+type userLinkWithSparkline struct {
+	models.UserLink
+	SparklineData string // comma-separated daily click counts, oldest first
+}
+
+// loadUserLinkCards fetches a user's personal links in the requested order
+// together with their sparkline data.
+func loadUserLinkCards(ctx context.Context, database *db.DB, userID uuid.UUID, sort string) ([]userLinkWithSparkline, error) {
+	var links []models.UserLink
+	var err error
+	if sort == userLinkSortRecent {
+		links, err = database.GetUserLinksByRecentUse(ctx, userID)
+	} else {
+		links, err = database.GetUserLinks(ctx, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(links))
+	for i, link := range links {
+		ids[i] = link.ID
+	}
+	historyMap, err := database.GetUserLinkClickHistoryBatch(ctx, ids, userLinkSparklineDays)
+	if err != nil {
+		return nil, err
+	}
+
+	cards := make([]userLinkWithSparkline, len(links))
+	for i, link := range links {
+		cards[i] = userLinkWithSparkline{UserLink: link, SparklineData: userLinkSparkline(historyMap[link.ID])}
+	}
+	return cards, nil
+}
+
+// userLinkSparkline formats daily click counts for the sparkline renderer,
+// zero-filling links without history.
+func userLinkSparkline(history []int) string {
+	if len(history) == 0 {
+		history = make([]int, userLinkSparklineDays)
+	}
+	parts := make([]string, len(history))
+	for i, v := range history {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
+
 // UserLinkHandler handles user-specific link management.
 type UserLinkHandler struct {
 	db  *db.DB
@@ -28,13 +85,21 @@ func NewUserLinkHandler(database *db.DB, cfg *config.Config) *UserLinkHandler {
 // List renders the my links page with all user link overrides, pending submissions, and shares.
 func (h *UserLinkHandler) List(c fiber.Ctx) error {
 	user := c.Locals("user").(*models.User)
+	sort := c.Query("sort")
 
 	// Get personal links
-	personalLinks, err := h.db.GetUserLinks(c.Context(), user.ID)
+	personalLinks, err := loadUserLinkCards(c.Context(), h.db, user.ID, sort)
 	if err != nil {
 		return err
 	}
 
+	if c.Get("HX-Request") == "true" {
+		return c.Render("partials/user_links_list", fiber.Map{
+			"UserLinks": personalLinks,
+			"Sort":      sort,
+		}, "")
+	}
+
 	// Get pending submissions (org/global links awaiting approval)
 	pendingLinks, err := h.db.GetPendingLinksByUser(c.Context(), user.ID)
 	if err != nil {
@@ -52,13 +117,14 @@ func (h *UserLinkHandler) List(c fiber.Ctx) error {
 		return err
 	}
 
-	return c.Render("my_links", MergeBranding(fiber.Map{
+	return c.Render("my_links", MergeBranding(c, fiber.Map{
 		"UserLinks":      personalLinks,
+		"Sort":           sort,
 		"PendingLinks":   pendingLinks,
 		"IncomingShares": incomingShares,
 		"OutgoingShares": outgoingShares,
 		"User":           user,
-	}, h.cfg, c.Path()))
+	}, h.cfg))
 }
 
 // PendingCount returns an HTML badge showing the number of pending submissions for the current user.
@@ -122,13 +188,15 @@ func (h *UserLinkHandler) Create(c fiber.Ctx) error {
 	}
 
 	// Return the updated list for HTMX
-	links, err := h.db.GetUserLinks(c.Context(), user.ID)
+	sort := c.FormValue("sort")
+	links, err := loadUserLinkCards(c.Context(), h.db, user.ID, sort)
 	if err != nil {
 		return err
 	}
 
 	return c.Render("partials/user_links_list", fiber.Map{
 		"UserLinks": links,
+		"Sort":      sort,
 	}, "")
 }
 
@@ -190,9 +258,15 @@ func (h *UserLinkHandler) Update(c fiber.Ctx) error {
 		return err
 	}
 
+	historyMap, err := h.db.GetUserLinkClickHistoryBatch(c.Context(), []uuid.UUID{link.ID}, userLinkSparklineDays)
+	if err != nil {
+		return err
+	}
+
 	return c.Render("partials/user_link_card", fiber.Map{
-		"Link": link,
-		"User": user,
+		"Link":          link,
+		"SparklineData": userLinkSparkline(historyMap[link.ID]),
+		"User":          user,
 	}, "")
 }
 
@@ -215,3 +289,120 @@ func (h *UserLinkHandler) Delete(c fiber.Ctx) error {
 	// Return empty for HTMX to remove the element
 	return c.SendString("")
 }
+
+// PromoteForm renders the inline form for proposing a personal link as an org
+// or global link.
+func (h *UserLinkHandler) PromoteForm(c fiber.Ctx) error {
+	user := c.Locals("user").(*models.User)
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid link ID")
+	}
+
+	link, err := h.db.GetUserLinkByID(c.Context(), id, user.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrUserLinkNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Link not found")
+		}
+		return err
+	}
+
+	return c.Render("partials/user_link_promote_form", fiber.Map{
+		"Link":          link,
+		"User":          user,
+		"CanProposeOrg": h.cfg.EnableOrgLinks && user.OrganizationID != nil,
+	}, "")
+}
+
+// Promote proposes a personal link as an org or global link. The link is
+// always submitted for moderator review, together with the personal link's
+// usage as evidence; once approved its click history moves to the new link
+// and the personal link is deleted unless keep_personal is set.
+func (h *UserLinkHandler) Promote(c fiber.Ctx) error {
+	user := c.Locals("user").(*models.User)
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid link ID")
+	}
+
+	userLink, err := h.db.GetUserLinkByID(c.Context(), id, user.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrUserLinkNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Link not found")
+		}
+		return err
+	}
+
+	reason := strings.TrimSpace(c.FormValue("reason"))
+	if reason == "" {
+		return htmxError(c, "Please explain why this link should be shared")
+	}
+
+	link := &models.Link{
+		Keyword:     userLink.Keyword,
+		URL:         userLink.URL,
+		Description: userLink.Description,
+		Reason:      reason,
+		SubmittedBy: &user.ID,
+	}
+	switch c.FormValue("scope") {
+	case models.ScopeGlobal:
+		link.Scope = models.ScopeGlobal
+	case models.ScopeOrg:
+		if !h.cfg.EnableOrgLinks {
+			return htmxError(c, "Organization links are not enabled")
+		}
+		if user.OrganizationID == nil {
+			return htmxError(c, "You must be a member of an organization to propose org links")
+		}
+		settings, err := h.db.GetOrgSettings(c.Context(), *user.OrganizationID)
+		if err != nil {
+			return err
+		}
+		if ok, msg := settings.ValidateLink(link.Keyword, link.URL); !ok {
+			return htmxError(c, msg)
+		}
+		link.Scope = models.ScopeOrg
+		link.OrganizationID = user.OrganizationID
+	default:
+		return htmxError(c, "Invalid scope")
+	}
+
+	if err := h.db.SubmitLinkForApproval(c.Context(), link); err != nil {
+		if errors.Is(err, db.ErrDuplicateKeyword) {
+			return htmxError(c, "A "+link.Scope+" link with this keyword already exists or is pending approval")
+		}
+		return err
+	}
+	if _, err := h.db.CreateLinkPromotion(c.Context(), link.ID, userLink.ID, c.FormValue("keep_personal") == "on"); err != nil {
+		_ = h.db.DeleteLink(c.Context(), link.ID)
+		return err
+	}
+
+	if Notifier != nil {
+		go Notifier.NotifyModeratorsLinkSubmitted(c.Context(), link, user)
+	}
+	var modIDs []uuid.UUID
+	if link.OrganizationID != nil {
+		modIDs, err = h.db.GetOrgModeratorIDs(c.Context(), *link.OrganizationID)
+	} else {
+		modIDs, err = h.db.GetGlobalModeratorIDs(c.Context())
+	}
+	if err == nil {
+		fanOutSubmissionNotifications(c, h.db, modIDs, link)
+	}
+
+	historyMap, err := h.db.GetUserLinkClickHistoryBatch(c.Context(), []uuid.UUID{userLink.ID}, userLinkSparklineDays)
+	if err != nil {
+		return err
+	}
+
+	return c.Render("partials/user_link_card", fiber.Map{
+		"Link":          userLink,
+		"SparklineData": userLinkSparkline(historyMap[userLink.ID]),
+		"User":          user,
+		"Proposed":      link.Scope,
+	}, "")
+}
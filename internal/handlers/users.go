@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
 
@@ -45,13 +47,13 @@ func (h *UserHandler) ListUsers(c fiber.Ctx) error {
 		return err
 	}
 
-	return c.Render("users", MergeBranding(fiber.Map{
+	return c.Render("users", MergeBranding(c, fiber.Map{
 		"User":      user,
 		"Users":     users,
 		"Orgs":      orgs,
 		"OrgCounts": orgCounts,
 		"Roles":     []string{models.RoleUser, models.RoleOrgMod, models.RoleGlobalMod, models.RoleAdmin},
-	}, h.cfg, c.Path()))
+	}, h.cfg))
 }
 
 // UpdateUserRole updates a user's role (admin only).
@@ -113,7 +115,8 @@ func (h *UserHandler) UpdateUserRole(c fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusOK)
 }
 
-// UpdateUserOrg updates a user's organization (admin only).
+// UpdateUserOrg sets a user's active organization, adding them to it if
+// needed; "none" removes them from all organizations (admin only).
 func (h *UserHandler) UpdateUserOrg(c fiber.Ctx) error {
 	currentUser, ok := c.Locals("user").(*models.User)
 	if !ok || !currentUser.IsAdmin() {
@@ -184,9 +187,321 @@ func (h *UserHandler) DeleteUser(c fiber.Ctx) error {
 	}
 
 	if err := h.db.DeleteUser(c.Context(), userID); err != nil {
+		if errors.Is(err, db.ErrUserAuthorsLinks) {
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
 		return err
 	}
 
 	// Return empty response - HTMX will remove the row with outerHTML swap
 	return c.SendString("")
 }
+
+// DeactivateUser blocks a user from signing in and signs them out everywhere,
+// keeping their links and attribution (admin only).
+func (h *UserHandler) DeactivateUser(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok || !currentUser.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid user ID")
+	}
+	if userID == currentUser.ID {
+		return fiber.NewError(fiber.StatusBadRequest, "cannot deactivate your own account")
+	}
+
+	if err := h.db.DeactivateUser(c.Context(), userID); err != nil {
+		return err
+	}
+	if _, err := h.db.RevokeAllUserSessions(c.Context(), userID); err != nil {
+		return err
+	}
+	return h.renderUserRow(c, currentUser, userID)
+}
+
+// ReactivateUser lets a deactivated user sign in again (admin only).
+func (h *UserHandler) ReactivateUser(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok || !currentUser.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid user ID")
+	}
+
+	if err := h.db.ReactivateUser(c.Context(), userID); err != nil {
+		return err
+	}
+	return h.renderUserRow(c, currentUser, userID)
+}
+
+// OffboardPage renders the offboarding form for a departing user (admin only).
+func (h *UserHandler) OffboardPage(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok || !currentUser.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid user ID")
+	}
+	if userID == currentUser.ID {
+		return fiber.NewError(fiber.StatusBadRequest, "cannot offboard your own account")
+	}
+
+	target, err := h.db.GetUserByID(c.Context(), userID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	authored, err := h.db.CountAuthoredLinks(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+	personalLinks, err := h.db.GetUserLinks(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+	editRequests, err := h.db.CountPendingEditRequestsByUser(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+	shares, err := h.db.GetIncomingShares(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	// Links can only be handed to someone who can still sign in.
+	users, err := h.db.GetAllUsersWithOrgs(c.Context())
+	if err != nil {
+		return err
+	}
+	var recipients []db.UserWithOrg
+	for _, u := range users {
+		if u.ID != userID && !u.IsDeactivated() {
+			recipients = append(recipients, u)
+		}
+	}
+
+	var org *models.Organization
+	if target.OrganizationID != nil {
+		if org, err = h.db.GetOrganizationByID(c.Context(), *target.OrganizationID); err != nil && !errors.Is(err, db.ErrOrgNotFound) {
+			return err
+		}
+	}
+
+	return c.Render("user_offboard", MergeBranding(c, fiber.Map{
+		"User":           currentUser,
+		"TargetUser":     target,
+		"TargetOrg":      org,
+		"AuthoredLinks":  authored,
+		"PersonalLinks":  personalLinks,
+		"EditRequests":   editRequests,
+		"IncomingShares": len(shares),
+		"Recipients":     recipients,
+	}, h.cfg))
+}
+
+// Offboard deactivates a departing user and hands their links to another
+// user or the organization (admin only).
+func (h *UserHandler) Offboard(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok || !currentUser.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid user ID")
+	}
+
+	opts := db.OffboardOptions{Reason: c.FormValue("reason")}
+	if to := c.FormValue("transfer_to"); to != "" && to != "org" {
+		id, err := uuid.Parse(to)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid transfer user ID")
+		}
+		opts.TransferTo = &id
+	}
+	args := c.Request().PostArgs()
+	for _, v := range args.PeekMulti("user_link_ids") {
+		id, err := uuid.Parse(string(v))
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid personal link ID")
+		}
+		opts.SubmitUserLinks = append(opts.SubmitUserLinks, id)
+	}
+
+	result, err := offboardUser(c, h.db, currentUser, userID, opts)
+	if err != nil {
+		return err
+	}
+	return c.Render("partials/offboard_result", fiber.Map{
+		"Result": result,
+	}, "")
+}
+
+// offboardUser validates an offboarding request and carries it out, signing
+// the user out everywhere. Validation failures are *fiber.Error.
+func offboardUser(c fiber.Ctx, database *db.DB, currentUser *models.User, userID uuid.UUID, opts db.OffboardOptions) (*db.OffboardResult, error) {
+	if userID == currentUser.ID {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "cannot offboard your own account")
+	}
+	target, err := database.GetUserByID(c.Context(), userID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.TransferTo != nil {
+		if *opts.TransferTo == userID {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "cannot transfer links to the departing user")
+		}
+		to, err := database.GetUserByID(c.Context(), *opts.TransferTo)
+		if errors.Is(err, db.ErrUserNotFound) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "transfer user not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if to.IsDeactivated() {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "cannot transfer links to a deactivated user")
+		}
+	}
+	if opts.Reason == "" {
+		name := target.Name
+		if name == "" {
+			name = target.Email
+		}
+		opts.Reason = "Submitted from " + name + "'s personal links when they were offboarded"
+	}
+
+	result, err := database.OffboardUser(c.Context(), userID, opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := database.RevokeAllUserSessions(c.Context(), userID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// renderUserRow renders a user's row in the user management table.
+func (h *UserHandler) renderUserRow(c fiber.Ctx, currentUser *models.User, userID uuid.UUID) error {
+	users, err := h.db.GetAllUsersWithOrgs(c.Context())
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		if u.ID == userID {
+			orgs, err := h.db.GetAllOrganizations(c.Context())
+			if err != nil {
+				return err
+			}
+			return c.Render("partials/user_row", fiber.Map{
+				"UserRow":     u,
+				"CurrentUser": currentUser,
+				"Orgs":        orgs,
+				"Roles":       []string{models.RoleUser, models.RoleOrgMod, models.RoleGlobalMod, models.RoleAdmin},
+			}, "")
+		}
+	}
+	return fiber.NewError(fiber.StatusNotFound, "user not found")
+}
+
+// ListSessions renders a user's active sessions (admin only).
+func (h *UserHandler) ListSessions(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok || !currentUser.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid user ID")
+	}
+
+	target, err := h.db.GetUserByID(c.Context(), userID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	sessions, err := activeSessions(c, h.db, userID)
+	if err != nil {
+		return err
+	}
+
+	return c.Render("user_sessions", MergeBranding(c, fiber.Map{
+		"User":        currentUser,
+		"TargetUser":  target,
+		"Sessions":    sessions,
+		"SessionsURL": "/admin/users/" + userID.String() + "/sessions",
+		"AdminView":   true,
+	}, h.cfg))
+}
+
+// RevokeSession signs out one of a user's sessions (admin only).
+func (h *UserHandler) RevokeSession(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok || !currentUser.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid user ID")
+	}
+	sessionID, err := uuid.Parse(c.Params("sessionId"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid session ID")
+	}
+
+	if err := h.db.RevokeUserSession(c.Context(), userID, sessionID); err != nil && !errors.Is(err, db.ErrUserSessionNotFound) {
+		return err
+	}
+	return h.renderSessionList(c, userID)
+}
+
+// RevokeAllSessions signs a user out everywhere (admin only).
+func (h *UserHandler) RevokeAllSessions(c fiber.Ctx) error {
+	currentUser, ok := c.Locals("user").(*models.User)
+	if !ok || !currentUser.IsAdmin() {
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid user ID")
+	}
+
+	if _, err := h.db.RevokeAllUserSessions(c.Context(), userID); err != nil {
+		return err
+	}
+	return h.renderSessionList(c, userID)
+}
+
+func (h *UserHandler) renderSessionList(c fiber.Ctx, userID uuid.UUID) error {
+	sessions, err := activeSessions(c, h.db, userID)
+	if err != nil {
+		return err
+	}
+	return c.Render("partials/session_list", fiber.Map{
+		"Sessions":    sessions,
+		"SessionsURL": "/admin/users/" + userID.String() + "/sessions",
+		"AdminView":   true,
+	}, "")
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"golinks/internal/config"
+	"golinks/internal/db"
+	"golinks/internal/models"
+	"golinks/internal/validation"
+)
+
+// wantedLimit is the number of entries shown in the "most wanted" report.
+const wantedLimit = 50
+
+// WantedHandler renders the "most wanted" missing keywords report.
+type WantedHandler struct {
+	db  *db.DB
+	cfg *config.Config
+}
+
+// NewWantedHandler creates a new wanted keywords handler.
+func NewWantedHandler(database *db.DB, cfg *config.Config) *WantedHandler {
+	return &WantedHandler{db: database, cfg: cfg}
+}
+
+// List renders the most requested keywords that do not exist for the viewer's
+// organization. Global moderators can switch to demand across all orgs.
+func (h *WantedHandler) List(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	allOrgs := c.Query("scope") == "all" && user.IsGlobalMod()
+
+	wanted, err := h.db.GetWantedKeywords(c.Context(), user.OrganizationID, allOrgs, wantedLimit)
+	if err != nil {
+		return err
+	}
+
+	_, canDismiss := user.ModerationScope()
+
+	return c.Render("wanted", MergeBranding(c, fiber.Map{
+		"User":       user,
+		"Wanted":     wanted,
+		"AllOrgs":    allOrgs,
+		"CanDismiss": canDismiss,
+	}, h.cfg))
+}
+
+// Dismiss permanently hides a keyword from the report (moderators only).
+// Global moderators dismiss it everywhere, org moderators for their org.
+func (h *WantedHandler) Dismiss(c fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	orgID, ok := user.ModerationScope()
+	if !ok {
+		return fiber.NewError(fiber.StatusForbidden, "moderator access required")
+	}
+
+	keyword := validation.NormalizeKeyword(c.Params("keyword"))
+	if !validation.ValidateKeyword(keyword) {
+		return htmxError(c, "Invalid keyword")
+	}
+
+	if err := h.db.DismissWantedKeyword(c.Context(), keyword, orgID, user.ID); err != nil {
+		return err
+	}
+
+	// Return empty for HTMX to remove the row
+	return c.SendString("")
+}
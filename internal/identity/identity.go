@@ -0,0 +1,187 @@
+// Package identity creates and updates users from what an authentication
+// source vouches for: OIDC claims or trusted reverse-proxy headers.
+package identity
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+
+	"golinks/internal/config"
+	"golinks/internal/db"
+	"golinks/internal/models"
+)
+
+// SyncUser creates or updates the user provider p vouches for with claims
+// and applies p's organization claim and group role mapping. fallbackGroups
+// are used when the claims carry no groups; debug logs missing groups.
+func SyncUser(ctx context.Context, database *db.DB, p *config.OIDCProvider, claimsMap map[string]any, fallbackGroups []string, debug bool) (*models.User, error) {
+	// Extract standard claims
+	sub, _ := claimsMap["sub"].(string)
+	email, _ := claimsMap["email"].(string)
+	name, _ := claimsMap["name"].(string)
+	picture, _ := claimsMap["picture"].(string)
+	username, _ := claimsMap["preferred_username"].(string)
+
+	// Upsert user first
+	user := &models.User{
+		Issuer:   p.Issuer,
+		Sub:      sub,
+		Username: username,
+		Email:    email,
+		Name:     name,
+		Picture:  picture,
+	}
+	if err := database.UpsertUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	var groups []string
+	if p.GroupsClaim != "" {
+		groups = ExtractGroups(claimsMap, p.GroupsClaim)
+		// Fall back to ID token groups if the userinfo merge overwrote them
+		if len(groups) == 0 {
+			groups = fallbackGroups
+		}
+		// Restricted links are shared with groups from the latest sign-in.
+		if err := database.SetUserGroups(ctx, user.ID, groups); err != nil {
+			log.Printf("Warning: failed to store groups for user %s: %v", sub, err)
+		}
+	}
+
+	// Resolve the group-mapped role first: moderators moderate every
+	// organization the claim puts them in.
+	var mappedRole string
+	if p.HasGroupRoleMapping() {
+		if len(groups) == 0 && debug {
+			log.Printf("Warning: OIDC group role mapping is configured but no groups found in claim '%s'", p.GroupsClaim)
+		}
+		mappedRole = ResolveRoleFromGroups(groups, p)
+	}
+
+	// Handle organization claim if configured. A single value or an array
+	// of slugs; each becomes a membership, and the first is the active
+	// organization for users who aren't in one of the others already.
+	if p.OrgClaim != "" {
+		var orgIDs []uuid.UUID
+		var createdOrgs []*models.Organization
+		for _, orgSlug := range ExtractGroups(claimsMap, p.OrgClaim) {
+			if orgSlug == "" {
+				continue
+			}
+			org, created, err := database.GetOrCreateOrganization(ctx, orgSlug)
+			if err != nil {
+				log.Printf("Warning: failed to get organization %s: %v", orgSlug, err)
+				continue
+			}
+			orgIDs = append(orgIDs, org.ID)
+			if created {
+				createdOrgs = append(createdOrgs, org)
+			}
+		}
+
+		if len(orgIDs) > 0 {
+			// Without group mapping, roles within organizations are managed
+			// by admins and kept.
+			var orgRole string
+			if p.HasGroupRoleMapping() {
+				orgRole = models.OrgRoleMember
+				if mappedRole == "moderator" {
+					orgRole = models.OrgRoleMod
+				}
+			}
+			if err := database.SetUserOrganizations(ctx, user.ID, orgIDs, orgRole); err != nil {
+				log.Printf("Warning: failed to update organizations for user %s: %v", sub, err)
+			} else if synced, err := database.GetUserByID(ctx, user.ID); err == nil {
+				user = synced
+			}
+		}
+
+		// New org + active group mapping → promote any existing users
+		// in this org who were previously mapped to moderator
+		if p.HasGroupRoleMapping() {
+			for _, org := range createdOrgs {
+				if promErr := database.PromoteOrgModerators(ctx, org.ID); promErr != nil {
+					log.Printf("Warning: failed to promote org moderators for new org %s: %v", org.Slug, promErr)
+				}
+			}
+		}
+	}
+
+	// Apply OIDC group-based role mapping when configured.
+	// Admin > moderator > user.  Moderator-mapped users become org_mod when they
+	// belong to an organisation, global_mod otherwise.
+	if p.HasGroupRoleMapping() {
+		finalRole := FinalRoleFromMapped(mappedRole, user.OrganizationID != nil)
+		if err := database.UpdateUserRoleFromOIDC(ctx, user.ID, mappedRole, finalRole); err != nil {
+			log.Printf("Warning: failed to update role from OIDC groups for user %s: %v", sub, err)
+		}
+	}
+
+	return user, nil
+}
+
+// ExtractGroups pulls a string slice out of a claims map value that may be
+// a []any (most providers) or a bare string.
+func ExtractGroups(claimsMap map[string]any, claimName string) []string {
+	val, ok := claimsMap[claimName]
+	if !ok {
+		return nil
+	}
+	switch v := val.(type) {
+	case []any:
+		groups := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+		return groups
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	}
+	return nil
+}
+
+// ResolveRoleFromGroups returns the highest role implied by the user's OIDC
+// groups: "admin", "moderator", or "user".  This is the intermediate value —
+// the final DB role is determined by FinalRoleFromMapped.
+func ResolveRoleFromGroups(groups []string, p *config.OIDCProvider) string {
+	groupSet := make(map[string]struct{}, len(groups))
+	for _, g := range groups {
+		groupSet[g] = struct{}{}
+	}
+
+	for _, ag := range p.AdminGroups {
+		if _, ok := groupSet[ag]; ok {
+			return "admin"
+		}
+	}
+	for _, mg := range p.ModeratorGroups {
+		if _, ok := groupSet[mg]; ok {
+			return "moderator"
+		}
+	}
+	return "user"
+}
+
+// FinalRoleFromMapped converts the intermediate mapped role into the actual
+// role constant stored in the database.  Moderator-mapped users become org_mod
+// when they belong to an organisation (scoped to that org's keywords only) or
+// global_mod when they do not.
+func FinalRoleFromMapped(mappedRole string, hasOrg bool) string {
+	switch mappedRole {
+	case "admin":
+		return models.RoleAdmin
+	case "moderator":
+		if hasOrg {
+			return models.RoleOrgMod
+		}
+		return models.RoleGlobalMod
+	default:
+		return models.RoleUser
+	}
+}
@@ -0,0 +1,63 @@
+package identity
+
+import (
+	"testing"
+
+	"golinks/internal/config"
+	"golinks/internal/models"
+)
+
+func TestResolveRoleFromGroupsPerProvider(t *testing.T) {
+	corp := &config.OIDCProvider{Name: "corp", AdminGroups: []string{"golinks-admin"}, ModeratorGroups: []string{"link-managers"}}
+	acme := &config.OIDCProvider{Name: "acme", ModeratorGroups: []string{"golinks-admin"}}
+
+	// The same group name can mean different things at different providers.
+	if got := ResolveRoleFromGroups([]string{"golinks-admin"}, corp); got != "admin" {
+		t.Errorf("corp role = %q, want admin", got)
+	}
+	if got := ResolveRoleFromGroups([]string{"golinks-admin"}, acme); got != "moderator" {
+		t.Errorf("acme role = %q, want moderator", got)
+	}
+	if got := ResolveRoleFromGroups([]string{"link-managers"}, acme); got != "user" {
+		t.Errorf("acme role for corp-only group = %q, want user", got)
+	}
+}
+
+func TestFinalRoleFromMapped(t *testing.T) {
+	tests := []struct {
+		mapped string
+		hasOrg bool
+		want   string
+	}{
+		{"admin", false, models.RoleAdmin},
+		{"admin", true, models.RoleAdmin},
+		{"moderator", true, models.RoleOrgMod},
+		{"moderator", false, models.RoleGlobalMod},
+		{"user", true, models.RoleUser},
+	}
+	for _, tt := range tests {
+		if got := FinalRoleFromMapped(tt.mapped, tt.hasOrg); got != tt.want {
+			t.Errorf("FinalRoleFromMapped(%q, %v) = %q, want %q", tt.mapped, tt.hasOrg, got, tt.want)
+		}
+	}
+}
+
+func TestExtractGroups(t *testing.T) {
+	claims := map[string]any{
+		"groups": []any{"eng", 42, "ops"},
+		"team":   "platform",
+		"empty":  "",
+	}
+	if got := ExtractGroups(claims, "groups"); len(got) != 2 || got[0] != "eng" || got[1] != "ops" {
+		t.Errorf("ExtractGroups(groups) = %v, want [eng ops]", got)
+	}
+	if got := ExtractGroups(claims, "team"); len(got) != 1 || got[0] != "platform" {
+		t.Errorf("ExtractGroups(team) = %v, want [platform]", got)
+	}
+	if got := ExtractGroups(claims, "empty"); got != nil {
+		t.Errorf("ExtractGroups(empty) = %v, want nil", got)
+	}
+	if got := ExtractGroups(claims, "missing"); got != nil {
+		t.Errorf("ExtractGroups(missing) = %v, want nil", got)
+	}
+}
@@ -7,8 +7,14 @@ import (
 	"net/http"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
 	"golinks/internal/db"
+	"golinks/internal/metrics"
 	"golinks/internal/models"
+	"golinks/internal/tracing"
 	"golinks/internal/validation"
 )
 
@@ -62,17 +68,27 @@ func (h *HealthChecker) Start(ctx context.Context) {
 
 // checkAll checks all links that need a health check.
 func (h *HealthChecker) checkAll(ctx context.Context) {
+	ctx, span := tracing.Tracer().Start(ctx, "health_checker run")
+	defer span.End()
+
 	links, err := h.db.GetLinksNeedingHealthCheck(ctx, h.maxAge, 50)
 	if err != nil {
-		slog.Error("health checker: failed to get links", "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		slog.ErrorContext(ctx, "health checker: failed to get links", "error", err)
 		return
 	}
 
+	span.SetAttributes(attribute.Int("health_check.links", len(links)))
+	metrics.HealthCheckBatchSize.Set(float64(len(links)))
+	metrics.HealthCheckBatchChecked.Set(0)
+	defer metrics.HealthCheckLastRun.SetToCurrentTime()
+
 	if len(links) == 0 {
 		return
 	}
 
-	slog.Info("health checker: checking links", "count", len(links))
+	slog.InfoContext(ctx, "health checker: checking links", "count", len(links))
 
 	for _, link := range links {
 		// Check context before each link
@@ -82,17 +98,31 @@ func (h *HealthChecker) checkAll(ctx context.Context) {
 		default:
 		}
 
-		status, errorMsg := h.checkURL(ctx, link.URL)
-		if err := h.db.UpdateLinkHealthStatus(ctx, link.ID, status, errorMsg); err != nil {
-			slog.Error("health checker: failed to update link status", "keyword", link.Keyword, "error", err)
-			continue
-		}
+		h.checkLink(ctx, link)
 
 		// Delay between checks to avoid overwhelming external servers
 		time.Sleep(1 * time.Second)
 	}
 }
 
+// checkLink checks a single link and stores the result, in its own span.
+func (h *HealthChecker) checkLink(ctx context.Context, link models.Link) {
+	ctx, span := tracing.Tracer().Start(ctx, "health_checker check",
+		trace.WithAttributes(attribute.String("link.keyword", link.Keyword)),
+	)
+	defer span.End()
+
+	status, errorMsg := h.checkURL(ctx, link.URL)
+	span.SetAttributes(attribute.String("health_check.status", status))
+	metrics.HealthChecks.WithLabelValues(status).Inc()
+	metrics.HealthCheckBatchChecked.Inc()
+	if err := h.db.UpdateLinkHealthStatus(ctx, link.ID, status, errorMsg); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		slog.ErrorContext(ctx, "health checker: failed to update link status", "keyword", link.Keyword, "error", err)
+	}
+}
+
 // checkURL performs a HEAD request to check if a URL is healthy.
 // Validates URLs before making requests to prevent SSRF attacks.
 func (h *HealthChecker) checkURL(ctx context.Context, url string) (string, *string) {
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"golinks/internal/db"
+	"golinks/internal/metrics"
+)
+
+// InactiveUserJob periodically deactivates accounts that haven't been used
+// for a while, so leavers nobody offboarded lose access. Their links and
+// attribution are kept; an admin can reactivate them.
+type InactiveUserJob struct {
+	db       *db.DB
+	interval time.Duration
+	maxIdle  time.Duration
+}
+
+// NewInactiveUserJob creates a job deactivating users inactive for longer
+// than maxIdle.
+func NewInactiveUserJob(database *db.DB, interval, maxIdle time.Duration) *InactiveUserJob {
+	return &InactiveUserJob{
+		db:       database,
+		interval: interval,
+		maxIdle:  maxIdle,
+	}
+}
+
+// Start begins the background deactivation loop.
+func (j *InactiveUserJob) Start(ctx context.Context) {
+	slog.Info("inactive user job started", "interval", j.interval, "max_idle", j.maxIdle)
+
+	// Run immediately on start
+	j.run(ctx)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("inactive user job stopped")
+			return
+		case <-ticker.C:
+			j.run(ctx)
+		}
+	}
+}
+
+// run deactivates inactive users and revokes any sessions they still hold.
+func (j *InactiveUserJob) run(ctx context.Context) {
+	ids, err := j.db.DeactivateInactiveUsers(ctx, time.Now().Add(-j.maxIdle))
+	if err != nil {
+		slog.Error("inactive user job: failed to deactivate users", "error", err)
+		return
+	}
+	metrics.UsersDeactivated.Add(float64(len(ids)))
+
+	for _, id := range ids {
+		slog.Info("inactive user job: user deactivated", "user_id", id)
+		if _, err := j.db.RevokeAllUserSessions(ctx, id); err != nil {
+			slog.Error("inactive user job: failed to revoke sessions", "user_id", id, "error", err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"golinks/internal/db"
+	"golinks/internal/metrics"
+)
+
+// KeywordMetricsJob periodically refreshes the top-N keyword lookup series so
+// scrapes never read the keyword_lookups table themselves.
+type KeywordMetricsJob struct {
+	db       *db.DB
+	interval time.Duration
+	topN     int
+}
+
+// NewKeywordMetricsJob creates a new keyword metrics job exporting the topN
+// most requested keywords per outcome.
+func NewKeywordMetricsJob(database *db.DB, interval time.Duration, topN int) *KeywordMetricsJob {
+	return &KeywordMetricsJob{
+		db:       database,
+		interval: interval,
+		topN:     topN,
+	}
+}
+
+// Start begins the background refresh loop.
+func (k *KeywordMetricsJob) Start(ctx context.Context) {
+	slog.Info("keyword metrics job started", "interval", k.interval, "top_n", k.topN)
+
+	// Run immediately on start
+	k.refresh(ctx)
+
+	ticker := time.NewTicker(k.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("keyword metrics job stopped")
+			return
+		case <-ticker.C:
+			k.refresh(ctx)
+		}
+	}
+}
+
+// refresh replaces the exported top-N snapshot. On failure the previous
+// snapshot is kept so a transient database error doesn't blank the series.
+func (k *KeywordMetricsJob) refresh(ctx context.Context) {
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	lookups, err := k.db.GetTopKeywordLookups(queryCtx, k.topN)
+	if err != nil {
+		slog.Warn("keyword metrics job: failed to load top keywords", "error", err)
+		return
+	}
+	metrics.SetTopKeywords(lookups)
+}
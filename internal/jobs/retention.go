@@ -0,0 +1,127 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"golinks/internal/db"
+	"golinks/internal/metrics"
+)
+
+// RetentionPolicy configures how long each class of historical data is kept.
+// A zero value disables the corresponding task.
+type RetentionPolicy struct {
+	ClickHistoryAge     time.Duration // hourly click history older than this is rolled up (links) or deleted (personal links)
+	KeywordLookupAge    time.Duration // missing-keyword lookups not seen for this long are deleted
+	KeywordLookupMax    int           // maximum number of not_found keyword_lookups rows kept
+	ReadNotificationAge time.Duration // read notifications older than this are deleted
+	RejectedLinkAge     time.Duration // rejected links reviewed longer ago than this are deleted
+	EditRequestAge      time.Duration // reviewed edit requests older than this are deleted
+	ExpiredSessionAge   time.Duration // tracked sessions that ended longer ago than this are deleted
+}
+
+// RetentionJob periodically prunes and rolls up historical data so high-churn
+// tables stay bounded.
+type RetentionJob struct {
+	db       *db.DB
+	interval time.Duration
+	policy   RetentionPolicy
+}
+
+// NewRetentionJob creates a new retention job.
+func NewRetentionJob(database *db.DB, interval time.Duration, policy RetentionPolicy) *RetentionJob {
+	return &RetentionJob{
+		db:       database,
+		interval: interval,
+		policy:   policy,
+	}
+}
+
+// Start begins the background retention loop.
+func (r *RetentionJob) Start(ctx context.Context) {
+	slog.Info("retention job started", "interval", r.interval)
+
+	// Run immediately on start
+	r.runAll(ctx)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("retention job stopped")
+			return
+		case <-ticker.C:
+			r.runAll(ctx)
+		}
+	}
+}
+
+// runAll executes every enabled retention task once.
+func (r *RetentionJob) runAll(ctx context.Context) {
+	now := time.Now().UTC()
+	p := r.policy
+
+	if p.ClickHistoryAge > 0 {
+		// Only roll up whole days so a partially elapsed day stays hourly.
+		cutoff := now.Add(-p.ClickHistoryAge).Truncate(24 * time.Hour)
+		r.run(ctx, "click_history", func(ctx context.Context) (int64, error) {
+			return r.db.RollupClickHistory(ctx, cutoff)
+		})
+		r.run(ctx, "user_link_click_history", func(ctx context.Context) (int64, error) {
+			return r.db.PruneUserLinkClickHistory(ctx, cutoff)
+		})
+	}
+	if p.KeywordLookupAge > 0 {
+		r.run(ctx, "keyword_lookups", func(ctx context.Context) (int64, error) {
+			return r.db.PruneNotFoundKeywordLookups(ctx, now.Add(-p.KeywordLookupAge))
+		})
+		r.run(ctx, "missing_keyword_lookups", func(ctx context.Context) (int64, error) {
+			return r.db.PruneMissingKeywordLookups(ctx, now.Add(-p.KeywordLookupAge))
+		})
+	}
+	if p.KeywordLookupMax > 0 {
+		r.run(ctx, "keyword_lookups", func(ctx context.Context) (int64, error) {
+			return r.db.CapNotFoundKeywordLookups(ctx, p.KeywordLookupMax)
+		})
+	}
+	if p.ReadNotificationAge > 0 {
+		r.run(ctx, "notifications", func(ctx context.Context) (int64, error) {
+			return r.db.DeleteReadNotificationsBefore(ctx, now.Add(-p.ReadNotificationAge))
+		})
+	}
+	if p.RejectedLinkAge > 0 {
+		r.run(ctx, "links", func(ctx context.Context) (int64, error) {
+			return r.db.DeleteRejectedLinksBefore(ctx, now.Add(-p.RejectedLinkAge))
+		})
+	}
+	if p.EditRequestAge > 0 {
+		r.run(ctx, "link_edit_requests", func(ctx context.Context) (int64, error) {
+			return r.db.DeleteReviewedEditRequestsBefore(ctx, now.Add(-p.EditRequestAge))
+		})
+	}
+	if p.ExpiredSessionAge > 0 {
+		r.run(ctx, "user_sessions", func(ctx context.Context) (int64, error) {
+			return r.db.DeleteExpiredUserSessions(ctx, now.Add(-p.ExpiredSessionAge))
+		})
+	}
+}
+
+// run executes a single retention task, logging and counting the rows removed.
+// Failures are logged and do not stop the remaining tasks.
+func (r *RetentionJob) run(ctx context.Context, table string, task func(context.Context) (int64, error)) {
+	if ctx.Err() != nil {
+		return
+	}
+	removed, err := task(ctx)
+	if err != nil {
+		slog.Error("retention job: task failed", "table", table, "error", err)
+		return
+	}
+	metrics.RetentionRowsRemoved.WithLabelValues(table).Add(float64(removed))
+	if removed > 0 {
+		slog.Info("retention job: rows removed", "table", table, "rows", removed)
+	}
+}
@@ -1,60 +1,165 @@
+// Package metrics defines the Prometheus metrics exported on /metrics.
+//
+// Every series has bounded cardinality: labels only ever take values from a
+// fixed set (outcomes, route patterns, tables) or from the top-N keyword
+// snapshot, which is replaced wholesale on each refresh.
 package metrics
 
 import (
 	"context"
 	"log/slog"
 	"sync"
-	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
-	"golinks/internal/db"
+	"golinks/internal/models"
 )
 
 var (
-	keywordLookupDesc = prometheus.NewDesc(
-		"golinks_keyword_lookups_total",
-		"Total keyword lookup count by outcome",
+	// KeywordLookups counts keyword lookups by outcome since process start.
+	KeywordLookups = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "golinks_keyword_lookups_total",
+			Help: "Total keyword lookups by outcome",
+		},
+		[]string{"outcome"},
+	)
+
+	// TopKeywordLookups exposes the all-time lookup counts of the most
+	// requested keywords per outcome, refreshed in the background.
+	TopKeywordLookups = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "golinks_top_keyword_lookups",
+			Help: "All-time lookup count of the top-N keywords per outcome",
+		},
 		[]string{"keyword", "outcome"},
-		nil,
 	)
-)
 
-// KeywordCollector is a custom Prometheus collector that reads keyword lookup
-// counts from the database on each scrape.
-type KeywordCollector struct {
-	db *db.DB
-}
+	// HTTPRequestDuration observes request latency by method, matched route
+	// pattern and status code.
+	HTTPRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "golinks_http_request_duration_seconds",
+			Help:    "HTTP request duration by method, route and status",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "route", "status"},
+	)
 
-// Describe sends the metric descriptor to the channel.
-func (c *KeywordCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- keywordLookupDesc
-}
+	// WriteBufferFlushDuration observes how long each write buffer flush takes.
+	WriteBufferFlushDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "golinks_write_buffer_flush_duration_seconds",
+			Help:    "Duration of write buffer flushes to the database",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
 
-// Collect queries the database for all keyword lookups and emits them as counters.
-func (c *KeywordCollector) Collect(ch chan<- prometheus.Metric) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// WriteBufferFlushedEntries is the number of entries written by the most
+	// recent write buffer flush.
+	WriteBufferFlushedEntries = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "golinks_write_buffer_last_flush_entries",
+			Help: "Number of entries written by the most recent write buffer flush",
+		},
+	)
 
-	lookups, err := c.db.GetAllKeywordLookups(ctx)
-	if err != nil {
-		slog.Warn("failed to collect keyword lookup metrics — scrape will show no keyword data", "error", err)
-		return
-	}
-	for _, l := range lookups {
-		ch <- prometheus.MustNewConstMetric(
-			keywordLookupDesc,
-			prometheus.CounterValue,
-			float64(l.Count),
-			l.Keyword,
-			l.Outcome,
-		)
-	}
+	// HealthCheckBatchSize is the number of links in the current (or most
+	// recent) health check run; HealthCheckBatchChecked is how many of them
+	// have been checked so far.
+	HealthCheckBatchSize = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "golinks_health_check_batch_size",
+			Help: "Number of links in the current health check run",
+		},
+	)
+	HealthCheckBatchChecked = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "golinks_health_check_batch_checked",
+			Help: "Number of links checked so far in the current health check run",
+		},
+	)
+
+	// HealthChecks counts completed link health checks by resulting status.
+	HealthChecks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "golinks_health_checks_total",
+			Help: "Total link health checks by resulting status",
+		},
+		[]string{"status"},
+	)
+
+	// HealthCheckLastRun is the Unix time the last health check run finished.
+	HealthCheckLastRun = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "golinks_health_check_last_run_timestamp_seconds",
+			Help: "Unix time the last health check run finished",
+		},
+	)
+
+	// OIDCProviderUp is 1 when the last OIDC issuer probe succeeded.
+	OIDCProviderUp = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "golinks_oidc_provider_up",
+			Help: "Whether the last OIDC issuer probe succeeded (1) or failed (0)",
+		},
+	)
+
+	// OIDCProbeLastRun is the Unix time of the last OIDC issuer probe.
+	OIDCProbeLastRun = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "golinks_oidc_probe_last_run_timestamp_seconds",
+			Help: "Unix time of the last OIDC issuer probe",
+		},
+	)
+
+	// RetentionRowsRemoved counts rows deleted or rolled up by the data
+	// retention job, labelled by the table they were removed from.
+	RetentionRowsRemoved = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "golinks_retention_rows_removed_total",
+			Help: "Total rows removed by the data retention job by table",
+		},
+		[]string{"table"},
+	)
+
+	// UsersDeactivated counts users deactivated by the inactive user job.
+	UsersDeactivated = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "golinks_inactive_users_deactivated_total",
+			Help: "Total users deactivated for inactivity",
+		},
+	)
+
+	// CSPViolations counts Content-Security-Policy violation reports by
+	// effective directive and disposition ("enforce" or "report").
+	CSPViolations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "golinks_csp_violations_total",
+			Help: "Total Content-Security-Policy violation reports by directive and disposition",
+		},
+		[]string{"directive", "disposition"},
+	)
+
+	// RateLimited counts requests rejected by the rate limiter by policy.
+	RateLimited = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "golinks_rate_limited_requests_total",
+			Help: "Total requests rejected by the rate limiter by policy",
+		},
+		[]string{"policy"},
+	)
+)
+
+// Store is the subset of the database used by the metrics package.
+type Store interface {
+	IncrementKeywordLookup(ctx context.Context, keyword, outcome string) error
+	WriteBufferDepth() int
 }
 
 // Recorder provides async keyword lookup recording.
 type Recorder struct {
-	db *db.DB
+	db Store
 }
 
 var (
@@ -62,17 +167,46 @@ var (
 	recorderOnce sync.Once
 )
 
-// Init registers the custom collector and initializes the recorder.
+// Init registers all metrics and initializes the recorder.
 // Must be called once at startup.
-func Init(database *db.DB) {
+func Init(database Store) {
 	recorderOnce.Do(func() {
 		recorder = &Recorder{db: database}
-		prometheus.MustRegister(&KeywordCollector{db: database})
+		prometheus.MustRegister(
+			KeywordLookups,
+			TopKeywordLookups,
+			HTTPRequestDuration,
+			WriteBufferFlushDuration,
+			WriteBufferFlushedEntries,
+			HealthCheckBatchSize,
+			HealthCheckBatchChecked,
+			HealthChecks,
+			HealthCheckLastRun,
+			OIDCProviderUp,
+			OIDCProbeLastRun,
+			RetentionRowsRemoved,
+			UsersDeactivated,
+			CSPViolations,
+			RateLimited,
+			prometheus.NewGaugeFunc(
+				prometheus.GaugeOpts{
+					Name: "golinks_write_buffer_pending_entries",
+					Help: "Number of counter entries waiting in the write buffer",
+				},
+				func() float64 { return float64(database.WriteBufferDepth()) },
+			),
+		)
+		// Pre-create outcome series so rates are defined before the first lookup.
+		for _, outcome := range []string{models.OutcomeResolved, models.OutcomeFallback, models.OutcomeNotFound} {
+			KeywordLookups.WithLabelValues(outcome)
+		}
 	})
 }
 
-// RecordKeywordLookup asynchronously records a keyword lookup outcome.
+// RecordKeywordLookup counts a keyword lookup outcome and asynchronously
+// persists the per-keyword count.
 func RecordKeywordLookup(keyword, outcome string) {
+	KeywordLookups.WithLabelValues(outcome).Inc()
 	if recorder == nil {
 		return
 	}
@@ -82,3 +216,12 @@ func RecordKeywordLookup(keyword, outcome string) {
 		}
 	}()
 }
+
+// SetTopKeywords replaces the top-N keyword series with the given snapshot,
+// so keywords that drop out of the top N stop being exported.
+func SetTopKeywords(lookups []models.KeywordLookup) {
+	TopKeywordLookups.Reset()
+	for _, l := range lookups {
+		TopKeywordLookups.WithLabelValues(l.Keyword, l.Outcome).Set(float64(l.Count))
+	}
+}
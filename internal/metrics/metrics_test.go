@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"golinks/internal/models"
+)
+
+func TestSetTopKeywordsReplacesSnapshot(t *testing.T) {
+	defer TopKeywordLookups.Reset()
+
+	SetTopKeywords([]models.KeywordLookup{
+		{Keyword: "docs", Outcome: models.OutcomeResolved, Count: 50},
+		{Keyword: "typo", Outcome: models.OutcomeNotFound, Count: 3},
+	})
+	if got := testutil.CollectAndCount(TopKeywordLookups); got != 2 {
+		t.Fatalf("series count = %d, want 2", got)
+	}
+
+	// A keyword that drops out of the top N must stop being exported.
+	SetTopKeywords([]models.KeywordLookup{
+		{Keyword: "docs", Outcome: models.OutcomeResolved, Count: 60},
+	})
+	if got := testutil.CollectAndCount(TopKeywordLookups); got != 1 {
+		t.Errorf("series count = %d, want 1", got)
+	}
+	if got := testutil.ToFloat64(TopKeywordLookups.WithLabelValues("docs", models.OutcomeResolved)); got != 60 {
+		t.Errorf("docs count = %v, want 60", got)
+	}
+}
+
+func TestRecordKeywordLookupCountsOutcomes(t *testing.T) {
+	before := testutil.ToFloat64(KeywordLookups.WithLabelValues(models.OutcomeNotFound))
+
+	RecordKeywordLookup("typo-one", models.OutcomeNotFound)
+	RecordKeywordLookup("typo-two", models.OutcomeNotFound)
+
+	if got := testutil.ToFloat64(KeywordLookups.WithLabelValues(models.OutcomeNotFound)) - before; got != 2 {
+		t.Errorf("not_found delta = %v, want 2", got)
+	}
+	if got := testutil.CollectAndCount(KeywordLookups); got > 3 {
+		t.Errorf("series count = %d, want at most one per outcome", got)
+	}
+}
@@ -1,8 +1,12 @@
 package middleware
 
 import (
+	"errors"
+	"log/slog"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/middleware/session"
@@ -13,6 +17,13 @@ import (
 	"golinks/internal/oidchealth"
 )
 
+// ErrUserDeactivated rejects authentication as a deactivated user.
+var ErrUserDeactivated = errors.New("user is deactivated")
+
+// lastLoginStampInterval bounds how often requests authenticated without a
+// sign-in refresh the user's last_login_at.
+const lastLoginStampInterval = time.Hour
+
 // cnUsernameRe extracts the username from a CN like "Full Name (username)".
 var cnUsernameRe = regexp.MustCompile(`\(([^)]+)\)\s*$`)
 
@@ -20,7 +31,21 @@ var cnUsernameRe = regexp.MustCompile(`\(([^)]+)\)\s*$`)
 type AuthMiddleware struct {
 	db               *db.DB
 	clientCertHeader string
+	certIssuer       string
+	primaryIssuer    string
 	oidcProbe        *oidchealth.Probe
+	debug            bool
+
+	// Trusted reverse-proxy authentication; nil proxy when disabled.
+	proxy         *trustedProxy
+	proxyProvider *config.OIDCProvider
+	proxyMu       sync.Mutex
+	proxySynced   map[string]time.Time // identity fingerprint -> last sync
+
+	// Sliding sessions; see SetSessionRefresher.
+	refresher       SessionRefresher
+	refreshInterval time.Duration
+	maxLifetime     time.Duration
 }
 
 // NewAuthMiddleware creates a new auth middleware instance.
@@ -28,12 +53,50 @@ func NewAuthMiddleware(db *db.DB, cfg *config.Config, oidcProbe *oidchealth.Prob
 	return &AuthMiddleware{
 		db:               db,
 		clientCertHeader: cfg.ClientCertHeader,
+		certIssuer:       clientCertIssuer(cfg),
+		primaryIssuer:    primaryOIDCIssuer(cfg),
 		oidcProbe:        oidcProbe,
+		debug:            cfg.IsDev(),
+		proxy:            newTrustedProxy(cfg),
+		proxyProvider:    cfg.ProxyAuthProvider(),
+		proxySynced:      make(map[string]time.Time),
+	}
+}
+
+// primaryOIDCIssuer returns the primary provider's issuer, or "" without OIDC.
+func primaryOIDCIssuer(cfg *config.Config) string {
+	if p := cfg.PrimaryOIDCProvider(); p != nil {
+		return p.Issuer
+	}
+	return ""
+}
+
+// clientCertIssuer returns the issuer of the users client certificates name:
+// the primary OIDC provider's, or the proxy issuer without OIDC. Usernames are
+// only unique per issuer.
+func clientCertIssuer(cfg *config.Config) string {
+	if issuer := primaryOIDCIssuer(cfg); issuer != "" {
+		return issuer
+	}
+	return cfg.ProxyAuthIssuer
+}
+
+// sessionIdentity returns the issuer and subject a session was signed in
+// with. Sessions from before multiple providers were supported carry only a
+// subject; they belong to the primary provider. sub is empty for sessions
+// that aren't signed in.
+func sessionIdentity(sess *session.Middleware, primaryIssuer string) (issuer, sub string) {
+	sub, _ = sess.Get("user_sub").(string)
+	issuer, ok := sess.Get("user_issuer").(string)
+	if !ok {
+		issuer = primaryIssuer
 	}
+	return issuer, sub
 }
 
-// RequireAuth ensures the user is authenticated via session or PKI cert.
-// Priority: 1) PKI cert (mTLS or header), 2) Session (OIDC)
+// RequireAuth ensures the user is authenticated via PKI cert, trusted proxy
+// headers or session.
+// Priority: 1) PKI cert (mTLS or header), 2) Trusted proxy, 3) Session (OIDC)
 func (m *AuthMiddleware) RequireAuth(c fiber.Ctx) error {
 	// Try PKI authentication first (mTLS or header)
 	if user, err := m.authenticateViaPKI(c); err == nil && user != nil {
@@ -41,19 +104,34 @@ func (m *AuthMiddleware) RequireAuth(c fiber.Ctx) error {
 		return c.Next()
 	}
 
+	// Then headers from a trusted authenticating proxy
+	if user, err := m.authenticateViaProxy(c); err == nil && user != nil {
+		c.Locals("user", user)
+		return c.Next()
+	}
+
 	// Fall back to session-based auth (OIDC)
 	sess := session.FromContext(c)
 	if sess == nil {
 		return m.redirectToLogin(c, nil)
 	}
 
-	userSub, ok := sess.Get("user_sub").(string)
-	if !ok || userSub == "" {
+	issuer, userSub := sessionIdentity(sess, m.primaryIssuer)
+	if userSub == "" {
 		return m.redirectToLogin(c, sess)
 	}
 
-	user, err := m.db.GetUserBySub(c.Context(), userSub)
-	if err != nil {
+	// A database outage leaves the session alone rather than signing out
+	// everyone who makes a request during it.
+	user, err := m.db.GetUserBySub(c.Context(), issuer, userSub)
+	ok := false
+	if err == nil {
+		user, ok, err = m.sessionActive(c, sess, user)
+	}
+	if err != nil && !errors.Is(err, db.ErrUserNotFound) {
+		return fiber.NewError(fiber.StatusServiceUnavailable, "session check unavailable, please try again")
+	}
+	if !ok {
 		sess.Destroy()
 		return m.redirectToLogin(c, nil)
 	}
@@ -96,18 +174,49 @@ func (m *AuthMiddleware) authenticateViaPKI(c fiber.Ctx) (*models.User, error) {
 		return nil, nil
 	}
 
-	return m.db.GetUserByUsername(c.Context(), username)
+	user, err := m.db.GetUserByUsername(c.Context(), m.certIssuer, username)
+	if err != nil {
+		return nil, err
+	}
+	return m.activeUser(c, user)
+}
+
+// activeUser rejects deactivated users. Users authenticated by a client
+// certificate or proxy headers never pass through the OIDC callback, so their
+// last_login_at is refreshed here, at most once per lastLoginStampInterval,
+// to keep them from looking inactive.
+func (m *AuthMiddleware) activeUser(c fiber.Ctx, user *models.User) (*models.User, error) {
+	if user.IsDeactivated() {
+		return nil, ErrUserDeactivated
+	}
+	if user.LastLoginAt == nil || time.Since(*user.LastLoginAt) > lastLoginStampInterval {
+		if err := m.db.UpdateUserLastLogin(c.Context(), user.ID); err != nil {
+			slog.WarnContext(c.Context(), "failed to update last_login_at", "user_id", user.ID, "error", err)
+		}
+	}
+	return user, nil
 }
 
 // extractUsernameFromCert extracts the username from client certificate CN.
 // Supports both mTLS (direct cert) and header-based (ingress-terminated TLS).
 // CN format: "Full Name (username)" -> extracts "username"
 func (m *AuthMiddleware) extractUsernameFromCert(c fiber.Ctx) string {
+	cn := clientCertCN(c, m.clientCertHeader)
+	if cn == "" {
+		return ""
+	}
+
+	return extractUsernameFromCN(cn)
+}
+
+// clientCertCN returns the client certificate CN from the given header (for
+// ingress-terminated TLS) or, failing that, from the mTLS peer certificate.
+func clientCertCN(c fiber.Ctx, header string) string {
 	var cn string
 
 	// Try header first (for ingress-terminated TLS)
-	if m.clientCertHeader != "" {
-		cn = c.Get(m.clientCertHeader)
+	if header != "" {
+		cn = c.Get(header)
 	}
 
 	// Try mTLS client cert if no header
@@ -119,11 +228,7 @@ func (m *AuthMiddleware) extractUsernameFromCert(c fiber.Ctx) string {
 		}
 	}
 
-	if cn == "" {
-		return ""
-	}
-
-	return extractUsernameFromCN(cn)
+	return cn
 }
 
 // extractUsernameFromCN parses username from CN format "Full Name (username)".
@@ -145,21 +250,37 @@ func (m *AuthMiddleware) OptionalAuth(c fiber.Ctx) error {
 		return c.Next()
 	}
 
+	// Then headers from a trusted authenticating proxy
+	if user, err := m.authenticateViaProxy(c); err == nil && user != nil {
+		c.Locals("user", user)
+		return c.Next()
+	}
+
 	// Try session-based auth
 	sess := session.FromContext(c)
 	if sess == nil {
 		return c.Next()
 	}
 
-	userSub, ok := sess.Get("user_sub").(string)
-	if !ok || userSub == "" {
+	issuer, userSub := sessionIdentity(sess, m.primaryIssuer)
+	if userSub == "" {
 		return c.Next()
 	}
 
-	user, err := m.db.GetUserBySub(c.Context(), userSub)
-	if err == nil {
-		c.Locals("user", user)
+	user, err := m.db.GetUserBySub(c.Context(), issuer, userSub)
+	if err != nil {
+		return c.Next()
 	}
+	user, ok, err := m.sessionActive(c, sess, user)
+	if err != nil {
+		// Keep the session through a database outage.
+		return fiber.NewError(fiber.StatusServiceUnavailable, "session check unavailable, please try again")
+	}
+	if !ok {
+		sess.Destroy()
+		return c.Next()
+	}
+	c.Locals("user", user)
 
 	return c.Next()
 }
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"golinks/internal/config"
+)
+
+const (
+	// CSPNonceLocal is the Locals key holding the request's CSP nonce.
+	CSPNonceLocal = "csp_nonce"
+	// CSPReportPath is where browsers send violation reports.
+	CSPReportPath = "/csp-report"
+	// cspReportGroup names the Reporting-Endpoints entry used by report-to.
+	cspReportGroup = "csp"
+)
+
+// CSPMiddleware sets a Content-Security-Policy with a fresh nonce per request.
+// Inline <script> and <style> elements are only allowed when they carry the
+// nonce, which templates receive as {{.CSPNonce}} via handlers.MergeBranding.
+// With CSP_REPORT_ONLY the policy is sent as
+// Content-Security-Policy-Report-Only so violations are reported but not
+// blocked.
+func CSPMiddleware(cfg *config.Config) fiber.Handler {
+	header := fiber.HeaderContentSecurityPolicy
+	if cfg.CSPReportOnly {
+		header = fiber.HeaderContentSecurityPolicyReportOnly
+	}
+	reportingEndpoints := ""
+	if base := strings.TrimRight(cfg.BaseURL, "/"); base != "" {
+		reportingEndpoints = cspReportGroup + `="` + base + CSPReportPath + `"`
+	}
+
+	return func(c fiber.Ctx) error {
+		nonce, err := newNonce()
+		if err != nil {
+			return err
+		}
+		c.Locals(CSPNonceLocal, nonce)
+		c.Set(header, buildCSP(cfg, nonce, reportingEndpoints != ""))
+		if reportingEndpoints != "" {
+			c.Set("Reporting-Endpoints", reportingEndpoints)
+		}
+		return c.Next()
+	}
+}
+
+// CSPNonce returns the nonce generated for this request by CSPMiddleware, or
+// "" when the middleware did not run.
+func CSPNonce(c fiber.Ctx) string {
+	nonce, _ := c.Locals(CSPNonceLocal).(string)
+	return nonce
+}
+
+// buildCSP assembles the policy. Inline event handlers and style attributes
+// are not allowed; templates use delegated listeners and CSS classes instead.
+func buildCSP(cfg *config.Config, nonce string, reportTo bool) string {
+	n := "'nonce-" + nonce + "'"
+	directives := [][]string{
+		{"default-src", "'self'"},
+		append([]string{"script-src", "'self'", n}, cfg.CSPScriptSources...),
+		append([]string{"style-src", "'self'", n}, cfg.CSPStyleSources...),
+		append([]string{"img-src", "'self'", "data:"}, cfg.CSPImgSources...),
+		append([]string{"font-src", "'self'"}, cfg.CSPFontSources...),
+		append([]string{"connect-src", "'self'"}, cfg.CSPConnectSources...),
+		{"object-src", "'none'"},
+		{"base-uri", "'self'"},
+		{"frame-ancestors", "'none'"},
+		{"report-uri", CSPReportPath},
+	}
+	if reportTo {
+		directives = append(directives, []string{"report-to", cspReportGroup})
+	}
+
+	parts := make([]string, len(directives))
+	for i, d := range directives {
+		parts[i] = strings.Join(d, " ")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// newNonce returns 128 random bits, base64url-encoded.
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"golinks/internal/config"
+)
+
+func newCSPTestApp(cfg *config.Config) *fiber.App {
+	app := fiber.New()
+	app.Use(CSPMiddleware(cfg))
+	app.Get("/", func(c fiber.Ctx) error {
+		return c.SendString(CSPNonce(c))
+	})
+	return app
+}
+
+func getWithCSP(t *testing.T, app *fiber.App) (policy, reportOnly, nonce string) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatalf("GET / error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return resp.Header.Get(fiber.HeaderContentSecurityPolicy),
+		resp.Header.Get(fiber.HeaderContentSecurityPolicyReportOnly),
+		string(body)
+}
+
+func TestCSPMiddlewareSetsNoncePolicy(t *testing.T) {
+	app := newCSPTestApp(&config.Config{BaseURL: "https://links.example.com"})
+
+	policy, reportOnly, nonce := getWithCSP(t, app)
+	if nonce == "" {
+		t.Fatal("CSPNonce() is empty")
+	}
+	if reportOnly != "" {
+		t.Errorf("report-only header set without CSP_REPORT_ONLY: %q", reportOnly)
+	}
+	for _, want := range []string{
+		"script-src 'self' 'nonce-" + nonce + "'",
+		"style-src 'self' 'nonce-" + nonce + "'",
+		"object-src 'none'",
+		"frame-ancestors 'none'",
+		"report-uri " + CSPReportPath,
+		"report-to " + cspReportGroup,
+	} {
+		if !strings.Contains(policy, want) {
+			t.Errorf("policy %q missing %q", policy, want)
+		}
+	}
+	if strings.Contains(policy, "unsafe-inline") || strings.Contains(policy, "unsafe-eval") {
+		t.Errorf("policy allows unsafe inline code: %q", policy)
+	}
+}
+
+func TestCSPMiddlewareNoncePerRequest(t *testing.T) {
+	app := newCSPTestApp(&config.Config{})
+
+	_, _, first := getWithCSP(t, app)
+	_, _, second := getWithCSP(t, app)
+	if first == second {
+		t.Errorf("nonce reused across requests: %q", first)
+	}
+}
+
+func TestCSPMiddlewareReportOnly(t *testing.T) {
+	app := newCSPTestApp(&config.Config{CSPReportOnly: true})
+
+	policy, reportOnly, _ := getWithCSP(t, app)
+	if policy != "" {
+		t.Errorf("enforcing header set in report-only mode: %q", policy)
+	}
+	if !strings.Contains(reportOnly, "report-uri "+CSPReportPath) {
+		t.Errorf("report-only policy = %q, want report-uri", reportOnly)
+	}
+}
+
+func TestCSPMiddlewareExtraSources(t *testing.T) {
+	app := newCSPTestApp(&config.Config{
+		CSPImgSources:     []string{"https://cdn.example.com"},
+		CSPConnectSources: []string{"https://api.example.com", "wss://ws.example.com"},
+	})
+
+	policy, _, _ := getWithCSP(t, app)
+	for _, want := range []string{
+		"img-src 'self' data: https://cdn.example.com",
+		"connect-src 'self' https://api.example.com wss://ws.example.com",
+		"font-src 'self';",
+	} {
+		if !strings.Contains(policy, want) {
+			t.Errorf("policy %q missing %q", policy, want)
+		}
+	}
+}
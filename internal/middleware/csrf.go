@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/extractors"
+	"github.com/gofiber/fiber/v3/middleware/csrf"
+	"github.com/gofiber/fiber/v3/middleware/session"
+
+	"golinks/internal/config"
+)
+
+const (
+	// CSRFHeader is the request header htmx sends the token in.
+	CSRFHeader = "X-CSRF-Token"
+	// CSRFFormField is the form field accepted for non-htmx form posts.
+	CSRFFormField = "_csrf"
+	// csrfCookieName is the double-submit cookie paired with the token.
+	csrfCookieName = "csrf_"
+	// BackChannelLogoutPath receives logout tokens POSTed by the OIDC
+	// provider, which has no CSRF token; the signed logout token is the
+	// request's proof of origin.
+	BackChannelLogoutPath = "/auth/backchannel-logout"
+	// scimPathPrefix is where bearer tokens authenticate requests; anywhere
+	// else a bearer header proves nothing about who sent the request.
+	scimPathPrefix = "/scim/v2"
+)
+
+// CSRFMiddleware validates a CSRF token on every unsafe request (POST, PUT,
+// DELETE, ...) made with the session cookie. Tokens are stored in the session
+// so they are shared across pods when the session store is Redis, and are
+// rendered into pages by CSRFTokenToViews.
+//
+// Clients that cannot be driven cross-site by a browser are exempt: SCIM
+// requests carrying a bearer token, PKI-authenticated requests without a
+// session cookie that no other site sent, and back-channel logout calls from
+// the OIDC provider.
+func CSRFMiddleware(cfg *config.Config, store *session.Store) fiber.Handler {
+	origins := trustedOrigins(cfg)
+	return csrf.New(csrf.Config{
+		Session: store,
+		Next: func(c fiber.Ctx) bool {
+			return c.Path() == BackChannelLogoutPath || isNonBrowserClient(c, cfg.ClientCertHeader, origins)
+		},
+		Extractor: extractors.Chain(
+			extractors.FromHeader(CSRFHeader),
+			extractors.FromForm(CSRFFormField),
+		),
+		CookieName:        csrfCookieName,
+		CookieSecure:      cfg.TLSEnabled || !cfg.IsDev(),
+		CookieHTTPOnly:    true,
+		CookieSameSite:    "Lax",
+		CookieSessionOnly: true,
+		TrustedOrigins:    origins,
+		ErrorHandler: func(c fiber.Ctx, err error) error {
+			slog.WarnContext(c.Context(), "csrf validation failed",
+				"method", c.Method(),
+				"path", c.Path(),
+				"ip", c.IP(),
+				"reason", err.Error(),
+			)
+			return fiber.NewError(fiber.StatusForbidden, "invalid or missing CSRF token")
+		},
+	})
+}
+
+// CSRFTokenToViews makes the request's CSRF token available to templates as
+// {{.CSRFToken}}. Register it directly after CSRFMiddleware.
+func CSRFTokenToViews(c fiber.Ctx) error {
+	if token := csrf.TokenFromContext(c); token != "" {
+		if err := c.ViewBind(fiber.Map{"CSRFToken": token}); err != nil {
+			return err
+		}
+	}
+	return c.Next()
+}
+
+// isNonBrowserClient reports whether the request comes from an API client
+// that a malicious page cannot forge. SCIM authenticates the bearer token
+// itself, which must be attached explicitly. Browsers attach client
+// certificates on their own, so a PKI request without a session cookie is
+// only exempt when it wasn't sent from another site.
+func isNonBrowserClient(c fiber.Ctx, certHeader string, origins []string) bool {
+	auth := c.Get(fiber.HeaderAuthorization)
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") && strings.HasPrefix(c.Path(), scimPathPrefix+"/") {
+		return true
+	}
+	if clientCertCN(c, certHeader) != "" && c.Cookies(sessionCookieName()) == "" {
+		return !crossSite(c, origins)
+	}
+	return false
+}
+
+// crossSite reports whether a browser may have sent the request from a page
+// outside origins and the request's own origin. Browsers label requests with
+// Sec-Fetch-Site and unsafe ones with Origin; a request with neither doesn't
+// come from a browser.
+func crossSite(c fiber.Ctx, origins []string) bool {
+	site := c.Get("Sec-Fetch-Site")
+	if site == "same-origin" || site == "none" {
+		return false
+	}
+	origin := strings.ToLower(c.Get(fiber.HeaderOrigin))
+	if origin == "" {
+		return site != ""
+	}
+	return origin != strings.ToLower(c.Scheme()+"://"+c.Host()) && !slices.Contains(origins, origin)
+}
+
+// sessionCookieName is the cookie the session middleware reads the session ID from.
+func sessionCookieName() string {
+	return session.ConfigDefault.Extractor.Key
+}
+
+// trustedOrigins returns the origins allowed to make unsafe requests besides
+// the request's own host: BASE_URL (which differs from the host seen by the
+// app behind a TLS-terminating proxy) and any CORS_ORIGINS.
+func trustedOrigins(cfg *config.Config) []string {
+	candidates := []string{cfg.BaseURL}
+	if cfg.CORSOrigins != "" {
+		candidates = append(candidates, strings.Split(cfg.CORSOrigins, ",")...)
+	}
+
+	var origins []string
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		u, err := url.Parse(strings.TrimSpace(candidate))
+		if err != nil || u.Scheme == "" || u.Host == "" || strings.Contains(u.Host, "*") {
+			continue
+		}
+		origin := strings.ToLower(u.Scheme + "://" + u.Host)
+		if !seen[origin] {
+			seen[origin] = true
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
@@ -0,0 +1,249 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/csrf"
+	"github.com/gofiber/fiber/v3/middleware/session"
+
+	"golinks/internal/config"
+)
+
+const testCertHeader = "X-Client-Cert-CN"
+
+// newCSRFTestApp mounts one representative unsafe route per route group
+// behind the session and CSRF middleware, mirroring server.New.
+func newCSRFTestApp(t *testing.T) *fiber.App {
+	t.Helper()
+
+	cfg := &config.Config{
+		Env:              "development",
+		BaseURL:          "https://links.example.com",
+		CORSOrigins:      "https://portal.example.com",
+		ClientCertHeader: testCertHeader,
+	}
+
+	app := fiber.New()
+	sessionMiddleware, store := session.NewWithStore()
+	app.Use(sessionMiddleware)
+	app.Use(CSRFMiddleware(cfg, store), CSRFTokenToViews)
+
+	app.Get("/page", func(c fiber.Ctx) error {
+		return c.SendString(csrf.TokenFromContext(c))
+	})
+	ok := func(c fiber.Ctx) error { return c.SendString("ok") }
+	app.Post("/links", ok)
+	app.Delete("/links/:id", ok)
+	app.Post("/moderation/:id/approve", ok)
+	app.Put("/manage/:id", ok)
+	app.Post("/admin/users/:id/role", ok)
+	app.Post("/my-links/share", ok)
+	app.Post("/notifications/:id/read", ok)
+	app.Post("/api/v1/links", ok)
+	app.Put("/api/v1/users/:id/role", ok)
+	app.Post(BackChannelLogoutPath, ok)
+	app.Post("/scim/v2/Users", ok)
+	return app
+}
+
+// csrfRouteGroups lists one unsafe route per route group.
+var csrfRouteGroups = []struct {
+	group  string
+	method string
+	path   string
+}{
+	{"links", "POST", "/links"},
+	{"links", "DELETE", "/links/00000000-0000-0000-0000-000000000001"},
+	{"moderation", "POST", "/moderation/00000000-0000-0000-0000-000000000001/approve"},
+	{"manage", "PUT", "/manage/00000000-0000-0000-0000-000000000001"},
+	{"admin", "POST", "/admin/users/00000000-0000-0000-0000-000000000001/role"},
+	{"my-links", "POST", "/my-links/share"},
+	{"notifications", "POST", "/notifications/00000000-0000-0000-0000-000000000001/read"},
+	{"api", "POST", "/api/v1/links"},
+	{"api", "PUT", "/api/v1/users/00000000-0000-0000-0000-000000000001/role"},
+}
+
+// browserSession performs a GET to obtain session and CSRF cookies plus the
+// token a rendered page would carry.
+func browserSession(t *testing.T, app *fiber.App) ([]*http.Cookie, string) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest("GET", "/page", nil))
+	if err != nil {
+		t.Fatalf("GET /page error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if len(body) == 0 {
+		t.Fatal("GET /page returned no CSRF token")
+	}
+	return resp.Cookies(), string(body)
+}
+
+func doRequest(t *testing.T, app *fiber.App, method, path string, cookies []*http.Cookie, headers map[string]string) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, path, err)
+	}
+	return resp.StatusCode
+}
+
+func TestCSRFRejectsBrowserRequestsWithoutToken(t *testing.T) {
+	app := newCSRFTestApp(t)
+	cookies, _ := browserSession(t, app)
+
+	for _, r := range csrfRouteGroups {
+		if got := doRequest(t, app, r.method, r.path, cookies, nil); got != fiber.StatusForbidden {
+			t.Errorf("[%s] %s %s without token = %d, want 403", r.group, r.method, r.path, got)
+		}
+	}
+}
+
+func TestCSRFRejectsWrongToken(t *testing.T) {
+	app := newCSRFTestApp(t)
+	cookies, _ := browserSession(t, app)
+
+	for _, r := range csrfRouteGroups {
+		got := doRequest(t, app, r.method, r.path, cookies, map[string]string{CSRFHeader: "forged"})
+		if got != fiber.StatusForbidden {
+			t.Errorf("[%s] %s %s with forged token = %d, want 403", r.group, r.method, r.path, got)
+		}
+	}
+}
+
+func TestCSRFAcceptsValidToken(t *testing.T) {
+	app := newCSRFTestApp(t)
+	cookies, token := browserSession(t, app)
+
+	for _, r := range csrfRouteGroups {
+		got := doRequest(t, app, r.method, r.path, cookies, map[string]string{CSRFHeader: token})
+		if got != fiber.StatusOK {
+			t.Errorf("[%s] %s %s with token = %d, want 200", r.group, r.method, r.path, got)
+		}
+	}
+}
+
+func TestCSRFOriginChecks(t *testing.T) {
+	app := newCSRFTestApp(t)
+	cookies, token := browserSession(t, app)
+
+	tests := []struct {
+		origin string
+		want   int
+	}{
+		{"https://links.example.com", fiber.StatusOK},  // BASE_URL behind a TLS proxy
+		{"https://portal.example.com", fiber.StatusOK}, // CORS_ORIGINS
+		{"https://evil.example.net", fiber.StatusForbidden},
+	}
+	for _, tt := range tests {
+		got := doRequest(t, app, "POST", "/links", cookies, map[string]string{
+			CSRFHeader: token,
+			"Origin":   tt.origin,
+		})
+		if got != tt.want {
+			t.Errorf("Origin %s = %d, want %d", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestCSRFExemptsSCIMBearerClients(t *testing.T) {
+	app := newCSRFTestApp(t)
+	bearer := map[string]string{"Authorization": "Bearer scim-token"}
+
+	if got := doRequest(t, app, "POST", "/scim/v2/Users", nil, bearer); got != fiber.StatusOK {
+		t.Errorf("POST /scim/v2/Users with bearer token = %d, want 200", got)
+	}
+
+	// Only SCIM authenticates bearer tokens; elsewhere the header proves
+	// nothing, and the session cookie still rides along.
+	cookies, _ := browserSession(t, app)
+	for _, r := range csrfRouteGroups {
+		if got := doRequest(t, app, r.method, r.path, cookies, bearer); got != fiber.StatusForbidden {
+			t.Errorf("[%s] %s %s with bearer token = %d, want 403", r.group, r.method, r.path, got)
+		}
+	}
+}
+
+func TestCSRFExemptsPKIClientsWithoutSession(t *testing.T) {
+	app := newCSRFTestApp(t)
+
+	for _, r := range csrfRouteGroups {
+		got := doRequest(t, app, r.method, r.path, nil, map[string]string{testCertHeader: "Service Account (svc-links)"})
+		if got != fiber.StatusOK {
+			t.Errorf("[%s] %s %s with client cert = %d, want 200", r.group, r.method, r.path, got)
+		}
+	}
+}
+
+func TestCSRFProtectsPKIBrowsersCrossSite(t *testing.T) {
+	app := newCSRFTestApp(t)
+
+	// Browsers attach client certificates to cross-site requests too, while
+	// the SameSite=Lax session cookie stays behind.
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"cross-site", map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example.net"}, fiber.StatusForbidden},
+		{"same-site", map[string]string{"Sec-Fetch-Site": "same-site", "Origin": "https://other.example.com"}, fiber.StatusForbidden},
+		{"origin only", map[string]string{"Origin": "https://evil.example.net"}, fiber.StatusForbidden},
+		{"fetch metadata only", map[string]string{"Sec-Fetch-Site": "cross-site"}, fiber.StatusForbidden},
+		{"same-origin", map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "https://links.example.com"}, fiber.StatusOK},
+		{"user-initiated", map[string]string{"Sec-Fetch-Site": "none"}, fiber.StatusOK},
+		{"trusted origin", map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://portal.example.com"}, fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		tt.headers[testCertHeader] = "Jane Doe (jdoe)"
+		if got := doRequest(t, app, "POST", "/links", nil, tt.headers); got != tt.want {
+			t.Errorf("%s: POST /links with client cert = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCSRFExemptsBackChannelLogout(t *testing.T) {
+	app := newCSRFTestApp(t)
+
+	// The OIDC provider posts logout tokens server-to-server with no token.
+	if got := doRequest(t, app, "POST", BackChannelLogoutPath, nil, nil); got != fiber.StatusOK {
+		t.Errorf("POST %s without token = %d, want 200", BackChannelLogoutPath, got)
+	}
+}
+
+func TestCSRFProtectsPKIBrowsersWithSession(t *testing.T) {
+	app := newCSRFTestApp(t)
+	cookies, _ := browserSession(t, app)
+
+	// A browser presenting a client cert automatically still rides its session
+	// cookie, so it must send a token like any other browser.
+	got := doRequest(t, app, "POST", "/links", cookies, map[string]string{testCertHeader: "Jane Doe (jdoe)"})
+	if got != fiber.StatusForbidden {
+		t.Errorf("POST /links with cert and session cookie but no token = %d, want 403", got)
+	}
+}
+
+func TestTrustedOrigins(t *testing.T) {
+	got := trustedOrigins(&config.Config{
+		BaseURL:     "https://Links.Example.com/",
+		CORSOrigins: "https://links.example.com, https://*.example.com,not a url,http://other:8080",
+	})
+	want := []string{"https://links.example.com", "http://other:8080"}
+	if len(got) != len(want) {
+		t.Fatalf("trustedOrigins() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("trustedOrigins()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
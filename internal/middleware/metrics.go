@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"golinks/internal/metrics"
+)
+
+// unmatchedRoute labels requests that did not match any route, so unknown
+// paths share one series instead of creating a series per path.
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware records request duration per method, route pattern and
+// status code. Routes are labelled by their registered pattern (for example
+// "/go/:keyword"), never by the raw path, to keep cardinality bounded.
+func MetricsMiddleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		metrics.HTTPRequestDuration.
+			WithLabelValues(c.Method(), routePattern(c), strconv.Itoa(responseStatus(c, err))).
+			Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// routePattern returns the registered pattern of the matched route, or
+// unmatchedRoute when no route handled the request.
+func routePattern(c fiber.Ctx) string {
+	if c.Matched() {
+		return c.Route().Path
+	}
+	return unmatchedRoute
+}
+
+// responseStatus returns the status code the response will be sent with.
+// When a handler returned an error the error handler has not run yet, so the
+// status is derived from the error the way the error handler does.
+func responseStatus(c fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	if e, ok := err.(*fiber.Error); ok {
+		return e.Code
+	}
+	return fiber.StatusInternalServerError
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"golinks/internal/metrics"
+)
+
+func TestMetricsMiddlewareLabelsByRoutePattern(t *testing.T) {
+	metrics.HTTPRequestDuration.Reset()
+	defer metrics.HTTPRequestDuration.Reset()
+
+	app := fiber.New()
+	app.Use(MetricsMiddleware())
+	app.Get("/go/:keyword", func(c fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+	app.Get("/fail", func(c fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusForbidden, "nope")
+	})
+
+	for _, path := range []string{"/go/docs", "/go/wiki", "/fail", "/no-such-page", "/another-typo"} {
+		if _, err := app.Test(httptest.NewRequest("GET", path, nil)); err != nil {
+			t.Fatalf("app.Test(%s) error = %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		route  string
+		status string
+		want   uint64
+	}{
+		{"/go/:keyword", "200", 2},
+		{"/fail", "403", 1},
+		{unmatchedRoute, "404", 2},
+	}
+
+	if got := testutil.CollectAndCount(metrics.HTTPRequestDuration); got != len(tests) {
+		t.Errorf("series count = %d, want %d", got, len(tests))
+	}
+
+	for _, tt := range tests {
+		observer := metrics.HTTPRequestDuration.WithLabelValues("GET", tt.route, tt.status)
+		var m dto.Metric
+		if err := observer.(prometheus.Metric).Write(&m); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if got := m.GetHistogram().GetSampleCount(); got != tt.want {
+			t.Errorf("route %s status %s: sample count = %d, want %d", tt.route, tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"golinks/internal/config"
+	"golinks/internal/db"
+	"golinks/internal/identity"
+	"golinks/internal/models"
+)
+
+// proxySyncInterval bounds how often an unchanged proxy identity re-syncs the
+// user's profile, organization and role.
+const proxySyncInterval = 5 * time.Minute
+
+// proxySyncedMax caps the proxy sync cache; it is cleared when full.
+const proxySyncedMax = 10000
+
+// trustedProxy reads the identity headers set by an authenticating reverse
+// proxy. Headers are only believed on requests whose peer address is one of
+// the allowed proxies; anyone else could set them.
+type trustedProxy struct {
+	userHeader   string
+	emailHeader  string
+	groupsHeader string
+	orgHeader    string
+	cidrs        []netip.Prefix
+}
+
+// proxyIdentity is what a trusted proxy vouches for.
+type proxyIdentity struct {
+	user   string
+	email  string
+	groups []string
+	org    string
+}
+
+// newTrustedProxy returns nil when proxy authentication is not configured.
+func newTrustedProxy(cfg *config.Config) *trustedProxy {
+	if !cfg.ProxyAuthEnabled() {
+		return nil
+	}
+	return &trustedProxy{
+		userHeader:   cfg.ProxyAuthUserHeader,
+		emailHeader:  cfg.ProxyAuthEmailHeader,
+		groupsHeader: cfg.ProxyAuthGroupsHeader,
+		orgHeader:    cfg.ProxyAuthOrgHeader,
+		cidrs:        cfg.ProxyAuthTrustedCIDRs,
+	}
+}
+
+// identity returns the identity in the request's proxy headers, if the
+// request carries a user header and comes straight from a trusted proxy.
+func (p *trustedProxy) identity(c fiber.Ctx) (proxyIdentity, bool) {
+	if p == nil {
+		return proxyIdentity{}, false
+	}
+	user := strings.TrimSpace(c.Get(p.userHeader))
+	if user == "" {
+		return proxyIdentity{}, false
+	}
+	if !p.trusts(c) {
+		slog.DebugContext(c.Context(), "ignoring proxy auth headers from untrusted peer", "peer", c.RequestCtx().RemoteIP().String())
+		return proxyIdentity{}, false
+	}
+
+	id := proxyIdentity{user: user}
+	if p.emailHeader != "" {
+		id.email = strings.TrimSpace(c.Get(p.emailHeader))
+	}
+	if p.groupsHeader != "" {
+		for _, g := range strings.Split(c.Get(p.groupsHeader), ",") {
+			if g = strings.TrimSpace(g); g != "" {
+				id.groups = append(id.groups, g)
+			}
+		}
+	}
+	if p.orgHeader != "" {
+		id.org = strings.TrimSpace(c.Get(p.orgHeader))
+	}
+	return id, true
+}
+
+// trusts reports whether the request's peer address (not X-Forwarded-For,
+// which the client controls) is an allowed proxy.
+func (p *trustedProxy) trusts(c fiber.Ctx) bool {
+	addr, ok := netip.AddrFromSlice(c.RequestCtx().RemoteIP())
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range p.cidrs {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// claims renders the identity the way identity.SyncUser expects OIDC claims,
+// using the claim names of config.ProxyAuthProvider.
+func (id proxyIdentity) claims() map[string]any {
+	groups := make([]any, len(id.groups))
+	for i, g := range id.groups {
+		groups[i] = g
+	}
+	claims := map[string]any{
+		"sub":                id.user,
+		"preferred_username": id.user,
+		"name":               id.user,
+		"email":              id.email,
+		"groups":             groups,
+	}
+	if id.org != "" {
+		claims["organization"] = id.org
+	}
+	return claims
+}
+
+// fingerprint identifies the identity's full contents, so any change to the
+// headers triggers a re-sync.
+func (id proxyIdentity) fingerprint() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{id.user, id.email, strings.Join(id.groups, ","), id.org}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// authenticateViaProxy returns the user a trusted proxy vouches for. The user
+// is created or updated, and their groups mapped to a role and organization,
+// like an OIDC sign-in; unchanged identities are re-synced at most once per
+// proxySyncInterval.
+func (m *AuthMiddleware) authenticateViaProxy(c fiber.Ctx) (*models.User, error) {
+	id, ok := m.proxy.identity(c)
+	if !ok {
+		return nil, nil
+	}
+	ctx := c.Context()
+	fingerprint := id.fingerprint()
+	now := time.Now()
+
+	m.proxyMu.Lock()
+	syncedAt, synced := m.proxySynced[fingerprint]
+	m.proxyMu.Unlock()
+	if synced && now.Sub(syncedAt) < proxySyncInterval {
+		user, err := m.db.GetUserBySub(ctx, m.proxyProvider.Issuer, id.user)
+		if err == nil {
+			return m.activeUser(c, user)
+		}
+		if !errors.Is(err, db.ErrUserNotFound) {
+			return nil, err
+		}
+	}
+
+	user, err := identity.SyncUser(ctx, m.db, m.proxyProvider, id.claims(), nil, m.debug)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to sync proxy-authenticated user", "user", id.user, "error", err)
+		return nil, err
+	}
+
+	m.proxyMu.Lock()
+	if len(m.proxySynced) >= proxySyncedMax {
+		m.proxySynced = make(map[string]time.Time)
+	}
+	m.proxySynced[fingerprint] = now
+	m.proxyMu.Unlock()
+
+	// Reload for the role the group mapping may just have changed.
+	if user, err = m.db.GetUserByID(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	return m.activeUser(c, user)
+}
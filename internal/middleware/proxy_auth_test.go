@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"golinks/internal/config"
+)
+
+// proxyIdentityApp echoes the identity trustedProxy reads from a request.
+// app.Test requests arrive from 0.0.0.0.
+func proxyIdentityApp(p *trustedProxy) *fiber.App {
+	app := fiber.New()
+	app.Get("/", func(c fiber.Ctx) error {
+		id, ok := p.identity(c)
+		if !ok {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+		return c.JSON(fiber.Map{"user": id.user, "email": id.email, "groups": id.groups, "org": id.org})
+	})
+	return app
+}
+
+func testProxy(cidr string) *trustedProxy {
+	return newTrustedProxy(&config.Config{
+		ProxyAuthUserHeader:   "X-Forwarded-User",
+		ProxyAuthEmailHeader:  "X-Forwarded-Email",
+		ProxyAuthGroupsHeader: "X-Forwarded-Groups",
+		ProxyAuthOrgHeader:    "X-Forwarded-Org",
+		ProxyAuthTrustedCIDRs: []netip.Prefix{netip.MustParsePrefix(cidr)},
+	})
+}
+
+func TestTrustedProxyIdentity(t *testing.T) {
+	app := proxyIdentityApp(testProxy("0.0.0.0/32"))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Forwarded-User", "alice")
+	req.Header.Set("X-Forwarded-Email", "alice@example.com")
+	req.Header.Set("X-Forwarded-Groups", "eng, golinks-admins,,")
+	req.Header.Set("X-Forwarded-Org", "acme")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	want := `{"email":"alice@example.com","groups":["eng","golinks-admins"],"org":"acme","user":"alice"}`
+	if string(body) != want {
+		t.Errorf("identity = %s, want %s", body, want)
+	}
+}
+
+func TestTrustedProxyIgnoresUntrustedPeer(t *testing.T) {
+	app := proxyIdentityApp(testProxy("10.0.0.0/8"))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Forwarded-User", "alice")
+	// X-Forwarded-For is client-controlled and must not make a peer trusted.
+	req.Header.Set("X-Forwarded-For", "10.1.2.3")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("status = %d, want 401 for headers from an untrusted peer", resp.StatusCode)
+	}
+}
+
+func TestTrustedProxyRequiresUserHeader(t *testing.T) {
+	app := proxyIdentityApp(testProxy("0.0.0.0/32"))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Forwarded-Email", "alice@example.com")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("status = %d, want 401 without a user header", resp.StatusCode)
+	}
+}
+
+func TestNewTrustedProxyDisabled(t *testing.T) {
+	if p := newTrustedProxy(&config.Config{ProxyAuthUserHeader: "X-Forwarded-User"}); p != nil {
+		t.Error("newTrustedProxy() without trusted CIDRs is enabled, want nil")
+	}
+	var p *trustedProxy
+	if _, ok := p.identity(nil); ok {
+		t.Error("nil trustedProxy returned an identity")
+	}
+}
@@ -0,0 +1,271 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/session"
+
+	"golinks/internal/config"
+	"golinks/internal/metrics"
+	"golinks/internal/models"
+	"golinks/internal/ratelimit"
+)
+
+// Rate limit policies. Each has its own budget per client and window.
+const (
+	RateLimitRedirect = "redirect"
+	RateLimitSearch   = "search"
+	RateLimitWrite    = "write"
+	RateLimitAPI      = "api"
+	RateLimitDefault  = "default"
+)
+
+// allPolicies is the override key matching every policy.
+const allPolicies = "*"
+
+// roleCacheTTL bounds how long a role change takes to affect rate limits.
+const roleCacheTTL = time.Minute
+
+// roleCacheMax caps the role cache; it is cleared when full.
+const roleCacheMax = 10000
+
+// RateLimitUsers is the subset of the database used to resolve roles.
+type RateLimitUsers interface {
+	GetUserBySub(ctx context.Context, issuer, sub string) (*models.User, error)
+	GetUserByUsername(ctx context.Context, issuer, username string) (*models.User, error)
+}
+
+// RateLimiter enforces per-client request budgets that depend on the kind of
+// route (redirect, search, write, API, other) and on who is asking: role and
+// bearer-token overrides can raise, lower or lift a policy's limit.
+type RateLimiter struct {
+	store            ratelimit.Store
+	users            RateLimitUsers
+	window           time.Duration
+	limits           map[string]int
+	roleOverrides    map[string]map[string]int
+	tokenOverrides   map[string]map[string]int
+	scimToken        string // SCIM bearer token SHA-256 (hex), "" when SCIM is off
+	clientCertHeader string
+	certIssuer       string
+	primaryIssuer    string
+	proxy            *trustedProxy
+	proxyIssuer      string
+
+	mu    sync.Mutex
+	roles map[string]cachedRole
+}
+
+type cachedRole struct {
+	role    string
+	expires time.Time
+}
+
+// rateLimitClient identifies who a request is counted against.
+type rateLimitClient struct {
+	key   string // limiter key, e.g. "user:<issuer> <sub>" or "ip:<addr>"
+	role  string // user role, "" when unknown
+	token string // bearer token SHA-256 (hex), "" unless the token is known
+}
+
+// NewRateLimiter creates a rate limiter. Counters live in store, which is
+// shared by all replicas when it is Redis-backed.
+func NewRateLimiter(cfg *config.Config, store ratelimit.Store, users RateLimitUsers) *RateLimiter {
+	scimToken := ""
+	if cfg.SCIMBearerToken != "" {
+		scimToken = hashToken(cfg.SCIMBearerToken)
+	}
+	return &RateLimiter{
+		store:  store,
+		users:  users,
+		window: time.Duration(cfg.RateLimitWindowSecs) * time.Second,
+		limits: map[string]int{
+			RateLimitRedirect: cfg.RateLimitRedirect,
+			RateLimitSearch:   cfg.RateLimitSearch,
+			RateLimitWrite:    cfg.RateLimitWrite,
+			RateLimitAPI:      cfg.RateLimitAPI,
+			RateLimitDefault:  cfg.RateLimitDefault,
+		},
+		roleOverrides:    cfg.RateLimitRoleOverrides,
+		tokenOverrides:   cfg.RateLimitTokenOverrides,
+		scimToken:        scimToken,
+		clientCertHeader: cfg.ClientCertHeader,
+		certIssuer:       clientCertIssuer(cfg),
+		primaryIssuer:    primaryOIDCIssuer(cfg),
+		proxy:            newTrustedProxy(cfg),
+		proxyIssuer:      cfg.ProxyAuthIssuer,
+		roles:            make(map[string]cachedRole),
+	}
+}
+
+// Handler counts the request against its policy and rejects it with 429 once
+// the budget for the current window is spent. Responses carry RateLimit-Limit,
+// RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers, plus
+// Retry-After when rejected. If the store is unavailable requests are let
+// through rather than failing the whole site.
+func (r *RateLimiter) Handler(c fiber.Ctx) error {
+	policy := rateLimitPolicy(c)
+	client := r.identify(c)
+	limit := r.limitFor(policy, client)
+	if limit == 0 {
+		return c.Next()
+	}
+
+	hits, resetIn, err := r.store.Hit(c.Context(), policy+":"+client.key, r.window)
+	if err != nil {
+		slog.WarnContext(c.Context(), "rate limit store unavailable, allowing request", "policy", policy, "error", err)
+		return c.Next()
+	}
+
+	reset := int((resetIn + time.Second - 1) / time.Second)
+	remaining := max(limit-hits, 0)
+	c.Set("RateLimit-Limit", strconv.Itoa(limit))
+	c.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	c.Set("RateLimit-Reset", strconv.Itoa(reset))
+	c.Set("RateLimit-Policy", strconv.Itoa(limit)+";w="+strconv.Itoa(int(r.window/time.Second)))
+
+	if hits > limit {
+		metrics.RateLimited.WithLabelValues(policy).Inc()
+		slog.WarnContext(c.Context(), "rate limit exceeded", "policy", policy, "key", client.key, "path", c.Path())
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(reset))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": "Rate limit exceeded. Please try again later.",
+		})
+	}
+	return c.Next()
+}
+
+// rateLimitPolicy classifies a request by route. API calls count as API
+// whatever their method; other unsafe methods count as writes.
+func rateLimitPolicy(c fiber.Ctx) string {
+	path := c.Path()
+	switch {
+	case strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/scim/"):
+		return RateLimitAPI
+	case !isSafeMethod(c.Method()):
+		return RateLimitWrite
+	case strings.HasPrefix(path, "/go/") || path == "/random":
+		return RateLimitRedirect
+	case path == "/search" || path == "/suggest" || path == "/links/check" || path == "/my-links/users/search":
+		return RateLimitSearch
+	default:
+		return RateLimitDefault
+	}
+}
+
+func isSafeMethod(method string) bool {
+	return method == fiber.MethodGet || method == fiber.MethodHead || method == fiber.MethodOptions
+}
+
+// limitFor returns the request budget for a client under a policy. A token
+// override beats a role override, which beats the policy default; within an
+// override a policy-specific entry beats "*".
+func (r *RateLimiter) limitFor(policy string, client rateLimitClient) int {
+	if client.token != "" {
+		if limit, ok := lookupOverride(r.tokenOverrides[client.token], policy); ok {
+			return limit
+		}
+	}
+	if client.role != "" {
+		if limit, ok := lookupOverride(r.roleOverrides[client.role], policy); ok {
+			return limit
+		}
+	}
+	return r.limits[policy]
+}
+
+func lookupOverride(overrides map[string]int, policy string) (int, bool) {
+	if limit, ok := overrides[policy]; ok {
+		return limit, true
+	}
+	limit, ok := overrides[allPolicies]
+	return limit, ok
+}
+
+// identify picks the limiter key: a known bearer token, a client
+// certificate, the signed-in user, or finally the client IP. Only tokens
+// listed in the token overrides, or the SCIM token on SCIM routes, get a
+// bucket of their own; any other bearer value is ignored so clients can't get
+// a new budget per request by making tokens up. Sessions without a user are
+// ignored for the same reason: anyone can get a new one by dropping cookies.
+func (r *RateLimiter) identify(c fiber.Ctx) rateLimitClient {
+	if auth := c.Get(fiber.HeaderAuthorization); len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		token := hashToken(strings.TrimSpace(auth[7:]))
+		_, listed := r.tokenOverrides[token]
+		scim := r.scimToken != "" && token == r.scimToken && strings.HasPrefix(c.Path(), "/scim/")
+		if listed || scim {
+			return rateLimitClient{key: "token:" + token, token: token}
+		}
+	}
+
+	if cn := clientCertCN(c, r.clientCertHeader); cn != "" {
+		if username := extractUsernameFromCN(cn); username != "" {
+			return rateLimitClient{key: "cert:" + username, role: r.role(c.Context(), "cert:"+username)}
+		}
+	}
+
+	if id, ok := r.proxy.identity(c); ok {
+		key := "user:" + r.proxyIssuer + " " + id.user
+		return rateLimitClient{key: key, role: r.role(c.Context(), key)}
+	}
+
+	if sess := session.FromContext(c); sess != nil {
+		if issuer, sub := sessionIdentity(sess, r.primaryIssuer); sub != "" {
+			key := "user:" + issuer + " " + sub
+			return rateLimitClient{key: key, role: r.role(c.Context(), key)}
+		}
+	}
+	return rateLimitClient{key: "ip:" + c.IP()}
+}
+
+// hashToken returns the hex SHA-256 of a bearer token, the form tokens take in
+// RATE_LIMIT_TOKEN_OVERRIDES and limiter keys.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// role returns the role of the user behind a "user:<issuer> <sub>" or
+// "cert:<username>" key, cached for roleCacheTTL. Unknown users get "".
+func (r *RateLimiter) role(ctx context.Context, key string) string {
+	if r.users == nil {
+		return ""
+	}
+	now := time.Now()
+	r.mu.Lock()
+	cached, ok := r.roles[key]
+	r.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.role
+	}
+
+	var user *models.User
+	var err error
+	if identity, ok := strings.CutPrefix(key, "user:"); ok {
+		// Issuers are URLs and never contain a space; subjects might.
+		issuer, sub, _ := strings.Cut(identity, " ")
+		user, err = r.users.GetUserBySub(ctx, issuer, sub)
+	} else if username, ok := strings.CutPrefix(key, "cert:"); ok {
+		user, err = r.users.GetUserByUsername(ctx, r.certIssuer, username)
+	}
+	role := ""
+	if err == nil && user != nil {
+		role = user.Role
+	}
+
+	r.mu.Lock()
+	if len(r.roles) >= roleCacheMax {
+		r.roles = make(map[string]cachedRole)
+	}
+	r.roles[key] = cachedRole{role: role, expires: now.Add(roleCacheTTL)}
+	r.mu.Unlock()
+	return role
+}
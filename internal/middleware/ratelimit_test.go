@@ -0,0 +1,307 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/session"
+
+	"golinks/internal/config"
+	"golinks/internal/models"
+	"golinks/internal/ratelimit"
+)
+
+// fakeRateLimitUsers resolves PKI usernames to roles.
+type fakeRateLimitUsers map[string]string
+
+func (f fakeRateLimitUsers) GetUserBySub(_ context.Context, issuer, sub string) (*models.User, error) {
+	return nil, errors.New("not found")
+}
+
+func (f fakeRateLimitUsers) GetUserByUsername(_ context.Context, issuer, username string) (*models.User, error) {
+	role, ok := f[username]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &models.User{Role: role}, nil
+}
+
+// failingStore simulates an unreachable Redis.
+type failingStore struct{}
+
+func (failingStore) Hit(context.Context, string, time.Duration) (int, time.Duration, error) {
+	return 0, 0, errors.New("connection refused")
+}
+
+func newRateLimitTestApp(cfg *config.Config, store ratelimit.Store, users RateLimitUsers) *fiber.App {
+	cfg.ClientCertHeader = testCertHeader
+	if cfg.RateLimitWindowSecs == 0 {
+		cfg.RateLimitWindowSecs = 60
+	}
+	app := fiber.New()
+	app.Use(NewRateLimiter(cfg, store, users).Handler)
+	ok := func(c fiber.Ctx) error { return c.SendString("ok") }
+	app.Get("/go/:keyword", ok)
+	app.Get("/search", ok)
+	app.Get("/browse", ok)
+	app.Post("/links", ok)
+	app.Post("/moderation/:id/approve", ok)
+	app.Get("/api/v1/links", ok)
+	app.Get("/scim/v2/Users", ok)
+	return app
+}
+
+func rateLimitRequest(t *testing.T, app *fiber.App, method, path string, headers map[string]string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, path, err)
+	}
+	return resp
+}
+
+func TestRateLimitPolicy(t *testing.T) {
+	tests := []struct {
+		method, path, want string
+	}{
+		{"GET", "/go/docs", RateLimitRedirect},
+		{"GET", "/random", RateLimitRedirect},
+		{"GET", "/search", RateLimitSearch},
+		{"GET", "/suggest", RateLimitSearch},
+		{"GET", "/links/check", RateLimitSearch},
+		{"POST", "/links", RateLimitWrite},
+		{"DELETE", "/my-links/1", RateLimitWrite},
+		{"GET", "/api/v1/links", RateLimitAPI},
+		{"POST", "/api/v1/links", RateLimitAPI},
+		{"PATCH", "/scim/v2/Users/1", RateLimitAPI},
+		{"GET", "/browse", RateLimitDefault},
+	}
+	for _, tt := range tests {
+		app := fiber.New()
+		var got string
+		app.Use(func(c fiber.Ctx) error {
+			got = rateLimitPolicy(c)
+			return nil
+		})
+		if _, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil)); err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s %s policy = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimiterRejectsOverLimitWithHeaders(t *testing.T) {
+	app := newRateLimitTestApp(&config.Config{RateLimitRedirect: 2}, ratelimit.NewMemoryStore(), nil)
+
+	for i := 1; i <= 2; i++ {
+		resp := rateLimitRequest(t, app, "GET", "/go/docs", nil)
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("request %d status = %d, want 200", i, resp.StatusCode)
+		}
+		if got := resp.Header.Get("RateLimit-Remaining"); got != []string{"1", "0"}[i-1] {
+			t.Errorf("request %d RateLimit-Remaining = %q", i, got)
+		}
+		if got := resp.Header.Get("RateLimit-Limit"); got != "2" {
+			t.Errorf("RateLimit-Limit = %q, want 2", got)
+		}
+		if got := resp.Header.Get("RateLimit-Policy"); got != "2;w=60" {
+			t.Errorf("RateLimit-Policy = %q, want 2;w=60", got)
+		}
+	}
+
+	resp := rateLimitRequest(t, app, "GET", "/go/docs", nil)
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("third request status = %d, want 429", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" || resp.Header.Get("RateLimit-Reset") == "" {
+		t.Error("429 response missing Retry-After or RateLimit-Reset")
+	}
+}
+
+func TestRateLimiterSeparatePolicyBudgets(t *testing.T) {
+	app := newRateLimitTestApp(&config.Config{
+		RateLimitRedirect: 1,
+		RateLimitWrite:    1,
+	}, ratelimit.NewMemoryStore(), nil)
+
+	rateLimitRequest(t, app, "GET", "/go/docs", nil)
+	if resp := rateLimitRequest(t, app, "GET", "/go/docs", nil); resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("redirect over budget status = %d, want 429", resp.StatusCode)
+	}
+	// Exhausting the redirect budget must not block writes.
+	if resp := rateLimitRequest(t, app, "POST", "/links", nil); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("write status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestRateLimiterZeroDisablesPolicy(t *testing.T) {
+	app := newRateLimitTestApp(&config.Config{}, ratelimit.NewMemoryStore(), nil)
+
+	for i := 0; i < 5; i++ {
+		resp := rateLimitRequest(t, app, "GET", "/browse", nil)
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("status = %d, want 200", resp.StatusCode)
+		}
+		if resp.Header.Get("RateLimit-Limit") != "" {
+			t.Error("RateLimit headers set for an unlimited policy")
+		}
+	}
+}
+
+func TestRateLimiterRoleOverride(t *testing.T) {
+	app := newRateLimitTestApp(&config.Config{
+		RateLimitWrite:         1,
+		RateLimitRoleOverrides: map[string]map[string]int{models.RoleGlobalMod: {RateLimitWrite: 3}},
+	}, ratelimit.NewMemoryStore(), fakeRateLimitUsers{"mod": models.RoleGlobalMod, "bob": models.RoleUser})
+
+	mod := map[string]string{testCertHeader: "Moderator (mod)"}
+	for i := 1; i <= 3; i++ {
+		if resp := rateLimitRequest(t, app, "POST", "/moderation/1/approve", mod); resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("moderator approval %d status = %d, want 200", i, resp.StatusCode)
+		}
+	}
+	if resp := rateLimitRequest(t, app, "POST", "/moderation/1/approve", mod); resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Errorf("moderator approval 4 status = %d, want 429", resp.StatusCode)
+	}
+
+	user := map[string]string{testCertHeader: "Bob (bob)"}
+	rateLimitRequest(t, app, "POST", "/links", user)
+	if resp := rateLimitRequest(t, app, "POST", "/links", user); resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Errorf("regular user second write status = %d, want 429", resp.StatusCode)
+	}
+}
+
+func TestRateLimiterTokenOverride(t *testing.T) {
+	sum := sha256.Sum256([]byte("svc-token"))
+	app := newRateLimitTestApp(&config.Config{
+		RateLimitAPI: 1,
+		RateLimitTokenOverrides: map[string]map[string]int{
+			hex.EncodeToString(sum[:]): {"*": 0},
+		},
+	}, ratelimit.NewMemoryStore(), nil)
+
+	svc := map[string]string{"Authorization": "Bearer svc-token"}
+	for i := 0; i < 5; i++ {
+		if resp := rateLimitRequest(t, app, "GET", "/api/v1/links", svc); resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("overridden token request %d status = %d, want 200", i, resp.StatusCode)
+		}
+	}
+
+	other := map[string]string{"Authorization": "Bearer other-token"}
+	rateLimitRequest(t, app, "GET", "/api/v1/links", other)
+	if resp := rateLimitRequest(t, app, "GET", "/api/v1/links", other); resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Errorf("other token second request status = %d, want 429", resp.StatusCode)
+	}
+}
+
+func TestRateLimiterIgnoresUnknownTokens(t *testing.T) {
+	sum := sha256.Sum256([]byte("svc-token"))
+	app := newRateLimitTestApp(&config.Config{
+		RateLimitAPI: 1,
+		RateLimitTokenOverrides: map[string]map[string]int{
+			hex.EncodeToString(sum[:]): {"*": 0},
+		},
+	}, ratelimit.NewMemoryStore(), nil)
+
+	// Made-up bearer values from one IP share the IP's bucket.
+	first := map[string]string{"Authorization": "Bearer random-1"}
+	if resp := rateLimitRequest(t, app, "GET", "/api/v1/links", first); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("first random token status = %d, want 200", resp.StatusCode)
+	}
+	second := map[string]string{"Authorization": "Bearer random-2"}
+	if resp := rateLimitRequest(t, app, "GET", "/api/v1/links", second); resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Errorf("second random token status = %d, want 429", resp.StatusCode)
+	}
+}
+
+func TestRateLimiterAnonymousSessionsShareIPBucket(t *testing.T) {
+	cfg := &config.Config{RateLimitRedirect: 1, RateLimitWindowSecs: 60}
+	app := fiber.New()
+	sessionMiddleware, _ := session.NewWithStore()
+	app.Use(sessionMiddleware)
+	app.Use(NewRateLimiter(cfg, ratelimit.NewMemoryStore(), nil).Handler)
+	// Like the CSRF token fetch, /browse hands out an anonymous session;
+	// /signed-in one that is signed in.
+	app.Get("/browse", func(c fiber.Ctx) error {
+		session.FromContext(c).Set("visited", true)
+		return c.SendString("ok")
+	})
+	app.Get("/signed-in", func(c fiber.Ctx) error {
+		session.FromContext(c).Set("user_sub", "jdoe")
+		return c.SendString("ok")
+	})
+	app.Get("/go/:keyword", func(c fiber.Ctx) error { return c.SendString("ok") })
+
+	cookieFrom := func(path string) *http.Cookie {
+		resp := rateLimitRequest(t, app, "GET", path, nil)
+		for _, cookie := range resp.Cookies() {
+			if cookie.Name == sessionCookieName() {
+				return cookie
+			}
+		}
+		t.Fatalf("GET %s set no session cookie", path)
+		return nil
+	}
+	redirect := func(cookie *http.Cookie) int {
+		req := httptest.NewRequest("GET", "/go/docs", nil)
+		req.AddCookie(cookie)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("GET /go/docs error = %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	// A client cycling cookie jars keeps spending the same IP budget.
+	if got := redirect(cookieFrom("/browse")); got != fiber.StatusOK {
+		t.Fatalf("first anonymous session status = %d, want 200", got)
+	}
+	if got := redirect(cookieFrom("/browse")); got != fiber.StatusTooManyRequests {
+		t.Errorf("second anonymous session status = %d, want 429", got)
+	}
+
+	// A signed-in session is counted against its user.
+	if got := redirect(cookieFrom("/signed-in")); got != fiber.StatusOK {
+		t.Errorf("signed-in session status = %d, want 200", got)
+	}
+}
+
+func TestRateLimiterSCIMToken(t *testing.T) {
+	app := newRateLimitTestApp(&config.Config{
+		RateLimitAPI:    1,
+		SCIMBearerToken: "scim-secret",
+	}, ratelimit.NewMemoryStore(), nil)
+
+	// The SCIM token gets its own bucket on SCIM routes only.
+	rateLimitRequest(t, app, "GET", "/api/v1/links", nil)
+	scim := map[string]string{"Authorization": "Bearer scim-secret"}
+	if resp := rateLimitRequest(t, app, "GET", "/scim/v2/Users", scim); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("SCIM request status = %d, want 200", resp.StatusCode)
+	}
+	if resp := rateLimitRequest(t, app, "GET", "/api/v1/links", scim); resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Errorf("SCIM token outside SCIM status = %d, want 429", resp.StatusCode)
+	}
+}
+
+func TestRateLimiterFailsOpen(t *testing.T) {
+	app := newRateLimitTestApp(&config.Config{RateLimitRedirect: 1}, failingStore{}, nil)
+
+	for i := 0; i < 3; i++ {
+		if resp := rateLimitRequest(t, app, "GET", "/go/docs", nil); resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("status = %d, want 200 when the store is down", resp.StatusCode)
+		}
+	}
+}
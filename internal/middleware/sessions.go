@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/session"
+
+	"golinks/internal/db"
+	"golinks/internal/models"
+)
+
+// Session lifetimes. A session ends after SessionIdleTimeout without requests
+// or SessionLifetime after sign-in; sessions holding a refresh token push
+// their end SessionLifetime forward on every successful refresh, up to
+// SESSION_MAX_LIFETIME_HOURS.
+const (
+	SessionIdleTimeout = 30 * time.Minute
+	SessionLifetime    = 24 * time.Hour
+)
+
+// SessionTrackedKey marks sessions that were recorded in the session registry
+// at sign-in. Sessions without it predate the registry and are recorded the
+// first time they are seen instead of being rejected.
+const SessionTrackedKey = "session_tracked"
+
+// maxUserAgentLength caps the user agent recorded for a session.
+const maxUserAgentLength = 512
+
+// sessionTouchInterval bounds how often a session's last-seen time is written.
+const sessionTouchInterval = time.Minute
+
+// ErrSessionRefreshRejected is returned by a SessionRefresher when the
+// identity provider refuses the refresh token or the user it names; the
+// session is revoked. Any other error is treated as a provider outage.
+var ErrSessionRefreshRejected = errors.New("session refresh rejected")
+
+// SessionRefresher re-validates a signed-in user with the identity provider.
+type SessionRefresher interface {
+	// RefreshSession redeems a sealed refresh token, re-syncs the user's
+	// profile, organization and role, and returns the updated user with the
+	// sealed refresh token to keep.
+	RefreshSession(ctx context.Context, user *models.User, refreshToken string) (*models.User, string, error)
+}
+
+// SessionLastSeenAfter returns the last-seen time before which a tracked
+// session has certainly been dropped by the session store for inactivity.
+func SessionLastSeenAfter(now time.Time) time.Time {
+	return now.Add(-SessionIdleTimeout - sessionTouchInterval)
+}
+
+// SetSessionRefresher enables sliding sessions: sessions with a refresh token
+// are re-validated with the provider every refreshInterval.
+func (m *AuthMiddleware) SetSessionRefresher(r SessionRefresher, refreshInterval, maxLifetime time.Duration) {
+	m.refresher = r
+	m.refreshInterval = refreshInterval
+	m.maxLifetime = maxLifetime
+}
+
+// sessionActive checks the signed-in session against the session registry.
+// Sessions of deactivated users and sessions that were revoked, have expired,
+// belong to another user or have dropped out of the registry are rejected;
+// sessions signed in before the registry existed are recorded on first sight.
+// Refreshable sessions are re-validated with the provider when due, which may
+// update the user. Active sessions get their last-seen time refreshed at most
+// once per sessionTouchInterval. An error means the registry couldn't be
+// checked, and the session is neither accepted nor rejected.
+func (m *AuthMiddleware) sessionActive(c fiber.Ctx, sess *session.Middleware, user *models.User) (*models.User, bool, error) {
+	if user.IsDeactivated() {
+		return nil, false, nil
+	}
+	ctx := c.Context()
+	tracked, err := m.db.GetUserSessionByHash(ctx, db.HashSessionID(sess.ID()))
+	if errors.Is(err, db.ErrUserSessionNotFound) {
+		if sess.Get(SessionTrackedKey) != nil {
+			return nil, false, nil
+		}
+		tracked, err = m.trackLegacySession(c, sess, user)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to look up session", "user_id", user.ID, "error", err)
+		return nil, false, err
+	}
+	if tracked.IsRevoked() || tracked.UserID != user.ID {
+		return nil, false, nil
+	}
+
+	now := time.Now()
+	if m.refreshDue(tracked, now) {
+		if user, err = m.refreshSession(ctx, tracked, user, now); err != nil {
+			return nil, false, nil
+		}
+	}
+	if tracked.IsExpired(now) {
+		return nil, false, nil
+	}
+
+	if now.Sub(tracked.LastSeenAt) >= sessionTouchInterval {
+		if err := m.db.TouchUserSession(ctx, tracked.ID, c.IP()); err != nil {
+			slog.WarnContext(ctx, "failed to update session last seen", "session_id", tracked.ID, "error", err)
+		}
+	}
+	return user, true, nil
+}
+
+// trackLegacySession records a session signed in before the session registry
+// existed, starting its lifetime now, so upgrading doesn't sign everyone out.
+func (m *AuthMiddleware) trackLegacySession(c fiber.Ctx, sess *session.Middleware, user *models.User) (*models.UserSession, error) {
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	tracked := &models.UserSession{
+		UserID:      user.ID,
+		SessionHash: db.HashSessionID(sess.ID()),
+		IPAddress:   c.IP(),
+		UserAgent:   userAgent,
+		ExpiresAt:   time.Now().Add(SessionLifetime),
+	}
+	if err := m.db.CreateUserSession(c.Context(), tracked); err != nil {
+		return nil, err
+	}
+	sess.Set(SessionTrackedKey, true)
+	slog.InfoContext(c.Context(), "recorded session signed in before session tracking", "user_id", user.ID, "session_id", tracked.ID)
+	return tracked, nil
+}
+
+// refreshDue reports whether a session should be re-validated now: it holds a
+// refresh token and was last refreshed (or signed in) a refresh interval ago,
+// or has reached its end.
+func (m *AuthMiddleware) refreshDue(tracked *models.UserSession, now time.Time) bool {
+	if m.refresher == nil || m.refreshInterval <= 0 || !tracked.CanRefresh() {
+		return false
+	}
+	last := tracked.CreatedAt
+	if tracked.RefreshedAt != nil {
+		last = *tracked.RefreshedAt
+	}
+	return now.Sub(last) >= m.refreshInterval || tracked.IsExpired(now)
+}
+
+// refreshSession redeems the session's refresh token and extends the session.
+// Only the request that claims the refresh talks to the provider; concurrent
+// requests carry on with the session as it is. A rejected refresh revokes the
+// session and returns an error; while the provider is unreachable the session
+// is kept until it expires.
+func (m *AuthMiddleware) refreshSession(ctx context.Context, tracked *models.UserSession, user *models.User, now time.Time) (*models.User, error) {
+	if m.oidcProbe != nil && !m.oidcProbe.IsHealthy() {
+		return user, nil
+	}
+	claimed, err := m.db.ClaimUserSessionRefresh(ctx, tracked.ID, tracked.RefreshedAt)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to claim session refresh", "session_id", tracked.ID, "error", err)
+		return user, nil
+	}
+	if !claimed {
+		return user, nil
+	}
+
+	refreshed, refreshToken, err := m.refresher.RefreshSession(ctx, user, tracked.RefreshToken)
+	if errors.Is(err, ErrSessionRefreshRejected) {
+		slog.InfoContext(ctx, "session refresh rejected, signing out", "user_id", user.ID, "session_id", tracked.ID, "error", err)
+		if err := m.db.RevokeUserSession(ctx, user.ID, tracked.ID); err != nil && !errors.Is(err, db.ErrUserSessionNotFound) {
+			slog.ErrorContext(ctx, "failed to revoke session", "session_id", tracked.ID, "error", err)
+		}
+		return nil, err
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "session refresh failed, keeping session", "user_id", user.ID, "session_id", tracked.ID, "error", err)
+		return user, nil
+	}
+
+	expiresAt := now.Add(SessionLifetime)
+	if limit := tracked.CreatedAt.Add(m.maxLifetime); m.maxLifetime > 0 && limit.Before(expiresAt) {
+		expiresAt = limit
+	}
+	if err := m.db.ExtendUserSession(ctx, tracked.ID, refreshToken, expiresAt); err != nil {
+		slog.ErrorContext(ctx, "failed to extend session", "session_id", tracked.ID, "error", err)
+		return refreshed, nil
+	}
+	tracked.ExpiresAt = expiresAt
+	return refreshed, nil
+}
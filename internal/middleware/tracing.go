@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"golinks/internal/tracing"
+)
+
+// TraceIDLocal is the Locals key holding the request's trace ID, used by the
+// request logger format as ${locals:trace_id}.
+const TraceIDLocal = "trace_id"
+
+// TracingMiddleware starts a server span for each request, continuing any
+// trace propagated by the caller. The span context is stored on the request
+// context so database, Redis and outbound calls made with c.Context() become
+// child spans. Spans are named by route pattern, never by raw path.
+func TracingMiddleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.Context(), headerCarrier{c})
+
+		ctx, span := tracing.Tracer().Start(ctx, c.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Method()),
+				attribute.String("url.path", c.Path()),
+			),
+		)
+		defer span.End()
+
+		c.SetContext(ctx)
+		if id := tracing.TraceID(ctx); id != "" {
+			c.Locals(TraceIDLocal, id)
+		}
+
+		err := c.Next()
+
+		route := routePattern(c)
+		status := responseStatus(c, err)
+		span.SetName(c.Method() + " " + route)
+		span.SetAttributes(
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", status),
+		)
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, strconv.Itoa(status))
+			if err != nil {
+				span.RecordError(err)
+			}
+		}
+		return err
+	}
+}
+
+// headerCarrier adapts request headers to propagation.TextMapCarrier.
+// Lookups go through c.Get, which is case-insensitive.
+type headerCarrier struct {
+	c fiber.Ctx
+}
+
+var _ propagation.TextMapCarrier = headerCarrier{}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(h.c.GetReqHeaders()))
+	for k := range h.c.GetReqHeaders() {
+		keys = append(keys, k)
+	}
+	return keys
+}
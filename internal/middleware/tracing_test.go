@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"golinks/internal/testutil"
+	"golinks/internal/tracing"
+)
+
+func TestTracingMiddlewareContinuesTrace(t *testing.T) {
+	exporter := testutil.InMemoryTracer(t)
+	prevPropagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prevPropagator) })
+
+	const parentTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	var handlerTraceID, localTraceID string
+	app := fiber.New()
+	app.Use(TracingMiddleware())
+	app.Get("/go/:keyword", func(c fiber.Ctx) error {
+		handlerTraceID = tracing.TraceID(c.Context())
+		localTraceID, _ = c.Locals(TraceIDLocal).(string)
+		return c.SendString("ok")
+	})
+
+	req := httptest.NewRequest("GET", "/go/docs", nil)
+	req.Header.Set("traceparent", "00-"+parentTraceID+"-00f067aa0ba902b7-01")
+	if _, err := app.Test(req); err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+
+	if handlerTraceID != parentTraceID {
+		t.Errorf("handler trace ID = %q, want %q", handlerTraceID, parentTraceID)
+	}
+	if localTraceID != parentTraceID {
+		t.Errorf("trace_id local = %q, want %q", localTraceID, parentTraceID)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /go/:keyword" {
+		t.Errorf("span name = %q, want %q", span.Name, "GET /go/:keyword")
+	}
+	if span.SpanKind != trace.SpanKindServer {
+		t.Errorf("span kind = %v, want server", span.SpanKind)
+	}
+	if span.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("parent span ID = %s, want 00f067aa0ba902b7", span.Parent.SpanID())
+	}
+}
+
+func TestTracingMiddlewareMarksServerErrors(t *testing.T) {
+	exporter := testutil.InMemoryTracer(t)
+
+	app := fiber.New()
+	app.Use(TracingMiddleware())
+	app.Get("/boom", func(c fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusBadGateway, "upstream down")
+	})
+	app.Get("/missing", func(c fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusNotFound, "not found")
+	})
+
+	for _, path := range []string{"/boom", "/missing", "/no-such-route"} {
+		if _, err := app.Test(httptest.NewRequest("GET", path, nil)); err != nil {
+			t.Fatalf("app.Test(%s) error = %v", path, err)
+		}
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want 3", len(spans))
+	}
+	want := []struct {
+		name  string
+		error bool
+	}{
+		{"GET /boom", true},
+		{"GET /missing", false},
+		{"GET " + unmatchedRoute, false},
+	}
+	for i, w := range want {
+		if spans[i].Name != w.name {
+			t.Errorf("span %d name = %q, want %q", i, spans[i].Name, w.name)
+		}
+		if isError := spans[i].Status.Code.String() == "Error"; isError != w.error {
+			t.Errorf("span %q error status = %v, want %v", spans[i].Name, isError, w.error)
+		}
+	}
+}
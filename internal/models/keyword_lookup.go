@@ -16,3 +16,11 @@ type KeywordLookup struct {
 	Count      int64
 	LastSeenAt time.Time
 }
+
+// WantedKeyword is a keyword users looked up that does not exist yet,
+// aggregated for the "most wanted" report.
+type WantedKeyword struct {
+	Keyword    string    `json:"keyword"`
+	Count      int64     `json:"count"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
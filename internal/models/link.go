@@ -1,6 +1,10 @@
 package models
 
 import (
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,6 +24,14 @@ const (
 	StatusDeletionRequested  = "deletion_requested"
 )
 
+// Link visibility constants
+const (
+	VisibilityPublic        = "public"        // everyone, including signed-out users
+	VisibilityAuthenticated = "authenticated" // any signed-in user
+	VisibilityOrg           = "org"           // direct members of the link's organization
+	VisibilityRestricted    = "restricted"    // the listed users and groups
+)
+
 // Health status constants
 const (
 	HealthUnknown   = "unknown"
@@ -47,6 +59,9 @@ type Link struct {
 	HealthStatus    string     `json:"health_status"`
 	HealthCheckedAt *time.Time `json:"health_checked_at"`
 	HealthError     *string    `json:"health_error"`
+	Visibility      string     `json:"visibility"`     // public, authenticated, org, restricted
+	AllowedGroups   []string   `json:"allowed_groups"` // Restricted links: groups (OIDC or SCIM) that can see it
+	AllowedUsers    []string   `json:"allowed_users"`  // Restricted links: email addresses of users who can see it
 
 	// Non-DB fields, populated via JOIN for management queries
 	AuthorName  string `json:"author_name,omitempty"`
@@ -68,6 +83,64 @@ func (l *Link) IsDeletionRequested() bool {
 	return l.Status == StatusDeletionRequested
 }
 
+// IsRestricted returns true if the link is hidden from some signed-in users.
+func (l *Link) IsRestricted() bool {
+	return l.Visibility == VisibilityOrg || l.Visibility == VisibilityRestricted
+}
+
+// NormalizeVisibility checks a link's visibility before it is saved: empty
+// means public, org visibility needs an org link, and restricted links need
+// at least one group or user. Emails are lowercased and the lists are cleared
+// unless the link is restricted. It returns an error describing the first
+// invalid value.
+func (l *Link) NormalizeVisibility() error {
+	switch l.Visibility {
+	case "":
+		l.Visibility = VisibilityPublic
+	case VisibilityPublic, VisibilityAuthenticated, VisibilityRestricted:
+	case VisibilityOrg:
+		if l.Scope != ScopeOrg {
+			return fmt.Errorf("only organization links can be limited to organization members")
+		}
+	default:
+		return fmt.Errorf("invalid visibility %q (want %s, %s, %s or %s)", l.Visibility,
+			VisibilityPublic, VisibilityAuthenticated, VisibilityOrg, VisibilityRestricted)
+	}
+
+	if l.Visibility != VisibilityRestricted {
+		l.AllowedGroups = []string{}
+		l.AllowedUsers = []string{}
+		return nil
+	}
+
+	groups := make([]string, 0, len(l.AllowedGroups))
+	for _, g := range l.AllowedGroups {
+		if g = strings.TrimSpace(g); g != "" && !slices.Contains(groups, g) {
+			groups = append(groups, g)
+		}
+	}
+	users := make([]string, 0, len(l.AllowedUsers))
+	for _, u := range l.AllowedUsers {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+		addr, err := mail.ParseAddress(u)
+		if err != nil {
+			return fmt.Errorf("invalid email address %q", u)
+		}
+		if e := strings.ToLower(addr.Address); !slices.Contains(users, e) {
+			users = append(users, e)
+		}
+	}
+	if len(groups) == 0 && len(users) == 0 {
+		return fmt.Errorf("restricted links need at least one group or user")
+	}
+	l.AllowedGroups = groups
+	l.AllowedUsers = users
+	return nil
+}
+
 // IsHealthy returns true if the link has a healthy status.
 func (l *Link) IsHealthy() bool {
 	return l.HealthStatus == HealthHealthy
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Link owner kinds.
+const (
+	OwnerKindUser  = "user"  // A single user
+	OwnerKindGroup = "group" // Members of an OIDC or SCIM group
+	OwnerKindOrg   = "org"   // Direct members of an organization
+)
+
+// Link owner event actions.
+const (
+	OwnerAdded   = "added"
+	OwnerRemoved = "removed"
+)
+
+// LinkOwner is a co-owner of a link. Owners edit the link without
+// moderation, approve edit suggestions for it and change its owners.
+// Exactly one of UserID, Group and OrganizationID is set, matching Kind.
+type LinkOwner struct {
+	ID             uuid.UUID  `json:"id"`
+	LinkID         uuid.UUID  `json:"link_id"`
+	Kind           string     `json:"kind"` // user, group, org
+	UserID         *uuid.UUID `json:"user_id,omitempty"`
+	Group          string     `json:"group,omitempty"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	CreatedBy      *uuid.UUID `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// Non-DB field: the user's email, the group name or the organization slug
+	Name string `json:"name"`
+}
+
+// Key identifies the owner independently of its link, for comparing owner
+// lists.
+func (o LinkOwner) Key() string {
+	switch o.Kind {
+	case OwnerKindUser:
+		if o.UserID != nil {
+			return OwnerKindUser + ":" + o.UserID.String()
+		}
+	case OwnerKindOrg:
+		if o.OrganizationID != nil {
+			return OwnerKindOrg + ":" + o.OrganizationID.String()
+		}
+	}
+	return o.Kind + ":" + o.Group
+}
+
+// LinkOwnerEvent records an owner being added to or removed from a link.
+type LinkOwnerEvent struct {
+	ID        uuid.UUID  `json:"id"`
+	LinkID    uuid.UUID  `json:"link_id"`
+	ActorID   *uuid.UUID `json:"actor_id"`
+	Action    string     `json:"action"`     // added, removed
+	OwnerKind string     `json:"owner_kind"` // user, group, org
+	OwnerName string     `json:"owner_name"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Non-DB field, populated via JOIN for display
+	ActorName string `json:"actor_name,omitempty"`
+}
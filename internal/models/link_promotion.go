@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LinkPromotion records a personal link proposed for org or global scope.
+// LinkID is the pending link submitted for review; the usage fields are a
+// snapshot of the personal link taken when it was proposed, shown to
+// moderators as evidence.
+type LinkPromotion struct {
+	LinkID            uuid.UUID  `json:"link_id"`
+	UserLinkID        *uuid.UUID `json:"user_link_id"` // nil once the personal link is deleted
+	UserID            uuid.UUID  `json:"user_id"`
+	ClickCount        int64      `json:"click_count"`
+	RecentClicks      int64      `json:"recent_clicks"` // last PromotionRecentDays days
+	LastUsedAt        *time.Time `json:"last_used_at"`
+	PersonalCreatedAt time.Time  `json:"personal_created_at"`
+	KeepPersonal      bool       `json:"keep_personal"`
+	AppliedAt         *time.Time `json:"applied_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// PromotionRecentDays is the window counted in LinkPromotion.RecentClicks.
+const PromotionRecentDays = 30
@@ -1,6 +1,9 @@
 package models
 
-import "testing"
+import (
+	"slices"
+	"testing"
+)
 
 func TestLink_IsPending(t *testing.T) {
 	tests := []struct {
@@ -64,3 +67,50 @@ func TestLinkConstants(t *testing.T) {
 		t.Errorf("StatusRejected = %q, want %q", StatusRejected, "rejected")
 	}
 }
+
+func TestLink_NormalizeVisibility(t *testing.T) {
+	tests := []struct {
+		name       string
+		link       Link
+		wantErr    bool
+		wantVis    string
+		wantGroups []string
+		wantUsers  []string
+	}{
+		{"empty is public", Link{Scope: ScopeGlobal}, false, VisibilityPublic, []string{}, []string{}},
+		{"authenticated", Link{Scope: ScopeGlobal, Visibility: VisibilityAuthenticated}, false, VisibilityAuthenticated, []string{}, []string{}},
+		{"org on org link", Link{Scope: ScopeOrg, Visibility: VisibilityOrg}, false, VisibilityOrg, []string{}, []string{}},
+		{"org on global link", Link{Scope: ScopeGlobal, Visibility: VisibilityOrg}, true, "", nil, nil},
+		{"unknown", Link{Scope: ScopeGlobal, Visibility: "secret"}, true, "", nil, nil},
+		{"lists cleared unless restricted", Link{Scope: ScopeGlobal, Visibility: VisibilityPublic, AllowedGroups: []string{"hr"}}, false, VisibilityPublic, []string{}, []string{}},
+		{
+			"restricted cleaned",
+			Link{Scope: ScopeGlobal, Visibility: VisibilityRestricted, AllowedGroups: []string{" hr ", "", "hr", "security"}, AllowedUsers: []string{"Ann <Ann@Example.com>", "ann@example.com"}},
+			false, VisibilityRestricted, []string{"hr", "security"}, []string{"ann@example.com"},
+		},
+		{"restricted without anyone", Link{Scope: ScopeGlobal, Visibility: VisibilityRestricted, AllowedGroups: []string{" "}}, true, "", nil, nil},
+		{"restricted bad email", Link{Scope: ScopeGlobal, Visibility: VisibilityRestricted, AllowedUsers: []string{"not-an-email"}}, true, "", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link := tt.link
+			err := link.NormalizeVisibility()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeVisibility() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if link.Visibility != tt.wantVis {
+				t.Errorf("Visibility = %q, want %q", link.Visibility, tt.wantVis)
+			}
+			if !slices.Equal(link.AllowedGroups, tt.wantGroups) {
+				t.Errorf("AllowedGroups = %v, want %v", link.AllowedGroups, tt.wantGroups)
+			}
+			if !slices.Equal(link.AllowedUsers, tt.wantUsers) {
+				t.Errorf("AllowedUsers = %v, want %v", link.AllowedUsers, tt.wantUsers)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ const (
 	NotifTypeLinkApproved   = "link_approved"
 	NotifTypeLinkRejected   = "link_rejected"
 	NotifTypeEditSuggested  = "edit_suggested"
+	NotifTypeLinkRetired    = "link_retired"
 )
 
 // Notification represents an in-app notification for a user.
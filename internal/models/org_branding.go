@@ -0,0 +1,50 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Length limits for organization branding text.
+const (
+	MaxBrandingTitleLen  = 100
+	MaxBrandingBannerLen = 500
+)
+
+// OrgBranding overrides the site-wide title, logo, accent color and banner
+// for an organization's members. Empty fields keep the value the member
+// would see otherwise.
+type OrgBranding struct {
+	OrganizationID  uuid.UUID  `json:"organization_id"`
+	SiteTitle       string     `json:"site_title"`
+	LogoURL         string     `json:"logo_url"`
+	AccentColor     string     `json:"accent_color"`
+	BannerText      string     `json:"banner_text"`
+	BannerTextColor string     `json:"banner_text_color"`
+	BannerBGColor   string     `json:"banner_bg_color"`
+	UpdatedBy       *uuid.UUID `json:"updated_by,omitempty"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Normalize trims edited branding and checks the length of its text before
+// it is saved. It returns an error describing the first invalid value; the
+// logo and colors are checked by validation.ValidateOrgBranding.
+func (b *OrgBranding) Normalize() error {
+	b.SiteTitle = strings.TrimSpace(b.SiteTitle)
+	b.BannerText = strings.TrimSpace(b.BannerText)
+	b.LogoURL = strings.TrimSpace(b.LogoURL)
+	b.AccentColor = strings.TrimSpace(b.AccentColor)
+	b.BannerTextColor = strings.TrimSpace(b.BannerTextColor)
+	b.BannerBGColor = strings.TrimSpace(b.BannerBGColor)
+	if utf8.RuneCountInString(b.SiteTitle) > MaxBrandingTitleLen {
+		return fmt.Errorf("title must be at most %d characters", MaxBrandingTitleLen)
+	}
+	if utf8.RuneCountInString(b.BannerText) > MaxBrandingBannerLen {
+		return fmt.Errorf("banner text must be at most %d characters", MaxBrandingBannerLen)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOrgBranding_Normalize(t *testing.T) {
+	b := &OrgBranding{
+		SiteTitle:     "  Acme Links ",
+		LogoURL:       " /static/acme.svg",
+		AccentColor:   " #ff6600 ",
+		BannerBGColor: "navy",
+	}
+	if err := b.Normalize(); err != nil {
+		t.Fatalf("Normalize() error = %v", err)
+	}
+	if b.SiteTitle != "Acme Links" || b.LogoURL != "/static/acme.svg" || b.AccentColor != "#ff6600" {
+		t.Errorf("Normalize() = %+v, want trimmed values", b)
+	}
+
+	tests := []struct {
+		name     string
+		branding OrgBranding
+	}{
+		{"long title", OrgBranding{SiteTitle: strings.Repeat("a", MaxBrandingTitleLen+1)}},
+		{"long banner", OrgBranding{BannerText: strings.Repeat("a", MaxBrandingBannerLen+1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.branding.Normalize(); err == nil {
+				t.Errorf("Normalize() succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrgSettings holds an organization's rules for its org links, editable by
+// its moderators and admins.
+type OrgSettings struct {
+	OrganizationID    uuid.UUID  `json:"organization_id"`
+	RequireModeration bool       `json:"require_moderation"` // members' submissions wait for a moderator (false = approved on creation)
+	AllowedDomains    []string   `json:"allowed_domains"`    // destination hosts allowed, subdomains included (empty = any)
+	KeywordPrefix     string     `json:"keyword_prefix"`     // prefix every keyword must start with, e.g. "eng-"
+	KeywordPattern    string     `json:"keyword_pattern"`    // regular expression every keyword must match in full
+	NotifyModerators  bool       `json:"notify_moderators"`  // email the organization's moderators about submissions
+	NotifyEmails      []string   `json:"notify_emails"`      // additional addresses emailed about submissions, e.g. a team list
+	UpdatedBy         *uuid.UUID `json:"updated_by,omitempty"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// DefaultOrgSettings returns the settings of an organization that has not
+// changed any: submissions need approval, any domain and keyword are allowed
+// and moderators are emailed.
+func DefaultOrgSettings(orgID uuid.UUID) *OrgSettings {
+	return &OrgSettings{
+		OrganizationID:    orgID,
+		RequireModeration: true,
+		AllowedDomains:    []string{},
+		NotifyModerators:  true,
+		NotifyEmails:      []string{},
+	}
+}
+
+// Normalize cleans up edited settings before they are saved: domains are
+// lowercased without wildcards or duplicates, the keyword prefix is
+// lowercased like keywords are, and the pattern and email addresses are
+// checked. It returns an error describing the first invalid value.
+func (s *OrgSettings) Normalize() error {
+	domains := make([]string, 0, len(s.AllowedDomains))
+	for _, d := range s.AllowedDomains {
+		d = strings.ToLower(strings.TrimSpace(d))
+		d = strings.TrimPrefix(strings.TrimPrefix(d, "*"), ".")
+		if d == "" {
+			continue
+		}
+		if strings.ContainsAny(d, "/:@ ") {
+			return fmt.Errorf("invalid domain %q: list host names only, e.g. example.com", d)
+		}
+		if !slices.Contains(domains, d) {
+			domains = append(domains, d)
+		}
+	}
+	s.AllowedDomains = domains
+
+	s.KeywordPrefix = strings.ToLower(strings.TrimSpace(s.KeywordPrefix))
+	s.KeywordPattern = strings.TrimSpace(s.KeywordPattern)
+	if s.KeywordPattern != "" {
+		if _, err := regexp.Compile(s.KeywordPattern); err != nil {
+			return fmt.Errorf("invalid keyword pattern: %v", err)
+		}
+	}
+
+	emails := make([]string, 0, len(s.NotifyEmails))
+	for _, e := range s.NotifyEmails {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		addr, err := mail.ParseAddress(e)
+		if err != nil {
+			return fmt.Errorf("invalid email address %q", e)
+		}
+		if !slices.Contains(emails, addr.Address) {
+			emails = append(emails, addr.Address)
+		}
+	}
+	s.NotifyEmails = emails
+	return nil
+}
+
+// AllowsHost reports whether a link may point at host: any host when no
+// domains are listed, otherwise a listed domain or one of its subdomains.
+func (s *OrgSettings) AllowsHost(host string) bool {
+	if len(s.AllowedDomains) == 0 {
+		return true
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, d := range s.AllowedDomains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateLink checks an org link's keyword and URL against the
+// organization's rules. The keyword is expected to be normalized and the URL
+// already checked by validation.ValidateURL.
+func (s *OrgSettings) ValidateLink(keyword, rawURL string) (bool, string) {
+	if s.KeywordPrefix != "" && !strings.HasPrefix(keyword, s.KeywordPrefix) {
+		return false, fmt.Sprintf("Keywords in this organization must start with %q", s.KeywordPrefix)
+	}
+	if s.KeywordPattern != "" {
+		re, err := regexp.Compile(`^(?:` + s.KeywordPattern + `)$`)
+		if err != nil || !re.MatchString(keyword) {
+			return false, fmt.Sprintf("Keywords in this organization must match %s", s.KeywordPattern)
+		}
+	}
+	if len(s.AllowedDomains) > 0 {
+		u, err := url.Parse(rawURL)
+		if err != nil || !s.AllowsHost(u.Hostname()) {
+			return false, "This organization only allows links to " + strings.Join(s.AllowedDomains, ", ")
+		}
+	}
+	return true, ""
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOrgSettings_Normalize(t *testing.T) {
+	s := &OrgSettings{
+		AllowedDomains: []string{" Example.com ", "*.corp.example.net", ".example.com", ""},
+		KeywordPrefix:  " ENG- ",
+		NotifyEmails:   []string{"Team <team@example.com>", "team@example.com", " "},
+	}
+	if err := s.Normalize(); err != nil {
+		t.Fatalf("Normalize() error = %v", err)
+	}
+	if want := []string{"example.com", "corp.example.net"}; !slices.Equal(s.AllowedDomains, want) {
+		t.Errorf("AllowedDomains = %v, want %v", s.AllowedDomains, want)
+	}
+	if s.KeywordPrefix != "eng-" {
+		t.Errorf("KeywordPrefix = %q, want %q", s.KeywordPrefix, "eng-")
+	}
+	if want := []string{"team@example.com"}; !slices.Equal(s.NotifyEmails, want) {
+		t.Errorf("NotifyEmails = %v, want %v", s.NotifyEmails, want)
+	}
+
+	invalid := []*OrgSettings{
+		{AllowedDomains: []string{"https://example.com/"}},
+		{KeywordPattern: "[a-z"},
+		{NotifyEmails: []string{"not an address"}},
+	}
+	for _, s := range invalid {
+		if err := s.Normalize(); err == nil {
+			t.Errorf("Normalize(%+v) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestOrgSettings_ValidateLink(t *testing.T) {
+	s := &OrgSettings{
+		AllowedDomains: []string{"example.com"},
+		KeywordPrefix:  "eng-",
+		KeywordPattern: `[a-z-]+`,
+	}
+
+	tests := []struct {
+		name    string
+		keyword string
+		url     string
+		want    bool
+	}{
+		{"allowed", "eng-wiki", "https://wiki.example.com/eng", true},
+		{"apex domain", "eng-home", "https://example.com/", true},
+		{"with port", "eng-dev", "http://dev.example.com:8080/", true},
+		{"missing prefix", "wiki", "https://example.com/", false},
+		{"pattern mismatch", "eng-wiki2", "https://example.com/", false},
+		{"other domain", "eng-wiki", "https://example.org/", false},
+		{"lookalike domain", "eng-wiki", "https://notexample.com/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, msg := s.ValidateLink(tt.keyword, tt.url); got != tt.want {
+				t.Errorf("ValidateLink(%q, %q) = %v (%s), want %v", tt.keyword, tt.url, got, msg, tt.want)
+			}
+		})
+	}
+
+	if ok, _ := DefaultOrgSettings(s.OrganizationID).ValidateLink("anything", "https://example.org/"); !ok {
+		t.Error("default settings rejected a link, want any link allowed")
+	}
+}
@@ -8,9 +8,36 @@ import (
 
 // Organization represents a group/team that can have its own links.
 type Organization struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Slug      string    `json:"slug"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	Slug      string     `json:"slug"`
+	ParentID  *uuid.UUID `json:"parent_id"` // Enclosing organization whose links are inherited (nil = top level)
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Organization membership roles.
+const (
+	OrgRoleMember = "member"
+	OrgRoleMod    = "org_mod"
+)
+
+// OrgMembership is a user's membership in an organization.
+type OrgMembership struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Name           string    `json:"name"`
+	Slug           string    `json:"slug"`
+	Role           string    `json:"role"`   // member or org_mod
+	Active         bool      `json:"active"` // the user's active organization
+	JoinedAt       time.Time `json:"joined_at"`
+}
+
+// OrgMember is a user's membership as seen from the organization.
+type OrgMember struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`   // member or org_mod
+	Active   bool      `json:"active"` // the organization is the user's active one
+	JoinedAt time.Time `json:"joined_at"`
 }
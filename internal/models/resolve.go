@@ -9,3 +9,78 @@ type ResolvedLink struct {
 	URL    string    `json:"url"`
 	Source string    `json:"source"` // "personal", "org", "global"
 }
+
+// Reasons a ResolveCandidate lost resolution.
+const (
+	ResolveLostPending  = "pending"   // awaiting moderation
+	ResolveLostRejected = "rejected"  // rejected by a moderator
+	ResolveLostWrongOrg = "wrong_org" // org link of an organization the user doesn't belong to
+	ResolveLostHidden   = "hidden"    // visibility excludes the user
+	ResolveLostShadowed = "shadowed"  // a link earlier in the resolution order won
+)
+
+// ResolveCandidate is one link defined for a keyword, as weighed when
+// resolving it for a user.
+type ResolveCandidate struct {
+	ID             uuid.UUID  `json:"id"`
+	Source         string     `json:"source"` // "personal", "org", "global"
+	URL            string     `json:"url"`
+	Status         string     `json:"status"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	OrgName        string     `json:"org_name,omitempty"`
+	Priority       int        `json:"priority"` // 1 personal, 2 org, 3 global
+	Depth          int        `json:"depth"`    // levels up the org tree an inherited org link comes from
+	Winner         bool       `json:"winner"`
+	LostReason     string     `json:"lost_reason,omitempty"`
+
+	// Non-DB field: whether the explained user created or submitted the link
+	Own bool `json:"-"`
+}
+
+// ResolveExplanation lays out how a keyword resolves for a user: every
+// candidate in resolution order, and the fallback redirect a browser would be
+// sent to when none of them wins.
+type ResolveExplanation struct {
+	Keyword        string             `json:"keyword"`
+	UserID         uuid.UUID          `json:"user_id"`
+	Email          string             `json:"email"`
+	OrganizationID *uuid.UUID         `json:"organization_id,omitempty"` // the user's active organization
+	OrgOrder       string             `json:"org_order"`
+	Candidates     []ResolveCandidate `json:"candidates"`
+	Fallback       *FallbackRedirect  `json:"fallback,omitempty"`
+	FallbackURL    string             `json:"fallback_url,omitempty"` // set when the fallback would apply
+}
+
+// RestrictTo drops the candidates viewer may not learn exist: links hidden
+// from them or in organizations they aren't in, and pending or rejected links
+// they didn't submit. Admins keep every candidate. Only admins explain
+// resolution for other users, so for anyone else the explained user is viewer.
+func (e *ResolveExplanation) RestrictTo(viewer *User) {
+	if viewer.IsAdmin() {
+		return
+	}
+	kept := e.Candidates[:0]
+	for _, c := range e.Candidates {
+		switch c.LostReason {
+		case ResolveLostHidden, ResolveLostWrongOrg:
+			continue
+		case ResolveLostPending, ResolveLostRejected:
+			if !c.Own {
+				continue
+			}
+		}
+		kept = append(kept, c)
+	}
+	e.Candidates = kept
+}
+
+// Winner returns the winning candidate, or nil if the keyword doesn't
+// resolve for the user.
+func (e *ResolveExplanation) Winner() *ResolveCandidate {
+	for i := range e.Candidates {
+		if e.Candidates[i].Winner {
+			return &e.Candidates[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestResolveExplanation_RestrictTo(t *testing.T) {
+	explain := func() *ResolveExplanation {
+		return &ResolveExplanation{Candidates: []ResolveCandidate{
+			{ID: uuid.New(), Source: "org", Winner: true},
+			{ID: uuid.New(), Source: "org", LostReason: ResolveLostHidden},
+			{ID: uuid.New(), Source: "org", LostReason: ResolveLostWrongOrg},
+			{ID: uuid.New(), Source: "global", LostReason: ResolveLostPending},
+			{ID: uuid.New(), Source: "global", LostReason: ResolveLostRejected, Own: true},
+			{ID: uuid.New(), Source: "global", LostReason: ResolveLostShadowed},
+		}}
+	}
+
+	tests := []struct {
+		name   string
+		viewer *User
+		want   []int // indexes of the candidates kept
+	}{
+		{"admin", &User{Role: RoleAdmin}, []int{0, 1, 2, 3, 4, 5}},
+		{"global mod", &User{Role: RoleGlobalMod}, []int{0, 4, 5}},
+		{"user", &User{Role: RoleUser}, []int{0, 4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := explain()
+			all := append([]ResolveCandidate(nil), e.Candidates...)
+			e.RestrictTo(tt.viewer)
+			if len(e.Candidates) != len(tt.want) {
+				t.Fatalf("RestrictTo() kept %d candidates, want %d", len(e.Candidates), len(tt.want))
+			}
+			for i, idx := range tt.want {
+				if e.Candidates[i].ID != all[idx].ID {
+					t.Errorf("candidate %d = %s, want candidate %d", i, e.Candidates[i].LostReason, idx)
+				}
+			}
+		})
+	}
+}
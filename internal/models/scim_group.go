@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SCIMGroup is a group pushed by a SCIM provisioning client. Its members are
+// mapped onto organizations and roles by name.
+type SCIMGroup struct {
+	ID          uuid.UUID         `json:"id"`
+	DisplayName string            `json:"display_name"`
+	ExternalID  string            `json:"external_id"`
+	Members     []SCIMGroupMember `json:"members"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// SCIMGroupMember is a user in a SCIM group.
+type SCIMGroupMember struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Display string    `json:"display"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShadowSide is one of the two links in a KeywordShadow.
+type ShadowSide struct {
+	ID             uuid.UUID  `json:"id"`
+	Scope          string     `json:"scope"` // personal, org, global
+	URL            string     `json:"url"`
+	HealthStatus   string     `json:"health_status"`
+	LastUsedAt     *time.Time `json:"last_used_at"` // latest click, nil if never clicked or history was pruned
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	OrgName        string     `json:"org_name,omitempty"`
+}
+
+// KeywordShadow is a keyword defined at two scopes, where Winner resolves
+// ahead of Shadowed (personal > org > global), so Shadowed is never reached.
+type KeywordShadow struct {
+	Keyword  string     `json:"keyword"`
+	Winner   ShadowSide `json:"winner"`
+	Shadowed ShadowSide `json:"shadowed"`
+}
+
+// SameURL reports whether both links point to the same place, making the
+// higher-priority one redundant rather than conflicting.
+func (s KeywordShadow) SameURL() bool {
+	return s.Winner.URL == s.Shadowed.URL
+}
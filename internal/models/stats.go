@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DailyClicks is the click total for a single UTC day.
+type DailyClicks struct {
+	Day    time.Time
+	Clicks int64
+}
+
+// ClickPoint is one bucket of a click time series.
+type ClickPoint struct {
+	Start  time.Time `json:"start"`
+	Clicks int64     `json:"clicks"`
+}
+
+// LinkStats contains click analytics for a single link over a selected range.
+type LinkStats struct {
+	LinkID        uuid.UUID    `json:"link_id"`
+	Keyword       string       `json:"keyword"`
+	Range         string       `json:"range"`       // 7d, 30d, 90d, 1y
+	Granularity   string       `json:"granularity"` // daily, weekly
+	From          time.Time    `json:"from"`
+	To            time.Time    `json:"to"`
+	Total         int64        `json:"total"`
+	PreviousTotal int64        `json:"previous_total"`
+	TrendPercent  *float64     `json:"trend_percent"` // nil when the previous period had no clicks
+	Series        []ClickPoint `json:"series"`
+}
+
+// TopLinkStat is a single row of a top-N click leaderboard.
+type TopLinkStat struct {
+	LinkID         uuid.UUID  `json:"link_id"`
+	Keyword        string     `json:"keyword"`
+	URL            string     `json:"url"`
+	Scope          string     `json:"scope"`
+	OrganizationID *uuid.UUID `json:"organization_id"`
+	Clicks         int64      `json:"clicks"`
+	PreviousClicks int64      `json:"previous_clicks"`
+	TrendPercent   *float64   `json:"trend_percent"`
+}
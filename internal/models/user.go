@@ -1,6 +1,7 @@
 package models
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +18,7 @@ const (
 // User represents a user authenticated via OIDC.
 type User struct {
 	ID             uuid.UUID  `json:"id"`
+	Issuer         string     `json:"issuer"`          // OIDC issuer the user signs in with; users are unique per (issuer, sub)
 	Sub            string     `json:"sub"`             // OIDC subject identifier
 	Username       string     `json:"username"`        // Extracted from PKI CN e.g. "heatht" from "Heath Taylor (heatht)"
 	Email          string     `json:"email"`
@@ -27,7 +29,14 @@ type User struct {
 	FallbackRedirectID *uuid.UUID `json:"fallback_redirect_id"` // User's chosen fallback redirect (nil = no fallback)
 	CreatedAt          time.Time  `json:"created_at"`
 	UpdatedAt          time.Time  `json:"updated_at"`
-	LastLoginAt        *time.Time `json:"last_login_at"` // Last successful OIDC sign-in; nil for users who have never logged in
+	LastLoginAt        *time.Time `json:"last_login_at"` // Last successful sign-in (hourly for PKI and proxy auth); nil for users who have never logged in
+	DeactivatedAt      *time.Time `json:"deactivated_at"` // Set when the account is deactivated; deactivated users can't sign in
+	SubOrganizationIDs []uuid.UUID `json:"-"`             // Org moderators: organizations nested under the active one, which they moderate too
+}
+
+// IsDeactivated returns true if the user's account has been deactivated.
+func (u *User) IsDeactivated() bool {
+	return u.DeactivatedAt != nil
 }
 
 // IsAdmin returns true if the user is an admin.
@@ -45,13 +54,27 @@ func (u *User) IsOrgMod() bool {
 	return u.Role == RoleOrgMod || u.Role == RoleGlobalMod || u.Role == RoleAdmin
 }
 
-// CanModerateOrg returns true if the user can moderate links for a specific org.
+// ModerationScope returns the organizations a user moderates: nil (all of
+// them) for global moderators and their own organization for org moderators.
+// ok is false for users who cannot moderate.
+func (u *User) ModerationScope() (orgID *uuid.UUID, ok bool) {
+	if u.IsGlobalMod() {
+		return nil, true
+	}
+	if u.Role == RoleOrgMod && u.OrganizationID != nil {
+		return u.OrganizationID, true
+	}
+	return nil, false
+}
+
+// CanModerateOrg returns true if the user can moderate links for a specific
+// org: org moderators moderate their organization and those nested under it.
 func (u *User) CanModerateOrg(orgID uuid.UUID) bool {
 	if u.IsGlobalMod() {
 		return true
 	}
-	if u.Role == RoleOrgMod && u.OrganizationID != nil && *u.OrganizationID == orgID {
-		return true
+	if u.Role == RoleOrgMod && u.OrganizationID != nil {
+		return *u.OrganizationID == orgID || slices.Contains(u.SubOrganizationIDs, orgID)
 	}
 	return false
 }
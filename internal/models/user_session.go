@@ -0,0 +1,96 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserSession is a signed-in browser session tracked for listing and remote
+// revocation. The session ID itself is never stored, only its hash.
+type UserSession struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	SessionHash string     `json:"-"`
+	IPAddress   string     `json:"ip_address"`
+	UserAgent   string     `json:"user_agent"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastSeenAt  time.Time  `json:"last_seen_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RefreshedAt *time.Time `json:"refreshed_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+
+	// OIDCSessionID is the provider's sid claim, matched by back-channel
+	// logout. RefreshToken is the sealed OIDC refresh token; empty when the
+	// session can't be extended.
+	OIDCSessionID string `json:"-"`
+	RefreshToken  string `json:"-"`
+
+	// Current marks the session making the request (set by handlers).
+	Current bool `json:"current"`
+}
+
+// IsRevoked reports whether the session was signed out remotely or by logout.
+func (s *UserSession) IsRevoked() bool {
+	return s.RevokedAt != nil
+}
+
+// IsExpired reports whether the session has run past its end.
+func (s *UserSession) IsExpired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// CanRefresh reports whether the session holds a refresh token.
+func (s *UserSession) CanRefresh() bool {
+	return s.RefreshToken != ""
+}
+
+// Device summarizes the user agent as "Browser on OS", e.g. "Firefox on
+// Linux". Unknown parts are left out; an unrecognized agent gives "Unknown
+// device".
+func (s *UserSession) Device() string {
+	ua := s.UserAgent
+	browser := ""
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/") || strings.Contains(ua, "CriOS/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	case strings.HasPrefix(ua, "curl/"):
+		browser = "curl"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPad"):
+		os = "iOS"
+	case strings.Contains(ua, "Android"):
+		os = "Android"
+	case strings.Contains(ua, "Windows"):
+		os = "Windows"
+	case strings.Contains(ua, "Mac OS X") || strings.Contains(ua, "Macintosh"):
+		os = "macOS"
+	case strings.Contains(ua, "CrOS"):
+		os = "ChromeOS"
+	case strings.Contains(ua, "Linux"):
+		os = "Linux"
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	default:
+		return "Unknown device"
+	}
+}
@@ -104,3 +104,52 @@ func TestUser_CanModerateOrg(t *testing.T) {
 		})
 	}
 }
+
+func TestUser_CanModerateOrg_SubOrganizations(t *testing.T) {
+	division := uuid.New()
+	team := uuid.New()
+
+	mod := &User{Role: RoleOrgMod, OrganizationID: &division, SubOrganizationIDs: []uuid.UUID{team}}
+	if !mod.CanModerateOrg(team) {
+		t.Error("org mod cannot moderate an organization nested under theirs")
+	}
+	if mod.CanModerateOrg(uuid.New()) {
+		t.Error("org mod can moderate an unrelated organization")
+	}
+
+	user := &User{Role: RoleUser, OrganizationID: &division, SubOrganizationIDs: []uuid.UUID{team}}
+	if user.CanModerateOrg(team) {
+		t.Error("regular user can moderate a nested organization")
+	}
+}
+
+func TestUser_ModerationScope(t *testing.T) {
+	orgID := uuid.New()
+
+	tests := []struct {
+		name      string
+		role      string
+		userOrgID *uuid.UUID
+		wantOrg   *uuid.UUID
+		wantOK    bool
+	}{
+		{"admin moderates everything", RoleAdmin, &orgID, nil, true},
+		{"global mod moderates everything", RoleGlobalMod, nil, nil, true},
+		{"org mod moderates own org", RoleOrgMod, &orgID, &orgID, true},
+		{"org mod with no org cannot moderate", RoleOrgMod, nil, nil, false},
+		{"regular user cannot moderate", RoleUser, &orgID, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &User{Role: tt.role, OrganizationID: tt.userOrgID}
+			gotOrg, gotOK := user.ModerationScope()
+			if gotOK != tt.wantOK {
+				t.Fatalf("ModerationScope() ok = %v, want %v", gotOK, tt.wantOK)
+			}
+			if (gotOrg == nil) != (tt.wantOrg == nil) || (gotOrg != nil && *gotOrg != *tt.wantOrg) {
+				t.Errorf("ModerationScope() org = %v, want %v", gotOrg, tt.wantOrg)
+			}
+		})
+	}
+}
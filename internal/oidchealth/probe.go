@@ -10,6 +10,8 @@ import (
 	"strings"
 	"sync/atomic"
 	"time"
+
+	"golinks/internal/metrics"
 )
 
 const (
@@ -34,6 +36,7 @@ func New(issuer string) *Probe {
 		client: &http.Client{Timeout: probeTimeout},
 	}
 	p.healthy.Store(true)
+	metrics.OIDCProviderUp.Set(1)
 	return p
 }
 
@@ -61,6 +64,7 @@ func (p *Probe) Start(ctx context.Context) {
 func (p *Probe) tick(ctx context.Context) {
 	reqCtx, cancel := context.WithTimeout(ctx, probeTimeout)
 	defer cancel()
+	defer metrics.OIDCProbeLastRun.SetToCurrentTime()
 
 	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, p.url, nil)
 	if err != nil {
@@ -82,6 +86,7 @@ func (p *Probe) tick(ctx context.Context) {
 
 func (p *Probe) markUnhealthy(reason string) {
 	wasHealthy := p.healthy.Swap(false)
+	metrics.OIDCProviderUp.Set(0)
 	firstProbe := !p.probedAt.Swap(true)
 	if wasHealthy || firstProbe {
 		slog.Warn("oidc issuer unreachable", "url", p.url, "reason", reason)
@@ -90,6 +95,7 @@ func (p *Probe) markUnhealthy(reason string) {
 
 func (p *Probe) markHealthy() {
 	wasUnhealthy := !p.healthy.Swap(true)
+	metrics.OIDCProviderUp.Set(1)
 	firstProbe := !p.probedAt.Swap(true)
 	switch {
 	case firstProbe:
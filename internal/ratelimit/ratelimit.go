@@ -0,0 +1,108 @@
+// Package ratelimit provides fixed-window hit counters shared by all
+// replicas (Redis) or local to one process (memory).
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store counts hits per key in fixed windows.
+type Store interface {
+	// Hit records one hit for key and returns the number of hits in the
+	// current window, including this one, and the time until it resets.
+	Hit(ctx context.Context, key string, window time.Duration) (int, time.Duration, error)
+}
+
+// keyPrefix namespaces limiter keys in a Redis instance shared with sessions.
+const keyPrefix = "golinks:ratelimit:"
+
+// hitScript increments the counter and starts the window on the first hit in
+// one round trip, so concurrent replicas never lose or double-start a window.
+// A key left without a TTL (e.g. by a failed earlier call) is given one.
+var hitScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {n, ttl}
+`)
+
+// RedisStore counts hits in Redis so limits hold across replicas.
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore creates a store on an existing Redis connection.
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Hit implements Store.
+func (s *RedisStore) Hit(ctx context.Context, key string, window time.Duration) (int, time.Duration, error) {
+	res, err := hitScript.Run(ctx, s.client, []string{keyPrefix + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(res[0]), time.Duration(res[1]) * time.Millisecond, nil
+}
+
+// MemoryStore counts hits in process memory. Limits apply per replica.
+type MemoryStore struct {
+	mu        sync.Mutex
+	windows   map[string]*memoryWindow
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryWindow struct {
+	hits    int
+	resetAt time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		windows: make(map[string]*memoryWindow),
+		now:     time.Now,
+	}
+}
+
+// Hit implements Store.
+func (s *MemoryStore) Hit(_ context.Context, key string, window time.Duration) (int, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now, window)
+
+	w, ok := s.windows[key]
+	if !ok || !now.Before(w.resetAt) {
+		w = &memoryWindow{resetAt: now.Add(window)}
+		s.windows[key] = w
+	}
+	w.hits++
+	return w.hits, w.resetAt.Sub(now), nil
+}
+
+// sweep drops expired windows at most once per window length, so the map
+// doesn't grow with every client ever seen.
+func (s *MemoryStore) sweep(now time.Time, window time.Duration) {
+	if now.Sub(s.lastSweep) < window {
+		return
+	}
+	s.lastSweep = now
+	for key, w := range s.windows {
+		if !now.Before(w.resetAt) {
+			delete(s.windows, key)
+		}
+	}
+}
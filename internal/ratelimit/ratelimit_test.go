@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMemoryStoreCountsWithinWindow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	for want := 1; want <= 3; want++ {
+		hits, resetIn, err := s.Hit(context.Background(), "k", time.Minute)
+		if err != nil {
+			t.Fatalf("Hit() error = %v", err)
+		}
+		if hits != want {
+			t.Errorf("hit %d: count = %d", want, hits)
+		}
+		if resetIn != time.Minute {
+			t.Errorf("hit %d: resetIn = %v, want 1m", want, resetIn)
+		}
+	}
+
+	// Keys are counted independently.
+	if hits, _, _ := s.Hit(context.Background(), "other", time.Minute); hits != 1 {
+		t.Errorf("other key count = %d, want 1", hits)
+	}
+}
+
+func TestMemoryStoreResetsAfterWindow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	s.Hit(context.Background(), "k", time.Minute)
+	now = now.Add(40 * time.Second)
+	if _, resetIn, _ := s.Hit(context.Background(), "k", time.Minute); resetIn != 20*time.Second {
+		t.Errorf("resetIn = %v, want 20s", resetIn)
+	}
+
+	now = now.Add(20 * time.Second)
+	if hits, _, _ := s.Hit(context.Background(), "k", time.Minute); hits != 1 {
+		t.Errorf("count after window = %d, want 1", hits)
+	}
+}
+
+func TestMemoryStoreSweepsExpiredWindows(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	s.Hit(context.Background(), "a", time.Minute)
+	s.Hit(context.Background(), "b", time.Minute)
+	now = now.Add(2 * time.Minute)
+	s.Hit(context.Background(), "c", time.Minute)
+
+	if len(s.windows) != 1 {
+		t.Errorf("windows = %d, want 1 after sweep", len(s.windows))
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL not set")
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		t.Fatalf("ParseURL() error = %v", err)
+	}
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "test:" + time.Now().Format(time.RFC3339Nano)
+	defer client.Del(ctx, keyPrefix+key)
+
+	s := NewRedisStore(client)
+	for want := 1; want <= 3; want++ {
+		hits, resetIn, err := s.Hit(ctx, key, time.Minute)
+		if err != nil {
+			t.Fatalf("Hit() error = %v", err)
+		}
+		if hits != want {
+			t.Errorf("hit %d: count = %d", want, hits)
+		}
+		if resetIn <= 0 || resetIn > time.Minute {
+			t.Errorf("hit %d: resetIn = %v, want (0, 1m]", want, resetIn)
+		}
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"time"
 
 	"golinks/internal/db"
 	"golinks/internal/email"
 	"golinks/internal/handlers"
 	"golinks/internal/handlers/api"
+	"golinks/internal/handlers/scim"
 	"golinks/internal/metrics"
 	"golinks/internal/middleware"
 	"golinks/internal/oidchealth"
@@ -16,9 +18,13 @@ import (
 
 // RegisterRoutes registers all application routes.
 func (s *Server) RegisterRoutes(ctx context.Context, database *db.DB, oidcProbe *oidchealth.Probe) error {
-	// Initialize Prometheus metrics collector
+	// Register Prometheus metrics
 	metrics.Init(database)
 
+	// Rate limiting per client and route policy; runs before unfurl so
+	// link-preview bots are limited too.
+	s.App.Use(middleware.NewRateLimiter(s.Cfg, s.rateLimitStore, database).Handler)
+
 	// Unfurl middleware - intercepts link-preview bots before auth runs.
 	// Must be registered before any RequireAuth route so bots never reach OIDC.
 	s.App.Use(middleware.UnfurlMiddleware(database, s.Cfg))
@@ -29,6 +35,7 @@ func (s *Server) RegisterRoutes(ctx context.Context, database *db.DB, oidcProbe
 	// Initialize email notifier
 	notifier := email.NewNotifier(s.Cfg, database)
 	handlers.SetNotifier(notifier)
+	handlers.SetBrandingDB(database)
 
 	// Initialize handlers
 	linkHandler := handlers.NewLinkHandler(database, s.Cfg)
@@ -38,6 +45,9 @@ func (s *Server) RegisterRoutes(ctx context.Context, database *db.DB, oidcProbe
 	moderationHandler := handlers.NewModerationHandler(database, s.Cfg, notifier)
 	manageHandler := handlers.NewManageHandler(database, s.Cfg)
 	healthHandler := handlers.NewHealthHandler(database)
+	statsHandler := handlers.NewStatsHandler(database, s.Cfg)
+	wantedHandler := handlers.NewWantedHandler(database, s.Cfg)
+	shadowHandler := handlers.NewShadowHandler(database, s.Cfg)
 	userHandler := handlers.NewUserHandler(database, s.Cfg)
 
 	// Kubernetes probe endpoints (no auth required)
@@ -45,9 +55,11 @@ func (s *Server) RegisterRoutes(ctx context.Context, database *db.DB, oidcProbe
 	s.App.Get("/healthz", probeHandler.Liveness)
 	s.App.Get("/readyz", probeHandler.Readiness)
 
-	// Auth routes - OIDC is always required for frontend access
-	if s.Cfg.OIDCIssuer == "" {
-		slog.Error("OIDC_ISSUER is required, all users must be authenticated")
+	// Auth routes - OIDC or a trusted authenticating proxy is always required
+	// for frontend access
+	primary := s.Cfg.PrimaryOIDCProvider()
+	if primary == nil && !s.Cfg.ProxyAuthEnabled() {
+		slog.Error("OIDC_ISSUER, OIDC_PROVIDERS or PROXY_AUTH_USER_HEADER with PROXY_AUTH_TRUSTED_CIDRS is required, all users must be authenticated")
 		os.Exit(1)
 	}
 
@@ -56,24 +68,49 @@ func (s *Server) RegisterRoutes(ctx context.Context, database *db.DB, oidcProbe
 		return err
 	}
 
+	// Users created before users were keyed by issuer belong to the primary
+	// provider.
+	if primary != nil {
+		if assigned, err := database.AssignLegacyUserIssuer(ctx, primary.Issuer); err != nil {
+			return err
+		} else if assigned > 0 {
+			slog.Info("assigned existing users to the primary OIDC provider", "issuer", primary.Issuer, "users", assigned)
+		}
+	}
+
+	s.App.Get("/login", authHandler.LoginPage)
 	s.App.Get("/auth/login", authHandler.Login)
 	s.App.Get("/auth/callback", authHandler.Callback)
 	s.App.Get("/auth/logout", authHandler.Logout)
 	s.App.Get("/auth/unavailable", authHandler.Unavailable)
+	s.App.Post(middleware.BackChannelLogoutPath, authHandler.BackChannelLogout)
+
+	// Sliding sessions: re-validate signed-in users with the provider.
+	authMiddleware.SetSessionRefresher(authHandler,
+		time.Duration(s.Cfg.OIDCRefreshMins)*time.Minute,
+		time.Duration(s.Cfg.SessionMaxLifetimeHours)*time.Hour)
 
 	// Frontend routes - always require authentication
 	s.App.Get("/", authMiddleware.RequireAuth, linkHandler.Index)
 	s.App.Get("/search", authMiddleware.RequireAuth, linkHandler.Search)
 	s.App.Get("/suggest", authMiddleware.RequireAuth, linkHandler.Suggest)
 	s.App.Get("/browse", authMiddleware.RequireAuth, linkHandler.Browse)
+	s.App.Get("/browse/wanted", authMiddleware.RequireAuth, wantedHandler.List)
+	s.App.Post("/browse/wanted/:keyword/dismiss", authMiddleware.RequireAuth, wantedHandler.Dismiss)
+	s.App.Get("/browse/shadows", authMiddleware.RequireAuth, shadowHandler.List)
 	s.App.Get("/new", authMiddleware.RequireAuth, linkHandler.New)
 	s.App.Get("/links/check", authMiddleware.RequireAuth, linkHandler.CheckKeyword)
 	s.App.Post("/links", authMiddleware.RequireAuth, linkHandler.Create)
 	s.App.Get("/links/:id/suggest-edit", authMiddleware.RequireAuth, linkHandler.SuggestEdit)
 	s.App.Post("/links/:id/suggest-edit", authMiddleware.RequireAuth, linkHandler.SubmitSuggestEdit)
 	s.App.Delete("/links/:id", authMiddleware.RequireAuth, linkHandler.Delete)
+	s.App.Get("/links/:id/stats", authMiddleware.RequireAuth, statsHandler.LinkStats)
 	s.App.Get("/profile", authMiddleware.RequireAuth, profileHandler.Show)
 	s.App.Patch("/profile/fallback", authMiddleware.RequireAuth, profileHandler.UpdateFallbackPreference)
+	s.App.Get("/profile/orgs", authMiddleware.RequireAuth, profileHandler.OrgSwitcher)
+	s.App.Post("/profile/active-org", authMiddleware.RequireAuth, profileHandler.SwitchOrg)
+	s.App.Post("/profile/sessions/revoke-all", authMiddleware.RequireAuth, profileHandler.RevokeAllSessions)
+	s.App.Delete("/profile/sessions/:id", authMiddleware.RequireAuth, profileHandler.RevokeSession)
 
 	// Notification bell routes
 	notifHandler := handlers.NewNotificationHandler(database)
@@ -103,6 +140,8 @@ func (s *Server) RegisterRoutes(ctx context.Context, database *db.DB, oidcProbe
 		s.App.Get("/my-links/:id/edit", authMiddleware.RequireAuth, userLinkHandler.Edit)
 		s.App.Put("/my-links/:id", authMiddleware.RequireAuth, userLinkHandler.Update)
 		s.App.Delete("/my-links/:id", authMiddleware.RequireAuth, userLinkHandler.Delete)
+		s.App.Get("/my-links/:id/promote", authMiddleware.RequireAuth, userLinkHandler.PromoteForm)
+		s.App.Post("/my-links/:id/promote", authMiddleware.RequireAuth, userLinkHandler.Promote)
 	}
 
 	// Moderation routes (moderators only — role checks in handlers)
@@ -120,13 +159,40 @@ func (s *Server) RegisterRoutes(ctx context.Context, database *db.DB, oidcProbe
 	s.App.Put("/manage/:id", authMiddleware.RequireAuth, manageHandler.Update)
 	s.App.Post("/manage/:id/edit-request", authMiddleware.RequireAuth, manageHandler.RequestEdit)
 	s.App.Post("/manage/:id/request-deletion", authMiddleware.RequireAuth, manageHandler.RequestDeletion)
+	s.App.Put("/manage/:id/owners", authMiddleware.RequireAuth, manageHandler.UpdateOwners)
+	s.App.Post("/manage/:id/retire", authMiddleware.RequireAuth, manageHandler.Retire)
 	s.App.Post("/health/:id", authMiddleware.RequireAuth, healthHandler.CheckLink)
 
+	// Click analytics (visibility checks in handlers)
+	s.App.Get("/stats", authMiddleware.RequireAuth, statsHandler.Top)
+	s.App.Get("/stats/top.csv", authMiddleware.RequireAuth, statsHandler.TopCSV)
+
 	// Admin routes (admin only)
 	s.App.Get("/admin/users", authMiddleware.RequireAuth, userHandler.ListUsers)
 	s.App.Post("/admin/users/:id/role", authMiddleware.RequireAuth, userHandler.UpdateUserRole)
 	s.App.Post("/admin/users/:id/org", authMiddleware.RequireAuth, userHandler.UpdateUserOrg)
 	s.App.Delete("/admin/users/:id", authMiddleware.RequireAuth, userHandler.DeleteUser)
+	s.App.Post("/admin/users/:id/deactivate", authMiddleware.RequireAuth, userHandler.DeactivateUser)
+	s.App.Post("/admin/users/:id/reactivate", authMiddleware.RequireAuth, userHandler.ReactivateUser)
+	s.App.Get("/admin/users/:id/offboard", authMiddleware.RequireAuth, userHandler.OffboardPage)
+	s.App.Post("/admin/users/:id/offboard", authMiddleware.RequireAuth, userHandler.Offboard)
+	s.App.Get("/admin/users/:id/sessions", authMiddleware.RequireAuth, userHandler.ListSessions)
+	s.App.Post("/admin/users/:id/sessions/revoke-all", authMiddleware.RequireAuth, userHandler.RevokeAllSessions)
+	s.App.Delete("/admin/users/:id/sessions/:sessionId", authMiddleware.RequireAuth, userHandler.RevokeSession)
+
+	// Admin organization management
+	orgHandler := handlers.NewOrgHandler(database, s.Cfg)
+	s.App.Get("/admin/orgs", authMiddleware.RequireAuth, orgHandler.List)
+	s.App.Get("/admin/orgs/:id", authMiddleware.RequireAuth, orgHandler.Show)
+	s.App.Post("/admin/orgs/:id", authMiddleware.RequireAuth, orgHandler.Update)
+	s.App.Delete("/admin/orgs/:id", authMiddleware.RequireAuth, orgHandler.Delete)
+	s.App.Post("/admin/orgs/:id/merge", authMiddleware.RequireAuth, orgHandler.Merge)
+	s.App.Post("/admin/orgs/:id/members", authMiddleware.RequireAuth, orgHandler.AddMember)
+	s.App.Post("/admin/orgs/:id/members/:userId/role", authMiddleware.RequireAuth, orgHandler.UpdateMemberRole)
+	s.App.Delete("/admin/orgs/:id/members/:userId", authMiddleware.RequireAuth, orgHandler.RemoveMember)
+	s.App.Get("/orgs/:id/settings", authMiddleware.RequireAuth, orgHandler.Settings)
+	s.App.Post("/orgs/:id/settings", authMiddleware.RequireAuth, orgHandler.SaveSettings)
+	s.App.Post("/orgs/:id/branding", authMiddleware.RequireAuth, orgHandler.SaveBranding)
 
 	// Admin fallback redirect management
 	fallbackHandler := handlers.NewFallbackRedirectHandler(database, s.Cfg)
@@ -135,6 +201,10 @@ func (s *Server) RegisterRoutes(ctx context.Context, database *db.DB, oidcProbe
 	s.App.Put("/admin/fallback-redirects/:id", authMiddleware.RequireAuth, fallbackHandler.Update)
 	s.App.Delete("/admin/fallback-redirects/:id", authMiddleware.RequireAuth, fallbackHandler.Delete)
 
+	// Admin resolution explainer
+	resolveExplainHandler := handlers.NewResolveExplainHandler(database, s.Cfg)
+	s.App.Get("/admin/resolve", authMiddleware.RequireAuth, resolveExplainHandler.Show)
+
 	// Random link route ("I'm Feeling Lucky") — only registered when the feature is enabled
 	if s.Cfg.EnableRandomKeywords {
 		s.App.Get("/random", authMiddleware.RequireAuth, redirectHandler.Random)
@@ -152,16 +222,24 @@ func (s *Server) RegisterRoutes(ctx context.Context, database *db.DB, oidcProbe
 	apiLinkHandler := api.NewLinkHandler(database, s.Cfg, notifier)
 	apiResolveHandler := api.NewResolveHandler(database, s.Cfg)
 	apiUserHandler := api.NewUserHandler(database, s.Cfg)
+	apiOrgHandler := api.NewOrgHandler(database, s.Cfg)
 	apiModerationHandler := api.NewModerationHandler(database, s.Cfg, notifier)
 	apiHealthHandler := api.NewHealthHandler(database)
+	apiStatsHandler := api.NewStatsHandler(database, s.Cfg)
+	apiWantedHandler := api.NewWantedHandler(database, s.Cfg)
+	apiShadowHandler := api.NewShadowHandler(database, s.Cfg)
 
 	// Link management API
 	s.App.Get("/api/v1/links", authMiddleware.RequireAuth, apiLinkHandler.List)
 	s.App.Post("/api/v1/links", authMiddleware.RequireAuth, apiLinkHandler.Create)
 	s.App.Get("/api/v1/links/check/:keyword", authMiddleware.RequireAuth, apiLinkHandler.CheckKeyword)
 	s.App.Get("/api/v1/links/:id", authMiddleware.RequireAuth, apiLinkHandler.Get)
+	s.App.Get("/api/v1/links/:id/stats", authMiddleware.RequireAuth, apiStatsHandler.LinkStats)
 	s.App.Put("/api/v1/links/:id", authMiddleware.RequireAuth, apiLinkHandler.Update)
 	s.App.Delete("/api/v1/links/:id", authMiddleware.RequireAuth, apiLinkHandler.Delete)
+	s.App.Get("/api/v1/links/:id/owners", authMiddleware.RequireAuth, apiLinkHandler.Owners)
+	s.App.Put("/api/v1/links/:id/owners", authMiddleware.RequireAuth, apiLinkHandler.UpdateOwners)
+	s.App.Post("/api/v1/links/:id/retire", authMiddleware.RequireAuth, apiLinkHandler.Retire)
 
 	// Keyword resolution API - auth depends on mode
 	if s.Cfg.IsSimpleMode() {
@@ -169,20 +247,60 @@ func (s *Server) RegisterRoutes(ctx context.Context, database *db.DB, oidcProbe
 	} else {
 		s.App.Get("/api/v1/resolve/:keyword", authMiddleware.RequireAuth, apiResolveHandler.Resolve)
 	}
+	s.App.Get("/api/v1/resolve/:keyword/explain", authMiddleware.RequireAuth, apiResolveHandler.Explain)
 
 	// User management API (admin checks enforced in handlers)
 	s.App.Get("/api/v1/users", authMiddleware.RequireAuth, apiUserHandler.List)
 	s.App.Put("/api/v1/users/:id/role", authMiddleware.RequireAuth, apiUserHandler.UpdateRole)
 	s.App.Put("/api/v1/users/:id/org", authMiddleware.RequireAuth, apiUserHandler.UpdateOrg)
+	s.App.Get("/api/v1/users/:id/orgs", authMiddleware.RequireAuth, apiUserHandler.ListOrgs)
+	s.App.Put("/api/v1/users/:id/orgs/:orgId", authMiddleware.RequireAuth, apiUserHandler.AddOrg)
+	s.App.Delete("/api/v1/users/:id/orgs/:orgId", authMiddleware.RequireAuth, apiUserHandler.RemoveOrg)
 	s.App.Delete("/api/v1/users/:id", authMiddleware.RequireAuth, apiUserHandler.Delete)
+	s.App.Post("/api/v1/users/:id/deactivate", authMiddleware.RequireAuth, apiUserHandler.Deactivate)
+	s.App.Post("/api/v1/users/:id/reactivate", authMiddleware.RequireAuth, apiUserHandler.Reactivate)
+	s.App.Post("/api/v1/users/:id/offboard", authMiddleware.RequireAuth, apiUserHandler.Offboard)
+	s.App.Get("/api/v1/users/:id/sessions", authMiddleware.RequireAuth, apiUserHandler.ListSessions)
+	s.App.Delete("/api/v1/users/:id/sessions", authMiddleware.RequireAuth, apiUserHandler.RevokeAllSessions)
+	s.App.Delete("/api/v1/users/:id/sessions/:sessionId", authMiddleware.RequireAuth, apiUserHandler.RevokeSession)
+
+	// Organization management API (admin checks enforced in handlers)
+	s.App.Get("/api/v1/orgs", authMiddleware.RequireAuth, apiOrgHandler.List)
+	s.App.Get("/api/v1/orgs/:id", authMiddleware.RequireAuth, apiOrgHandler.Get)
+	s.App.Put("/api/v1/orgs/:id", authMiddleware.RequireAuth, apiOrgHandler.Update)
+	s.App.Delete("/api/v1/orgs/:id", authMiddleware.RequireAuth, apiOrgHandler.Delete)
+	s.App.Post("/api/v1/orgs/:id/merge", authMiddleware.RequireAuth, apiOrgHandler.Merge)
+	s.App.Put("/api/v1/orgs/:id/parent", authMiddleware.RequireAuth, apiOrgHandler.SetParent)
+	s.App.Get("/api/v1/orgs/:id/members", authMiddleware.RequireAuth, apiOrgHandler.ListMembers)
+	s.App.Put("/api/v1/orgs/:id/members/:userId", authMiddleware.RequireAuth, apiOrgHandler.SetMember)
+	s.App.Delete("/api/v1/orgs/:id/members/:userId", authMiddleware.RequireAuth, apiOrgHandler.RemoveMember)
+	s.App.Get("/api/v1/orgs/:id/settings", authMiddleware.RequireAuth, apiOrgHandler.GetSettings)
+	s.App.Put("/api/v1/orgs/:id/settings", authMiddleware.RequireAuth, apiOrgHandler.UpdateSettings)
+	s.App.Get("/api/v1/orgs/:id/branding", authMiddleware.RequireAuth, apiOrgHandler.GetBranding)
+	s.App.Put("/api/v1/orgs/:id/branding", authMiddleware.RequireAuth, apiOrgHandler.UpdateBranding)
 
 	// Moderation API (moderator checks enforced in handlers)
 	s.App.Get("/api/v1/moderation/pending", authMiddleware.RequireAuth, apiModerationHandler.ListPending)
 	s.App.Post("/api/v1/moderation/:id/approve", authMiddleware.RequireAuth, apiModerationHandler.Approve)
 	s.App.Post("/api/v1/moderation/:id/reject", authMiddleware.RequireAuth, apiModerationHandler.Reject)
 
+	// Click analytics API (visibility checks enforced in handlers)
+	s.App.Get("/api/v1/stats/top", authMiddleware.RequireAuth, apiStatsHandler.Top)
+
+	// Most wanted missing keywords API (moderator checks for dismissal in handler)
+	s.App.Get("/api/v1/wanted", authMiddleware.RequireAuth, apiWantedHandler.List)
+	s.App.Post("/api/v1/wanted/:keyword/dismiss", authMiddleware.RequireAuth, apiWantedHandler.Dismiss)
+
+	// Scope shadowing report API (moderator checks for scope=orgs in handler)
+	s.App.Get("/api/v1/shadows", authMiddleware.RequireAuth, apiShadowHandler.List)
+
 	// Health check API (moderator checks enforced in handler)
 	s.App.Post("/api/v1/health/:id", authMiddleware.RequireAuth, apiHealthHandler.CheckLink)
 
+	// --- SCIM 2.0 provisioning (bearer token, no user session) ---
+	if s.Cfg.SCIMEnabled() {
+		scim.NewHandler(database, s.Cfg).Register(s.App.Group("/scim/v2"))
+	}
+
 	return nil
 }
@@ -27,13 +27,21 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"golinks/internal/config"
+	"golinks/internal/handlers"
+	"golinks/internal/middleware"
 	"golinks/internal/models"
+	"golinks/internal/ratelimit"
+	"golinks/internal/tracing"
 )
 
 // Server wraps the Fiber app and configuration.
 type Server struct {
 	App *fiber.App
 	Cfg *config.Config
+
+	// rateLimitStore holds rate limit counters: Redis (shared by all
+	// replicas) when SESSION_STORE=redis, otherwise process memory.
+	rateLimitStore ratelimit.Store
 }
 
 // New creates a new server with middleware configured.
@@ -68,6 +76,18 @@ func New(cfg *config.Config) *Server {
 			return t.Format("Jan 2")
 		}
 	})
+	engine.AddFunc("add", func(a, b int) int {
+		return a + b
+	})
+	engine.AddFunc("trendPercent", func(p *float64) string {
+		if p == nil {
+			return "—"
+		}
+		return fmt.Sprintf("%+.1f%%", *p)
+	})
+	engine.AddFunc("trendDown", func(p *float64) bool {
+		return p != nil && *p < 0
+	})
 
 	// Initialize Fiber
 	app := fiber.New(fiber.Config{
@@ -82,7 +102,7 @@ func New(cfg *config.Config) *Server {
 				message = e.Message
 			}
 
-			slog.Error("request error",
+			slog.ErrorContext(c.Context(), "request error",
 				"status", code,
 				"method", c.Method(),
 				"path", c.Path(),
@@ -112,6 +132,7 @@ func New(cfg *config.Config) *Server {
 				"SiteFooter":               template.HTML(cfg.SiteFooter), // nolint:gosec
 				"SiteLogoURL":              cfg.SiteLogoURL,
 				"EnableAnimatedBackground": cfg.EnableAnimatedBackground,
+				"CSPNonce":                 middleware.CSPNonce(c),
 			})
 			if renderErr != nil {
 				slog.Error("failed to render error template",
@@ -129,6 +150,13 @@ func New(cfg *config.Config) *Server {
 		EnableStackTrace: cfg.IsDev(),
 	}))
 
+	// Tracing span per request; must precede the logger and metrics so both
+	// see the trace ID and every later handler runs inside the span.
+	app.Use(middleware.TracingMiddleware())
+
+	// Request duration histogram per route pattern
+	app.Use(middleware.MetricsMiddleware())
+
 	// Security headers
 	app.Use(func(c fiber.Ctx) error {
 		c.Set("X-Content-Type-Options", "nosniff")
@@ -141,11 +169,15 @@ func New(cfg *config.Config) *Server {
 		}
 		return c.Next()
 	})
+
+	// Content-Security-Policy with a per-request nonce for inline scripts/styles
+	app.Use(middleware.CSPMiddleware(cfg))
+
 	app.Use(logger.New(logger.Config{
 		// Write to stderr so container log collectors capture Fiber request logs
 		// alongside slog output (which also writes to stderr).
 		Stream:     os.Stderr,
-		Format:     "${time} | ${status} | ${latency} | ${ip} | ${method} | ${path} | ${error}\n",
+		Format:     "${time} | ${status} | ${latency} | ${ip} | ${method} | ${path} | ${error} | ${locals:" + middleware.TraceIDLocal + "}\n",
 		TimeFormat: "2006-01-02 15:04:05",
 	}))
 
@@ -159,6 +191,16 @@ func New(cfg *config.Config) *Server {
 	// Prometheus metrics endpoint - no auth, before session middleware
 	app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
 
+	// CSP violation reports - sent by browsers without a CSRF token, so
+	// registered before session/CSRF middleware and limited per IP instead.
+	app.Post(middleware.CSPReportPath, limiter.New(limiter.Config{
+		Max:        30,
+		Expiration: 1 * time.Minute,
+		LimitReached: func(c fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusTooManyRequests)
+		},
+	}), handlers.CSPReport)
+
 	slog.Debug("static file middleware registered", "root", "./static")
 
 	// --- Middleware applied only to dynamic routes (registered after static) ---
@@ -187,53 +229,32 @@ func New(cfg *config.Config) *Server {
 		CookieSecure:    cfg.TLSEnabled || !cfg.IsDev(),
 		CookieHTTPOnly:  true,
 		CookieSameSite:  "Lax",
-		AbsoluteTimeout: 24 * time.Hour,
+		IdleTimeout:     middleware.SessionIdleTimeout,
+		AbsoluteTimeout: time.Duration(cfg.SessionMaxLifetimeHours) * time.Hour,
 	}
+	var rateLimitStore ratelimit.Store = ratelimit.NewMemoryStore()
 	if cfg.SessionStore == "redis" {
-		sessionCfg.Storage = redisstore.New(redisstore.Config{
+		store := redisstore.New(redisstore.Config{
 			URL: cfg.EffectiveRedisURL(),
 		})
+		store.Conn().AddHook(tracing.RedisHook())
+		sessionCfg.Storage = store
+		rateLimitStore = ratelimit.NewRedisStore(store.Conn())
 		slog.Info("session store: redis", "url", cfg.RedisURL)
 	} else {
 		slog.Info("session store: memory")
 	}
-	sessionMiddleware, _ := session.NewWithStore(sessionCfg)
+	sessionMiddleware, sessionStore := session.NewWithStore(sessionCfg)
 	app.Use(sessionMiddleware)
 
-	// rateLimitKey returns a per-user or per-session key for rate limiting.
-	// Authenticated users are keyed by their OIDC subject; unauthenticated
-	// sessions by their session ID; fallback to IP for sessionless requests.
-	rateLimitKey := func(c fiber.Ctx) string {
-		sess := session.FromContext(c)
-		if sess != nil {
-			if userSub, ok := sess.Get("user_sub").(string); ok && userSub != "" {
-				return "user:" + userSub
-			}
-			if id := sess.ID(); id != "" {
-				return "sess:" + id
-			}
-		}
-		return "ip:" + c.IP()
-	}
-
-	// Rate limiting middleware - 100 requests per minute per user/session
-	app.Use(limiter.New(limiter.Config{
-		Max:          100,
-		Expiration:   1 * time.Minute,
-		KeyGenerator: rateLimitKey,
-		LimitReached: func(c fiber.Ctx) error {
-			slog.Warn("rate limit exceeded", "key", rateLimitKey(c), "path", c.Path())
-			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
-				"error": "Rate limit exceeded. Please try again later.",
-			})
-		},
-		SkipFailedRequests:     false,
-		SkipSuccessfulRequests: false,
-	}))
+	// CSRF protection for cookie-authenticated unsafe requests; the token is
+	// exposed to templates, which hand it to htmx as a request header.
+	app.Use(middleware.CSRFMiddleware(cfg, sessionStore), middleware.CSRFTokenToViews)
 
 	return &Server{
-		App: app,
-		Cfg: cfg,
+		App:            app,
+		Cfg:            cfg,
+		rateLimitStore: rateLimitStore,
 	}
 }
 
@@ -48,6 +48,7 @@ func cleanupTestData(ctx context.Context, pool *pgxpool.Pool) {
 	// Delete in order to respect foreign keys
 	pool.Exec(ctx, "DELETE FROM user_links")
 	pool.Exec(ctx, "DELETE FROM links")
+	pool.Exec(ctx, "DELETE FROM scim_groups")
 	pool.Exec(ctx, "DELETE FROM users")
 	pool.Exec(ctx, "DELETE FROM organizations")
 }
@@ -78,7 +79,7 @@ func CreateTestUser(t *testing.T, database *db.DB, sub, email, role string) stri
 	err := database.Pool.QueryRow(ctx, `
 		INSERT INTO users (sub, email, name, role)
 		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (sub) DO UPDATE SET email = EXCLUDED.email
+		ON CONFLICT (issuer, sub) DO UPDATE SET email = EXCLUDED.email
 		RETURNING id
 	`, sub, email, fmt.Sprintf("Test User %s", sub), role).Scan(&id)
 	if err != nil {
@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// logHandler decorates records logged with a span-carrying context
+// (slog.InfoContext and friends) with trace_id and span_id attributes.
+type logHandler struct {
+	slog.Handler
+}
+
+// NewLogHandler wraps h so records carry the trace and span IDs of the
+// active span, letting log lines be joined with traces.
+func NewLogHandler(h slog.Handler) slog.Handler {
+	return logHandler{Handler: h}
+}
+
+// Handle adds trace attributes when ctx holds a valid span.
+func (h logHandler) Handle(ctx context.Context, r slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs keeps the wrapper when attributes are bound to the logger.
+func (h logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return logHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup keeps the wrapper when a group is bound to the logger.
+func (h logHandler) WithGroup(name string) slog.Handler {
+	return logHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PgxTracer creates spans for pgx queries, batches and pool acquires.
+// Statements are recorded with their placeholders; argument values are not.
+// Set it as pgxpool.Config.ConnConfig.Tracer.
+type PgxTracer struct{}
+
+var (
+	_ pgx.QueryTracer       = PgxTracer{}
+	_ pgx.BatchTracer       = PgxTracer{}
+	_ pgxpool.AcquireTracer = PgxTracer{}
+)
+
+// TraceQueryStart starts a span named after the SQL operation.
+func (PgxTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	op := sqlOperation(data.SQL)
+	ctx, _ = Tracer().Start(ctx, "db "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", op),
+			attribute.String("db.statement", strings.TrimSpace(data.SQL)),
+		),
+	)
+	return ctx
+}
+
+// TraceQueryEnd ends the query span, recording rows affected and errors.
+func (PgxTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	endWithError(span, data.Err)
+}
+
+// TraceBatchStart starts a span covering a whole batch.
+func (PgxTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	size := 0
+	if data.Batch != nil {
+		size = data.Batch.Len()
+	}
+	ctx, _ = Tracer().Start(ctx, "db batch",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.Int("db.batch.size", size),
+		),
+	)
+	return ctx
+}
+
+// TraceBatchQuery records failed statements within a batch as span events.
+func (PgxTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	if data.Err != nil {
+		trace.SpanFromContext(ctx).RecordError(data.Err,
+			trace.WithAttributes(attribute.String("db.statement", strings.TrimSpace(data.SQL))),
+		)
+	}
+}
+
+// TraceBatchEnd ends the batch span.
+func (PgxTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	endWithError(trace.SpanFromContext(ctx), data.Err)
+}
+
+// TraceAcquireStart starts a span measuring the wait for a pool connection.
+func (PgxTracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	ctx, _ = Tracer().Start(ctx, "db acquire", trace.WithSpanKind(trace.SpanKindClient))
+	return ctx
+}
+
+// TraceAcquireEnd ends the acquire span.
+func (PgxTracer) TraceAcquireEnd(ctx context.Context, _ *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	endWithError(trace.SpanFromContext(ctx), data.Err)
+}
+
+// sqlOperation returns the leading SQL keyword (SELECT, INSERT, WITH, ...).
+func sqlOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+	return strings.ToUpper(fields[0])
+}
+
+func endWithError(span trace.Span, err error) {
+	if err != nil && err != pgx.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
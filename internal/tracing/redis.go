@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// redisHook creates a client span for every Redis command and pipeline.
+// Only command names are recorded, never keys or values, since keys embed
+// user subjects and session IDs.
+type redisHook struct{}
+
+// RedisHook returns a go-redis hook that traces commands. Attach it with
+// client.AddHook(tracing.RedisHook()).
+func RedisHook() redis.Hook {
+	return redisHook{}
+}
+
+func (redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, span := Tracer().Start(ctx, "redis.dial", trace.WithSpanKind(trace.SpanKindClient))
+		defer span.End()
+		conn, err := next(ctx, network, addr)
+		recordRedisError(span, err)
+		return conn, err
+	}
+}
+
+func (redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := Tracer().Start(ctx, "redis "+cmd.FullName(),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("db.operation", cmd.FullName()),
+			),
+		)
+		defer span.End()
+		err := next(ctx, cmd)
+		recordRedisError(span, err)
+		return err
+	}
+}
+
+func (redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := Tracer().Start(ctx, "redis pipeline",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.Int("db.redis.num_cmd", len(cmds)),
+			),
+		)
+		defer span.End()
+		err := next(ctx, cmds)
+		recordRedisError(span, err)
+		return err
+	}
+}
+
+// recordRedisError marks the span failed, except for redis.Nil which only
+// signals a missing key.
+func recordRedisError(span trace.Span, err error) {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,85 @@
+// Package tracing configures OpenTelemetry trace export and provides the
+// shared instrumentation used across HTTP, database, Redis, SMTP and
+// background jobs.
+//
+// Export is configured entirely through the standard OTEL_* environment
+// variables (OTEL_EXPORTER_OTLP_ENDPOINT, OTEL_SERVICE_NAME,
+// OTEL_TRACES_SAMPLER, ...). When no OTLP endpoint is set the global no-op
+// provider stays in place and instrumentation costs next to nothing.
+package tracing
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope used for all golinks spans.
+const ScopeName = "golinks"
+
+// Tracer returns the golinks tracer from the current global provider.
+// It is looked up on each call so a provider installed after package
+// initialisation (or swapped in tests) is always honoured.
+func Tracer() trace.Tracer {
+	return otel.Tracer(ScopeName)
+}
+
+// Init installs an OTLP/HTTP trace exporter as the global tracer provider when
+// enabled, and always installs the W3C trace-context and baggage propagators.
+// The returned shutdown function flushes pending spans and must be called
+// before the process exits.
+func Init(ctx context.Context, enabled bool, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the default name.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := NewProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	slog.Info("tracing enabled", "exporter", "otlp/http", "service", serviceName)
+
+	return tp.Shutdown, nil
+}
+
+// NewProvider builds a tracer provider with the given options. Tests pass
+// sdktrace.WithSyncer(tracetest.NewInMemoryExporter()) to capture spans.
+func NewProvider(opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+// TraceID returns the hex trace ID of the span in ctx, or "" when ctx carries
+// no valid span.
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
@@ -0,0 +1,177 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// inMemoryTracer mirrors testutil.InMemoryTracer, which this package cannot
+// import without a cycle through the db package.
+func inMemoryTracer(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		_ = tp.Shutdown(context.Background())
+		otel.SetTracerProvider(prev)
+	})
+	return exporter
+}
+
+func attrValue(attrs []attribute.KeyValue, key string) string {
+	for _, a := range attrs {
+		if string(a.Key) == key {
+			return a.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestInitDisabledIsNoop(t *testing.T) {
+	shutdown, err := Init(context.Background(), false, "golinks")
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+}
+
+func TestLogHandlerAddsTraceID(t *testing.T) {
+	inMemoryTracer(t)
+
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(slog.NewTextHandler(&buf, nil))).With("component", "test")
+
+	ctx, span := Tracer().Start(context.Background(), "op")
+	logger.InfoContext(ctx, "inside span")
+	span.End()
+
+	line := buf.String()
+	if !strings.Contains(line, "trace_id="+span.SpanContext().TraceID().String()) {
+		t.Errorf("log line missing trace_id: %s", line)
+	}
+	if !strings.Contains(line, "span_id="+span.SpanContext().SpanID().String()) {
+		t.Errorf("log line missing span_id: %s", line)
+	}
+
+	buf.Reset()
+	logger.Info("no span")
+	if strings.Contains(buf.String(), "trace_id=") {
+		t.Errorf("log line without span should not have trace_id: %s", buf.String())
+	}
+}
+
+func TestPgxTracerRecordsQueries(t *testing.T) {
+	exporter := inMemoryTracer(t)
+	tracer := PgxTracer{}
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{
+		SQL:  "\n\t\tSELECT id FROM users WHERE sub = $1",
+		Args: []any{"secret-subject"},
+	})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	ctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "UPDATE links SET x = 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("deadlock detected")})
+
+	ctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: pgx.ErrNoRows})
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want 3", len(spans))
+	}
+
+	if spans[0].Name != "db SELECT" {
+		t.Errorf("span name = %q, want %q", spans[0].Name, "db SELECT")
+	}
+	if got := attrValue(spans[0].Attributes, "db.statement"); got != "SELECT id FROM users WHERE sub = $1" {
+		t.Errorf("db.statement = %q", got)
+	}
+	for _, a := range spans[0].Attributes {
+		if strings.Contains(a.Value.Emit(), "secret-subject") {
+			t.Errorf("query arguments must not be recorded, found in %s", a.Key)
+		}
+	}
+
+	if spans[1].Status.Code != codes.Error {
+		t.Errorf("failed query status = %v, want Error", spans[1].Status.Code)
+	}
+	if spans[2].Status.Code == codes.Error {
+		t.Error("pgx.ErrNoRows should not mark the span as failed")
+	}
+}
+
+func TestPgxTracerRecordsBatches(t *testing.T) {
+	exporter := inMemoryTracer(t)
+	tracer := PgxTracer{}
+
+	batch := &pgx.Batch{}
+	batch.Queue("UPDATE links SET click_count = click_count + 1")
+	batch.Queue("UPDATE links SET click_count = click_count + 2")
+
+	ctx := tracer.TraceBatchStart(context.Background(), nil, pgx.TraceBatchStartData{Batch: batch})
+	tracer.TraceBatchQuery(ctx, nil, pgx.TraceBatchQueryData{SQL: "UPDATE links", Err: errors.New("boom")})
+	tracer.TraceBatchEnd(ctx, nil, pgx.TraceBatchEndData{})
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	if got := attrValue(spans[0].Attributes, "db.batch.size"); got != "2" {
+		t.Errorf("db.batch.size = %q, want 2", got)
+	}
+	if len(spans[0].Events) != 1 {
+		t.Errorf("recorded %d error events, want 1", len(spans[0].Events))
+	}
+}
+
+func TestRedisHookTracesCommands(t *testing.T) {
+	exporter := inMemoryTracer(t)
+	hook := RedisHook()
+
+	process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		return nil
+	})
+	if err := process(context.Background(), redis.NewStatusCmd(context.Background(), "set", "click:user:link", 1)); err != nil {
+		t.Fatalf("process() error = %v", err)
+	}
+
+	missing := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		return redis.Nil
+	})
+	_ = missing(context.Background(), redis.NewStringCmd(context.Background(), "get", "session:abc"))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	if spans[0].Name != "redis set" {
+		t.Errorf("span name = %q, want %q", spans[0].Name, "redis set")
+	}
+	for _, s := range spans {
+		for _, a := range s.Attributes {
+			if strings.Contains(a.Value.Emit(), "click:") || strings.Contains(a.Value.Emit(), "session:") {
+				t.Errorf("redis keys must not be recorded, found in %s", a.Key)
+			}
+		}
+	}
+	if spans[1].Status.Code == codes.Error {
+		t.Error("redis.Nil should not mark the span as failed")
+	}
+}
@@ -0,0 +1,141 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golinks/internal/models"
+)
+
+// cssColorRe matches hex colors (#rgb, #rrggbb, #rrggbbaa).
+var cssColorRe = regexp.MustCompile(`^#[0-9a-fA-F]{3,8}$`)
+
+// cssNamedColors is the set of valid CSS named colours. Restricting to known
+// names prevents arbitrary alphabetic strings from being injected into HTML.
+var cssNamedColors = map[string]bool{
+	"black": true, "white": true, "red": true, "green": true, "blue": true,
+	"yellow": true, "orange": true, "purple": true, "pink": true, "brown": true,
+	"gray": true, "grey": true, "cyan": true, "magenta": true, "lime": true,
+	"maroon": true, "navy": true, "olive": true, "teal": true, "aqua": true,
+	"coral": true, "salmon": true, "gold": true, "indigo": true, "violet": true,
+	"transparent": true,
+}
+
+// SanitizeCSSColor validates a color value, returning the fallback if invalid.
+// Allows hex colors (#rgb / #rrggbb / #rrggbbaa) and a whitelist of CSS named colors.
+// Organization branding colors go through the same rules.
+func SanitizeCSSColor(val, fallback string) string {
+	val = strings.TrimSpace(val)
+	if cssColorRe.MatchString(val) {
+		return val
+	}
+	if cssNamedColors[strings.ToLower(val)] {
+		return val
+	}
+	return fallback
+}
+
+// ValidateOrgBranding checks normalized organization branding before it is
+// saved: colors must pass SanitizeCSSColor, and the logo must be a path on
+// this site or an http(s) URL that the img-src sources (CSP_IMG_SOURCES)
+// let browsers load. It returns an error describing the first invalid value.
+func ValidateOrgBranding(b *models.OrgBranding, imgSources []string) error {
+	if b.LogoURL != "" && !isSitePath(b.LogoURL) {
+		if ok, msg := ValidateURL(b.LogoURL); !ok {
+			return fmt.Errorf("logo: %s", msg)
+		}
+		u, _ := url.Parse(b.LogoURL)
+		if !imgSourcesAllow(imgSources, u) {
+			return fmt.Errorf("logo: images from %s are blocked by the site's content security policy; use a path on this site such as /static/logo.svg, or ask an admin to add the host to CSP_IMG_SOURCES", u.Host)
+		}
+	}
+
+	for _, c := range []struct {
+		name, val string
+	}{
+		{"accent color", b.AccentColor},
+		{"banner text color", b.BannerTextColor},
+		{"banner background color", b.BannerBGColor},
+	} {
+		if c.val != "" && SanitizeCSSColor(c.val, "") == "" {
+			return fmt.Errorf("invalid %s %q: use a hex color such as #0891b2 or a basic color name", c.name, c.val)
+		}
+	}
+	return nil
+}
+
+// isSitePath reports whether s is an absolute path on this site, such as
+// /static/logo.svg (but not the protocol-relative //host/logo.svg).
+func isSitePath(s string) bool {
+	return strings.HasPrefix(s, "/") && !strings.HasPrefix(s, "//") && !strings.Contains(s, `\`)
+}
+
+// imgSourcesAllow reports whether any of the CSP source expressions in
+// sources matches the http(s) URL u: "*", a scheme such as "https:", or a
+// host with optional scheme, port and path, such as "https://*.example.com".
+// Keywords like 'self' never match another host.
+func imgSourcesAllow(sources []string, u *url.URL) bool {
+	for _, src := range sources {
+		if cspSourceMatches(src, u) {
+			return true
+		}
+	}
+	return false
+}
+
+func cspSourceMatches(src string, u *url.URL) bool {
+	scheme := strings.ToLower(u.Scheme)
+	switch {
+	case src == "*":
+		return true
+	case strings.HasPrefix(src, "'"):
+		return false
+	case strings.HasSuffix(src, ":") && !strings.Contains(src, "/"):
+		return schemeMatches(strings.ToLower(strings.TrimSuffix(src, ":")), scheme)
+	}
+
+	if srcScheme, rest, ok := strings.Cut(src, "://"); ok {
+		if !schemeMatches(strings.ToLower(srcScheme), scheme) {
+			return false
+		}
+		src = rest
+	}
+	hostPort, path, hasPath := strings.Cut(src, "/")
+	host, port, hasPort := strings.Cut(strings.ToLower(hostPort), ":")
+
+	urlHost := strings.ToLower(u.Hostname())
+	if suffix, ok := strings.CutPrefix(host, "*."); ok {
+		if !strings.HasSuffix(urlHost, "."+suffix) {
+			return false
+		}
+	} else if host != urlHost {
+		return false
+	}
+
+	if hasPort && port != "*" {
+		urlPort := u.Port()
+		if urlPort == "" {
+			urlPort = map[string]string{"http": "80", "https": "443"}[scheme]
+		}
+		if port != urlPort {
+			return false
+		}
+	}
+
+	if hasPath && path != "" {
+		path = "/" + path
+		if strings.HasSuffix(path, "/") {
+			return strings.HasPrefix(u.Path, path)
+		}
+		return u.Path == path
+	}
+	return true
+}
+
+// schemeMatches reports whether a source's scheme allows the URL's: http
+// sources also allow https, as browsers upgrade them.
+func schemeMatches(srcScheme, scheme string) bool {
+	return srcScheme == scheme || (srcScheme == "http" && scheme == "https")
+}
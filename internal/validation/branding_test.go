@@ -0,0 +1,66 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"golinks/internal/models"
+)
+
+func TestSanitizeCSSColor(t *testing.T) {
+	tests := []struct {
+		val, want string
+	}{
+		{"#0891b2", "#0891b2"},
+		{" #fff ", "#fff"},
+		{"Navy", "Navy"},
+		{"rebeccapurple", "fallback"},
+		{"red; background: url(x)", "fallback"},
+		{"", "fallback"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeCSSColor(tt.val, "fallback"); got != tt.want {
+			t.Errorf("SanitizeCSSColor(%q) = %q, want %q", tt.val, got, tt.want)
+		}
+	}
+}
+
+func TestValidateOrgBranding(t *testing.T) {
+	imgSources := []string{"https://cdn.example.com", "*.assets.example.net", "https://static.example.org/brand/", "'self'"}
+
+	tests := []struct {
+		name     string
+		branding models.OrgBranding
+		wantErr  string
+	}{
+		{"empty", models.OrgBranding{}, ""},
+		{"site path logo", models.OrgBranding{LogoURL: "/static/acme.svg"}, ""},
+		{"allowed host", models.OrgBranding{LogoURL: "https://cdn.example.com/acme.svg"}, ""},
+		{"allowed wildcard host", models.OrgBranding{LogoURL: "https://img.assets.example.net/acme.png"}, ""},
+		{"allowed path", models.OrgBranding{LogoURL: "https://static.example.org/brand/acme.svg"}, ""},
+		{"colors", models.OrgBranding{AccentColor: "#ff6600", BannerTextColor: "white", BannerBGColor: "navy"}, ""},
+		{"off-site logo", models.OrgBranding{LogoURL: "https://evil.example.com/logo.svg"}, "CSP_IMG_SOURCES"},
+		{"other path on allowed host", models.OrgBranding{LogoURL: "https://static.example.org/other/logo.svg"}, "CSP_IMG_SOURCES"},
+		{"wildcard apex", models.OrgBranding{LogoURL: "https://assets.example.net/logo.svg"}, "CSP_IMG_SOURCES"},
+		{"javascript logo", models.OrgBranding{LogoURL: "javascript:alert(1)"}, "logo"},
+		{"protocol-relative logo", models.OrgBranding{LogoURL: "//evil.example.com/logo.svg"}, "logo"},
+		{"css injection", models.OrgBranding{AccentColor: "red; background: url(x)"}, "accent color"},
+		{"unknown color name", models.OrgBranding{BannerTextColor: "rebeccapurple"}, "banner text color"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOrgBranding(&tt.branding, imgSources)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("ValidateOrgBranding() error = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("ValidateOrgBranding() error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Without CSP_IMG_SOURCES only site paths work.
+	if err := ValidateOrgBranding(&models.OrgBranding{LogoURL: "https://cdn.example.com/acme.svg"}, nil); err == nil {
+		t.Error("ValidateOrgBranding() accepted an off-site logo with no img-src sources")
+	}
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_notifications_read_created;
+DROP TABLE IF EXISTS click_history_daily;
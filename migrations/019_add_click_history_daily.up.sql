@@ -0,0 +1,15 @@
+-- Daily roll-up of click_history. The retention job moves hourly buckets older
+-- than CLICK_HISTORY_RETENTION_DAYS into this table so long-range analytics keep
+-- working while the hourly table stays small.
+CREATE TABLE IF NOT EXISTS click_history_daily (
+    link_id     UUID NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    day         DATE NOT NULL,
+    click_count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (link_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_click_history_daily_day ON click_history_daily(day);
+
+-- Supports the read-notification purge (read = TRUE AND created_at < cutoff).
+CREATE INDEX IF NOT EXISTS idx_notifications_read_created
+    ON notifications(created_at) WHERE read = TRUE;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS user_link_click_history;
@@ -0,0 +1,19 @@
+-- Hourly click buckets for personal links, mirroring click_history for links.
+-- Powers the sparklines and "recently used" ordering on /my-links.
+CREATE TABLE IF NOT EXISTS user_link_click_history (
+    user_link_id UUID NOT NULL REFERENCES user_links(id) ON DELETE CASCADE,
+    hour_bucket  TIMESTAMP WITH TIME ZONE NOT NULL,
+    click_count  INTEGER NOT NULL DEFAULT 1,
+    UNIQUE (user_link_id, hour_bucket)
+);
+
+-- Supports the retention purge (hour_bucket < cutoff).
+CREATE INDEX IF NOT EXISTS idx_user_link_click_history_hour
+    ON user_link_click_history(hour_bucket);
+
+-- Same batched upsert pattern as click_history, so the same autovacuum tuning.
+ALTER TABLE user_link_click_history SET (
+    autovacuum_vacuum_scale_factor  = 0.01,
+    autovacuum_analyze_scale_factor = 0.005,
+    autovacuum_vacuum_cost_delay    = 2
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS dismissed_wanted_keywords;
+DROP TABLE IF EXISTS missing_keyword_lookups;
@@ -0,0 +1,35 @@
+-- Missing keyword demand per organization. Every not_found/fallback lookup is
+-- counted against the requester's organization (NULL for users without one)
+-- to power the "most wanted" report at /browse/wanted.
+CREATE TABLE IF NOT EXISTS missing_keyword_lookups (
+    keyword         TEXT NOT NULL,
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    count           BIGINT NOT NULL DEFAULT 0,
+    last_seen_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- NULL organization_id must still collide on upsert, so the unique key maps it
+-- to the nil UUID.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_missing_keyword_lookups_key
+    ON missing_keyword_lookups(keyword, COALESCE(organization_id, '00000000-0000-0000-0000-000000000000'::uuid));
+
+CREATE INDEX IF NOT EXISTS idx_missing_keyword_lookups_org_count
+    ON missing_keyword_lookups(organization_id, count DESC);
+
+ALTER TABLE missing_keyword_lookups SET (
+    autovacuum_vacuum_scale_factor  = 0.01,
+    autovacuum_analyze_scale_factor = 0.005,
+    autovacuum_vacuum_cost_delay    = 2
+);
+
+-- Keywords moderators dismissed from the report. organization_id NULL hides the
+-- keyword everywhere (global moderators); otherwise only for that organization.
+CREATE TABLE IF NOT EXISTS dismissed_wanted_keywords (
+    keyword         TEXT NOT NULL,
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    dismissed_by    UUID REFERENCES users(id) ON DELETE SET NULL,
+    dismissed_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dismissed_wanted_keywords_key
+    ON dismissed_wanted_keywords(keyword, COALESCE(organization_id, '00000000-0000-0000-0000-000000000000'::uuid));
@@ -0,0 +1,20 @@
+-- Signed-in browser sessions, tracked so users and admins can see where an
+-- account is signed in and revoke sessions remotely. The session itself lives
+-- in the session store (memory or Redis); only a SHA-256 of its ID is kept
+-- here so a database dump can't be replayed as cookies.
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_hash TEXT NOT NULL UNIQUE,
+    user_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    ip_address   TEXT NOT NULL DEFAULT '',
+    user_agent   TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user
+    ON user_sessions(user_id, last_seen_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_created_at
+    ON user_sessions(created_at);
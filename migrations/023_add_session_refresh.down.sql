@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_user_sessions_expires_at;
+DROP INDEX IF EXISTS idx_user_sessions_oidc_sid;
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_created_at
+    ON user_sessions(created_at);
+
+ALTER TABLE user_sessions
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS refreshed_at,
+    DROP COLUMN IF EXISTS refresh_token,
+    DROP COLUMN IF EXISTS oidc_sid;
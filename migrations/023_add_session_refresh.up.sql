@@ -0,0 +1,22 @@
+-- Sliding OIDC sessions and back-channel logout. expires_at is the session's
+-- current end, pushed forward each time the refresh token is redeemed;
+-- oidc_sid is the provider's session ID named in back-channel logout tokens.
+-- refresh_token is sealed with a key derived from SESSION_SECRET.
+ALTER TABLE user_sessions
+    ADD COLUMN IF NOT EXISTS oidc_sid      TEXT,
+    ADD COLUMN IF NOT EXISTS refresh_token TEXT,
+    ADD COLUMN IF NOT EXISTS refreshed_at  TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS expires_at    TIMESTAMPTZ;
+
+UPDATE user_sessions SET expires_at = created_at + INTERVAL '24 hours' WHERE expires_at IS NULL;
+
+ALTER TABLE user_sessions
+    ALTER COLUMN expires_at SET NOT NULL,
+    ALTER COLUMN expires_at SET DEFAULT NOW() + INTERVAL '24 hours';
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_oidc_sid
+    ON user_sessions(oidc_sid) WHERE oidc_sid IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_user_sessions_created_at;
+CREATE INDEX IF NOT EXISTS idx_user_sessions_expires_at
+    ON user_sessions(expires_at);
@@ -0,0 +1,8 @@
+-- Fails if two providers share a subject or username; merge or delete those
+-- users first.
+DROP INDEX IF EXISTS idx_users_issuer_username;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users(username) WHERE username IS NOT NULL;
+DROP INDEX IF EXISTS idx_users_issuer_sub;
+ALTER TABLE users ADD CONSTRAINT users_sub_key UNIQUE (sub);
+CREATE INDEX IF NOT EXISTS idx_users_sub ON users(sub);
+ALTER TABLE users DROP COLUMN IF EXISTS issuer;
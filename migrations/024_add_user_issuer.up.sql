@@ -0,0 +1,13 @@
+-- Users are identified by (issuer, sub): subjects are only unique per identity
+-- provider. Existing users get an empty issuer until the server starts and
+-- assigns them to the primary provider.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS issuer TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_sub_key;
+DROP INDEX IF EXISTS idx_users_sub;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_issuer_sub ON users(issuer, sub);
+
+-- Usernames (preferred_username, PKI and proxy names) are also only unique per
+-- provider: two providers may each have a jsmith.
+DROP INDEX IF EXISTS idx_users_username;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_issuer_username ON users(issuer, username) WHERE username IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS scim_group_members;
+DROP TABLE IF EXISTS scim_groups;
+ALTER TABLE users DROP COLUMN IF EXISTS deactivated_at;
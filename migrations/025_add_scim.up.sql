@@ -0,0 +1,21 @@
+-- Deactivated users keep their links and attribution but can't sign in.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+
+-- Groups pushed by a SCIM client. Groups named with SCIM_ORG_GROUP_PREFIX map
+-- members onto organizations; the OIDC admin and moderator groups map them
+-- onto roles.
+CREATE TABLE IF NOT EXISTS scim_groups (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    display_name TEXT NOT NULL UNIQUE,
+    external_id  TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS scim_group_members (
+    group_id UUID NOT NULL REFERENCES scim_groups(id) ON DELETE CASCADE,
+    user_id  UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scim_group_members_user ON scim_group_members(user_id);
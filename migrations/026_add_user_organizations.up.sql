@@ -0,0 +1,19 @@
+-- Users can belong to several organizations, each with its own role.
+-- users.organization_id remains the active organization: the one new links
+-- are created in and moderation applies to. It is always one of the user's
+-- memberships.
+CREATE TABLE IF NOT EXISTS user_organizations (
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    role            TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('member', 'org_mod')),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, organization_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_organizations_org ON user_organizations(organization_id);
+
+INSERT INTO user_organizations (user_id, organization_id, role, created_at)
+SELECT id, organization_id, CASE WHEN role = 'org_mod' THEN 'org_mod' ELSE 'member' END, created_at
+FROM users
+WHERE organization_id IS NOT NULL
+ON CONFLICT DO NOTHING;
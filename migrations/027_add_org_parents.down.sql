@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_organizations_parent;
+ALTER TABLE organizations DROP COLUMN IF EXISTS parent_id;
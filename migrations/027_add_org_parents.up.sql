@@ -0,0 +1,9 @@
+-- Organizations can be nested (division > department > team). Links of an
+-- organization are inherited by its descendants, and org moderators of an
+-- organization also moderate its descendants. Cycles are rejected by the
+-- application when a parent is set.
+ALTER TABLE organizations
+    ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES organizations(id) ON DELETE SET NULL,
+    ADD CONSTRAINT organizations_parent_not_self CHECK (parent_id <> id);
+
+CREATE INDEX IF NOT EXISTS idx_organizations_parent ON organizations(parent_id);
@@ -0,0 +1,14 @@
+-- Per-organization rules for org links. Organizations without a row use the
+-- defaults: submissions need approval, any destination and keyword are
+-- allowed, and submission emails go to the organization's moderators.
+CREATE TABLE IF NOT EXISTS org_settings (
+    organization_id    UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    require_moderation BOOLEAN NOT NULL DEFAULT TRUE,
+    allowed_domains    TEXT[] NOT NULL DEFAULT '{}',
+    keyword_prefix     TEXT NOT NULL DEFAULT '',
+    keyword_pattern    TEXT NOT NULL DEFAULT '',
+    notify_moderators  BOOLEAN NOT NULL DEFAULT TRUE,
+    notify_emails      TEXT[] NOT NULL DEFAULT '{}',
+    updated_by         UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,14 @@
+-- Per-organization branding shown to the organization's members in place of
+-- SITE_TITLE, SITE_LOGO_URL and the banner settings. Empty values fall back
+-- to the enclosing organization's branding, then to the site-wide settings.
+CREATE TABLE IF NOT EXISTS org_branding (
+    organization_id   UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    site_title        TEXT NOT NULL DEFAULT '',
+    logo_url          TEXT NOT NULL DEFAULT '',
+    accent_color      TEXT NOT NULL DEFAULT '',
+    banner_text       TEXT NOT NULL DEFAULT '',
+    banner_text_color TEXT NOT NULL DEFAULT '',
+    banner_bg_color   TEXT NOT NULL DEFAULT '',
+    updated_by        UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
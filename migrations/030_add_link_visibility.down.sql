@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN IF EXISTS groups;
+ALTER TABLE links
+    DROP COLUMN IF EXISTS allowed_users,
+    DROP COLUMN IF EXISTS allowed_groups,
+    DROP COLUMN IF EXISTS visibility;
@@ -0,0 +1,14 @@
+-- Who can see and resolve an approved link. public links are visible to
+-- everyone, including signed-out users; authenticated links to any signed-in
+-- user; org links only to direct members of the link's organization; and
+-- restricted links only to the listed users (by email) and groups. Authors
+-- and admins always see their links.
+ALTER TABLE links
+    ADD COLUMN IF NOT EXISTS visibility TEXT NOT NULL DEFAULT 'public'
+        CHECK (visibility IN ('public', 'authenticated', 'org', 'restricted')),
+    ADD COLUMN IF NOT EXISTS allowed_groups TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS allowed_users TEXT[] NOT NULL DEFAULT '{}';
+
+-- The groups claim from each user's last sign-in, matched against
+-- links.allowed_groups alongside SCIM group memberships.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS groups TEXT[] NOT NULL DEFAULT '{}';
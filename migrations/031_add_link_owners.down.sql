@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS link_owner_events;
+DROP TABLE IF EXISTS link_owners;
@@ -0,0 +1,32 @@
+-- Co-owners of a link: a user, a group (OIDC groups claim or SCIM group name)
+-- or an organization whose direct members own it. Owners edit the link
+-- without moderation, approve edit suggestions for it and change its owners.
+CREATE TABLE IF NOT EXISTS link_owners (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    link_id         UUID NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    user_id         UUID REFERENCES users(id) ON DELETE CASCADE,
+    group_name      TEXT,
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    created_by      UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (num_nonnulls(user_id, group_name, organization_id) = 1)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_link_owners_user ON link_owners (link_id, user_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_link_owners_group ON link_owners (link_id, group_name) WHERE group_name IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_link_owners_org ON link_owners (link_id, organization_id) WHERE organization_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_link_owners_user_id ON link_owners (user_id) WHERE user_id IS NOT NULL;
+
+-- Every owner added to or removed from a link. The owner is kept by name so
+-- the history survives deleted users and organizations.
+CREATE TABLE IF NOT EXISTS link_owner_events (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    link_id    UUID NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    actor_id   UUID REFERENCES users(id) ON DELETE SET NULL,
+    action     TEXT NOT NULL CHECK (action IN ('added', 'removed')),
+    owner_kind TEXT NOT NULL CHECK (owner_kind IN ('user', 'group', 'org')),
+    owner_name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_owner_events_link ON link_owner_events (link_id, created_at DESC);
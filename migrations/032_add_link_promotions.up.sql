@@ -0,0 +1,19 @@
+-- Personal links proposed for org or global scope. Each row belongs to the
+-- pending link submitted for review and snapshots the personal link's usage
+-- as evidence for the moderator. When the link is approved the personal
+-- link's click history moves to it, and the personal link is deleted unless
+-- the submitter chose to keep it.
+CREATE TABLE IF NOT EXISTS link_promotions (
+    link_id             UUID PRIMARY KEY REFERENCES links(id) ON DELETE CASCADE,
+    user_link_id        UUID REFERENCES user_links(id) ON DELETE SET NULL,
+    user_id             UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    click_count         BIGINT NOT NULL DEFAULT 0,
+    recent_clicks       BIGINT NOT NULL DEFAULT 0,
+    last_used_at        TIMESTAMPTZ,
+    personal_created_at TIMESTAMPTZ NOT NULL,
+    keep_personal       BOOLEAN NOT NULL DEFAULT FALSE,
+    applied_at          TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_promotions_user_link ON link_promotions (user_link_id) WHERE user_link_id IS NOT NULL;